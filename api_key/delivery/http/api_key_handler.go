@@ -0,0 +1,94 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sicozz/papyrus/auth/delivery/http/middleware"
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/domain/dtos"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+	"github.com/sicozz/papyrus/utils/delivery"
+)
+
+// ApiKeyHandler will initialize the API key endpoints
+type ApiKeyHandler struct {
+	KUsecase domain.ApiKeyUsecase
+	log      utils.AggregatedLogger
+}
+
+func NewApiKeyHandler(e *echo.Echo, ku domain.ApiKeyUsecase, authMw echo.MiddlewareFunc, authz *middleware.AuthzMiddleware) {
+	logger := utils.NewAggregatedLogger(constants.Delivery, constants.ApiKey)
+	handler := &ApiKeyHandler{ku, logger}
+	self := authz.Require(middleware.Self("uname"))
+	selfOrRead := authz.Require(middleware.Self("uname"), middleware.Can(domain.PermUserRead))
+	selfOrWrite := authz.Require(middleware.Self("uname"), middleware.Can(domain.PermUserWrite))
+	g := e.Group("/user/:uname/api_key", authMw)
+	g.GET("", handler.Fetch, selfOrRead)
+	g.POST("", handler.Store, self)
+	g.DELETE("/:uuid", handler.Revoke, selfOrWrite)
+}
+
+func (h *ApiKeyHandler) Fetch(c echo.Context) error {
+	h.log.Info("REQ: fetch")
+	ctx := c.Request().Context()
+	uname := c.Param("uname")
+	keys, rErr := h.KUsecase.Fetch(ctx, uname)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.JSON(http.StatusOK, keys)
+}
+
+func (h *ApiKeyHandler) Store(c echo.Context) error {
+	h.log.Info("REQ: store")
+	ctx := c.Request().Context()
+	uname := c.Param("uname")
+
+	var kDto dtos.ApiKeyDto
+	err := c.Bind(&kDto)
+	if err != nil {
+		errBody := dtos.NewErrDto(fmt.Sprint("Req body binding failed: ", err))
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	if ok, err := delivery.IsRequestValid(&kDto); !ok {
+		errBody, err := dtos.NewValidationErrDto(err.Error())
+		if err != nil {
+			errValid := dtos.NewErrDto(fmt.Sprint("Req body validation failed: ", err))
+			return c.JSON(http.StatusBadRequest, errValid)
+		}
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	k := domain.ApiKey{
+		Name:      kDto.Name,
+		Scopes:    kDto.Scopes,
+		ExpiresAt: kDto.ExpiresAt,
+	}
+	res, rErr := h.KUsecase.Store(ctx, uname, k)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.JSON(http.StatusCreated, res)
+}
+
+func (h *ApiKeyHandler) Revoke(c echo.Context) error {
+	h.log.Info("REQ: revoke")
+	ctx := c.Request().Context()
+	uname := c.Param("uname")
+	uuid := c.Param("uuid")
+	rErr := h.KUsecase.Revoke(ctx, uname, uuid)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.NoContent(http.StatusOK)
+}
@@ -0,0 +1,166 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+	"github.com/sicozz/papyrus/utils/repository"
+)
+
+type postgresApiKeyRepository struct {
+	Conn *sql.DB
+	log  utils.AggregatedLogger
+}
+
+/*
+* NewPostgresApiKeyRepository will create an object that represent the
+* ApiKeyRepository interface
+ */
+func NewPostgresApiKeyRepository(conn *sql.DB) domain.ApiKeyRepository {
+	logger := utils.NewAggregatedLogger(constants.Repository, constants.ApiKey)
+	return &postgresApiKeyRepository{conn, logger}
+}
+
+func (r *postgresApiKeyRepository) fetch(ctx context.Context, query string, args ...interface{}) (res []domain.ApiKey, err error) {
+	rows, err := r.Conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		r.log.Error(err)
+		return nil, repository.MapErr(err)
+	}
+
+	defer func() {
+		errRow := rows.Close()
+		if errRow != nil {
+			r.log.Error(errRow)
+		}
+	}()
+
+	res = make([]domain.ApiKey, 0)
+	for rows.Next() {
+		t := domain.ApiKey{}
+		var expiresAt, lastUsedAt sql.NullTime
+		// Get from db
+		err = rows.Scan(
+			&t.Uuid,
+			&t.UserUuid,
+			&t.Name,
+			&t.Prefix,
+			&t.KeyHash,
+			pq.Array(&t.Scopes),
+			&expiresAt,
+			&lastUsedAt,
+			&t.CreatedAt,
+			&t.Revoked,
+		)
+
+		if err != nil {
+			r.log.Error("IN [fetch]:", err)
+			return nil, err
+		}
+		if expiresAt.Valid {
+			t.ExpiresAt = &expiresAt.Time
+		}
+		if lastUsedAt.Valid {
+			t.LastUsedAt = &lastUsedAt.Time
+		}
+		res = append(res, t)
+	}
+
+	return res, rows.Err()
+}
+
+// Get every key of a user, newest first
+func (r *postgresApiKeyRepository) GetByUser(ctx context.Context, userUuid string) (res []domain.ApiKey, err error) {
+	query :=
+		`SELECT uuid, user_, name, prefix, key_hash, scopes, expires_at, last_used_at, created_at, revoked
+		FROM api_key
+		WHERE user_ = $1
+		ORDER BY created_at DESC`
+
+	res, err = r.fetch(ctx, query, userUuid)
+	if err != nil {
+		r.log.Error("IN [GetByUser]: could not fetch api keys ->", err)
+	}
+
+	return
+}
+
+// Get key by the hash of its secret
+func (r *postgresApiKeyRepository) GetByHash(ctx context.Context, hash string) (res domain.ApiKey, err error) {
+	query :=
+		`SELECT uuid, user_, name, prefix, key_hash, scopes, expires_at, last_used_at, created_at, revoked
+		FROM api_key
+		WHERE key_hash = $1`
+
+	keys, err := r.fetch(ctx, query, hash)
+	if err != nil {
+		return domain.ApiKey{}, err
+	}
+
+	if len(keys) < 1 {
+		return domain.ApiKey{}, domain.ErrNotFound
+	}
+
+	res = keys[0]
+
+	return
+}
+
+// Store a new key
+func (r *postgresApiKeyRepository) Store(ctx context.Context, k *domain.ApiKey) (err error) {
+	query :=
+		`INSERT INTO api_key (user_, name, prefix, key_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING uuid, created_at`
+	stmt, err := r.Conn.PrepareContext(ctx, query)
+	if err != nil {
+		r.log.Error("IN [Store]: could not prepare context ->", err)
+		return
+	}
+	defer stmt.Close()
+
+	err = stmt.QueryRowContext(
+		ctx,
+		k.UserUuid,
+		k.Name,
+		k.Prefix,
+		k.KeyHash,
+		pq.Array(k.Scopes),
+		k.ExpiresAt,
+	).Scan(&k.Uuid, &k.CreatedAt)
+
+	return
+}
+
+// Revoke a key of a user. Reports false when no active key matched
+func (r *postgresApiKeyRepository) Revoke(ctx context.Context, userUuid string, uuid string) (res bool, err error) {
+	query := `UPDATE api_key SET revoked=true WHERE uuid=$1 AND user_=$2 AND NOT revoked`
+	result, err := r.Conn.ExecContext(ctx, query, uuid, userUuid)
+	if err != nil {
+		r.log.Error("IN [Revoke]: could not revoke api key ->", err)
+		return
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return
+	}
+
+	return n == 1, nil
+}
+
+// Record a use of a key
+func (r *postgresApiKeyRepository) Touch(ctx context.Context, uuid string, date time.Time) (err error) {
+	query := `UPDATE api_key SET last_used_at=$2 WHERE uuid=$1`
+	_, err = r.Conn.ExecContext(ctx, query, uuid, date)
+	if err != nil {
+		r.log.Error("IN [Touch]: could not record api key use ->", err)
+	}
+
+	return
+}
@@ -0,0 +1,188 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+	"github.com/sicozz/papyrus/utils/token"
+)
+
+// prefixLen is the number of leading key characters kept to identify a key
+const prefixLen = 12
+
+type apiKeyUsecase struct {
+	apiKeyRepo     domain.ApiKeyRepository
+	userUcase      domain.UserUsecase
+	authorizer     domain.Authorizer
+	clock          domain.Clock
+	contextTimeout time.Duration
+	log            utils.AggregatedLogger
+}
+
+// NewApiKeyUsecase will create a new apiKeyUsecase object representation of domain.ApiKeyUsecase interface
+func NewApiKeyUsecase(
+	kr domain.ApiKeyRepository,
+	uu domain.UserUsecase,
+	az domain.Authorizer,
+	clk domain.Clock,
+	timeout time.Duration,
+) domain.ApiKeyUsecase {
+	logger := utils.NewAggregatedLogger(constants.Usecase, constants.ApiKey)
+	return &apiKeyUsecase{
+		apiKeyRepo:     kr,
+		userUcase:      uu,
+		authorizer:     az,
+		clock:          clk,
+		contextTimeout: timeout,
+		log:            logger,
+	}
+}
+
+func (u *apiKeyUsecase) Fetch(c context.Context, uname string) (res []domain.ApiKey, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	user, rErr := u.userUcase.GetByUsername(ctx, uname)
+	if rErr != nil {
+		return
+	}
+
+	res, err := u.apiKeyRepo.GetByUser(ctx, user.Uuid)
+	if err != nil {
+		err = errors.New("Api key fetch failed")
+		rErr = domain.NewUCaseErr(http.StatusInternalServerError, err)
+		return
+	}
+
+	return
+}
+
+func (u *apiKeyUsecase) Store(c context.Context, uname string, k domain.ApiKey) (res domain.ApiKeySecret, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	user, rErr := u.userUcase.GetByUsername(ctx, uname)
+	if rErr != nil {
+		return
+	}
+
+	if k.ExpiresAt != nil {
+		exp := k.ExpiresAt.UTC()
+		if !exp.After(u.clock.Now()) {
+			err := errors.New("Expiry must be in the future")
+			rErr = domain.NewUCaseErr(http.StatusBadRequest, err)
+			return
+		}
+		k.ExpiresAt = &exp
+	}
+
+	// A key can never grant more than its owner holds
+	if k.Scopes == nil {
+		k.Scopes = []string{}
+	}
+	for _, s := range k.Scopes {
+		allowed, err := u.authorizer.Can(ctx, user, s)
+		if err != nil {
+			u.log.Error("IN [Store]: could not check scope {", s, "} ->", err)
+			rErr = domain.NewUCaseErr(http.StatusInternalServerError, err)
+			return
+		}
+		if !allowed {
+			err = errors.New(fmt.Sprint("Scope not granted to the user: ", s))
+			rErr = domain.NewUCaseErr(http.StatusForbidden, err)
+			return
+		}
+	}
+
+	secret, err := token.New()
+	if err != nil {
+		u.log.Error("IN [Store]: could not generate key ->", err)
+		rErr = domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Api key creation failed"))
+		return
+	}
+
+	key := domain.ApiKeyPrefix + secret
+	k.UserUuid = user.Uuid
+	k.Prefix = key[:prefixLen]
+	k.KeyHash = token.Hash(key)
+	k.Revoked = false
+	k.LastUsedAt = nil
+	if err = u.apiKeyRepo.Store(ctx, &k); err != nil {
+		u.log.Error("IN [Store]: could not store api key ->", err)
+		rErr = domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Api key creation failed"))
+		return
+	}
+
+	res = domain.ApiKeySecret{ApiKey: k, Key: key}
+	return
+}
+
+func (u *apiKeyUsecase) Revoke(c context.Context, uname string, uuid string) (rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	user, rErr := u.userUcase.GetByUsername(ctx, uname)
+	if rErr != nil {
+		return
+	}
+
+	revoked, err := u.apiKeyRepo.Revoke(ctx, user.Uuid, uuid)
+	if err != nil {
+		rErr = domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Api key revocation failed"))
+		return
+	}
+	if !revoked {
+		err = errors.New(fmt.Sprint("Api key not found. uuid: ", uuid))
+		rErr = domain.NewUCaseErr(http.StatusNotFound, err)
+		return
+	}
+
+	return
+}
+
+func (u *apiKeyUsecase) Authenticate(c context.Context, key string) (res domain.User, k domain.ApiKey, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	invalidErr := domain.NewUCaseErr(http.StatusUnauthorized, errors.New("Invalid api key"))
+
+	k, err := u.apiKeyRepo.GetByHash(ctx, token.Hash(key))
+	if errors.Is(err, domain.ErrNotFound) {
+		return res, k, invalidErr
+	}
+	if err != nil {
+		u.log.Error("IN [Authenticate]: could not get api key ->", err)
+		return res, k, domain.NewRepoErr(err, "")
+	}
+
+	now := u.clock.Now()
+	if k.Revoked || (k.ExpiresAt != nil && now.After(*k.ExpiresAt)) {
+		return res, k, invalidErr
+	}
+
+	res, rErr = u.userUcase.GetByUuid(ctx, k.UserUuid)
+	if rErr != nil {
+		if rErr.GetStatus() == http.StatusNotFound {
+			rErr = invalidErr
+		}
+		return
+	}
+
+	if res.State.Description != domain.UserStateActive {
+		err = errors.New("Account not active")
+		return res, k, domain.NewUCaseErr(http.StatusForbidden, err)
+	}
+
+	if err = u.apiKeyRepo.Touch(ctx, k.Uuid, now); err != nil {
+		u.log.Warn("IN [Authenticate]: could not record use of key {", k.Prefix, "}")
+	}
+	k.LastUsedAt = &now
+
+	return
+}
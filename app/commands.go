@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	_dirRepo "github.com/sicozz/papyrus/dir/repository/postgres"
+	_dirUsecase "github.com/sicozz/papyrus/dir/usecase"
+	"github.com/sicozz/papyrus/domain"
+	_permissionRepo "github.com/sicozz/papyrus/permission/repository/postgres"
+	_permissionUsecase "github.com/sicozz/papyrus/permission/usecase"
+	_projectRepo "github.com/sicozz/papyrus/project/repository/postgres"
+)
+
+// commandTimeout bounds maintenance commands, which may walk whole tables
+const commandTimeout = 5 * time.Minute
+
+// runCommand executes a maintenance subcommand instead of serving http
+func runCommand(dbConn *sql.DB, args []string) {
+	switch args[0] {
+	case "dir-check":
+		runDirCheck(dbConn, args[1:])
+	case "migrate":
+		runMigrate(dbConn, args[1:])
+	default:
+		log.Fatalf("unknown command %q, available: dir-check, migrate", args[0])
+	}
+}
+
+/*
+* runMigrate applies (up), reverts (down) or lists (status) the embedded
+* migrations. Up applies every pending one unless -n bounds them, down
+* reverts the latest one unless -n asks for more
+ */
+func runMigrate(dbConn *sql.DB, args []string) {
+	if len(args) == 0 {
+		log.Fatal("usage: migrate up|down|status [-n N]")
+	}
+
+	fs := flag.NewFlagSet("migrate "+args[0], flag.ExitOnError)
+	n := fs.Int("n", 0, "number of migrations, all pending ones by default for up and 1 for down")
+	_ = fs.Parse(args[1:])
+
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	mg := newMigrator(dbConn)
+	var done []domain.Migration
+	var err error
+	switch args[0] {
+	case "up":
+		done, err = mg.Up(ctx, *n)
+	case "down":
+		if *n == 0 {
+			*n = 1
+		}
+		done, err = mg.Down(ctx, *n)
+	case "status":
+		runMigrateStatus(ctx, mg)
+		return
+	default:
+		log.Fatalf("unknown migrate action %q, available: up, down, status", args[0])
+	}
+
+	for _, m := range done {
+		log.Printf("%s %d_%s", args[0], m.Version, m.Name)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("%d migrations %s", len(done), args[0])
+}
+
+func runMigrateStatus(ctx context.Context, mg domain.Migrator) {
+	status, err := mg.Status(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	pending, unknown := 0, 0
+	for _, s := range status {
+		state := "pending"
+		switch {
+		case s.Applied && !s.Known:
+			state = "unknown, applied " + s.Date.Format(time.RFC3339)
+			unknown++
+		case s.Applied:
+			state = "applied " + s.Date.Format(time.RFC3339)
+		default:
+			pending++
+		}
+		fmt.Printf("%d_%s\t%s\n", s.Version, s.Name, state)
+	}
+	log.Printf("%d migrations, %d pending, %d unknown", len(status), pending, unknown)
+}
+
+/*
+* runDirCheck recomputes the cached path and child_count of every dir and
+* reports the drifted ones. With -repair they are rewritten, otherwise any
+* drift makes the command exit with status 1
+ */
+func runDirCheck(dbConn *sql.DB, args []string) {
+	fs := flag.NewFlagSet("dir-check", flag.ExitOnError)
+	repair := fs.Bool("repair", false, "rewrite the drifted dirs")
+	_ = fs.Parse(args)
+
+	du := _dirUsecase.NewDirUsecase(
+		_dirRepo.NewPostgresDirRepository(dbConn),
+		_projectRepo.NewPostgresProjectRepository(dbConn),
+		_permissionUsecase.NewAuthorizer(_permissionRepo.NewPostgresPermissionRepository(dbConn)),
+		commandTimeout,
+	)
+	drift, rErr := du.Check(context.Background(), *repair)
+	if rErr != nil {
+		log.Fatal(rErr)
+	}
+
+	for _, d := range drift {
+		log.Printf(
+			"dir %s: path %q expected %q, child_count %d expected %d",
+			d.Uuid, d.Path, d.ExpectedPath, d.ChildCount, d.ExpectedChildCount,
+		)
+	}
+	log.Printf("%d drifted dirs, repaired: %v", len(drift), *repair)
+
+	if len(drift) > 0 && !*repair {
+		os.Exit(1)
+	}
+}
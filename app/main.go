@@ -1,29 +1,99 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
 	"net/url"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	_ "github.com/lib/pq"
+	_apiKeyHttpDelivery "github.com/sicozz/papyrus/api_key/delivery/http"
+	_apiKeyRepo "github.com/sicozz/papyrus/api_key/repository/postgres"
+	_apiKeyUsecase "github.com/sicozz/papyrus/api_key/usecase"
+	_auditHttpDelivery "github.com/sicozz/papyrus/audit/delivery/http"
+	_auditRepo "github.com/sicozz/papyrus/audit/repository/postgres"
+	_auditUsecase "github.com/sicozz/papyrus/audit/usecase"
+	_authHttpDelivery "github.com/sicozz/papyrus/auth/delivery/http"
+	_authMiddleware "github.com/sicozz/papyrus/auth/delivery/http/middleware"
+	_authUsecase "github.com/sicozz/papyrus/auth/usecase"
+	_dirHttpDelivery "github.com/sicozz/papyrus/dir/delivery/http"
+	_dirRepo "github.com/sicozz/papyrus/dir/repository/postgres"
+	_dirUsecase "github.com/sicozz/papyrus/dir/usecase"
+	"github.com/sicozz/papyrus/domain"
+	_fileHttpDelivery "github.com/sicozz/papyrus/file/delivery/http"
+	_fileRepo "github.com/sicozz/papyrus/file/repository/postgres"
+	_fileUsecase "github.com/sicozz/papyrus/file/usecase"
+	_fileGrantHttpDelivery "github.com/sicozz/papyrus/file_grant/delivery/http"
+	_fileGrantRepo "github.com/sicozz/papyrus/file_grant/repository/postgres"
+	_fileGrantUsecase "github.com/sicozz/papyrus/file_grant/usecase"
+	_jobRepo "github.com/sicozz/papyrus/job/repository/postgres"
+	_jobUsecase "github.com/sicozz/papyrus/job/usecase"
+	_loginThrottleRepo "github.com/sicozz/papyrus/login_throttle/repository/postgres"
+	_loginThrottleUsecase "github.com/sicozz/papyrus/login_throttle/usecase"
+	_migrationRepo "github.com/sicozz/papyrus/migration/repository/postgres"
+	_migrationUsecase "github.com/sicozz/papyrus/migration/usecase"
+	"github.com/sicozz/papyrus/misc/migrations"
+	_notificationHttpDelivery "github.com/sicozz/papyrus/notification/delivery/http"
+	_notificationRepo "github.com/sicozz/papyrus/notification/repository/postgres"
+	_notificationUsecase "github.com/sicozz/papyrus/notification/usecase"
+	_passwordHttpDelivery "github.com/sicozz/papyrus/password/delivery/http"
+	_passwordUsecase "github.com/sicozz/papyrus/password/usecase"
+	_permissionHttpDelivery "github.com/sicozz/papyrus/permission/delivery/http"
+	_permissionRepo "github.com/sicozz/papyrus/permission/repository/postgres"
+	_permissionUsecase "github.com/sicozz/papyrus/permission/usecase"
+	_planHttpDelivery "github.com/sicozz/papyrus/plan/delivery/http"
+	_planRepo "github.com/sicozz/papyrus/plan/repository/postgres"
+	_planUsecase "github.com/sicozz/papyrus/plan/usecase"
+	_projectHttpDelivery "github.com/sicozz/papyrus/project/delivery/http"
+	_projectRepo "github.com/sicozz/papyrus/project/repository/postgres"
+	_projectUsecase "github.com/sicozz/papyrus/project/usecase"
+	_roleHttpDelivery "github.com/sicozz/papyrus/role/delivery/http"
 	_roleRepo "github.com/sicozz/papyrus/role/repository/postgres"
+	_roleUsecase "github.com/sicozz/papyrus/role/usecase"
+	_sessionRepo "github.com/sicozz/papyrus/session/repository/postgres"
+	_taskHttpDelivery "github.com/sicozz/papyrus/task/delivery/http"
+	_taskRepo "github.com/sicozz/papyrus/task/repository/postgres"
+	_taskUsecase "github.com/sicozz/papyrus/task/usecase"
+	_totpHttpDelivery "github.com/sicozz/papyrus/totp/delivery/http"
+	_totpRepo "github.com/sicozz/papyrus/totp/repository/postgres"
+	_totpUsecase "github.com/sicozz/papyrus/totp/usecase"
+	_uploadSessionRepo "github.com/sicozz/papyrus/upload_session/repository/postgres"
 	_userHttpDelivery "github.com/sicozz/papyrus/user/delivery/http"
 	_userRepo "github.com/sicozz/papyrus/user/repository/postgres"
 	_userUsecase "github.com/sicozz/papyrus/user/usecase"
+	_userStateHttpDelivery "github.com/sicozz/papyrus/user_state/delivery/http"
 	_userStateRepo "github.com/sicozz/papyrus/user_state/repository/postgres"
+	_userStateUsecase "github.com/sicozz/papyrus/user_state/usecase"
+	_userTokenRepo "github.com/sicozz/papyrus/user_token/repository/postgres"
+	"github.com/sicozz/papyrus/utils/blob"
+	"github.com/sicozz/papyrus/utils/clock"
+	"github.com/sicozz/papyrus/utils/hasher"
+	"github.com/sicozz/papyrus/utils/mail"
+	_verificationHttpDelivery "github.com/sicozz/papyrus/verification/delivery/http"
+	_verificationUsecase "github.com/sicozz/papyrus/verification/usecase"
+	_versionRepo "github.com/sicozz/papyrus/version/repository/postgres"
 	"github.com/spf13/viper"
 )
 
+// defaultAuthSecret is the placeholder shipped in config.json
+const defaultAuthSecret = "change-me-in-production"
+
 func init() {
 	viper.SetConfigFile(`config.json`)
 	err := viper.ReadInConfig()
 	if err != nil {
 		panic(err)
 	}
+	// Secrets can come from the environment, e.g. PAPYRUS_AUTH_SECRET
+	viper.SetEnvPrefix("papyrus")
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	viper.AutomaticEnv()
 
 	if viper.GetBool(`debug`) {
 		log.Println("Service RUN on DEBUG mode")
@@ -64,26 +134,261 @@ func main() {
 		}
 	}()
 
+	if len(os.Args) > 1 {
+		runCommand(dbConn, os.Args[1:])
+		return
+	}
+
+	// Anyone reading the repository could forge tokens signed with the shipped secret
+	if s := viper.GetString("auth.secret"); s == "" || s == defaultAuthSecret {
+		log.Fatal("auth.secret is unset or still the shipped default, set PAPYRUS_AUTH_SECRET")
+	}
+
+	applied, err := newMigrator(dbConn).Up(context.Background(), 0)
+	if err != nil {
+		log.Fatal("could not migrate the database: ", err)
+	}
+	for _, m := range applied {
+		log.Printf("applied migration %d_%s", m.Version, m.Name)
+	}
+
 	e := echo.New()
+	// No proxy is trusted, X-Forwarded-For and X-Real-IP would let a client pick the ip it is throttled by
+	e.IPExtractor = echo.ExtractIPDirect()
 	e.Use(middleware.CORS())
 
 	timeoutContext := time.Duration(viper.GetInt("context.timeout")) * time.Second
 	rr := _roleRepo.NewPostgresRoleRepository(dbConn)
 	ur := _userRepo.NewPostgresUserRepository(dbConn)
 	usr := _userStateRepo.NewPostgresUserStateRepository(dbConn)
-	uu := _userUsecase.NewUserUsecase(ur, rr, usr, timeoutContext)
-	_userHttpDelivery.NewUserHandler(e, uu)
+	pr := _permissionRepo.NewPostgresPermissionRepository(dbConn)
+	az := _permissionUsecase.NewAuthorizer(pr)
+	ph := hasher.NewBcryptHasher(viper.GetInt("security.bcrypt_cost"))
+	clk := clock.NewSystemClock()
+	ltr := _loginThrottleRepo.NewPostgresLoginThrottleRepository(dbConn)
+	ltu := _loginThrottleUsecase.NewLoginThrottleUsecase(
+		ltr,
+		domain.LoginPolicy{
+			MaxFailures:   viper.GetInt64("login.max_failures"),
+			IpMaxFailures: viper.GetInt64("login.ip_max_failures"),
+			LockDuration:  time.Duration(viper.GetInt("login.lock_duration")) * time.Second,
+			BackoffBase:   time.Duration(viper.GetInt("login.backoff_base")) * time.Second,
+			BackoffMax:    time.Duration(viper.GetInt("login.backoff_max")) * time.Second,
+		},
+		clk,
+		timeoutContext,
+	)
+	pp := domain.PasswordPolicy{
+		MinLength:     viper.GetInt("password_policy.min_length"),
+		RequireUpper:  viper.GetBool("password_policy.require_upper"),
+		RequireLower:  viper.GetBool("password_policy.require_lower"),
+		RequireDigit:  viper.GetBool("password_policy.require_digit"),
+		RequireSymbol: viper.GetBool("password_policy.require_symbol"),
+	}
+	utr := _userTokenRepo.NewPostgresUserTokenRepository(dbConn)
+	ms, err := newMailSender()
+	if err != nil {
+		log.Fatal(err)
+	}
+	nr := _notificationRepo.NewPostgresNotificationRepository(dbConn)
+	nu := _notificationUsecase.NewNotificationUsecase(nr, clk, timeoutContext)
+	// Notifications are mailed in the background, not to hold the request that raised them
+	nms := mail.NewQueuedSender(
+		ms,
+		viper.GetInt("mail.queue.size"),
+		viper.GetInt("mail.queue.workers"),
+		time.Duration(viper.GetInt("mail.queue.timeout"))*time.Second,
+	)
+	nt := _notificationUsecase.NewNotifier(nr, ur, nms, clk)
+	vu := _verificationUsecase.NewVerificationUsecase(
+		ur,
+		usr,
+		utr,
+		ms,
+		nt,
+		time.Duration(viper.GetInt("verification.ttl"))*time.Second,
+		viper.GetString("verification.url"),
+		timeoutContext,
+	)
+	sr := _sessionRepo.NewPostgresSessionRepository(dbConn)
+	uu := _userUsecase.NewUserUsecase(ur, rr, usr, sr, ph, pp, az, ltu, vu, nt, timeoutContext)
+	tr := _totpRepo.NewPostgresTotpRepository(dbConn)
+	tu := _totpUsecase.NewTotpUsecase(
+		ur,
+		tr,
+		az,
+		clk,
+		viper.GetString("totp.issuer"),
+		viper.GetStringSlice("totp.required_roles"),
+		timeoutContext,
+	)
+	au := _authUsecase.NewAuthUsecase(
+		uu,
+		tu,
+		ltu,
+		sr,
+		viper.GetString("auth.secret"),
+		time.Duration(viper.GetInt("auth.access_ttl"))*time.Second,
+		time.Duration(viper.GetInt("auth.refresh_ttl"))*time.Second,
+		timeoutContext,
+	)
+	pwu := _passwordUsecase.NewPasswordUsecase(
+		ur,
+		utr,
+		sr,
+		az,
+		ph,
+		ms,
+		pp,
+		time.Duration(viper.GetInt("password_reset.ttl"))*time.Second,
+		viper.GetString("password_reset.url"),
+		timeoutContext,
+	)
+	ru := _roleUsecase.NewRoleUsecase(rr, ur, timeoutContext)
+	usu := _userStateUsecase.NewUserStateUsecase(usr, ur, timeoutContext)
+	kr := _apiKeyRepo.NewPostgresApiKeyRepository(dbConn)
+	ku := _apiKeyUsecase.NewApiKeyUsecase(kr, uu, az, clk, timeoutContext)
+	authMw := _authMiddleware.NewAuthMiddleware(au, ku)
+	pu := _permissionUsecase.NewPermissionUsecase(pr, rr, az, timeoutContext)
+	authzMw := _authMiddleware.NewAuthzMiddleware(az)
+	dr := _dirRepo.NewPostgresDirRepository(dbConn)
+	pjr := _projectRepo.NewPostgresProjectRepository(dbConn)
+	du := _dirUsecase.NewDirUsecase(dr, pjr, az, timeoutContext)
+	bs, err := newBlobStore()
+	if err != nil {
+		log.Fatal(err)
+	}
+	fr := _fileRepo.NewPostgresFileRepository(dbConn)
+	upr := _uploadSessionRepo.NewPostgresUploadSessionRepository(dbConn)
+	vr := _versionRepo.NewPostgresVersionRepository(dbConn)
+	gr := _fileGrantRepo.NewPostgresFileGrantRepository(dbConn)
+	fa := _permissionUsecase.NewFileAccess(gr, pjr, az)
+	adr := _auditRepo.NewPostgresAuditRepository(dbConn)
+	fu, err := _fileUsecase.NewFileUsecase(
+		fr,
+		upr,
+		vr,
+		fa,
+		adr,
+		dr,
+		ur,
+		bs,
+		nt,
+		clk,
+		viper.GetString("files.staging_dir"),
+		viper.GetInt64("files.max_size"),
+		timeoutContext,
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+	gu := _fileGrantUsecase.NewFileGrantUsecase(gr, fr, dr, ur, fa, timeoutContext)
+	adu := _auditUsecase.NewAuditUsecase(adr, ur, timeoutContext)
+	pju := _projectUsecase.NewProjectUsecase(pjr, ur, az, timeoutContext)
+	plr := _planRepo.NewPostgresPlanRepository(dbConn)
+	plu := _planUsecase.NewPlanUsecase(plr, pjr, ur, az, clk, timeoutContext)
+	tkr := _taskRepo.NewPostgresTaskRepository(dbConn)
+	tku := _taskUsecase.NewTaskUsecase(tkr, plr, pjr, dr, ur, fa, az, nt, clk, timeoutContext)
+	_authHttpDelivery.NewAuthHandler(e, au)
+	_userHttpDelivery.NewUserHandler(e, uu, authMw.Authenticate, authzMw)
+	_roleHttpDelivery.NewRoleHandler(e, ru, authMw.Authenticate, authzMw)
+	_userStateHttpDelivery.NewUserStateHandler(e, usu, authMw.Authenticate, authzMw)
+	_passwordHttpDelivery.NewPasswordHandler(e, pwu, authMw.Authenticate, authzMw)
+	_verificationHttpDelivery.NewVerificationHandler(e, vu, authMw.Authenticate, authzMw)
+	_permissionHttpDelivery.NewPermissionHandler(e, pu, authMw.Authenticate, authzMw)
+	_totpHttpDelivery.NewTotpHandler(e, tu, authMw.Authenticate, authzMw)
+	_apiKeyHttpDelivery.NewApiKeyHandler(e, ku, authMw.Authenticate, authzMw)
+	_dirHttpDelivery.NewDirHandler(e, du, authMw.Authenticate, authzMw)
+	_fileHttpDelivery.NewFileHandler(e, fu, authMw.Authenticate)
+	_fileGrantHttpDelivery.NewFileGrantHandler(e, gu, authMw.Authenticate, authzMw)
+	_auditHttpDelivery.NewAuditHandler(e, adu, authMw.Authenticate, authzMw)
+	_projectHttpDelivery.NewProjectHandler(e, pju, authMw.Authenticate, authzMw)
+	_planHttpDelivery.NewPlanHandler(e, plu, authMw.Authenticate, authzMw)
+	_taskHttpDelivery.NewTaskHandler(e, tku, authMw.Authenticate)
+	_notificationHttpDelivery.NewNotificationHandler(e, nu, authMw.Authenticate)
+	if viper.GetBool("scheduler.enabled") {
+		go newScheduler(dbConn, clk, nt).Start(context.Background())
+	}
 	e.Logger.Fatal(e.Start(":9090"))
 	/**
 	* TODO: - Improve error management and logging
 	* TODO: - Add unit testing for everything created
 	* TODO: - Add logs and make them good (change log flags)
 	**/
-	/**
-	* TODO: Add field to dir: number of children
-	* This is meant to cache the number of children and it will reduce the
-	* computational cost
-	* TODO: Add field to dir: path
-	* Same as the last one, cache to improve computation
-	 */
+}
+
+// newBlobStore builds the file content store selected by files.store
+func newBlobStore() (domain.BlobStore, error) {
+	switch store := viper.GetString("files.store"); store {
+	case "local":
+		return blob.NewLocalStore(viper.GetString("files.local_root"))
+	case "s3":
+		return blob.NewS3Store(blob.S3Config{
+			Endpoint:  viper.GetString("files.s3.endpoint"),
+			Region:    viper.GetString("files.s3.region"),
+			Bucket:    viper.GetString("files.s3.bucket"),
+			AccessKey: viper.GetString("files.s3.access_key"),
+			SecretKey: viper.GetString("files.s3.secret_key"),
+			PathStyle: viper.GetBool("files.s3.path_style"),
+			PartSize:  viper.GetInt64("files.s3.part_size"),
+		})
+	default:
+		return nil, fmt.Errorf("unknown files.store %q", store)
+	}
+}
+
+// newMailSender builds the outgoing mail sender selected by mail.sender
+func newMailSender() (domain.MailSender, error) {
+	switch sender := viper.GetString("mail.sender"); sender {
+	case "", "log":
+		return mail.NewLogSender(), nil
+	case "smtp":
+		return mail.NewSMTPSender(mail.SMTPConfig{
+			Host:     viper.GetString("mail.smtp.host"),
+			Port:     viper.GetString("mail.smtp.port"),
+			Username: viper.GetString("mail.smtp.username"),
+			Password: viper.GetString("mail.smtp.password"),
+			From:     viper.GetString("mail.smtp.from"),
+		}), nil
+	default:
+		return nil, fmt.Errorf("unknown mail.sender %q", sender)
+	}
+}
+
+// newMigrator builds the migrator of the migrations embedded in the binary
+func newMigrator(dbConn *sql.DB) domain.Migrator {
+	ms, err := _migrationUsecase.LoadMigrations(migrations.FS)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return _migrationUsecase.NewMigrator(
+		_migrationRepo.NewPostgresMigrationRepository(dbConn),
+		ms,
+		clock.NewSystemClock(),
+	)
+}
+
+// newScheduler builds the background job runner with every job registered
+func newScheduler(dbConn *sql.DB, clk domain.Clock, n domain.Notifier) domain.Scheduler {
+	node, err := os.Hostname()
+	if err != nil {
+		node = "unknown"
+	}
+
+	sch := _jobUsecase.NewScheduler(
+		_jobRepo.NewPostgresJobRepository(dbConn),
+		clk,
+		fmt.Sprint(node, ":", os.Getpid()),
+		time.Duration(viper.GetInt("scheduler.tick"))*time.Second,
+	)
+	sch.Register(_jobUsecase.NewDeadlineJob(
+		_jobRepo.NewPostgresDeadlineRepository(dbConn),
+		n,
+		clk,
+		time.Duration(viper.GetInt("scheduler.deadline_interval"))*time.Second,
+		time.Duration(viper.GetInt("scheduler.escalate_after"))*time.Second,
+	))
+
+	return sch
 }
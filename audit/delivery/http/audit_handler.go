@@ -0,0 +1,135 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sicozz/papyrus/auth/delivery/http/middleware"
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/domain/dtos"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+	"github.com/sicozz/papyrus/utils/delivery"
+)
+
+const dateLayout = "2006-01-02"
+
+// AuditHandler will initialize the audit/ resources endpoint
+type AuditHandler struct {
+	AUsecase domain.AuditUsecase
+	log      utils.AggregatedLogger
+}
+
+func NewAuditHandler(e *echo.Echo, au domain.AuditUsecase, authMw echo.MiddlewareFunc, authz *middleware.AuthzMiddleware) {
+	logger := utils.NewAggregatedLogger(constants.Delivery, constants.Audit)
+	handler := &AuditHandler{au, logger}
+	g := e.Group("/audit", authMw, authz.Require(middleware.Can(domain.PermAuditRead)))
+	g.GET("/download", handler.FetchDownloads)
+	g.GET("/upload", handler.FetchUploads)
+}
+
+/*
+* parseDate reads an RFC 3339 time or a plain date. A plain upper bound
+* covers its whole day, so to=2026-03-31 includes the last day of a quarter
+ */
+func parseDate(s string, upper bool) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(dateLayout, s)
+	if err != nil {
+		return t, errors.New(fmt.Sprint("Invalid date, expected ", dateLayout, " or RFC 3339: ", s))
+	}
+	if upper {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// csvResponse starts the response on its first write, so a failure before any row can still answer JSON
+type csvResponse struct {
+	c       echo.Context
+	name    string
+	started bool
+}
+
+func (w *csvResponse) Write(p []byte) (int, error) {
+	if !w.started {
+		w.started = true
+		header := w.c.Response().Header()
+		header.Set(echo.HeaderContentType, "text/csv; charset=utf-8")
+		header.Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", w.name))
+		w.c.Response().WriteHeader(http.StatusOK)
+	}
+	return w.c.Response().Write(p)
+}
+
+func (h *AuditHandler) FetchDownloads(c echo.Context) error {
+	h.log.Info("REQ: fetch downloads")
+	return h.fetch(c, domain.TransferDownload)
+}
+
+func (h *AuditHandler) FetchUploads(c echo.Context) error {
+	h.log.Info("REQ: fetch uploads")
+	return h.fetch(c, domain.TransferUpload)
+}
+
+// fetch answers a page of transfers of kind, or all of them as CSV with format=csv
+func (h *AuditHandler) fetch(c echo.Context, kind string) error {
+	ctx := c.Request().Context()
+	var qDto dtos.TransferQueryDto
+	err := c.Bind(&qDto)
+	if err != nil {
+		errBody := dtos.NewErrDto(fmt.Sprint("Req query binding failed: ", err))
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	if ok, err := delivery.IsRequestValid(&qDto); !ok {
+		errBody, err := dtos.NewValidationErrDto(err.Error())
+		if err != nil {
+			errValid := dtos.NewErrDto(fmt.Sprint("Req query validation failed: ", err))
+			return c.JSON(http.StatusBadRequest, errValid)
+		}
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	f := domain.TransferFilter{
+		Kind:     kind,
+		Limit:    qDto.Limit,
+		Cursor:   qDto.Cursor,
+		Username: qDto.User,
+		File:     qDto.File,
+		Dir:      qDto.Dir,
+	}
+	if f.From, err = parseDate(qDto.From, false); err == nil {
+		f.To, err = parseDate(qDto.To, true)
+	}
+	if err != nil {
+		errBody := dtos.NewErrDto(err.Error())
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	if qDto.Format == "csv" {
+		w := &csvResponse{c: c, name: kind + "s.csv"}
+		rErr := h.AUsecase.Export(ctx, f, w)
+		if rErr != nil && !w.started {
+			errBody := dtos.NewErrDto(rErr.Error())
+			return c.JSON(rErr.GetStatus(), errBody)
+		}
+		return nil
+	}
+
+	page, rErr := h.AUsecase.Fetch(ctx, f)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.JSON(http.StatusOK, page)
+}
@@ -0,0 +1,213 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+	"github.com/sicozz/papyrus/utils/repository"
+)
+
+// transferOrder is the only order transfers are listed in, newest first
+const transferOrder = "-date"
+
+// transferTables maps every kind of transfer to the table logging it
+var transferTables = map[string]string{
+	domain.TransferDownload: "download",
+	domain.TransferUpload:   "upload",
+}
+
+type postgresAuditRepository struct {
+	Conn *sql.DB
+	log  utils.AggregatedLogger
+}
+
+// NewPostgresAuditRepository will create an object that represent the AuditRepository interface
+func NewPostgresAuditRepository(conn *sql.DB) domain.AuditRepository {
+	logger := utils.NewAggregatedLogger(constants.Repository, constants.Audit)
+	return &postgresAuditRepository{conn, logger}
+}
+
+// filterConds turns the filters of f into conditions over t, the transfers, and fl, their files
+func filterConds(f domain.TransferFilter, args []interface{}) ([]string, []interface{}) {
+	conds := make([]string, 0)
+	if f.User != "" {
+		args = append(args, f.User)
+		conds = append(conds, fmt.Sprintf("t.user_ = $%d", len(args)))
+	}
+	if f.File != "" {
+		args = append(args, f.File)
+		conds = append(conds, fmt.Sprintf("t.file = $%d", len(args)))
+	}
+	if f.Dir != "" {
+		args = append(args, f.Dir)
+		conds = append(conds, fmt.Sprintf(
+			`fl.dir IN (WITH RECURSIVE sub AS (
+				SELECT uuid FROM dir WHERE uuid = $%d
+				UNION ALL
+				SELECT d.uuid FROM dir d JOIN sub ON d.parent_dir = sub.uuid
+			) SELECT uuid FROM sub)`,
+			len(args),
+		))
+	}
+	// Time bounds go as text: a timestamp param would be shifted by the session time zone
+	if !f.From.IsZero() {
+		args = append(args, repository.FormatCursorTime(f.From.UTC()))
+		conds = append(conds, fmt.Sprintf("t.date >= $%d::timestamp", len(args)))
+	}
+	if !f.To.IsZero() {
+		args = append(args, repository.FormatCursorTime(f.To.UTC()))
+		conds = append(conds, fmt.Sprintf("t.date < $%d::timestamp", len(args)))
+	}
+
+	return conds, args
+}
+
+func whereClause(conds []string) string {
+	if len(conds) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(conds, " AND ")
+}
+
+func transferSelect(table string) string {
+	return fmt.Sprintf(
+		`SELECT t.uuid, t.date, t.user_, u.username, t.file, fl.code, fl.name, fl.dir,
+			t.version, t.bytes
+		FROM %s t
+		JOIN user_ u ON u.uuid = t.user_
+		JOIN file fl ON fl.uuid = t.file`,
+		table,
+	)
+}
+
+// each runs query and calls fn on every transfer it returns
+func (r *postgresAuditRepository) each(ctx context.Context, fn func(domain.Transfer) error, query string, args ...interface{}) (err error) {
+	rows, err := r.Conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		r.log.Error(err)
+		return err
+	}
+
+	defer func() {
+		errRow := rows.Close()
+		if errRow != nil {
+			r.log.Error(errRow)
+		}
+	}()
+
+	for rows.Next() {
+		t := domain.Transfer{}
+		err = rows.Scan(
+			&t.Uuid,
+			&t.Date,
+			&t.User,
+			&t.Username,
+			&t.File,
+			&t.FileCode,
+			&t.FileName,
+			&t.Dir,
+			&t.Version,
+			&t.Bytes,
+		)
+		if err != nil {
+			r.log.Error("IN [each]:", err)
+			return err
+		}
+		if err = fn(t); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// Log a download
+func (r *postgresAuditRepository) RecordDownload(ctx context.Context, t *domain.Transfer) (err error) {
+	query :=
+		`INSERT INTO download (date, user_, file, version, bytes)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING uuid`
+	err = r.Conn.QueryRowContext(ctx, query, t.Date, t.User, t.File, t.Version, t.Bytes).Scan(&t.Uuid)
+	if err != nil {
+		r.log.Error("IN [RecordDownload]: could not log download ->", err)
+	}
+
+	return
+}
+
+// Get a page of the transfers matching f, newest first
+func (r *postgresAuditRepository) GetPage(ctx context.Context, f domain.TransferFilter) (res []domain.Transfer, next string, err error) {
+	table, ok := transferTables[f.Kind]
+	if !ok {
+		return nil, "", domain.ErrBadParamInput
+	}
+
+	conds, args := filterConds(f, make([]interface{}, 0))
+	if f.Cursor != "" {
+		key, uuid, err := repository.DecodeCursor(f.Cursor, transferOrder)
+		if err != nil {
+			return nil, "", domain.ErrBadParamInput
+		}
+		if _, err = repository.ParseCursorTime(key); err != nil {
+			return nil, "", domain.ErrBadParamInput
+		}
+		args = append(args, key, uuid)
+		conds = append(conds, fmt.Sprintf("(t.date, t.uuid) < ($%d::timestamp, $%d::uuid)", len(args)-1, len(args)))
+	}
+
+	// One extra row tells whether there is a next page
+	args = append(args, f.Limit+1)
+	query := fmt.Sprintf(
+		`%s
+		%s
+		ORDER BY t.date DESC, t.uuid DESC
+		LIMIT $%d`,
+		transferSelect(table), whereClause(conds), len(args),
+	)
+
+	res = make([]domain.Transfer, 0)
+	err = r.each(ctx, func(t domain.Transfer) error {
+		res = append(res, t)
+		return nil
+	}, query, args...)
+	if err != nil {
+		r.log.Error("IN [GetPage]: could not fetch transfers ->", err)
+		return nil, "", err
+	}
+
+	if int64(len(res)) > f.Limit {
+		res = res[:f.Limit]
+		last := res[len(res)-1]
+		next = repository.EncodeCursor(transferOrder, repository.FormatCursorTime(last.Date), last.Uuid)
+	}
+
+	return
+}
+
+// Call fn on every transfer matching f, newest first
+func (r *postgresAuditRepository) Each(ctx context.Context, f domain.TransferFilter, fn func(domain.Transfer) error) (err error) {
+	table, ok := transferTables[f.Kind]
+	if !ok {
+		return domain.ErrBadParamInput
+	}
+
+	conds, args := filterConds(f, make([]interface{}, 0))
+	query := fmt.Sprintf(
+		`%s
+		%s
+		ORDER BY t.date DESC, t.uuid DESC`,
+		transferSelect(table), whereClause(conds),
+	)
+
+	err = r.each(ctx, fn, query, args...)
+	if err != nil {
+		r.log.Error("IN [Each]: could not walk transfers ->", err)
+	}
+
+	return
+}
@@ -0,0 +1,151 @@
+package usecase
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+)
+
+const (
+	defPageLimit = 50
+	maxPageLimit = 200
+)
+
+// csvHeader names the columns of an export, in the order of csvRecord
+var csvHeader = []string{"date", "username", "user", "file", "file_code", "file_name", "dir", "version", "bytes"}
+
+type auditUsecase struct {
+	auditRepo      domain.AuditRepository
+	userRepo       domain.UserRepository
+	contextTimeout time.Duration
+	log            utils.AggregatedLogger
+}
+
+// NewAuditUsecase will create a new auditUsecase object representation of domain.AuditUsecase interface
+func NewAuditUsecase(ar domain.AuditRepository, ur domain.UserRepository, timeout time.Duration) domain.AuditUsecase {
+	logger := utils.NewAggregatedLogger(constants.Usecase, constants.Audit)
+	return &auditUsecase{
+		auditRepo:      ar,
+		userRepo:       ur,
+		contextTimeout: timeout,
+		log:            logger,
+	}
+}
+
+/*
+* csvCell neutralizes text a spreadsheet would run as a formula by prefixing a
+* quote, as OWASP advises against CSV injection
+ */
+func csvCell(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+func csvRecord(t domain.Transfer) []string {
+	return []string{
+		t.Date.UTC().Format(time.RFC3339),
+		csvCell(t.Username),
+		t.User,
+		t.File,
+		csvCell(t.FileCode),
+		csvCell(t.FileName),
+		t.Dir,
+		strconv.Itoa(t.Version),
+		strconv.FormatInt(t.Bytes, 10),
+	}
+}
+
+// resolve checks the filters of f and resolves its username
+func (u *auditUsecase) resolve(ctx context.Context, f *domain.TransferFilter) (rErr domain.RequestErr) {
+	if !f.From.IsZero() && !f.To.IsZero() && !f.From.Before(f.To) {
+		return domain.NewUCaseErr(http.StatusBadRequest, errors.New("The date range is empty, from must be before to"))
+	}
+
+	if f.Username != "" {
+		user, err := u.userRepo.GetByUsername(ctx, f.Username)
+		if errors.Is(err, domain.ErrNotFound) {
+			err = errors.New(fmt.Sprint("User not found. username: ", f.Username))
+			return domain.NewUCaseErr(http.StatusBadRequest, err)
+		}
+		if err != nil {
+			return domain.NewRepoErr(err, "")
+		}
+		f.User = user.Uuid
+	}
+
+	return
+}
+
+func (u *auditUsecase) Fetch(c context.Context, f domain.TransferFilter) (res domain.TransferPage, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	if f.Limit <= 0 {
+		f.Limit = defPageLimit
+	}
+	if f.Limit > maxPageLimit {
+		f.Limit = maxPageLimit
+	}
+
+	if rErr = u.resolve(ctx, &f); rErr != nil {
+		return
+	}
+
+	transfers, next, err := u.auditRepo.GetPage(ctx, f)
+	if errors.Is(err, domain.ErrBadParamInput) {
+		rErr = domain.NewUCaseErr(http.StatusBadRequest, errors.New("Invalid cursor"))
+		return
+	}
+	if err != nil {
+		rErr = domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Audit trail fetch failed"))
+		return
+	}
+
+	res = domain.TransferPage{Transfers: transfers, NextCursor: next}
+	return
+}
+
+/*
+* Export streams the matching transfers as they are read, so exports of any
+* size run in constant memory. Only the filter resolution is bound by the
+* usecase timeout, the export lasts as long as c
+ */
+func (u *auditUsecase) Export(c context.Context, f domain.TransferFilter, w io.Writer) (rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	rErr = u.resolve(ctx, &f)
+	cancel()
+	if rErr != nil {
+		return
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Audit trail export failed"))
+	}
+
+	err := u.auditRepo.Each(c, f, func(t domain.Transfer) error {
+		return cw.Write(csvRecord(t))
+	})
+	if err == nil {
+		cw.Flush()
+		err = cw.Error()
+	}
+	if err != nil {
+		u.log.Error("IN [Export]: could not export transfers ->", err)
+		return domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Audit trail export failed"))
+	}
+
+	return
+}
@@ -0,0 +1,57 @@
+package usecase_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"github.com/sicozz/papyrus/audit/usecase"
+	"github.com/sicozz/papyrus/domain"
+)
+
+type fakeAuditRepo struct {
+	domain.AuditRepository
+	transfers []domain.Transfer
+}
+
+func (r fakeAuditRepo) Each(ctx context.Context, f domain.TransferFilter, fn func(domain.Transfer) error) error {
+	for _, t := range r.transfers {
+		if err := fn(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestExportNeutralizesFormulas(t *testing.T) {
+	ar := fakeAuditRepo{transfers: []domain.Transfer{
+		{Date: time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC), Username: "ana", FileCode: "=HYPERLINK(\"x\")", FileName: "+cmd.xlsx"},
+		{Date: time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC), Username: "@bob", FileCode: "-2+3", FileName: "\tcalc"},
+		{Date: time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC), Username: "eve", FileCode: "DOC-1", FileName: "\rnote.txt"},
+	}}
+	au := usecase.NewAuditUsecase(ar, nil, time.Second)
+
+	var buf bytes.Buffer
+	if rErr := au.Export(context.Background(), domain.TransferFilter{}, &buf); rErr != nil {
+		t.Fatalf("export: %v", rErr)
+	}
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("reading export: %v", err)
+	}
+	want := [][3]string{
+		{"ana", "'=HYPERLINK(\"x\")", "'+cmd.xlsx"},
+		{"'@bob", "'-2+3", "'\tcalc"},
+		{"eve", "DOC-1", "'\rnote.txt"},
+	}
+	for i, w := range want {
+		// username, file_code and file_name columns
+		got := [3]string{rows[i+1][1], rows[i+1][4], rows[i+1][5]}
+		if got != w {
+			t.Errorf("row %d: got %q, want %q", i+1, got, w)
+		}
+	}
+}
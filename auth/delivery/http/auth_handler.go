@@ -0,0 +1,201 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/domain/dtos"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+	"github.com/sicozz/papyrus/utils/delivery"
+)
+
+// AuthHandler will initialize the authentication endpoints
+type AuthHandler struct {
+	AUsecase domain.AuthUsecase
+	log      utils.AggregatedLogger
+}
+
+func NewAuthHandler(e *echo.Echo, au domain.AuthUsecase) {
+	logger := utils.NewAggregatedLogger(constants.Delivery, constants.Auth)
+	handler := &AuthHandler{au, logger}
+	e.POST("/login", handler.Login)
+	e.POST("/login/totp", handler.LoginTotp)
+	e.POST("/login/totp/enroll", handler.EnrollTotp)
+	e.POST("/login/totp/enroll/confirm", handler.ConfirmTotp)
+	e.POST("/token/refresh", handler.Refresh)
+	e.POST("/logout", handler.Logout)
+}
+
+func (h *AuthHandler) Login(c echo.Context) error {
+	h.log.Info("REQ: login")
+	ctx := c.Request().Context()
+	var lDto dtos.LoginDto
+	err := c.Bind(&lDto)
+	if err != nil {
+		errBody := dtos.NewErrDto(fmt.Sprint("Req body binding failed: ", err))
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	if ok, err := delivery.IsRequestValid(&lDto); !ok {
+		errBody, err := dtos.NewValidationErrDto(err.Error())
+		if err != nil {
+			errValid := dtos.NewErrDto(fmt.Sprint("Req body validation failed: ", err))
+			return c.JSON(http.StatusBadRequest, errValid)
+		}
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	res, rErr := h.AUsecase.Login(ctx, lDto.Username, lDto.Password, c.RealIP())
+	if rErr != nil {
+		errBody := dtos.NewErrDto("Wrong username or password")
+		if rErr.GetStatus() != http.StatusUnauthorized {
+			errBody = dtos.NewErrDto(rErr.Error())
+		}
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.JSON(http.StatusOK, res)
+}
+
+func (h *AuthHandler) LoginTotp(c echo.Context) error {
+	h.log.Info("REQ: login totp")
+	ctx := c.Request().Context()
+	var mDto dtos.MfaCodeDto
+	err := c.Bind(&mDto)
+	if err != nil {
+		errBody := dtos.NewErrDto(fmt.Sprint("Req body binding failed: ", err))
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	if ok, err := delivery.IsRequestValid(&mDto); !ok {
+		errBody, err := dtos.NewValidationErrDto(err.Error())
+		if err != nil {
+			errValid := dtos.NewErrDto(fmt.Sprint("Req body validation failed: ", err))
+			return c.JSON(http.StatusBadRequest, errValid)
+		}
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	tokens, rErr := h.AUsecase.LoginTotp(ctx, mDto.MfaToken, mDto.Code, c.RealIP())
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.JSON(http.StatusOK, tokens)
+}
+
+func (h *AuthHandler) EnrollTotp(c echo.Context) error {
+	h.log.Info("REQ: enroll totp")
+	ctx := c.Request().Context()
+	var mDto dtos.MfaTokenDto
+	err := c.Bind(&mDto)
+	if err != nil {
+		errBody := dtos.NewErrDto(fmt.Sprint("Req body binding failed: ", err))
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	if ok, err := delivery.IsRequestValid(&mDto); !ok {
+		errBody, err := dtos.NewValidationErrDto(err.Error())
+		if err != nil {
+			errValid := dtos.NewErrDto(fmt.Sprint("Req body validation failed: ", err))
+			return c.JSON(http.StatusBadRequest, errValid)
+		}
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	enrollment, rErr := h.AUsecase.EnrollTotp(ctx, mDto.MfaToken)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.JSON(http.StatusCreated, enrollment)
+}
+
+func (h *AuthHandler) ConfirmTotp(c echo.Context) error {
+	h.log.Info("REQ: confirm totp")
+	ctx := c.Request().Context()
+	var mDto dtos.MfaCodeDto
+	err := c.Bind(&mDto)
+	if err != nil {
+		errBody := dtos.NewErrDto(fmt.Sprint("Req body binding failed: ", err))
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	if ok, err := delivery.IsRequestValid(&mDto); !ok {
+		errBody, err := dtos.NewValidationErrDto(err.Error())
+		if err != nil {
+			errValid := dtos.NewErrDto(fmt.Sprint("Req body validation failed: ", err))
+			return c.JSON(http.StatusBadRequest, errValid)
+		}
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	res, rErr := h.AUsecase.ConfirmTotp(ctx, mDto.MfaToken, mDto.Code)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.JSON(http.StatusOK, res)
+}
+
+func (h *AuthHandler) Refresh(c echo.Context) error {
+	h.log.Info("REQ: refresh")
+	ctx := c.Request().Context()
+	var rDto dtos.RefreshTokenDto
+	err := c.Bind(&rDto)
+	if err != nil {
+		errBody := dtos.NewErrDto(fmt.Sprint("Req body binding failed: ", err))
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	if ok, err := delivery.IsRequestValid(&rDto); !ok {
+		errBody, err := dtos.NewValidationErrDto(err.Error())
+		if err != nil {
+			errValid := dtos.NewErrDto(fmt.Sprint("Req body validation failed: ", err))
+			return c.JSON(http.StatusBadRequest, errValid)
+		}
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	tokens, rErr := h.AUsecase.Refresh(ctx, rDto.RefreshToken)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.JSON(http.StatusOK, tokens)
+}
+
+func (h *AuthHandler) Logout(c echo.Context) error {
+	h.log.Info("REQ: logout")
+	ctx := c.Request().Context()
+	var rDto dtos.RefreshTokenDto
+	err := c.Bind(&rDto)
+	if err != nil {
+		errBody := dtos.NewErrDto(fmt.Sprint("Req body binding failed: ", err))
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	if ok, err := delivery.IsRequestValid(&rDto); !ok {
+		errBody, err := dtos.NewValidationErrDto(err.Error())
+		if err != nil {
+			errValid := dtos.NewErrDto(fmt.Sprint("Req body validation failed: ", err))
+			return c.JSON(http.StatusBadRequest, errValid)
+		}
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	rErr := h.AUsecase.Logout(ctx, rDto.RefreshToken)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.NoContent(http.StatusOK)
+}
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/domain/dtos"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+)
+
+const bearerPrefix = `Bearer `
+
+// AuthMiddleware represents the authentication middleware
+type AuthMiddleware struct {
+	AUsecase domain.AuthUsecase
+	KUsecase domain.ApiKeyUsecase
+	log      utils.AggregatedLogger
+}
+
+// NewAuthMiddleware will create a new AuthMiddleware
+func NewAuthMiddleware(au domain.AuthUsecase, ku domain.ApiKeyUsecase) *AuthMiddleware {
+	logger := utils.NewAggregatedLogger(constants.Delivery, constants.Auth)
+	return &AuthMiddleware{au, ku, logger}
+}
+
+/*
+* Authenticate validates the bearer access token or API key of the request and
+* loads the authenticated user into the request context (see
+* domain.UserFromContext). Requests made with a scoped key also carry its scopes
+ */
+func (m *AuthMiddleware) Authenticate(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		header := c.Request().Header.Get(echo.HeaderAuthorization)
+		if !strings.HasPrefix(header, bearerPrefix) {
+			errBody := dtos.NewErrDto("Missing bearer access token")
+			return c.JSON(http.StatusUnauthorized, errBody)
+		}
+
+		ctx := c.Request().Context()
+		token := strings.TrimPrefix(header, bearerPrefix)
+		if strings.HasPrefix(token, domain.ApiKeyPrefix) {
+			user, key, rErr := m.KUsecase.Authenticate(ctx, token)
+			if rErr != nil {
+				m.log.Warn("REQ: rejected api key ->", rErr)
+				errBody := dtos.NewErrDto(rErr.Error())
+				return c.JSON(rErr.GetStatus(), errBody)
+			}
+
+			ctx = domain.NewContextWithUser(ctx, user)
+			ctx = domain.NewContextWithScopes(ctx, key.Scopes)
+			c.SetRequest(c.Request().WithContext(ctx))
+			return next(c)
+		}
+
+		user, rErr := m.AUsecase.Authenticate(ctx, token)
+		if rErr != nil {
+			m.log.Warn("REQ: rejected access token ->", rErr)
+			errBody := dtos.NewErrDto(rErr.Error())
+			return c.JSON(rErr.GetStatus(), errBody)
+		}
+
+		ctx = domain.NewContextWithUser(ctx, user)
+		c.SetRequest(c.Request().WithContext(ctx))
+		return next(c)
+	}
+}
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/domain/dtos"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+)
+
+/*
+* Rule is a declarative authorization requirement evaluated against the
+* authenticated user of the request
+ */
+type Rule func(c echo.Context, m *AuthzMiddleware, u domain.User) (bool, domain.RequestErr)
+
+// AuthzMiddleware represents the authorization middleware
+type AuthzMiddleware struct {
+	Authorizer domain.Authorizer
+	log        utils.AggregatedLogger
+}
+
+// NewAuthzMiddleware will create a new AuthzMiddleware
+func NewAuthzMiddleware(az domain.Authorizer) *AuthzMiddleware {
+	logger := utils.NewAggregatedLogger(constants.Delivery, constants.Authz)
+	return &AuthzMiddleware{az, logger}
+}
+
+/*
+* Require grants access when at least one of the rules holds. It must run
+* after AuthMiddleware.Authenticate
+ */
+func (m *AuthzMiddleware) Require(rules ...Rule) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			user, ok := domain.UserFromContext(c.Request().Context())
+			if !ok {
+				errBody := dtos.NewErrDto("Authentication required")
+				return c.JSON(http.StatusUnauthorized, errBody)
+			}
+
+			for _, rule := range rules {
+				allowed, rErr := rule(c, m, user)
+				if rErr != nil {
+					errBody := dtos.NewErrDto(rErr.Error())
+					return c.JSON(rErr.GetStatus(), errBody)
+				}
+				if allowed {
+					return next(c)
+				}
+			}
+
+			m.log.Warn("REQ: forbidden", c.Request().Method, c.Path(), "for {", user.Username, "}")
+			errBody := dtos.NewErrDto("Insufficient privileges")
+			return c.JSON(http.StatusForbidden, errBody)
+		}
+	}
+}
+
+// Can holds when the user's role grants the permission perm
+func Can(perm string) Rule {
+	return func(c echo.Context, m *AuthzMiddleware, u domain.User) (bool, domain.RequestErr) {
+		ctx := c.Request().Context()
+		allowed, err := m.Authorizer.Can(ctx, u, perm)
+		if err != nil {
+			m.log.Error("IN [Can]: could not check permission {", perm, "} ->", err)
+			return false, domain.NewUCaseErr(http.StatusInternalServerError, err)
+		}
+
+		return allowed, nil
+	}
+}
+
+/*
+* Self holds when the path param unameParam is the user's own username. It
+* never holds for requests restricted by a scoped API key
+ */
+func Self(unameParam string) Rule {
+	return func(c echo.Context, m *AuthzMiddleware, u domain.User) (bool, domain.RequestErr) {
+		if _, scoped := domain.ScopesFromContext(c.Request().Context()); scoped {
+			return false, nil
+		}
+		return c.Param(unameParam) == u.Username, nil
+	}
+}
@@ -0,0 +1,119 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sicozz/papyrus/auth/delivery/http/middleware"
+	"github.com/sicozz/papyrus/domain"
+)
+
+var (
+	ana = domain.User{Uuid: "u-ana", Username: "ana", Role: domain.Role{Code: 2}}
+	bob = domain.User{Uuid: "u-bob", Username: "bob", Role: domain.Role{Code: 3}}
+)
+
+// fakeAuthorizer grants user.write to role 2, user.read to nobody and fails on any other permission
+type fakeAuthorizer struct{ domain.Authorizer }
+
+func (fakeAuthorizer) Can(ctx context.Context, u domain.User, perm string) (bool, error) {
+	switch perm {
+	case domain.PermUserWrite:
+		return u.Role.Code == 2, nil
+	case domain.PermUserRead:
+		return false, nil
+	}
+	return false, errors.New("permission store down")
+}
+
+// denied is a rule that never holds
+func denied(c echo.Context, m *middleware.AuthzMiddleware, u domain.User) (bool, domain.RequestErr) {
+	return false, nil
+}
+
+/*
+* serve runs rules on a GET /user/:uname request for uname, as u when set and
+* under scopes when set, answering 200 when they let it through
+ */
+func serve(u *domain.User, scopes []string, uname string, rules ...middleware.Rule) int {
+	e := echo.New()
+	mw := middleware.NewAuthzMiddleware(fakeAuthorizer{})
+	e.GET("/user/:uname", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	}, mw.Require(rules...))
+
+	req := httptest.NewRequest(http.MethodGet, "/user/"+uname, nil)
+	ctx := req.Context()
+	if u != nil {
+		ctx = domain.NewContextWithUser(ctx, *u)
+	}
+	if scopes != nil {
+		ctx = domain.NewContextWithScopes(ctx, scopes)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req.WithContext(ctx))
+
+	return rec.Code
+}
+
+func TestRequire(t *testing.T) {
+	cases := []struct {
+		name  string
+		user  *domain.User
+		rules []middleware.Rule
+		want  int
+	}{
+		{"no user", nil, []middleware.Rule{middleware.Can(domain.PermUserWrite)}, http.StatusUnauthorized},
+		{"no rule", &ana, nil, http.StatusForbidden},
+		{"no rule holds", &bob, []middleware.Rule{denied, middleware.Can(domain.PermUserWrite)}, http.StatusForbidden},
+		{"one rule holds", &ana, []middleware.Rule{denied, middleware.Can(domain.PermUserWrite)}, http.StatusOK},
+		{"a failing rule", &ana, []middleware.Rule{middleware.Can(domain.PermFileRead)}, http.StatusInternalServerError},
+		{"held before a failing rule", &ana, []middleware.Rule{middleware.Can(domain.PermUserWrite), middleware.Can(domain.PermFileRead)}, http.StatusOK},
+	}
+	for _, c := range cases {
+		if got := serve(c.user, nil, "eve", c.rules...); got != c.want {
+			t.Errorf("%s: got %d, want %d", c.name, got, c.want)
+		}
+	}
+}
+
+func TestCan(t *testing.T) {
+	cases := []struct {
+		name string
+		user domain.User
+		perm string
+		want int
+	}{
+		{"granted", ana, domain.PermUserWrite, http.StatusOK},
+		{"not granted", bob, domain.PermUserWrite, http.StatusForbidden},
+		{"granted to nobody", ana, domain.PermUserRead, http.StatusForbidden},
+		{"unresolved", ana, domain.PermFileRead, http.StatusInternalServerError},
+	}
+	for _, c := range cases {
+		if got := serve(&c.user, nil, "eve", middleware.Can(c.perm)); got != c.want {
+			t.Errorf("%s: got %d, want %d", c.name, got, c.want)
+		}
+	}
+}
+
+func TestSelf(t *testing.T) {
+	cases := []struct {
+		name   string
+		scopes []string
+		uname  string
+		want   int
+	}{
+		{"own username", nil, "bob", http.StatusOK},
+		{"someone else", nil, "eve", http.StatusForbidden},
+		{"own username with a scoped key", []string{domain.PermUserRead}, "bob", http.StatusForbidden},
+	}
+	for _, c := range cases {
+		if got := serve(&bob, c.scopes, c.uname, middleware.Self("uname")); got != c.want {
+			t.Errorf("%s: got %d, want %d", c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,378 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+	"github.com/sicozz/papyrus/utils/token"
+)
+
+const (
+	tokenType = `Bearer`
+	mfaTTL    = 5 * time.Minute
+)
+
+// Audiences keeping access tokens and second step tokens apart
+const (
+	audAccess    = "access"
+	audMfaVerify = "mfa_verify"
+	audMfaEnroll = "mfa_enroll"
+)
+
+var invalidMfaErr = domain.NewUCaseErr(http.StatusUnauthorized, errors.New("Invalid or expired mfa token"))
+
+// checkActive refuses tokens of users who were deactivated after they got them
+func checkActive(user domain.User) domain.RequestErr {
+	if user.State.Description != domain.UserStateActive {
+		return domain.NewUCaseErr(http.StatusForbidden, errors.New("Account not active"))
+	}
+	return nil
+}
+
+type accessClaims struct {
+	Username string `json:"username"`
+	jwt.RegisteredClaims
+}
+
+type authUsecase struct {
+	userUcase      domain.UserUsecase
+	totpUcase      domain.TotpUsecase
+	throttleUcase  domain.LoginThrottleUsecase
+	sessionRepo    domain.SessionRepository
+	secret         []byte
+	accessTTL      time.Duration
+	refreshTTL     time.Duration
+	contextTimeout time.Duration
+	log            utils.AggregatedLogger
+}
+
+// NewAuthUsecase will create a new authUsecase object representation of domain.AuthUsecase interface
+func NewAuthUsecase(
+	uu domain.UserUsecase,
+	tu domain.TotpUsecase,
+	ltu domain.LoginThrottleUsecase,
+	sr domain.SessionRepository,
+	secret string,
+	accessTTL time.Duration,
+	refreshTTL time.Duration,
+	timeout time.Duration,
+) domain.AuthUsecase {
+	logger := utils.NewAggregatedLogger(constants.Usecase, constants.Auth)
+	return &authUsecase{
+		userUcase:      uu,
+		totpUcase:      tu,
+		throttleUcase:  ltu,
+		sessionRepo:    sr,
+		secret:         []byte(secret),
+		accessTTL:      accessTTL,
+		refreshTTL:     refreshTTL,
+		contextTimeout: timeout,
+		log:            logger,
+	}
+}
+
+func (a *authUsecase) sign(user domain.User, aud string, now time.Time, ttl time.Duration) (string, error) {
+	claims := accessClaims{
+		Username: user.Username,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   user.Uuid,
+			Audience:  jwt.ClaimStrings{aud},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(a.secret)
+}
+
+// parse validates a signed token and checks it was issued for aud
+func (a *authUsecase) parse(signed string, aud string) (claims accessClaims, err error) {
+	_, err = jwt.ParseWithClaims(signed, &claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		return a.secret, nil
+	})
+	if err != nil {
+		return
+	}
+
+	if !claims.VerifyAudience(aud, true) {
+		err = fmt.Errorf("unexpected audience: %v", claims.Audience)
+	}
+	return
+}
+
+// mfaUser resolves the user a second step token was issued to
+func (a *authUsecase) mfaUser(ctx context.Context, mfaToken string, aud string) (res domain.User, rErr domain.RequestErr) {
+	claims, err := a.parse(mfaToken, aud)
+	if err != nil {
+		return res, invalidMfaErr
+	}
+
+	res, rErr = a.userUcase.GetByUuid(ctx, claims.Subject)
+	if rErr != nil && rErr.GetStatus() == http.StatusNotFound {
+		return res, invalidMfaErr
+	}
+
+	return
+}
+
+// issue a new access token and a new server side refresh token for user
+func (a *authUsecase) issue(ctx context.Context, user domain.User) (res domain.TokenPair, err error) {
+	now := time.Now().UTC()
+	access, err := a.sign(user, audAccess, now, a.accessTTL)
+	if err != nil {
+		return
+	}
+
+	refresh, err := token.New()
+	if err != nil {
+		return
+	}
+
+	s := domain.Session{
+		UserUuid:  user.Uuid,
+		TokenHash: token.Hash(refresh),
+		ExpiresAt: now.Add(a.refreshTTL),
+	}
+	err = a.sessionRepo.Store(ctx, &s)
+	if err != nil {
+		return
+	}
+
+	res = domain.TokenPair{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		TokenType:    tokenType,
+		ExpiresIn:    int64(a.accessTTL.Seconds()),
+	}
+	return
+}
+
+func (a *authUsecase) Login(c context.Context, uname string, passwd string, ip string) (res domain.LoginResult, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	user, rErr := a.userUcase.Login(ctx, uname, passwd, ip)
+	if rErr != nil {
+		return
+	}
+
+	enabled, rErr := a.totpUcase.Enabled(ctx, user)
+	if rErr != nil {
+		return
+	}
+
+	aud := ""
+	if enabled {
+		res.MfaStep, aud = domain.MfaStepVerify, audMfaVerify
+	} else if a.totpUcase.Required(user) {
+		res.MfaStep, aud = domain.MfaStepEnroll, audMfaEnroll
+	}
+
+	if aud != "" {
+		mfaToken, err := a.sign(user, aud, time.Now().UTC(), mfaTTL)
+		if err != nil {
+			a.log.Error("IN [Login]: could not sign mfa token ->", err)
+			err = errors.New("Token issuing failed")
+			rErr = domain.NewUCaseErr(http.StatusInternalServerError, err)
+			return
+		}
+		res.MfaToken = mfaToken
+		return
+	}
+
+	tokens, err := a.issue(ctx, user)
+	if err != nil {
+		a.log.Error("IN [Login]: could not issue tokens ->", err)
+		err = errors.New("Token issuing failed")
+		rErr = domain.NewUCaseErr(http.StatusInternalServerError, err)
+		return
+	}
+
+	res.TokenPair = &tokens
+	return
+}
+
+func (a *authUsecase) LoginTotp(c context.Context, mfaToken string, code string, ip string) (res domain.TokenPair, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	user, rErr := a.mfaUser(ctx, mfaToken, audMfaVerify)
+	if rErr != nil {
+		return
+	}
+
+	if rErr = a.throttleUcase.Check(ctx, user.Username, ip); rErr != nil {
+		return
+	}
+
+	if rErr = a.totpUcase.Verify(ctx, user, code); rErr != nil {
+		if rErr.GetStatus() == http.StatusUnauthorized {
+			a.throttleUcase.RecordFailure(ctx, user.Username, ip)
+		}
+		return
+	}
+
+	res, err := a.issue(ctx, user)
+	if err != nil {
+		a.log.Error("IN [LoginTotp]: could not issue tokens ->", err)
+		err = errors.New("Token issuing failed")
+		rErr = domain.NewUCaseErr(http.StatusInternalServerError, err)
+		return
+	}
+
+	return
+}
+
+func (a *authUsecase) EnrollTotp(c context.Context, mfaToken string) (res domain.TotpEnrollment, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	user, rErr := a.mfaUser(ctx, mfaToken, audMfaEnroll)
+	if rErr != nil {
+		return
+	}
+
+	return a.totpUcase.Enroll(ctx, user.Username)
+}
+
+func (a *authUsecase) ConfirmTotp(c context.Context, mfaToken string, code string) (res domain.TotpConfirmation, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	user, rErr := a.mfaUser(ctx, mfaToken, audMfaEnroll)
+	if rErr != nil {
+		return
+	}
+
+	codes, rErr := a.totpUcase.Confirm(ctx, user.Username, code)
+	if rErr != nil {
+		return
+	}
+
+	tokens, err := a.issue(ctx, user)
+	if err != nil {
+		a.log.Error("IN [ConfirmTotp]: could not issue tokens ->", err)
+		err = errors.New("Token issuing failed")
+		rErr = domain.NewUCaseErr(http.StatusInternalServerError, err)
+		return
+	}
+
+	res = domain.TotpConfirmation{TokenPair: tokens, RecoveryCodes: codes}
+	return
+}
+
+func (a *authUsecase) Refresh(c context.Context, refreshToken string) (res domain.TokenPair, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	invalidErr := domain.NewUCaseErr(http.StatusUnauthorized, errors.New("Invalid refresh token"))
+
+	s, err := a.sessionRepo.GetByTokenHash(ctx, token.Hash(refreshToken))
+	if errors.Is(err, domain.ErrNotFound) {
+		return res, invalidErr
+	}
+	if err != nil {
+		a.log.Error("IN [Refresh]: could not get session ->", err)
+		return res, domain.NewRepoErr(err, "")
+	}
+
+	if time.Now().UTC().After(s.ExpiresAt) {
+		return res, invalidErr
+	}
+
+	// A revoked token being replayed means it leaked: end every session
+	rotated, err := a.sessionRepo.Revoke(ctx, s.Uuid)
+	if err != nil {
+		a.log.Error("IN [Refresh]: could not revoke session ->", err)
+		rErr = domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Token refresh failed"))
+		return
+	}
+	if !rotated {
+		a.log.Warn("IN [Refresh]: refresh token reuse detected for user {", s.UserUuid, "}")
+		if err = a.sessionRepo.RevokeAllByUser(ctx, s.UserUuid); err != nil {
+			a.log.Error("IN [Refresh]: could not revoke user sessions ->", err)
+		}
+		return res, invalidErr
+	}
+
+	user, rErr := a.userUcase.GetByUuid(ctx, s.UserUuid)
+	if rErr != nil {
+		if rErr.GetStatus() == http.StatusNotFound {
+			rErr = invalidErr
+		}
+		return
+	}
+	if rErr = checkActive(user); rErr != nil {
+		return
+	}
+
+	res, err = a.issue(ctx, user)
+	if err != nil {
+		a.log.Error("IN [Refresh]: could not issue tokens ->", err)
+		err = errors.New("Token issuing failed")
+		rErr = domain.NewUCaseErr(http.StatusInternalServerError, err)
+		return
+	}
+
+	return
+}
+
+func (a *authUsecase) Logout(c context.Context, refreshToken string) (rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	s, err := a.sessionRepo.GetByTokenHash(ctx, token.Hash(refreshToken))
+	if errors.Is(err, domain.ErrNotFound) {
+		rErr = domain.NewUCaseErr(http.StatusUnauthorized, errors.New("Invalid refresh token"))
+		return
+	}
+	if err != nil {
+		a.log.Error("IN [Logout]: could not get session ->", err)
+		rErr = domain.NewRepoErr(err, "")
+		return
+	}
+
+	_, err = a.sessionRepo.Revoke(ctx, s.Uuid)
+	if err != nil {
+		a.log.Error("IN [Logout]: could not revoke session ->", err)
+		rErr = domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Logout failed"))
+		return
+	}
+
+	return
+}
+
+func (a *authUsecase) Authenticate(c context.Context, accessToken string) (res domain.User, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	claims, err := a.parse(accessToken, audAccess)
+	if err != nil {
+		rErr = domain.NewUCaseErr(http.StatusUnauthorized, errors.New("Invalid access token"))
+		return
+	}
+
+	res, rErr = a.userUcase.GetByUuid(ctx, claims.Subject)
+	if rErr != nil {
+		if rErr.GetStatus() == http.StatusNotFound {
+			rErr = domain.NewUCaseErr(http.StatusUnauthorized, errors.New("Invalid access token"))
+		}
+		return domain.User{}, rErr
+	}
+
+	if rErr = checkActive(res); rErr != nil {
+		return domain.User{}, rErr
+	}
+
+	return
+}
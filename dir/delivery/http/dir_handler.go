@@ -0,0 +1,201 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sicozz/papyrus/auth/delivery/http/middleware"
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/domain/dtos"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+	"github.com/sicozz/papyrus/utils/delivery"
+)
+
+// DirHandler will initialize the dir/ resources endpoint
+type DirHandler struct {
+	DUsecase domain.DirUsecase
+	log      utils.AggregatedLogger
+}
+
+func NewDirHandler(e *echo.Echo, du domain.DirUsecase, authMw echo.MiddlewareFunc, authz *middleware.AuthzMiddleware) {
+	logger := utils.NewAggregatedLogger(constants.Delivery, constants.Dir)
+	handler := &DirHandler{du, logger}
+	canRead := authz.Require(middleware.Can(domain.PermFileRead))
+	canWrite := authz.Require(middleware.Can(domain.PermFileWrite))
+	g := e.Group("/dir", authMw)
+	g.GET("", handler.FetchRoots, canRead)
+	g.POST("", handler.Store, canWrite)
+	g.GET("/lookup", handler.GetByPath, canRead)
+	g.GET("/:uuid", handler.GetByUuid, canRead)
+	g.GET("/:uuid/children", handler.GetChildren, canRead)
+	g.GET("/:uuid/tree", handler.GetTree, canRead)
+	g.PATCH("/:uuid", handler.Rename, canWrite)
+	g.POST("/:uuid/move", handler.Move, canWrite)
+	g.DELETE("/:uuid", handler.Delete, canWrite)
+}
+
+func (h *DirHandler) FetchRoots(c echo.Context) error {
+	h.log.Info("REQ: fetch roots")
+	ctx := c.Request().Context()
+	dirs, rErr := h.DUsecase.GetChildren(ctx, "")
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.JSON(http.StatusOK, dirs)
+}
+
+func (h *DirHandler) GetByUuid(c echo.Context) error {
+	h.log.Info("REQ: get by uuid")
+	ctx := c.Request().Context()
+	uuid := c.Param("uuid")
+	dir, rErr := h.DUsecase.GetByUuid(ctx, uuid)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.JSON(http.StatusOK, dir)
+}
+
+func (h *DirHandler) GetByPath(c echo.Context) error {
+	h.log.Info("REQ: get by path")
+	ctx := c.Request().Context()
+	path := c.QueryParam("path")
+	dir, rErr := h.DUsecase.GetByPath(ctx, path)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.JSON(http.StatusOK, dir)
+}
+
+func (h *DirHandler) GetChildren(c echo.Context) error {
+	h.log.Info("REQ: get children")
+	ctx := c.Request().Context()
+	uuid := c.Param("uuid")
+	dirs, rErr := h.DUsecase.GetChildren(ctx, uuid)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.JSON(http.StatusOK, dirs)
+}
+
+func (h *DirHandler) GetTree(c echo.Context) error {
+	h.log.Info("REQ: get tree")
+	ctx := c.Request().Context()
+	uuid := c.Param("uuid")
+	tree, rErr := h.DUsecase.GetTree(ctx, uuid)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.JSON(http.StatusOK, tree)
+}
+
+func (h *DirHandler) Store(c echo.Context) error {
+	h.log.Info("REQ: store")
+	ctx := c.Request().Context()
+	var dir domain.Dir
+	err := c.Bind(&dir)
+	if err != nil {
+		errBody := dtos.NewErrDto(fmt.Sprint("Req body binding failed: ", err))
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	if ok, err := delivery.IsRequestValid(&dir); !ok {
+		errBody, err := dtos.NewValidationErrDto(err.Error())
+		if err != nil {
+			errValid := dtos.NewErrDto(fmt.Sprint("Req body validation failed: ", err))
+			return c.JSON(http.StatusBadRequest, errValid)
+		}
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	rErr := h.DUsecase.Store(ctx, &dir)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.JSON(http.StatusCreated, dir)
+}
+
+func (h *DirHandler) Rename(c echo.Context) error {
+	h.log.Info("REQ: rename")
+	ctx := c.Request().Context()
+	uuid := c.Param("uuid")
+	var rDto dtos.DirRenameDto
+	err := c.Bind(&rDto)
+	if err != nil {
+		errBody := dtos.NewErrDto(fmt.Sprint("Req body binding failed: ", err))
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	if ok, err := delivery.IsRequestValid(&rDto); !ok {
+		errBody, err := dtos.NewValidationErrDto(err.Error())
+		if err != nil {
+			errValid := dtos.NewErrDto(fmt.Sprint("Req body validation failed: ", err))
+			return c.JSON(http.StatusBadRequest, errValid)
+		}
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	rErr := h.DUsecase.Rename(ctx, uuid, rDto.Name)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+func (h *DirHandler) Move(c echo.Context) error {
+	h.log.Info("REQ: move")
+	ctx := c.Request().Context()
+	uuid := c.Param("uuid")
+	var mDto dtos.DirMoveDto
+	err := c.Bind(&mDto)
+	if err != nil {
+		errBody := dtos.NewErrDto(fmt.Sprint("Req body binding failed: ", err))
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	if ok, err := delivery.IsRequestValid(&mDto); !ok {
+		errBody, err := dtos.NewValidationErrDto(err.Error())
+		if err != nil {
+			errValid := dtos.NewErrDto(fmt.Sprint("Req body validation failed: ", err))
+			return c.JSON(http.StatusBadRequest, errValid)
+		}
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	rErr := h.DUsecase.Move(ctx, uuid, mDto.ParentDir)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+func (h *DirHandler) Delete(c echo.Context) error {
+	h.log.Info("REQ: delete")
+	ctx := c.Request().Context()
+	uuid := c.Param("uuid")
+	recursive := c.QueryParam("recursive") == "true"
+	rErr := h.DUsecase.Delete(ctx, uuid, recursive)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.NoContent(http.StatusOK)
+}
@@ -0,0 +1,464 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+	"github.com/sicozz/papyrus/utils/repository"
+)
+
+/*
+* dirTreeLock serializes the operations writing the tree: two concurrent moves
+* cannot build a cycle and no path rewrite misses a child inserted meanwhile
+ */
+const dirTreeLock int64 = 0x646972
+
+const pathSep = `/`
+
+// driftQuery recomputes path and child_count from parent_dir alone
+const driftQuery = `WITH RECURSIVE t AS (
+		SELECT uuid, '/' || name AS path FROM dir WHERE parent_dir IS NULL
+		UNION ALL
+		SELECT d.uuid, t.path || '/' || d.name FROM dir d JOIN t ON d.parent_dir = t.uuid
+	), c AS (
+		SELECT p.uuid, COUNT(ch.uuid) AS n FROM dir p LEFT JOIN dir ch ON ch.parent_dir = p.uuid GROUP BY p.uuid
+	)
+	SELECT d.uuid, d.path, t.path, d.child_count, c.n
+	FROM dir d JOIN t ON t.uuid = d.uuid JOIN c ON c.uuid = d.uuid
+	WHERE d.path <> t.path OR d.child_count <> c.n`
+
+type postgresDirRepository struct {
+	Conn *sql.DB
+	log  utils.AggregatedLogger
+}
+
+// NewPostgresDirRepository will create an object that represent the DirRepository interface
+func NewPostgresDirRepository(conn *sql.DB) domain.DirRepository {
+	logger := utils.NewAggregatedLogger(constants.Repository, constants.Dir)
+	return &postgresDirRepository{conn, logger}
+}
+
+// nullableUuid maps the empty parent of root directories to NULL
+func nullableUuid(uuid string) interface{} {
+	if uuid == "" {
+		return nil
+	}
+	return uuid
+}
+
+// below returns the LIKE pattern matching every path under path
+func below(path string) string {
+	esc := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(path)
+	return esc + pathSep + "%"
+}
+
+// childPath returns the path of a child called name of a dir at parentPath
+func childPath(parentPath string, name string) string {
+	return parentPath + pathSep + name
+}
+
+func (r *postgresDirRepository) fetch(ctx context.Context, query string, args ...interface{}) (res []domain.Dir, err error) {
+	rows, err := r.Conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		r.log.Error(err)
+		return nil, repository.MapErr(err)
+	}
+
+	defer func() {
+		errRow := rows.Close()
+		if errRow != nil {
+			r.log.Error(errRow)
+		}
+	}()
+
+	res = make([]domain.Dir, 0)
+	for rows.Next() {
+		t := domain.Dir{}
+		parent := sql.NullString{}
+		// Get from db
+		err = rows.Scan(
+			&t.Uuid,
+			&t.Name,
+			&parent,
+			&t.Path,
+			&t.ChildCount,
+		)
+
+		if err != nil {
+			r.log.Error("IN [fetch]:", err)
+			return nil, err
+		}
+		t.ParentDir = parent.String
+		res = append(res, t)
+	}
+
+	return res, nil
+}
+
+func (r *postgresDirRepository) fetchDrift(ctx context.Context, q interface {
+	QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
+}) (res []domain.DirDrift, err error) {
+	rows, err := q.QueryContext(ctx, driftQuery)
+	if err != nil {
+		r.log.Error("IN [fetchDrift]:", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	res = make([]domain.DirDrift, 0)
+	for rows.Next() {
+		t := domain.DirDrift{}
+		err = rows.Scan(&t.Uuid, &t.Path, &t.ExpectedPath, &t.ChildCount, &t.ExpectedChildCount)
+		if err != nil {
+			r.log.Error("IN [fetchDrift]:", err)
+			return nil, err
+		}
+		res = append(res, t)
+	}
+
+	return res, rows.Err()
+}
+
+// lockTree takes the tree lock until the end of tx
+func lockTree(ctx context.Context, tx *sql.Tx) (err error) {
+	_, err = tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, dirTreeLock)
+	return
+}
+
+// lockPath locks the row of a dir for the rest of tx and returns its path
+func lockPath(ctx context.Context, tx *sql.Tx, uuid string) (res string, err error) {
+	err = tx.QueryRowContext(ctx, `SELECT path FROM dir WHERE uuid = $1 FOR UPDATE`, uuid).Scan(&res)
+	return res, repository.MapErr(err)
+}
+
+// Get dir by uuid
+func (r *postgresDirRepository) GetByUuid(ctx context.Context, uuid string) (res domain.Dir, err error) {
+	query := `SELECT uuid, name, parent_dir, path, child_count FROM dir WHERE uuid = $1`
+
+	dirs, err := r.fetch(ctx, query, uuid)
+	if err != nil {
+		return domain.Dir{}, err
+	}
+
+	if len(dirs) < 1 {
+		return domain.Dir{}, domain.ErrNotFound
+	}
+
+	res = dirs[0]
+
+	return
+}
+
+// Get dir by its full path
+func (r *postgresDirRepository) GetByPath(ctx context.Context, path string) (res domain.Dir, err error) {
+	query := `SELECT uuid, name, parent_dir, path, child_count FROM dir WHERE path = $1`
+
+	dirs, err := r.fetch(ctx, query, path)
+	if err != nil {
+		return domain.Dir{}, err
+	}
+
+	if len(dirs) < 1 {
+		return domain.Dir{}, domain.ErrNotFound
+	}
+
+	res = dirs[0]
+
+	return
+}
+
+// Get the children of a dir, or the roots when parent is empty
+func (r *postgresDirRepository) GetChildren(ctx context.Context, parent string) (res []domain.Dir, err error) {
+	query :=
+		`SELECT uuid, name, parent_dir, path, child_count
+		FROM dir
+		WHERE parent_dir IS NOT DISTINCT FROM $1::uuid
+		ORDER BY name`
+
+	res, err = r.fetch(ctx, query, nullableUuid(parent))
+	if err != nil {
+		r.log.Error("IN [GetChildren]: could not fetch dirs ->", err)
+	}
+
+	return
+}
+
+// Get a dir and all its descendants
+func (r *postgresDirRepository) GetSubtree(ctx context.Context, uuid string) (res []domain.Dir, err error) {
+	root, err := r.GetByUuid(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	query :=
+		`SELECT uuid, name, parent_dir, path, child_count
+		FROM dir
+		WHERE path LIKE $1
+		ORDER BY name`
+
+	res, err = r.fetch(ctx, query, below(root.Path))
+	if err != nil {
+		r.log.Error("IN [GetSubtree]: could not fetch dirs ->", err)
+		return nil, err
+	}
+
+	return append([]domain.Dir{root}, res...), nil
+}
+
+// Know if a sibling already uses a name
+func (r *postgresDirRepository) ExistByName(ctx context.Context, parent string, name string) (res bool, err error) {
+	query :=
+		`SELECT COUNT(*) > 0
+		FROM dir
+		WHERE parent_dir IS NOT DISTINCT FROM $1::uuid AND name = $2`
+	err = r.Conn.QueryRowContext(ctx, query, nullableUuid(parent), name).Scan(&res)
+	if err != nil {
+		r.log.Error("IN [ExistByName]: could not check name ->", err)
+	}
+
+	return
+}
+
+// Store a new dir, keeping the parent's child count
+func (r *postgresDirRepository) Store(ctx context.Context, d *domain.Dir) (err error) {
+	tx, err := r.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		r.log.Error("IN [Store]: could not begin transaction ->", err)
+		return
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if err = lockTree(ctx, tx); err != nil {
+		r.log.Error("IN [Store]: could not take tree lock ->", err)
+		return
+	}
+
+	parentPath := ""
+	if d.ParentDir != "" {
+		if parentPath, err = lockPath(ctx, tx, d.ParentDir); err != nil {
+			r.log.Error("IN [Store]: could not lock parent dir ->", err)
+			return
+		}
+	}
+
+	query :=
+		`INSERT INTO dir (name, parent_dir, path)
+		VALUES ($1, $2, $3)
+		RETURNING uuid`
+	d.Path = childPath(parentPath, d.Name)
+	d.ChildCount = 0
+	err = tx.QueryRowContext(ctx, query, d.Name, nullableUuid(d.ParentDir), d.Path).Scan(&d.Uuid)
+	if err != nil {
+		r.log.Error("IN [Store]: could not store dir ->", err)
+		return repository.MapErr(err)
+	}
+
+	if d.ParentDir != "" {
+		_, err = tx.ExecContext(ctx, `UPDATE dir SET child_count = child_count + 1 WHERE uuid = $1`, d.ParentDir)
+		if err != nil {
+			r.log.Error("IN [Store]: could not update child count ->", err)
+			return
+		}
+	}
+
+	return tx.Commit()
+}
+
+// repath rewrites the path of a dir and of all its descendants
+func repath(ctx context.Context, tx *sql.Tx, oldPath string, newPath string) (err error) {
+	query :=
+		`UPDATE dir
+		SET path = $2 || substr(path, length($1) + 1)
+		WHERE path = $1 OR path LIKE $3`
+	_, err = tx.ExecContext(ctx, query, oldPath, newPath, below(oldPath))
+	return
+}
+
+// Rename a dir along with the paths below it
+func (r *postgresDirRepository) Rename(ctx context.Context, uuid string, name string) (err error) {
+	tx, err := r.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		r.log.Error("IN [Rename]: could not begin transaction ->", err)
+		return
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if err = lockTree(ctx, tx); err != nil {
+		r.log.Error("IN [Rename]: could not take tree lock ->", err)
+		return
+	}
+
+	oldPath, err := lockPath(ctx, tx, uuid)
+	if err != nil {
+		r.log.Error("IN [Rename]: could not lock dir ->", err)
+		return
+	}
+
+	if _, err = tx.ExecContext(ctx, `UPDATE dir SET name=$2 WHERE uuid=$1`, uuid, name); err != nil {
+		r.log.Error("IN [Rename]: could not rename dir ->", err)
+		return repository.MapErr(err)
+	}
+
+	newPath := childPath(oldPath[:strings.LastIndex(oldPath, pathSep)], name)
+	if err = repath(ctx, tx, oldPath, newPath); err != nil {
+		r.log.Error("IN [Rename]: could not update paths ->", err)
+		return repository.MapErr(err)
+	}
+
+	return tx.Commit()
+}
+
+// Move a dir under parent, refusing to create a cycle
+func (r *postgresDirRepository) Move(ctx context.Context, uuid string, parent string) (err error) {
+	tx, err := r.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		r.log.Error("IN [Move]: could not begin transaction ->", err)
+		return
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if err = lockTree(ctx, tx); err != nil {
+		r.log.Error("IN [Move]: could not take tree lock ->", err)
+		return
+	}
+
+	d := domain.Dir{}
+	old := sql.NullString{}
+	query := `SELECT name, parent_dir, path FROM dir WHERE uuid = $1 FOR UPDATE`
+	if err = tx.QueryRowContext(ctx, query, uuid).Scan(&d.Name, &old, &d.Path); err != nil {
+		r.log.Error("IN [Move]: could not lock dir ->", err)
+		return repository.MapErr(err)
+	}
+
+	parentPath := ""
+	if parent != "" {
+		if parentPath, err = lockPath(ctx, tx, parent); err != nil {
+			r.log.Error("IN [Move]: could not lock parent dir ->", err)
+			return
+		}
+		// The new parent lies in the subtree when its path starts with ours
+		if parentPath == d.Path || strings.HasPrefix(parentPath, d.Path+pathSep) {
+			return domain.ErrDirCycle
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE dir SET parent_dir=$2 WHERE uuid=$1`, uuid, nullableUuid(parent))
+	if err != nil {
+		r.log.Error("IN [Move]: could not move dir ->", err)
+		return repository.MapErr(err)
+	}
+
+	if err = repath(ctx, tx, d.Path, childPath(parentPath, d.Name)); err != nil {
+		r.log.Error("IN [Move]: could not update paths ->", err)
+		return repository.MapErr(err)
+	}
+
+	query = `UPDATE dir SET child_count = child_count + $2 WHERE uuid = $1`
+	if old.Valid {
+		if _, err = tx.ExecContext(ctx, query, old.String, -1); err != nil {
+			r.log.Error("IN [Move]: could not update child count ->", err)
+			return
+		}
+	}
+	if parent != "" {
+		if _, err = tx.ExecContext(ctx, query, parent, 1); err != nil {
+			r.log.Error("IN [Move]: could not update child count ->", err)
+			return
+		}
+	}
+
+	return tx.Commit()
+}
+
+// removeSubtree deletes a dir, and its descendants when recursive, keeping the parent's child count
+func (r *postgresDirRepository) removeSubtree(ctx context.Context, uuid string, recursive bool) (err error) {
+	tx, err := r.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		r.log.Error("IN [removeSubtree]: could not begin transaction ->", err)
+		return
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if err = lockTree(ctx, tx); err != nil {
+		r.log.Error("IN [removeSubtree]: could not take tree lock ->", err)
+		return
+	}
+
+	parent := sql.NullString{}
+	path := ""
+	query := `SELECT parent_dir, path FROM dir WHERE uuid = $1 FOR UPDATE`
+	if err = tx.QueryRowContext(ctx, query, uuid).Scan(&parent, &path); err != nil {
+		r.log.Error("IN [removeSubtree]: could not lock dir ->", err)
+		return repository.MapErr(err)
+	}
+
+	if recursive {
+		_, err = tx.ExecContext(ctx, `DELETE FROM dir WHERE uuid = $1 OR path LIKE $2`, uuid, below(path))
+	} else {
+		_, err = tx.ExecContext(ctx, `DELETE FROM dir WHERE uuid = $1`, uuid)
+	}
+	if err != nil {
+		r.log.Error("IN [removeSubtree]: could not delete dir ->", err)
+		return repository.MapDeleteErr(err)
+	}
+
+	if parent.Valid {
+		query = `UPDATE dir SET child_count = child_count - 1 WHERE uuid = $1`
+		if _, err = tx.ExecContext(ctx, query, parent.String); err != nil {
+			r.log.Error("IN [removeSubtree]: could not update child count ->", err)
+			return
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Delete a dir without children
+func (r *postgresDirRepository) Delete(ctx context.Context, uuid string) (err error) {
+	return r.removeSubtree(ctx, uuid, false)
+}
+
+// Delete a dir and all its descendants in a single statement
+func (r *postgresDirRepository) DeleteTree(ctx context.Context, uuid string) (err error) {
+	return r.removeSubtree(ctx, uuid, true)
+}
+
+// Find the dirs whose cached path or child count differ from the tree
+func (r *postgresDirRepository) FindDrift(ctx context.Context) (res []domain.DirDrift, err error) {
+	return r.fetchDrift(ctx, r.Conn)
+}
+
+// Rewrite the cached columns of the dirs that drifted
+func (r *postgresDirRepository) RepairDrift(ctx context.Context) (res []domain.DirDrift, err error) {
+	tx, err := r.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		r.log.Error("IN [RepairDrift]: could not begin transaction ->", err)
+		return
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	// Keep writers out while the expected values are computed and applied
+	if err = lockTree(ctx, tx); err != nil {
+		r.log.Error("IN [RepairDrift]: could not take tree lock ->", err)
+		return
+	}
+
+	res, err = r.fetchDrift(ctx, tx)
+	if err != nil {
+		return
+	}
+
+	query := `UPDATE dir SET path = $2, child_count = $3 WHERE uuid = $1`
+	for _, d := range res {
+		if _, err = tx.ExecContext(ctx, query, d.Uuid, d.ExpectedPath, d.ExpectedChildCount); err != nil {
+			r.log.Error("IN [RepairDrift]: could not repair dir {", d.Uuid, "} ->", err)
+			return nil, err
+		}
+	}
+
+	return res, tx.Commit()
+}
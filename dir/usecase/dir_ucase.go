@@ -0,0 +1,441 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+)
+
+type dirUsecase struct {
+	dirRepo        domain.DirRepository
+	projectRepo    domain.ProjectRepository
+	authorizer     domain.Authorizer
+	contextTimeout time.Duration
+	log            utils.AggregatedLogger
+}
+
+/*
+* NewDirUsecase will create a new dirUsecase object representation of
+* domain.DirUsecase interface. The dirs of a project are only visible to its
+* members and to project managers
+ */
+func NewDirUsecase(dr domain.DirRepository, pr domain.ProjectRepository, az domain.Authorizer, timeout time.Duration) domain.DirUsecase {
+	logger := utils.NewAggregatedLogger(constants.Usecase, constants.Dir)
+	return &dirUsecase{
+		dirRepo:        dr,
+		projectRepo:    pr,
+		authorizer:     az,
+		contextTimeout: timeout,
+		log:            logger,
+	}
+}
+
+// checkName rejects names that would be ambiguous inside a path
+func checkName(name string) (rErr domain.RequestErr) {
+	if strings.TrimSpace(name) == "" || name == "." || name == ".." || strings.Contains(name, "/") {
+		err := errors.New(fmt.Sprint("Invalid directory name: ", name))
+		rErr = domain.NewUCaseErr(http.StatusBadRequest, err)
+	}
+
+	return
+}
+
+// viewer returns the authenticated user and whether they manage every project
+func (u *dirUsecase) viewer(ctx context.Context) (res domain.User, manager bool, rErr domain.RequestErr) {
+	res, ok := domain.UserFromContext(ctx)
+	if !ok {
+		return res, false, domain.NewUCaseErr(http.StatusUnauthorized, errors.New("Authentication required"))
+	}
+
+	manager, err := u.authorizer.Can(ctx, res, domain.PermProjectManage)
+	if err != nil {
+		u.log.Error("IN [viewer]: could not check permission ->", err)
+		return res, false, domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Access check failed"))
+	}
+
+	return
+}
+
+// visible reports whether the authenticated user may see dir, outsiders of its project may not
+func (u *dirUsecase) visible(ctx context.Context, dir string) (res bool, rErr domain.RequestErr) {
+	user, manager, rErr := u.viewer(ctx)
+	if rErr != nil || manager {
+		return manager, rErr
+	}
+
+	p, err := u.projectRepo.GetByDir(ctx, dir)
+	if errors.Is(err, domain.ErrNotFound) {
+		return true, nil
+	}
+	if err == nil {
+		res, err = u.projectRepo.IsMember(ctx, p.Uuid, user.Uuid)
+	}
+	if err != nil {
+		u.log.Error("IN [visible]: could not check membership ->", err)
+		return false, domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Access check failed"))
+	}
+
+	return
+}
+
+// hiddenRoots returns the root dirs of the projects the authenticated user can not see
+func (u *dirUsecase) hiddenRoots(ctx context.Context) (res map[string]bool, rErr domain.RequestErr) {
+	user, manager, rErr := u.viewer(ctx)
+	if rErr != nil || manager {
+		return
+	}
+
+	all, err := u.projectRepo.GetAll(ctx)
+	var mine []domain.Project
+	if err == nil {
+		mine, err = u.projectRepo.GetByMember(ctx, user.Uuid)
+	}
+	if err != nil {
+		u.log.Error("IN [hiddenRoots]: could not fetch projects ->", err)
+		return nil, domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Access check failed"))
+	}
+
+	res = make(map[string]bool, len(all))
+	for _, p := range all {
+		res[p.Dir] = true
+	}
+	for _, p := range mine {
+		delete(res, p.Dir)
+	}
+
+	return
+}
+
+// get returns the dir, answering 404 to the outsiders of its project as if it did not exist
+func (u *dirUsecase) get(ctx context.Context, uuid string) (res domain.Dir, rErr domain.RequestErr) {
+	notFound := domain.NewUCaseErr(http.StatusNotFound, errors.New(fmt.Sprint("Directory not found. uuid: ", uuid)))
+	res, err := u.dirRepo.GetByUuid(ctx, uuid)
+	if err != nil {
+		return res, domain.NewRepoErr(err, notFound.Error())
+	}
+
+	ok, rErr := u.visible(ctx, uuid)
+	if rErr == nil && !ok {
+		rErr = notFound
+	}
+	if rErr != nil {
+		return domain.Dir{}, rErr
+	}
+
+	return
+}
+
+// checkFreeName fails when a child of parent already uses name
+func (u *dirUsecase) checkFreeName(ctx context.Context, parent string, name string) (rErr domain.RequestErr) {
+	taken, err := u.dirRepo.ExistByName(ctx, parent, name)
+	if err != nil {
+		return domain.NewUCaseErr(http.StatusInternalServerError, err)
+	}
+	if taken {
+		err = errors.New(fmt.Sprint("Directory name already in use: ", name))
+		return domain.NewUCaseErr(http.StatusConflict, err)
+	}
+
+	return
+}
+
+// checkOpen fails when the dir lies in the tree of a closed project
+func (u *dirUsecase) checkOpen(ctx context.Context, uuid string) (rErr domain.RequestErr) {
+	closed, err := u.projectRepo.IsClosedDir(ctx, uuid)
+	if err != nil {
+		u.log.Error("IN [checkOpen]: could not check project state ->", err)
+		return domain.NewUCaseErr(http.StatusInternalServerError, err)
+	}
+	if closed {
+		err = errors.New(fmt.Sprint("Directory belongs to a closed project. uuid: ", uuid))
+		return domain.NewUCaseErr(http.StatusConflict, err)
+	}
+
+	return
+}
+
+func (u *dirUsecase) GetByUuid(c context.Context, uuid string) (res domain.Dir, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	return u.get(ctx, uuid)
+}
+
+func (u *dirUsecase) GetByPath(c context.Context, path string) (res domain.Dir, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	msg := fmt.Sprint("Directory not found. path: ", path)
+	res, err := u.dirRepo.GetByPath(ctx, path)
+	if err != nil {
+		return res, domain.NewRepoErr(err, msg)
+	}
+
+	ok, rErr := u.visible(ctx, res.Uuid)
+	if rErr == nil && !ok {
+		rErr = domain.NewUCaseErr(http.StatusNotFound, errors.New(msg))
+	}
+	if rErr != nil {
+		return domain.Dir{}, rErr
+	}
+
+	return
+}
+
+func (u *dirUsecase) GetChildren(c context.Context, parent string) (res []domain.Dir, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	if parent != "" {
+		if _, rErr = u.get(ctx, parent); rErr != nil {
+			return
+		}
+	}
+
+	hidden, rErr := u.hiddenRoots(ctx)
+	if rErr != nil {
+		return
+	}
+
+	children, err := u.dirRepo.GetChildren(ctx, parent)
+	if err != nil {
+		u.log.Error("IN [GetChildren]: could not get children ->", err)
+		rErr = domain.NewUCaseErr(http.StatusInternalServerError, err)
+		return
+	}
+
+	res = make([]domain.Dir, 0, len(children))
+	for _, d := range children {
+		if !hidden[d.Uuid] {
+			res = append(res, d)
+		}
+	}
+
+	return
+}
+
+func (u *dirUsecase) GetTree(c context.Context, uuid string) (res domain.DirTree, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	if _, rErr = u.get(ctx, uuid); rErr != nil {
+		return
+	}
+	hidden, rErr := u.hiddenRoots(ctx)
+	if rErr != nil {
+		return
+	}
+
+	dirs, err := u.dirRepo.GetSubtree(ctx, uuid)
+	if err != nil {
+		u.log.Error("IN [GetTree]: could not get subtree ->", err)
+		rErr = domain.NewRepoErr(err, fmt.Sprint("Directory not found. uuid: ", uuid))
+		return
+	}
+	if len(dirs) == 0 {
+		err = errors.New(fmt.Sprint("Directory not found. uuid: ", uuid))
+		rErr = domain.NewUCaseErr(http.StatusNotFound, err)
+		return
+	}
+
+	// dirs come sorted by name, so children keep that order. Hidden project roots prune their whole subtree
+	children := map[string][]domain.Dir{}
+	var root domain.Dir
+	for _, d := range dirs {
+		if d.Uuid == uuid {
+			root = d
+			continue
+		}
+		if !hidden[d.Uuid] {
+			children[d.ParentDir] = append(children[d.ParentDir], d)
+		}
+	}
+
+	var build func(d domain.Dir) domain.DirTree
+	build = func(d domain.Dir) domain.DirTree {
+		t := domain.DirTree{Dir: d, Children: make([]domain.DirTree, 0)}
+		for _, child := range children[d.Uuid] {
+			t.Children = append(t.Children, build(child))
+		}
+		return t
+	}
+
+	return build(root), nil
+}
+
+func (u *dirUsecase) Store(c context.Context, d *domain.Dir) (rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	if rErr = checkName(d.Name); rErr != nil {
+		return
+	}
+
+	if d.ParentDir != "" {
+		if _, rErr = u.get(ctx, d.ParentDir); rErr != nil {
+			return
+		}
+		if rErr = u.checkOpen(ctx, d.ParentDir); rErr != nil {
+			return
+		}
+	}
+
+	if rErr = u.checkFreeName(ctx, d.ParentDir, d.Name); rErr != nil {
+		return
+	}
+
+	err := u.dirRepo.Store(ctx, d)
+	if errors.Is(err, domain.ErrConflict) {
+		err = errors.New(fmt.Sprint("Directory name already in use: ", d.Name))
+		return domain.NewUCaseErr(http.StatusConflict, err)
+	}
+	if err != nil {
+		u.log.Error("IN [Store]: could not store dir ->", err)
+		return domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Directory creation failed"))
+	}
+
+	return
+}
+
+func (u *dirUsecase) Rename(c context.Context, uuid string, name string) (rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	if rErr = checkName(name); rErr != nil {
+		return
+	}
+
+	d, rErr := u.get(ctx, uuid)
+	if rErr != nil {
+		return
+	}
+	if d.Name == name {
+		return
+	}
+	if rErr = u.checkOpen(ctx, uuid); rErr != nil {
+		return
+	}
+
+	if rErr = u.checkFreeName(ctx, d.ParentDir, name); rErr != nil {
+		return
+	}
+
+	err := u.dirRepo.Rename(ctx, uuid, name)
+	if errors.Is(err, domain.ErrConflict) {
+		err = errors.New(fmt.Sprint("Directory name already in use: ", name))
+		return domain.NewUCaseErr(http.StatusConflict, err)
+	}
+	if err != nil {
+		return domain.NewRepoErr(err, "Directory not found")
+	}
+
+	return
+}
+
+func (u *dirUsecase) Move(c context.Context, uuid string, parent string) (rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	d, rErr := u.get(ctx, uuid)
+	if rErr != nil {
+		return
+	}
+	if d.ParentDir == parent {
+		return
+	}
+	if rErr = u.checkOpen(ctx, uuid); rErr != nil {
+		return
+	}
+
+	if parent != "" {
+		if _, rErr = u.get(ctx, parent); rErr != nil {
+			return
+		}
+		if rErr = u.checkOpen(ctx, parent); rErr != nil {
+			return
+		}
+	}
+
+	if rErr = u.checkFreeName(ctx, parent, d.Name); rErr != nil {
+		return
+	}
+
+	err := u.dirRepo.Move(ctx, uuid, parent)
+	if errors.Is(err, domain.ErrDirCycle) {
+		err = errors.New("A directory cannot be moved into itself or its subdirectories")
+		return domain.NewUCaseErr(http.StatusConflict, err)
+	}
+	if errors.Is(err, domain.ErrConflict) {
+		err = errors.New(fmt.Sprint("Directory name already in use: ", d.Name))
+		return domain.NewUCaseErr(http.StatusConflict, err)
+	}
+	if err != nil {
+		return domain.NewRepoErr(err, "Directory not found")
+	}
+
+	return
+}
+
+func (u *dirUsecase) Delete(c context.Context, uuid string, recursive bool) (rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	d, rErr := u.get(ctx, uuid)
+	if rErr != nil {
+		return
+	}
+	if rErr = u.checkOpen(ctx, uuid); rErr != nil {
+		return
+	}
+
+	var err error
+	if recursive {
+		err = u.dirRepo.DeleteTree(ctx, uuid)
+	} else {
+		if d.ChildCount > 0 {
+			err = errors.New("Directory not empty, delete it recursively instead")
+			return domain.NewUCaseErr(http.StatusConflict, err)
+		}
+		err = u.dirRepo.Delete(ctx, uuid)
+	}
+
+	if errors.Is(err, domain.ErrConflict) {
+		err = errors.New("Directory still holds documents or is referenced")
+		return domain.NewUCaseErr(http.StatusConflict, err)
+	}
+	if err != nil {
+		return domain.NewRepoErr(err, "Directory not found")
+	}
+
+	u.log.Info("IN [Delete]: deleted dir {", uuid, "} recursive:", recursive)
+	return
+}
+
+func (u *dirUsecase) Check(c context.Context, repair bool) (res []domain.DirDrift, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	var err error
+	if repair {
+		res, err = u.dirRepo.RepairDrift(ctx)
+	} else {
+		res, err = u.dirRepo.FindDrift(ctx)
+	}
+	if err != nil {
+		u.log.Error("IN [Check]: could not check dir consistency ->", err)
+		rErr = domain.NewUCaseErr(http.StatusInternalServerError, err)
+		return
+	}
+
+	if len(res) > 0 {
+		u.log.Warn("IN [Check]: found", len(res), "drifted dirs, repaired:", repair)
+	}
+	return
+}
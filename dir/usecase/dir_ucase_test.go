@@ -0,0 +1,227 @@
+package usecase_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sicozz/papyrus/dir/usecase"
+	"github.com/sicozz/papyrus/domain"
+)
+
+var (
+	ana  = domain.User{Uuid: "u-ana", Username: "ana"}
+	boss = domain.User{Uuid: "u-boss", Username: "boss"}
+)
+
+/*
+* The tree of the tests: Apollo is a project of ana, Zeus and Hermes are
+* projects without members, Hermes being moved below a plain dir
+ */
+var dirs = []domain.Dir{
+	{Uuid: "shared", Name: "Shared", Path: "/Shared"},
+	{Uuid: "hermes", Name: "Hermes", ParentDir: "shared", Path: "/Shared/Hermes"},
+	{Uuid: "notes", Name: "Notes", ParentDir: "shared", Path: "/Shared/Notes"},
+	{Uuid: "apollo", Name: "Apollo", Path: "/Apollo"},
+	{Uuid: "specs", Name: "Specs", ParentDir: "apollo", Path: "/Apollo/Specs"},
+	{Uuid: "zeus", Name: "Zeus", Path: "/Zeus"},
+	{Uuid: "plans", Name: "Plans", ParentDir: "zeus", Path: "/Zeus/Plans"},
+}
+
+var projects = []domain.Project{
+	{Uuid: "p-apollo", Name: "Apollo", Dir: "apollo"},
+	{Uuid: "p-zeus", Name: "Zeus", Dir: "zeus"},
+	{Uuid: "p-hermes", Name: "Hermes", Dir: "hermes"},
+}
+
+var members = map[string][]string{"p-apollo": {ana.Uuid}}
+
+func dirByUuid(uuid string) (domain.Dir, bool) {
+	for _, d := range dirs {
+		if d.Uuid == uuid {
+			return d, true
+		}
+	}
+	return domain.Dir{}, false
+}
+
+func under(path string, root string) bool {
+	return path == root || strings.HasPrefix(path, root+"/")
+}
+
+type fakeDirRepo struct{ domain.DirRepository }
+
+func (fakeDirRepo) GetByUuid(ctx context.Context, uuid string) (domain.Dir, error) {
+	d, ok := dirByUuid(uuid)
+	if !ok {
+		return domain.Dir{}, domain.ErrNotFound
+	}
+	return d, nil
+}
+
+func (fakeDirRepo) GetByPath(ctx context.Context, path string) (domain.Dir, error) {
+	for _, d := range dirs {
+		if d.Path == path {
+			return d, nil
+		}
+	}
+	return domain.Dir{}, domain.ErrNotFound
+}
+
+func (fakeDirRepo) GetChildren(ctx context.Context, parent string) ([]domain.Dir, error) {
+	res := make([]domain.Dir, 0)
+	for _, d := range dirs {
+		if d.ParentDir == parent {
+			res = append(res, d)
+		}
+	}
+	return res, nil
+}
+
+func (fakeDirRepo) GetSubtree(ctx context.Context, uuid string) ([]domain.Dir, error) {
+	root, ok := dirByUuid(uuid)
+	if !ok {
+		return nil, nil
+	}
+	res := make([]domain.Dir, 0)
+	for _, d := range dirs {
+		if under(d.Path, root.Path) {
+			res = append(res, d)
+		}
+	}
+	return res, nil
+}
+
+type fakeProjectRepo struct{ domain.ProjectRepository }
+
+func (fakeProjectRepo) GetAll(ctx context.Context) ([]domain.Project, error) {
+	return projects, nil
+}
+
+func (fakeProjectRepo) GetByMember(ctx context.Context, userUuid string) ([]domain.Project, error) {
+	res := make([]domain.Project, 0)
+	for _, p := range projects {
+		for _, m := range members[p.Uuid] {
+			if m == userUuid {
+				res = append(res, p)
+			}
+		}
+	}
+	return res, nil
+}
+
+func (fakeProjectRepo) GetByDir(ctx context.Context, dir string) (res domain.Project, err error) {
+	d, _ := dirByUuid(dir)
+	depth := -1
+	for _, p := range projects {
+		root, _ := dirByUuid(p.Dir)
+		if under(d.Path, root.Path) && len(root.Path) > depth {
+			res, depth = p, len(root.Path)
+		}
+	}
+	if depth < 0 {
+		return res, domain.ErrNotFound
+	}
+	return res, nil
+}
+
+func (r fakeProjectRepo) IsMember(ctx context.Context, uuid string, userUuid string) (bool, error) {
+	for _, m := range members[uuid] {
+		if m == userUuid {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (fakeProjectRepo) IsClosedDir(ctx context.Context, dir string) (bool, error) {
+	return false, nil
+}
+
+// fakeAuthorizer makes boss the only project manager
+type fakeAuthorizer struct{ domain.Authorizer }
+
+func (fakeAuthorizer) Can(ctx context.Context, u domain.User, perm string) (bool, error) {
+	return perm == domain.PermProjectManage && u.Uuid == boss.Uuid, nil
+}
+
+func newUsecase() domain.DirUsecase {
+	return usecase.NewDirUsecase(fakeDirRepo{}, fakeProjectRepo{}, fakeAuthorizer{}, time.Second)
+}
+
+func as(u domain.User) context.Context {
+	return domain.NewContextWithUser(context.Background(), u)
+}
+
+func names(ds []domain.Dir) string {
+	res := make([]string, 0, len(ds))
+	for _, d := range ds {
+		res = append(res, d.Name)
+	}
+	return strings.Join(res, ",")
+}
+
+func treeNames(t domain.DirTree) string {
+	res := t.Name
+	for _, c := range t.Children {
+		res += "(" + treeNames(c) + ")"
+	}
+	return res
+}
+
+func TestOutsidersDoNotSeeProjectDirs(t *testing.T) {
+	du := newUsecase()
+
+	for _, uuid := range []string{"zeus", "plans", "hermes"} {
+		_, rErr := du.GetByUuid(as(ana), uuid)
+		if rErr == nil || rErr.GetStatus() != http.StatusNotFound {
+			t.Errorf("outsider reading %s: got %v, want 404", uuid, rErr)
+		}
+	}
+	if _, rErr := du.GetByPath(as(ana), "/Zeus/Plans"); rErr == nil || rErr.GetStatus() != http.StatusNotFound {
+		t.Errorf("outsider reading /Zeus/Plans by path: got %v, want 404", rErr)
+	}
+	if _, rErr := du.GetChildren(as(ana), "zeus"); rErr == nil || rErr.GetStatus() != http.StatusNotFound {
+		t.Errorf("outsider listing zeus: got %v, want 404", rErr)
+	}
+	if _, rErr := du.GetTree(as(ana), "zeus"); rErr == nil || rErr.GetStatus() != http.StatusNotFound {
+		t.Errorf("outsider reading the tree of zeus: got %v, want 404", rErr)
+	}
+	if rErr := du.Rename(as(ana), "plans", "Other"); rErr == nil || rErr.GetStatus() != http.StatusNotFound {
+		t.Errorf("outsider renaming plans: got %v, want 404", rErr)
+	}
+
+	for _, uuid := range []string{"apollo", "specs", "shared", "notes"} {
+		if _, rErr := du.GetByUuid(as(ana), uuid); rErr != nil {
+			t.Errorf("member reading %s: %v", uuid, rErr)
+		}
+	}
+}
+
+func TestListingsHideForeignProjects(t *testing.T) {
+	du := newUsecase()
+
+	roots, rErr := du.GetChildren(as(ana), "")
+	if rErr != nil || names(roots) != "Shared,Apollo" {
+		t.Fatalf("roots for a member: got %q, %v", names(roots), rErr)
+	}
+	children, rErr := du.GetChildren(as(ana), "shared")
+	if rErr != nil || names(children) != "Notes" {
+		t.Fatalf("children of shared: got %q, %v", names(children), rErr)
+	}
+	tree, rErr := du.GetTree(as(ana), "shared")
+	if rErr != nil || treeNames(tree) != "Shared(Notes)" {
+		t.Fatalf("tree of shared: got %q, %v", treeNames(tree), rErr)
+	}
+
+	roots, rErr = du.GetChildren(as(boss), "")
+	if rErr != nil || names(roots) != "Shared,Apollo,Zeus" {
+		t.Fatalf("roots for a manager: got %q, %v", names(roots), rErr)
+	}
+	tree, rErr = du.GetTree(as(boss), "shared")
+	if rErr != nil || treeNames(tree) != "Shared(Hermes)(Notes)" {
+		t.Fatalf("tree of shared for a manager: got %q, %v", treeNames(tree), rErr)
+	}
+}
@@ -0,0 +1,63 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// ApiKeyPrefix marks bearer credentials that are API keys instead of access tokens
+const ApiKeyPrefix = `pk_`
+
+const ctxScopesKey ctxKey = "authScopes"
+
+// ApiKey is representing the personal API key data struct
+type ApiKey struct {
+	Uuid       string     `json:"uuid"`
+	UserUuid   string     `json:"user_uuid"`
+	Name       string     `json:"name" validate:"required,max=64"`
+	Prefix     string     `json:"prefix"`
+	KeyHash    string     `json:"-"`
+	Scopes     []string   `json:"scopes" validate:"dive,max=64"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Revoked    bool       `json:"revoked"`
+}
+
+// ApiKeySecret is representing a freshly created key, the only time Key is shown
+type ApiKeySecret struct {
+	ApiKey
+	Key string `json:"key"`
+}
+
+// ApiKeyUsecase represents the API key's usecases
+type ApiKeyUsecase interface {
+	Fetch(c context.Context, uname string) ([]ApiKey, RequestErr)
+	Store(c context.Context, uname string, k ApiKey) (ApiKeySecret, RequestErr)
+	Revoke(c context.Context, uname string, uuid string) RequestErr
+	// Authenticate resolves the owner of a key and records its use
+	Authenticate(c context.Context, key string) (User, ApiKey, RequestErr)
+}
+
+// ApiKeyRepository represents the API key's repository contract
+type ApiKeyRepository interface {
+	GetByUser(ctx context.Context, userUuid string) ([]ApiKey, error)
+	GetByHash(ctx context.Context, hash string) (ApiKey, error)
+	Store(ctx context.Context, k *ApiKey) error
+	Revoke(ctx context.Context, userUuid string, uuid string) (bool, error)
+	Touch(ctx context.Context, uuid string, date time.Time) error
+}
+
+/*
+* NewContextWithScopes returns a copy of ctx restricting the authenticated user
+* to scopes. An empty list leaves the user's permissions untouched
+ */
+func NewContextWithScopes(ctx context.Context, scopes []string) context.Context {
+	return context.WithValue(ctx, ctxScopesKey, scopes)
+}
+
+// ScopesFromContext returns the scopes the request is restricted to, if any
+func ScopesFromContext(ctx context.Context) ([]string, bool) {
+	s, ok := ctx.Value(ctxScopesKey).([]string)
+	return s, ok && len(s) > 0
+}
@@ -0,0 +1,65 @@
+package domain
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// Kinds of file transfer kept by the audit trail (see download and upload)
+const (
+	TransferDownload = "download"
+	TransferUpload   = "upload"
+)
+
+// Transfer is representing a download or an upload of a file
+type Transfer struct {
+	Uuid     string    `json:"uuid"`
+	Date     time.Time `json:"date"`
+	User     string    `json:"user"`
+	Username string    `json:"username"`
+	File     string    `json:"file"`
+	FileCode string    `json:"file_code"`
+	FileName string    `json:"file_name"`
+	Dir      string    `json:"dir"`
+	Version  int       `json:"version"`
+	Bytes    int64     `json:"bytes"`
+}
+
+// TransferFilter is representing a query over the downloads or the uploads
+type TransferFilter struct {
+	Kind   string
+	Limit  int64
+	Cursor string
+	// Username holds the username, User its resolved uuid
+	Username string
+	User     string
+	File     string
+	// Dir matches the files of its whole subtree
+	Dir string
+	// From and To bound the date, To excluded, zero values leave it open
+	From time.Time
+	To   time.Time
+}
+
+// TransferPage is representing one page of transfers, newest first
+type TransferPage struct {
+	Transfers  []Transfer `json:"transfers"`
+	NextCursor string     `json:"next_cursor,omitempty"`
+}
+
+// AuditUsecase represents the transfer audit trail's usecases
+type AuditUsecase interface {
+	Fetch(c context.Context, f TransferFilter) (TransferPage, RequestErr)
+	// Export writes every transfer matching f as CSV to w, ignoring its limit and cursor
+	Export(c context.Context, f TransferFilter, w io.Writer) RequestErr
+}
+
+// AuditRepository represents the transfer audit trail's repository contract
+type AuditRepository interface {
+	// RecordDownload logs a download, uploads are logged along with the content they store
+	RecordDownload(ctx context.Context, t *Transfer) error
+	GetPage(ctx context.Context, f TransferFilter) ([]Transfer, string, error)
+	// Each calls fn on every transfer matching f, newest first, ignoring its limit and cursor
+	Each(ctx context.Context, f TransferFilter, fn func(Transfer) error) error
+}
@@ -0,0 +1,78 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+type ctxKey string
+
+const ctxUserKey ctxKey = "authUser"
+
+// Session is representing the server side refresh token data struct
+type Session struct {
+	Uuid      string    `json:"uuid"`
+	UserUuid  string    `json:"user_uuid"`
+	TokenHash string    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Revoked   bool      `json:"revoked"`
+}
+
+// TokenPair is representing the tokens issued to an authenticated user
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// Second login steps announced by LoginResult
+const (
+	MfaStepVerify = "verify"
+	MfaStepEnroll = "enroll"
+)
+
+// LoginResult is representing the outcome of the password step of a login,
+// either the tokens or the second step to complete with MfaToken
+type LoginResult struct {
+	*TokenPair
+	MfaStep  string `json:"mfa_step,omitempty"`
+	MfaToken string `json:"mfa_token,omitempty"`
+}
+
+// TotpConfirmation is representing the tokens issued after a login time enrollment
+type TotpConfirmation struct {
+	TokenPair
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// AuthUsecase represents the authentication usecases
+type AuthUsecase interface {
+	Login(c context.Context, uname string, passwd string, ip string) (LoginResult, RequestErr)
+	LoginTotp(c context.Context, mfaToken string, code string, ip string) (TokenPair, RequestErr)
+	EnrollTotp(c context.Context, mfaToken string) (TotpEnrollment, RequestErr)
+	ConfirmTotp(c context.Context, mfaToken string, code string) (TotpConfirmation, RequestErr)
+	Refresh(c context.Context, refreshToken string) (TokenPair, RequestErr)
+	Logout(c context.Context, refreshToken string) RequestErr
+	Authenticate(c context.Context, accessToken string) (User, RequestErr)
+}
+
+// SessionRepository represents the session's repository contract
+type SessionRepository interface {
+	GetByTokenHash(ctx context.Context, hash string) (Session, error)
+	Store(ctx context.Context, s *Session) error
+	Revoke(ctx context.Context, uuid string) (bool, error)
+	RevokeAllByUser(ctx context.Context, userUuid string) error
+}
+
+// NewContextWithUser returns a copy of ctx carrying the authenticated user
+func NewContextWithUser(ctx context.Context, u User) context.Context {
+	return context.WithValue(ctx, ctxUserKey, u)
+}
+
+// UserFromContext returns the authenticated user carried by ctx, if any
+func UserFromContext(ctx context.Context) (User, bool) {
+	u, ok := ctx.Value(ctxUserKey).(User)
+	return u, ok
+}
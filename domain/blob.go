@@ -0,0 +1,25 @@
+package domain
+
+import (
+	"context"
+	"io"
+)
+
+// BlobInfo is representing what a BlobStore knows about a stored blob
+type BlobInfo struct {
+	Key  string
+	Size int64
+}
+
+/*
+* BlobStore represents the storage of file contents. Implementations stream
+* in both directions and return ErrNotFound for unknown keys
+ */
+type BlobStore interface {
+	// Put stores r under key, size is -1 when unknown
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get reads length bytes from offset, length -1 reads to the end
+	Get(ctx context.Context, key string, offset int64, length int64) (io.ReadCloser, error)
+	Stat(ctx context.Context, key string) (BlobInfo, error)
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,8 @@
+package domain
+
+import "time"
+
+// Clock represents the source of the current time, replaceable by a fake one
+type Clock interface {
+	Now() time.Time
+}
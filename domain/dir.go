@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrDirCycle will throw if a directory is moved below itself
+var ErrDirCycle = errors.New("directory cannot be moved into its own subtree")
+
+// Dir is representing the directory data struct. Roots have no ParentDir
+type Dir struct {
+	Uuid      string `json:"uuid"`
+	Name      string `json:"name" validate:"required,max=256,excludes=/"`
+	ParentDir string `json:"parent_dir,omitempty" validate:"omitempty,uuid"`
+	// Path and ChildCount are cached by the repository, e.g. /Quality/Procedures
+	Path       string `json:"path"`
+	ChildCount int64  `json:"child_count"`
+}
+
+// DirDrift is representing a directory whose cached columns went out of sync
+type DirDrift struct {
+	Uuid               string `json:"uuid"`
+	Path               string `json:"path"`
+	ExpectedPath       string `json:"expected_path"`
+	ChildCount         int64  `json:"child_count"`
+	ExpectedChildCount int64  `json:"expected_child_count"`
+}
+
+// DirTree is representing a directory along with all its descendants
+type DirTree struct {
+	Dir
+	Children []DirTree `json:"children"`
+}
+
+// DirUsecase represents the directory's usecases
+type DirUsecase interface {
+	GetByUuid(c context.Context, uuid string) (Dir, RequestErr)
+	GetByPath(c context.Context, path string) (Dir, RequestErr)
+	// GetChildren lists the children of parent, or the roots when parent is empty
+	GetChildren(c context.Context, parent string) ([]Dir, RequestErr)
+	GetTree(c context.Context, uuid string) (DirTree, RequestErr)
+	Store(c context.Context, d *Dir) RequestErr
+	Rename(c context.Context, uuid string, name string) RequestErr
+	// Move places the directory under parent, or makes it a root when parent is empty
+	Move(c context.Context, uuid string, parent string) RequestErr
+	Delete(c context.Context, uuid string, recursive bool) RequestErr
+	// Check lists the directories with stale cached columns, fixing them when repair is set
+	Check(c context.Context, repair bool) ([]DirDrift, RequestErr)
+}
+
+// DirRepository represents the directory's repository contract
+type DirRepository interface {
+	GetByUuid(ctx context.Context, uuid string) (Dir, error)
+	GetByPath(ctx context.Context, path string) (Dir, error)
+	GetChildren(ctx context.Context, parent string) ([]Dir, error)
+	// GetSubtree returns the directory and all its descendants, found by path prefix
+	GetSubtree(ctx context.Context, uuid string) ([]Dir, error)
+	ExistByName(ctx context.Context, parent string, name string) (bool, error)
+	// Store inserts the directory and updates the parent's child count
+	Store(ctx context.Context, d *Dir) error
+	// Rename also rewrites the path of every descendant
+	Rename(ctx context.Context, uuid string, name string) error
+	// Move reparents a directory, failing with ErrDirCycle when parent is below it
+	Move(ctx context.Context, uuid string, parent string) error
+	Delete(ctx context.Context, uuid string) error
+	// DeleteTree removes the directory and all its descendants
+	DeleteTree(ctx context.Context, uuid string) error
+	// FindDrift recomputes the cached columns and reports the rows that differ
+	FindDrift(ctx context.Context) ([]DirDrift, error)
+	// RepairDrift rewrites the differing rows and reports them
+	RepairDrift(ctx context.Context) ([]DirDrift, error)
+}
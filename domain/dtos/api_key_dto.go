@@ -0,0 +1,9 @@
+package dtos
+
+import "time"
+
+type ApiKeyDto struct {
+	Name      string     `json:"name" validate:"required,max=64"`
+	Scopes    []string   `json:"scopes" validate:"max=16,dive,required,max=64"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
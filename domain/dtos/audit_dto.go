@@ -0,0 +1,12 @@
+package dtos
+
+type TransferQueryDto struct {
+	User   string `query:"user" validate:"max=32"`
+	File   string `query:"file" validate:"omitempty,uuid"`
+	Dir    string `query:"dir" validate:"omitempty,uuid"`
+	From   string `query:"from"`
+	To     string `query:"to"`
+	Limit  int64  `query:"limit" validate:"min=0"`
+	Cursor string `query:"cursor"`
+	Format string `query:"format" validate:"omitempty,oneof=json csv"`
+}
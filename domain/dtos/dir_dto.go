@@ -0,0 +1,9 @@
+package dtos
+
+type DirRenameDto struct {
+	Name string `json:"name" validate:"required,max=256,excludes=/"`
+}
+
+type DirMoveDto struct {
+	ParentDir string `json:"parent_dir" validate:"omitempty,uuid"`
+}
@@ -0,0 +1,24 @@
+package dtos
+
+type UploadCreateDto struct {
+	Code         string `json:"code" validate:"required,max=32"`
+	Name         string `json:"name" validate:"required,max=256"`
+	Type         string `json:"type" validate:"required,oneof=documento formato"`
+	RevisionUser string `json:"revision_user" validate:"required,max=32"`
+	ApprovalUser string `json:"approval_user" validate:"required,max=32"`
+	MimeType     string `json:"mime_type" validate:"max=255"`
+	Size         int64  `json:"size" validate:"required,min=1"`
+}
+
+type FileTransitionDto struct {
+	Version int    `json:"version" validate:"min=0"`
+	Comment string `json:"comment" validate:"max=1024"`
+}
+
+type FileRejectDto struct {
+	Comment string `json:"comment" validate:"required,max=1024"`
+}
+
+type VersionRestoreDto struct {
+	Note string `json:"note" validate:"max=1024"`
+}
@@ -0,0 +1,12 @@
+package dtos
+
+type GrantChangeDto struct {
+	Username string `json:"username" validate:"required,max=32"`
+	Access   string `json:"access" validate:"required,oneof=read write"`
+	// Allowed is null to clear the grant
+	Allowed *bool `json:"allowed"`
+}
+
+type GrantChangesDto struct {
+	Grants []GrantChangeDto `json:"grants" validate:"required,min=1,max=500,dive"`
+}
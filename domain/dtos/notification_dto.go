@@ -0,0 +1,13 @@
+package dtos
+
+import "github.com/sicozz/papyrus/domain"
+
+type NotificationQueryDto struct {
+	Unread string `query:"unread" validate:"omitempty,oneof=true false"`
+	Limit  int64  `query:"limit" validate:"min=0"`
+	Cursor string `query:"cursor"`
+}
+
+type NotificationPreferencesDto struct {
+	Preferences []domain.NotificationPreference `json:"preferences" validate:"required,dive"`
+}
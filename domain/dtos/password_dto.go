@@ -0,0 +1,11 @@
+package dtos
+
+type PasswdChangeDto struct {
+	Current  string `json:"current_password" validate:"required"`
+	Password string `json:"new_password" validate:"required"`
+}
+
+type PasswdResetDto struct {
+	Token    string `json:"token" form:"token" validate:"required"`
+	Password string `json:"new_password" form:"new_password" validate:"required"`
+}
@@ -0,0 +1,12 @@
+package dtos
+
+type PlanQueryDto struct {
+	Project  string `query:"project" validate:"omitempty,uuid"`
+	State    string `query:"state" validate:"omitempty,oneof=abierto cerrado abandonado"`
+	Assignee string `query:"assignee" validate:"max=32"`
+	Overdue  string `query:"overdue" validate:"omitempty,oneof=true false"`
+}
+
+type PlanStateDto struct {
+	State string `json:"state" validate:"required,oneof=abierto cerrado abandonado"`
+}
@@ -0,0 +1,10 @@
+package dtos
+
+type ProjectUpdateDto struct {
+	Name        string `json:"name" validate:"max=64,excludes=/"`
+	Description string `json:"description" validate:"max=1024"`
+}
+
+type ProjectStateDto struct {
+	State string `json:"state" validate:"required,oneof=inactivo activo cerrado"`
+}
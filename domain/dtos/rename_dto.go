@@ -0,0 +1,5 @@
+package dtos
+
+type RenameDto struct {
+	Description string `json:"description" validate:"required,ascii,max=32"`
+}
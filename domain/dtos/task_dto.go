@@ -0,0 +1,8 @@
+package dtos
+
+type TaskQueryDto struct {
+	Scope   string `query:"scope" validate:"omitempty,oneof=mine issued"`
+	Plan    string `query:"plan" validate:"omitempty,uuid"`
+	State   string `query:"state" validate:"omitempty,oneof=abierta cerrada abandonada"`
+	Overdue string `query:"overdue" validate:"omitempty,oneof=true false"`
+}
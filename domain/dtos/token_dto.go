@@ -0,0 +1,5 @@
+package dtos
+
+type RefreshTokenDto struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
@@ -0,0 +1,19 @@
+package dtos
+
+type TotpCodeDto struct {
+	Code string `json:"code" validate:"required,max=32"`
+}
+
+// TotpDisableDto carries the code owners need to drop their own second factor
+type TotpDisableDto struct {
+	Code string `json:"code" validate:"max=32"`
+}
+
+type MfaTokenDto struct {
+	MfaToken string `json:"mfa_token" validate:"required"`
+}
+
+type MfaCodeDto struct {
+	MfaToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code" validate:"required,max=32"`
+}
@@ -2,6 +2,7 @@ package domain
 
 import (
 	"errors"
+	"net/http"
 )
 
 var (
@@ -36,3 +37,24 @@ func (u uCaseErr) Error() string {
 func NewUCaseErr(status int, err error) uCaseErr {
 	return uCaseErr{status, err}
 }
+
+/*
+* NewRepoErr translates an error returned by a repository into a RequestErr.
+* The sentinel errors answer their status with msg, any other failure is an
+* internal error whose cause stays out of the response
+ */
+func NewRepoErr(err error, msg string) RequestErr {
+	var status int
+	switch {
+	case errors.Is(err, ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, ErrConflict):
+		status = http.StatusConflict
+	case errors.Is(err, ErrBadParamInput):
+		status = http.StatusBadRequest
+	default:
+		return NewUCaseErr(http.StatusInternalServerError, errors.New("Internal server error"))
+	}
+
+	return NewUCaseErr(status, errors.New(msg))
+}
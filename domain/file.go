@@ -0,0 +1,143 @@
+package domain
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// Base file types, states and stages (see file_type, file_state and file_stage)
+const (
+	FileTypeDocument = "documento"
+	FileTypeFormat   = "formato"
+
+	FileStateInactive = "inactivo"
+	FileStateActive   = "activo"
+	FileStateObsolete = "obsoleto"
+
+	FileStageLoaded   = "cargado"
+	FileStageReviewed = "revisado"
+	FileStageApproved = "aprobado"
+)
+
+// Lifecycle actions over the stage of a file
+const (
+	FileActionReview  = "review"
+	FileActionApprove = "approve"
+	FileActionReject  = "reject"
+)
+
+// File is representing the stored document data struct
+type File struct {
+	Uuid         string    `json:"uuid"`
+	Code         string    `json:"code"`
+	Name         string    `json:"name"`
+	Path         string    `json:"-"`
+	CreationDate time.Time `json:"creation_date"`
+	InputDate    time.Time `json:"input_date"`
+	Type         string    `json:"type"`
+	State        string    `json:"state"`
+	Stage        string    `json:"stage"`
+	Dir          string    `json:"dir"`
+	RevisionUser string    `json:"revision_user"`
+	ApprovalUser string    `json:"approval_user"`
+	Size         int64     `json:"size"`
+	MimeType     string    `json:"mime_type"`
+	Sha256       string    `json:"sha256"`
+	// Version is the number of the head version, ApprovedVersion the last approved
+	Version         int `json:"version"`
+	ApprovedVersion int `json:"approved_version,omitempty"`
+}
+
+// FileMeta is representing what an uploader tells about a new file
+type FileMeta struct {
+	Code string `json:"code" validate:"required,max=32"`
+	Name string `json:"name" validate:"required,max=256"`
+	Type string `json:"type" validate:"required,oneof=documento formato"`
+	Dir  string `json:"dir" validate:"required,uuid"`
+	// Usernames of the reviewer and the approver
+	RevisionUser string `json:"revision_user" validate:"required,max=32"`
+	ApprovalUser string `json:"approval_user" validate:"required,max=32"`
+	// MimeType as declared by the client, detected from the content when missing
+	MimeType string `json:"mime_type" validate:"max=255"`
+}
+
+// UploadSession is representing a resumable upload in progress
+type UploadSession struct {
+	Uuid      string    `json:"uuid"`
+	UserUuid  string    `json:"user_uuid"`
+	Meta      FileMeta  `json:"meta"`
+	Size      int64     `json:"size"`
+	Offset    int64     `json:"offset"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// FileTransition is representing a stage change of a file, kept as its history
+type FileTransition struct {
+	Uuid     string    `json:"uuid"`
+	File     string    `json:"file"`
+	Action   string    `json:"action"`
+	From     string    `json:"from"`
+	To       string    `json:"to"`
+	User     string    `json:"user"`
+	Username string    `json:"username"`
+	Version  int       `json:"version"`
+	Comment  string    `json:"comment"`
+	Date     time.Time `json:"date"`
+}
+
+// FileUsecase represents the file's usecases
+type FileUsecase interface {
+	GetByUuid(c context.Context, uuid string) (File, RequestErr)
+	FetchByDir(c context.Context, dir string) ([]File, RequestErr)
+	// Upload streams content to the blob store and records the new file
+	Upload(c context.Context, meta FileMeta, content io.Reader) (File, RequestErr)
+	// Open streams length bytes of the content of f from offset, length -1 reads to the end
+	Open(c context.Context, f File, offset int64, length int64) (io.ReadCloser, RequestErr)
+	CreateUpload(c context.Context, meta FileMeta, size int64) (UploadSession, RequestErr)
+	GetUpload(c context.Context, uuid string) (UploadSession, RequestErr)
+	// AppendUpload writes a chunk at offset, returning the file once the upload is complete
+	AppendUpload(c context.Context, uuid string, offset int64, chunk io.Reader) (UploadSession, *File, RequestErr)
+	AbortUpload(c context.Context, uuid string) RequestErr
+	/*
+	* Review moves a loaded file to reviewed, only its reviewer may do it. A
+	* non zero version must be the head, so the reviewer knows what is reviewed
+	 */
+	Review(c context.Context, uuid string, version int, comment string) (File, RequestErr)
+	// Approve moves a reviewed file to approved, only its approver may do it
+	Approve(c context.Context, uuid string, version int, comment string) (File, RequestErr)
+	// Reject sends a loaded or reviewed file back to loaded with a comment
+	Reject(c context.Context, uuid string, comment string) (File, RequestErr)
+	GetHistory(c context.Context, uuid string) ([]FileTransition, RequestErr)
+	GetVersions(c context.Context, uuid string) ([]FileVersion, RequestErr)
+	GetVersion(c context.Context, uuid string, number int) (FileVersion, RequestErr)
+	// UploadVersion streams content as the new head version of the file
+	UploadVersion(c context.Context, uuid string, meta VersionMeta, content io.Reader) (FileVersion, RequestErr)
+	OpenVersion(c context.Context, v FileVersion, offset int64, length int64) (io.ReadCloser, RequestErr)
+	// RestoreVersion makes a copy of an older version the new head
+	RestoreVersion(c context.Context, uuid string, number int, note string) (FileVersion, RequestErr)
+	DiffVersions(c context.Context, uuid string, from int, to int) (VersionDiff, RequestErr)
+}
+
+// FileRepository represents the file's repository contract
+type FileRepository interface {
+	GetByUuid(ctx context.Context, uuid string) (File, error)
+	GetByDir(ctx context.Context, dir string) ([]File, error)
+	// Store records f along with v as its first version
+	Store(ctx context.Context, f *File, v *FileVersion) error
+	/*
+	* Transition moves the stage of t.File from t.From to t.To and records t,
+	* false when the file was not at t.From or its head is not t.Version
+	 */
+	Transition(ctx context.Context, t *FileTransition) (bool, error)
+	GetHistory(ctx context.Context, file string) ([]FileTransition, error)
+}
+
+// UploadSessionRepository represents the resumable upload's repository contract
+type UploadSessionRepository interface {
+	GetByUuid(ctx context.Context, uuid string) (UploadSession, error)
+	Store(ctx context.Context, s *UploadSession) error
+	// Advance moves the offset from from to to, false when it was not at from
+	Advance(ctx context.Context, uuid string, from int64, to int64) (bool, error)
+	Delete(ctx context.Context, uuid string) error
+}
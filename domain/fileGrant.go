@@ -0,0 +1,82 @@
+package domain
+
+import "context"
+
+// Kinds of access a grant can allow or deny (see read_permission and write_permission)
+const (
+	AccessRead  = "read"
+	AccessWrite = "write"
+)
+
+// FileGrant is representing an explicit allow or deny of a user on a file or a dir
+type FileGrant struct {
+	Access   string `json:"access"`
+	User     string `json:"user"`
+	Username string `json:"username"`
+	File     string `json:"file,omitempty"`
+	Dir      string `json:"dir,omitempty"`
+	Allowed  bool   `json:"allowed"`
+}
+
+// GrantChange is representing an entry of a bulk grant update, a nil Allowed clears the grant
+type GrantChange struct {
+	Username string `json:"username"`
+	Access   string `json:"access"`
+	Allowed  *bool  `json:"allowed"`
+}
+
+// Access is representing the effective access of a user on a file
+type Access struct {
+	Username string `json:"username"`
+	Read     bool   `json:"read"`
+	Write    bool   `json:"write"`
+}
+
+// FilePermissions is representing the grants that apply to a file
+type FilePermissions struct {
+	File string `json:"file"`
+	// Grants are set on the file itself, Inherited on its dir and the ancestors
+	Grants    []FileGrant `json:"grants"`
+	Inherited []FileGrant `json:"inherited"`
+	Access    Access      `json:"access"`
+}
+
+// FileAccess represents the service resolving the effective access of users on files
+type FileAccess interface {
+	/*
+	* Allowed resolves access of u on file, which lives in dir. An empty file
+	* resolves access on dir itself. Only the members of a project and project
+	* managers reach below its root, and nobody writes below a closed one. Then
+	* an explicit deny on the file or on any dir above it wins, then an explicit
+	* allow, then the file.read or file.write permission of the role
+	 */
+	Allowed(ctx context.Context, u User, access string, dir string, file string) (bool, error)
+	// Filter keeps the files, all living in dir, that u can access
+	Filter(ctx context.Context, u User, access string, dir string, files []File) ([]File, error)
+}
+
+// FileGrantUsecase represents the file grant's usecases
+type FileGrantUsecase interface {
+	// GetByFile returns the grants on a file and the effective access of uname, the caller when empty
+	GetByFile(c context.Context, uuid string, uname string) (FilePermissions, RequestErr)
+	SetOnFile(c context.Context, uuid string, changes []GrantChange) RequestErr
+	// GetByDir returns the grants on a dir and its ancestors
+	GetByDir(c context.Context, uuid string) ([]FileGrant, RequestErr)
+	SetOnDir(c context.Context, uuid string, changes []GrantChange) RequestErr
+}
+
+// FileGrantRepository represents the file grant's repository contract
+type FileGrantRepository interface {
+	/*
+	* GetDirDecision folds the grants of user on dir and its ancestors. found is
+	* false when there is none, allowed is false when any of them denies
+	 */
+	GetDirDecision(ctx context.Context, access string, user string, dir string) (allowed bool, found bool, err error)
+	// GetFileDecisions returns the explicit grants of user on files
+	GetFileDecisions(ctx context.Context, access string, user string, files []string) (map[string]bool, error)
+	GetByFile(ctx context.Context, file string) ([]FileGrant, error)
+	// GetByDir returns the grants on dir and its ancestors
+	GetByDir(ctx context.Context, dir string) ([]FileGrant, error)
+	// Apply upserts set and removes unset in one transaction
+	Apply(ctx context.Context, set []FileGrant, unset []FileGrant) error
+}
@@ -0,0 +1,8 @@
+package domain
+
+// PasswordHasher represents the password hashing contract
+type PasswordHasher interface {
+	Hash(passwd string) (string, error)
+	Compare(hash string, passwd string) error
+	NeedsRehash(hash string) bool
+}
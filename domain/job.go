@@ -0,0 +1,82 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Outcomes of a job run
+const (
+	JobRunOk     = "ok"
+	JobRunFailed = "failed"
+)
+
+// Job is representing a unit of background work run once every Interval
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// JobRun is representing one execution of a job by one node
+type JobRun struct {
+	Uuid     string    `json:"uuid"`
+	Job      string    `json:"job"`
+	Node     string    `json:"node"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	Status   string    `json:"status"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// Kinds of target and levels of the deadline notices
+const (
+	DeadlineTask       = "task"
+	DeadlinePlan       = "plan"
+	DeadlineReminder   = "reminder"
+	DeadlineEscalation = "escalation"
+)
+
+// Deadline is representing an open task or plan with its deadline
+type Deadline struct {
+	Kind     string
+	Uuid     string
+	Title    string
+	Deadline time.Time
+	Issuer   string
+	Assignee string
+}
+
+// Scheduler represents the in-process runner of the background jobs
+type Scheduler interface {
+	Register(j Job)
+	// Start runs the due jobs on every tick until ctx is done
+	Start(ctx context.Context)
+	// RunDue runs once every registered job whose interval elapsed on this or any other node
+	RunDue(ctx context.Context)
+}
+
+// JobRepository represents the job run's repository contract
+type JobRepository interface {
+	/*
+	* TryLock takes the advisory lock of job on a connection of its own, so
+	* only one node runs it at a time. ok is false when another node holds it,
+	* release must be called otherwise
+	 */
+	TryLock(ctx context.Context, job string) (release func(), ok bool, err error)
+	// GetLastRun returns the latest run of job, found is false when it never ran
+	GetLastRun(ctx context.Context, job string) (res JobRun, found bool, err error)
+	StoreRun(ctx context.Context, r *JobRun) error
+}
+
+// DeadlineRepository represents the deadline notice's repository contract
+type DeadlineRepository interface {
+	/*
+	* GetUnnoticed lists the open tasks and plans due before due and not yet
+	* given a notice of level, by deadline then uuid, starting past after. The
+	* zero Deadline starts from the first one
+	 */
+	GetUnnoticed(ctx context.Context, level string, due time.Time, after Deadline, limit int64) ([]Deadline, error)
+	// MarkNoticed records that target got its notice of level
+	MarkNoticed(ctx context.Context, target string, level string, date time.Time) error
+}
@@ -0,0 +1,58 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Kinds of login throttle counters
+const (
+	ThrottleByUser = `user`
+	ThrottleByIp   = `ip`
+)
+
+// LoginAttempt is representing a recorded login attempt
+type LoginAttempt struct {
+	Uuid     string    `json:"uuid"`
+	Username string    `json:"username"`
+	Ip       string    `json:"ip"`
+	Success  bool      `json:"success"`
+	Date     time.Time `json:"date"`
+}
+
+// LoginLock is representing the failure counter of a username or an ip
+type LoginLock struct {
+	Kind        string    `json:"kind"`
+	Key         string    `json:"key"`
+	Failures    int64     `json:"failures"`
+	LastFailure time.Time `json:"last_failure"`
+	LockedUntil time.Time `json:"locked_until"`
+}
+
+// LoginPolicy is representing the throttling parameters of the login
+type LoginPolicy struct {
+	MaxFailures   int64
+	IpMaxFailures int64
+	LockDuration  time.Duration
+	BackoffBase   time.Duration
+	BackoffMax    time.Duration
+}
+
+// LoginThrottleUsecase represents the login throttling usecases
+type LoginThrottleUsecase interface {
+	Check(c context.Context, uname string, ip string) RequestErr
+	RecordFailure(c context.Context, uname string, ip string)
+	RecordSuccess(c context.Context, uname string, ip string)
+	GetLock(c context.Context, uname string) (LoginLock, RequestErr)
+	Unlock(c context.Context, uname string) RequestErr
+	FetchAttempts(c context.Context, uname string, limit int64) ([]LoginAttempt, RequestErr)
+}
+
+// LoginThrottleRepository represents the login throttle's repository contract
+type LoginThrottleRepository interface {
+	GetLock(ctx context.Context, kind string, key string) (LoginLock, error)
+	RegisterFailure(ctx context.Context, kind string, key string, date time.Time, window time.Time, locks []time.Duration) (int64, error)
+	Clear(ctx context.Context, kind string, key string) error
+	StoreAttempt(ctx context.Context, a *LoginAttempt) error
+	GetAttempts(ctx context.Context, uname string, limit int64) ([]LoginAttempt, error)
+}
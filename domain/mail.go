@@ -0,0 +1,8 @@
+package domain
+
+import "context"
+
+// MailSender represents the outgoing mail contract
+type MailSender interface {
+	Send(ctx context.Context, to string, subject string, body string) error
+}
@@ -0,0 +1,64 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrUnknownMigration will throw if the database holds migrations newer than the binary knows
+var ErrUnknownMigration = errors.New("database has migrations unknown to this build")
+
+// Migration is representing an ordered change of the schema or its seed data
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	// Down is empty when the migration can not be reverted
+	Down string
+}
+
+// AppliedMigration is representing a migration recorded in the database
+type AppliedMigration struct {
+	Version int64
+	Name    string
+	Date    time.Time
+}
+
+// MigrationStatus is representing a migration, known or applied
+type MigrationStatus struct {
+	Version int64
+	Name    string
+	Applied bool
+	Date    time.Time
+	// Known tells whether this build embeds the migration
+	Known bool
+}
+
+// Migrator represents the schema migration usecases
+type Migrator interface {
+	// Up applies up to n pending migrations in version order, all of them when n <= 0
+	Up(ctx context.Context, n int) ([]Migration, error)
+	// Down reverts the n latest applied migrations
+	Down(ctx context.Context, n int) ([]Migration, error)
+	Status(ctx context.Context) ([]MigrationStatus, error)
+}
+
+// MigrationRepository represents the migration tracking repository contract
+type MigrationRepository interface {
+	// Lock blocks until this process is the only one migrating
+	Lock(ctx context.Context) (release func(), err error)
+	// Init creates the tracking table when missing
+	Init(ctx context.Context) error
+	// LegacyVersion reads the version recorded by the migrate cli, ErrNotFound without one
+	LegacyVersion(ctx context.Context) (version int64, dirty bool, err error)
+	// HasSchema reports whether the tables predating the migrations exist
+	HasSchema(ctx context.Context) (bool, error)
+	GetApplied(ctx context.Context) ([]AppliedMigration, error)
+	// Apply runs the up script of m and records it, atomically
+	Apply(ctx context.Context, m Migration, date time.Time) error
+	// Revert runs the down script of m and forgets it, atomically
+	Revert(ctx context.Context, m Migration) error
+	// Record marks m as applied without running it
+	Record(ctx context.Context, m Migration, date time.Time) error
+}
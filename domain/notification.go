@@ -0,0 +1,114 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Events users get notified about
+const (
+	EventTaskOverdue   = "task.overdue"
+	EventTaskEscalated = "task.escalated"
+	EventTaskAssigned  = "task.assigned"
+	EventPlanOverdue   = "plan.overdue"
+	EventPlanEscalated = "plan.escalated"
+	EventFileReview    = "file.review"
+	EventFileApproval  = "file.approval"
+	EventUserActivated = "user.activated"
+)
+
+// Events lists every event a user can set preferences for
+var Events = []string{
+	EventTaskOverdue,
+	EventTaskEscalated,
+	EventTaskAssigned,
+	EventPlanOverdue,
+	EventPlanEscalated,
+	EventFileReview,
+	EventFileApproval,
+	EventUserActivated,
+}
+
+// Channels notifications are delivered through
+const (
+	ChannelInbox = "inbox"
+	ChannelEmail = "email"
+)
+
+// Channels lists every delivery channel, all of them are on by default
+var Channels = []string{ChannelInbox, ChannelEmail}
+
+// Notification is representing a message about an event addressed to a user
+type Notification struct {
+	Uuid    string `json:"uuid"`
+	User    string `json:"user"`
+	Event   string `json:"event"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+	// Ref is the uuid of the task, plan or file the event is about
+	Ref  string    `json:"ref,omitempty"`
+	Date time.Time `json:"date"`
+	Read bool      `json:"read"`
+}
+
+// NewActivationNotification builds the notice telling user their account is active
+func NewActivationNotification(user User, date time.Time) Notification {
+	return Notification{
+		User:    user.Uuid,
+		Event:   EventUserActivated,
+		Subject: "Papyrus account activated",
+		Body:    "Hello " + user.Name + ", your Papyrus account " + user.Username + " is now active.",
+		Ref:     user.Uuid,
+		Date:    date,
+	}
+}
+
+// NotificationFilter is representing a query over the inbox of a user
+type NotificationFilter struct {
+	User   string
+	Unread bool
+	Limit  int64
+	Cursor string
+}
+
+// NotificationPage is representing one page of the inbox, newest first
+type NotificationPage struct {
+	Notifications []Notification `json:"notifications"`
+	Unread        int64          `json:"unread"`
+	NextCursor    string         `json:"next_cursor,omitempty"`
+}
+
+// NotificationPreference is representing whether an event reaches a user through a channel
+type NotificationPreference struct {
+	Event   string `json:"event" validate:"required"`
+	Channel string `json:"channel" validate:"required,oneof=inbox email"`
+	Enabled bool   `json:"enabled"`
+}
+
+// Notifier represents the service delivering notifications to users
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// NotificationUsecase represents the inbox usecases, always on the authenticated user
+type NotificationUsecase interface {
+	Fetch(c context.Context, f NotificationFilter) (NotificationPage, RequestErr)
+	MarkRead(c context.Context, uuid string) RequestErr
+	MarkAllRead(c context.Context) RequestErr
+	// GetPreferences returns every event and channel pair, defaults included
+	GetPreferences(c context.Context) ([]NotificationPreference, RequestErr)
+	SetPreferences(c context.Context, prefs []NotificationPreference) RequestErr
+}
+
+// NotificationRepository represents the inbox and preferences repository contract
+type NotificationRepository interface {
+	Store(ctx context.Context, n *Notification) error
+	GetPage(ctx context.Context, f NotificationFilter) ([]Notification, string, error)
+	CountUnread(ctx context.Context, user string) (int64, error)
+	// MarkRead reports whether an unread notification of user was marked
+	MarkRead(ctx context.Context, user string, uuid string, date time.Time) (bool, error)
+	MarkAllRead(ctx context.Context, user string, date time.Time) error
+	// GetPreferences returns the stored preferences only, missing ones are enabled
+	GetPreferences(ctx context.Context, user string) ([]NotificationPreference, error)
+	SetPreferences(ctx context.Context, user string, prefs []NotificationPreference) error
+}
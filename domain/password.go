@@ -0,0 +1,10 @@
+package domain
+
+import "context"
+
+// PasswordUsecase represents the password management usecases
+type PasswordUsecase interface {
+	Change(c context.Context, uname string, current string, nPasswd string) RequestErr
+	RequestReset(c context.Context, uname string) RequestErr
+	Reset(c context.Context, token string, nPasswd string) RequestErr
+}
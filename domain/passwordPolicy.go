@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"unicode"
+)
+
+// bcrypt ignores everything past this length
+const maxPasswdLen = 72
+
+// PasswordPolicy is representing the rules new passwords must follow
+type PasswordPolicy struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+}
+
+// Check reports the first rule of the policy that passwd breaks
+func (p PasswordPolicy) Check(passwd string) error {
+	if len(passwd) < p.MinLength {
+		return errors.New(fmt.Sprint("Password must have at least ", p.MinLength, " characters"))
+	}
+
+	if len(passwd) > maxPasswdLen {
+		return errors.New(fmt.Sprint("Password must have at most ", maxPasswdLen, " characters"))
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range passwd {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+
+	switch {
+	case p.RequireUpper && !upper:
+		return errors.New("Password must have an uppercase letter")
+	case p.RequireLower && !lower:
+		return errors.New("Password must have a lowercase letter")
+	case p.RequireDigit && !digit:
+		return errors.New("Password must have a digit")
+	case p.RequireSymbol && !symbol:
+		return errors.New("Password must have a symbol")
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrLastHolder will throw if a write would leave no user holding a permission
+var ErrLastHolder = errors.New("no user would hold the permission anymore")
+
+// Permissions seeded in the permission table
+const (
+	PermUserRead      = `user.read`
+	PermUserWrite     = `user.write`
+	PermRoleManage    = `role.manage`
+	PermFileRead      = `file.read`
+	PermFileWrite     = `file.write`
+	PermFileApprove   = `file.approve`
+	PermFileGrant     = `file.grant`
+	PermAuditRead     = `audit.read`
+	PermProjectManage = `project.manage`
+)
+
+// Permission is representing the Permission data struct
+type Permission struct {
+	Code        int64  `json:"code"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// PermissionUsecase represents the permission's usecases
+type PermissionUsecase interface {
+	Fetch(c context.Context) ([]Permission, RequestErr)
+	GetByRole(c context.Context, roleCode int64) ([]Permission, RequestErr)
+	Grant(c context.Context, roleCode int64, name string) RequestErr
+	Revoke(c context.Context, roleCode int64, name string) RequestErr
+}
+
+// PermissionRepository represents the permission's repository contract
+type PermissionRepository interface {
+	GetAll(ctx context.Context) ([]Permission, error)
+	GetByName(ctx context.Context, name string) (Permission, error)
+	GetByRole(ctx context.Context, roleCode int64) ([]Permission, error)
+	RoleHas(ctx context.Context, roleCode int64, name string) (bool, error)
+	Grant(ctx context.Context, roleCode int64, permCode int64) error
+	Revoke(ctx context.Context, roleCode int64, permCode int64, keep string) error
+}
+
+// Authorizer represents the authorization service consulted by the usecases
+type Authorizer interface {
+	// Can reports whether the role of u grants perm, within the scopes of ctx
+	Can(ctx context.Context, u User, perm string) (bool, error)
+	// Covers reports whether the role of u grants every permission of r
+	Covers(ctx context.Context, u User, r Role) (bool, error)
+	// Authorize checks perm against the authenticated user of ctx
+	Authorize(ctx context.Context, perm string) RequestErr
+}
@@ -0,0 +1,101 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Base plan states seeded in the plan_state table
+const (
+	PlanStateOpen      = "abierto"
+	PlanStateClosed    = "cerrado"
+	PlanStateAbandoned = "abandonado"
+)
+
+// Plan is representing a corrective action plan raised over a non-conformity
+type Plan struct {
+	Uuid        string `json:"uuid"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	// Origin tells where the non-conformity was found, Analysis its root cause
+	Origin        string    `json:"origin"`
+	Analysis      string    `json:"analysis"`
+	DiscoveryDate time.Time `json:"discovery_date"`
+	RecordDate    time.Time `json:"record_date"`
+	// TerminationDate is the deadline, past it an open plan is overdue
+	TerminationDate time.Time `json:"termination_date"`
+	State           string    `json:"state"`
+	Project         string    `json:"project"`
+	IssuingUser     string    `json:"issuing_user"`
+	OffenderUser    string    `json:"offender_user"`
+	AssignedUser    string    `json:"assigned_user,omitempty"`
+	Overdue         bool      `json:"overdue"`
+}
+
+// PlanMeta is representing what the issuer tells about a new plan
+type PlanMeta struct {
+	Title           string    `json:"title" validate:"required,max=64"`
+	Description     string    `json:"description" validate:"required,max=1024"`
+	Origin          string    `json:"origin" validate:"required,max=1024"`
+	Analysis        string    `json:"analysis" validate:"max=1024"`
+	DiscoveryDate   time.Time `json:"discovery_date" validate:"required"`
+	TerminationDate time.Time `json:"termination_date" validate:"required"`
+	Project         string    `json:"project" validate:"required,uuid"`
+	// Usernames of the offender and, optionally, of the assignee
+	OffenderUser string `json:"offender_user" validate:"required,max=32"`
+	AssignedUser string `json:"assigned_user" validate:"max=32"`
+}
+
+// PlanUpdate is representing an edition of a plan, empty fields keep their value
+type PlanUpdate struct {
+	Title           string    `json:"title" validate:"max=64"`
+	Description     string    `json:"description" validate:"max=1024"`
+	Origin          string    `json:"origin" validate:"max=1024"`
+	Analysis        string    `json:"analysis" validate:"max=1024"`
+	DiscoveryDate   time.Time `json:"discovery_date"`
+	TerminationDate time.Time `json:"termination_date"`
+	OffenderUser    string    `json:"offender_user" validate:"max=32"`
+}
+
+// PlanFilter is representing a query over the plans
+type PlanFilter struct {
+	Project string
+	State   string
+	// Assignee holds the username, AssigneeUuid its resolved uuid
+	Assignee     string
+	AssigneeUuid string
+	// Overdue keeps only the overdue plans, or only the others, when set
+	Overdue *bool
+	// Now and Member are set by the usecase, Member limits to the projects of a user
+	Now    time.Time
+	Member string
+}
+
+// PlanUsecase represents the plan's usecases
+type PlanUsecase interface {
+	// Fetch lists the plans matching f within the projects visible to the authenticated user
+	Fetch(c context.Context, f PlanFilter) ([]Plan, RequestErr)
+	GetByUuid(c context.Context, uuid string) (Plan, RequestErr)
+	Store(c context.Context, m PlanMeta) (Plan, RequestErr)
+	Update(c context.Context, uuid string, upd PlanUpdate) (Plan, RequestErr)
+	// Assign hands the plan to a member of its project, an empty uname unassigns it
+	Assign(c context.Context, uuid string, uname string) RequestErr
+	// ChgState moves the plan to state, closing needs an analysis and a closed task
+	ChgState(c context.Context, uuid string, state string) RequestErr
+	Delete(c context.Context, uuid string) RequestErr
+}
+
+// PlanRepository represents the plan's repository contract
+type PlanRepository interface {
+	Fetch(ctx context.Context, f PlanFilter) ([]Plan, error)
+	GetByUuid(ctx context.Context, uuid string) (Plan, error)
+	Store(ctx context.Context, p *Plan) error
+	// Update writes the editable fields of p
+	Update(ctx context.Context, p *Plan) error
+	Assign(ctx context.Context, uuid string, userUuid string) error
+	// ChgState moves the plan from one state to another, reporting false when it was not in from
+	ChgState(ctx context.Context, uuid string, from string, to string) (bool, error)
+	// CountTasks counts the tasks of the plan in state
+	CountTasks(ctx context.Context, uuid string, state string) (int64, error)
+	Delete(ctx context.Context, uuid string) error
+}
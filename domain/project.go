@@ -0,0 +1,64 @@
+package domain
+
+import "context"
+
+// Base project states seeded in the project_state table
+const (
+	ProjectStateInactive = "inactivo"
+	ProjectStateActive   = "activo"
+	ProjectStateClosed   = "cerrado"
+)
+
+// Project is representing the project data struct. Dir is the root of its documents
+type Project struct {
+	Uuid        string `json:"uuid"`
+	Name        string `json:"name" validate:"required,max=64,excludes=/"`
+	Description string `json:"description" validate:"max=1024"`
+	State       string `json:"state"`
+	Dir         string `json:"dir"`
+}
+
+// ProjectMember is representing a user taking part in a project
+type ProjectMember struct {
+	UserUuid string `json:"user_uuid"`
+	Username string `json:"username"`
+	Name     string `json:"name"`
+	Lastname string `json:"lastname"`
+}
+
+// ProjectUsecase represents the project's usecases
+type ProjectUsecase interface {
+	// Fetch lists the projects of the authenticated user, or all of them for managers
+	Fetch(c context.Context) ([]Project, RequestErr)
+	GetByUuid(c context.Context, uuid string) (Project, RequestErr)
+	// Store creates the project, its root dir, and makes the creator a member
+	Store(c context.Context, p *Project) RequestErr
+	// Update renames the project along with its root dir
+	Update(c context.Context, uuid string, name string, description string) RequestErr
+	// ChgState moves the project to state, closing it makes its dir read-only
+	ChgState(c context.Context, uuid string, state string) RequestErr
+	GetMembers(c context.Context, uuid string) ([]ProjectMember, RequestErr)
+	AddMember(c context.Context, uuid string, uname string) RequestErr
+	RemoveMember(c context.Context, uuid string, uname string) RequestErr
+}
+
+// ProjectRepository represents the project's repository contract
+type ProjectRepository interface {
+	GetAll(ctx context.Context) ([]Project, error)
+	GetByMember(ctx context.Context, userUuid string) ([]Project, error)
+	GetByUuid(ctx context.Context, uuid string) (Project, error)
+	// GetByDir returns the project whose tree holds dir, the innermost one when roots nest
+	GetByDir(ctx context.Context, dir string) (Project, error)
+	// Store inserts the project along with a root dir named after it, and owner as member
+	Store(ctx context.Context, p *Project, owner string) error
+	// Update changes the project and the name of its root dir in one transaction
+	Update(ctx context.Context, uuid string, name string, description string) error
+	// ChgState moves the project from one state to another, reporting false when it was not in from
+	ChgState(ctx context.Context, uuid string, from string, to string) (bool, error)
+	IsMember(ctx context.Context, uuid string, userUuid string) (bool, error)
+	GetMembers(ctx context.Context, uuid string) ([]ProjectMember, error)
+	AddMember(ctx context.Context, uuid string, userUuid string) error
+	RemoveMember(ctx context.Context, uuid string, userUuid string) error
+	// IsClosedDir reports whether dir lies in the tree of a closed project
+	IsClosedDir(ctx context.Context, dir string) (bool, error)
+}
@@ -2,10 +2,28 @@ package domain
 
 import "context"
 
+// Base roles seeded in the role table
+const (
+	RoleStandard = `estandar`
+	RoleAdmin    = `admin`
+	RoleSuper    = `super`
+)
+
 // Role is representing the Role data struct
 type Role struct {
 	Code        int64  `json:"code"`
-	Description string `json:"description"`
+	Description string `json:"description" validate:"required,ascii,max=32"`
+	Level       int64  `json:"level" validate:"min=0"`
+}
+
+// RoleUsecase represents the role's usecases
+type RoleUsecase interface {
+	Fetch(c context.Context) ([]Role, RequestErr)
+	GetByDescription(c context.Context, desc string) (Role, RequestErr)
+	GetByCode(c context.Context, code int64) (Role, RequestErr)
+	Store(c context.Context, r *Role) RequestErr
+	Rename(c context.Context, code int64, desc string) RequestErr
+	Delete(c context.Context, code int64) RequestErr
 }
 
 // RoleRepository represents the role's repository contract
@@ -13,4 +31,7 @@ type RoleRepository interface {
 	GetByCode(ctx context.Context, code int64) (Role, error)
 	GetAll(ctx context.Context) ([]Role, error)
 	GetByDescription(ctx context.Context, desc string) (Role, error)
+	Store(ctx context.Context, r *Role) error
+	Rename(ctx context.Context, code int64, desc string) error
+	Delete(ctx context.Context, code int64) error
 }
@@ -0,0 +1,90 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Base task states seeded in the task_state table
+const (
+	TaskStateOpen      = "abierta"
+	TaskStateClosed    = "cerrada"
+	TaskStateAbandoned = "abandonada"
+)
+
+// Scopes of a task listing relative to the authenticated user
+const (
+	TaskScopeMine   = "mine"
+	TaskScopeIssued = "issued"
+)
+
+// Task is representing a unit of work, optionally part of a plan
+type Task struct {
+	Uuid        string    `json:"uuid"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Date        time.Time `json:"date"`
+	Deadline    time.Time `json:"deadline"`
+	State       string    `json:"state"`
+	// Dir is where the work happens, EvidenceDir holds the proof it was done
+	Dir          string `json:"dir"`
+	EvidenceDir  string `json:"evidence_dir"`
+	IssuingUser  string `json:"issuing_user"`
+	AssignedUser string `json:"assigned_user,omitempty"`
+	Plan         string `json:"plan,omitempty"`
+	Overdue      bool   `json:"overdue"`
+}
+
+// TaskMeta is representing what the issuer tells about a new task
+type TaskMeta struct {
+	Title       string    `json:"title" validate:"required,max=64,excludes=/"`
+	Description string    `json:"description" validate:"required,max=1024"`
+	Deadline    time.Time `json:"deadline" validate:"required"`
+	Dir         string    `json:"dir" validate:"required,uuid"`
+	// EvidenceDir is created below Dir, named after the title, when missing
+	EvidenceDir  string `json:"evidence_dir" validate:"omitempty,uuid"`
+	AssignedUser string `json:"assigned_user" validate:"max=32"`
+	Plan         string `json:"plan" validate:"omitempty,uuid"`
+}
+
+// TaskFilter is representing a query over the tasks
+type TaskFilter struct {
+	// Scope is one of TaskScopeMine or TaskScopeIssued, empty lists every visible task
+	Scope string
+	Plan  string
+	State string
+	// Overdue keeps only the overdue tasks, or only the others, when set
+	Overdue *bool
+	// Set by the usecase: Assignee and Issuer from Scope, Viewer limits to the tasks a user sees
+	Assignee string
+	Issuer   string
+	Viewer   string
+	Now      time.Time
+}
+
+// TaskUsecase represents the task's usecases
+type TaskUsecase interface {
+	// Fetch lists the tasks matching f among those visible to the authenticated user
+	Fetch(c context.Context, f TaskFilter) ([]Task, RequestErr)
+	GetByUuid(c context.Context, uuid string) (Task, RequestErr)
+	Store(c context.Context, m TaskMeta) (Task, RequestErr)
+	// Assign hands an open task to uname, replacing any previous assignee
+	Assign(c context.Context, uuid string, uname string) RequestErr
+	// Close needs at least one file in the evidence dir
+	Close(c context.Context, uuid string) RequestErr
+	Abandon(c context.Context, uuid string) RequestErr
+}
+
+// TaskRepository represents the task's repository contract
+type TaskRepository interface {
+	Fetch(ctx context.Context, f TaskFilter) ([]Task, error)
+	GetByUuid(ctx context.Context, uuid string) (Task, error)
+	Store(ctx context.Context, t *Task) error
+	Assign(ctx context.Context, uuid string, userUuid string) error
+	// ChgState moves the task from one state to another, reporting false when it was not in from
+	ChgState(ctx context.Context, uuid string, from string, to string) (bool, error)
+	// CountEvidence counts the active files right in the evidence dir of the task
+	CountEvidence(ctx context.Context, uuid string) (int64, error)
+	// IsVisible reports whether the task is issued to or by the user, or part of a plan of their projects
+	IsVisible(ctx context.Context, uuid string, userUuid string) (bool, error)
+}
@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Totp is representing the TOTP enrollment of a user
+type Totp struct {
+	UserUuid  string
+	Secret    string
+	Confirmed bool
+	LastStep  int64
+	CreatedAt time.Time
+}
+
+// TotpEnrollment is representing what a client needs to set up an authenticator
+type TotpEnrollment struct {
+	Secret string `json:"secret"`
+	Uri    string `json:"uri"`
+}
+
+// TotpUsecase represents the two-factor authentication usecases
+type TotpUsecase interface {
+	Enroll(c context.Context, uname string) (TotpEnrollment, RequestErr)
+	Confirm(c context.Context, uname string, code string) ([]string, RequestErr)
+	// Disable drops the enrollment, owners must prove it with a TOTP or recovery code
+	Disable(c context.Context, uname string, code string) RequestErr
+	// Enabled reports whether the user has a confirmed enrollment
+	Enabled(c context.Context, u User) (bool, RequestErr)
+	// Required reports whether the policy makes 2FA mandatory for the user
+	Required(u User) bool
+	// Verify checks a TOTP code or a recovery code of the user
+	Verify(c context.Context, u User, code string) RequestErr
+}
+
+// TotpRepository represents the TOTP's repository contract
+type TotpRepository interface {
+	GetByUser(ctx context.Context, userUuid string) (Totp, error)
+	Store(ctx context.Context, t *Totp) error
+	Confirm(ctx context.Context, userUuid string) error
+	Delete(ctx context.Context, userUuid string) error
+	// UseStep records step as used, false when it or a later one already was
+	UseStep(ctx context.Context, userUuid string, step int64) (bool, error)
+	StoreRecoveryCodes(ctx context.Context, userUuid string, hashes []string) error
+	// UseRecoveryCode marks the recovery code with hash as used, false when there is no unused one
+	UseRecoveryCode(ctx context.Context, userUuid string, hash string, now time.Time) (bool, error)
+}
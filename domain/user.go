@@ -1,47 +1,90 @@
 package domain
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 // User is representing the User data struct
 type User struct {
 	Uuid     string    `json:"uuid"`
 	Username string    `json:"username" validate:"required,ascii"`
 	Email    string    `json:"email" validate:"required,email,ascii"`
-	Password string    `json:"password" validate:"required,ascii"`
+	Password string    `json:"password" validate:"required,ascii,max=72"`
 	Name     string    `json:"name" validate:"required,ascii"`
 	Lastname string    `json:"lastname" validate:"required,ascii"`
 	Role     Role      `json:"role"`
 	State    UserState `json:"state"`
+	// CreatedAt is set by the database on insert
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Sortable user fields, a leading '-' sorts descending
+const (
+	UserSortCreatedAt = "created_at"
+	UserSortUsername  = "username"
+	UserSortEmail     = "email"
+	UserSortName      = "name"
+	UserSortLastname  = "lastname"
+)
+
+// UserFilter is representing a page request over the users
+type UserFilter struct {
+	Limit  int64
+	Cursor string
+	Sort   string
+	// Role and State hold descriptions, RoleCode and StateCode their resolved codes
+	Role      string
+	State     string
+	RoleCode  int64
+	StateCode int64
+	// Query matches a substring of the name, lastname, username or email
+	Query string
+}
+
+// UserPage is representing one page of users
+type UserPage struct {
+	Users      []User `json:"users"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	Total      int64  `json:"total"`
 }
 
 // UserUsecase represents the user's usecases
 type UserUsecase interface {
-	Fetch(c context.Context) ([]User, RequestErr)
-	// GetByUuid(c context.Context, uuid string) (User, error)
+	Fetch(c context.Context, f UserFilter) (UserPage, RequestErr)
+	GetByUuid(c context.Context, uuid string) (User, RequestErr)
 	// GetByEmail(c context.Context, email string) (User, error)
 	GetByUsername(c context.Context, uname string) (User, RequestErr)
 	// Update(c context.Context, u *User) error
 	Store(c context.Context, u *User) RequestErr
 	Delete(c context.Context, uname string) RequestErr
-	Login(c context.Context, uname string, passwd string) (User, RequestErr)
+	Login(c context.Context, uname string, passwd string, ip string) (User, RequestErr)
 	Update(c context.Context, uname string, uUp *User) RequestErr
+	GetLock(c context.Context, uname string) (LoginLock, RequestErr)
+	Unlock(c context.Context, uname string) RequestErr
+	FetchLoginAttempts(c context.Context, uname string, limit int64) ([]LoginAttempt, RequestErr)
 }
 
 // UserRepository represents the user's repository contract
 type UserRepository interface {
 	// TODO reorganize functions
-	GetAll(ctx context.Context) ([]User, error)
-	// GetByUuid(ctx context.Context, uuid string) (User, error)
+	// GetPage returns the users of f after its cursor and the cursor of the next page
+	GetPage(ctx context.Context, f UserFilter) ([]User, string, error)
+	Count(ctx context.Context, f UserFilter) (int64, error)
+	GetByUuid(ctx context.Context, uuid string) (User, error)
 	// GetByEmail(ctx context.Context, email string) (User, error)
 	GetByUsername(ctx context.Context, uname string) (User, error)
-	ExistByUname(ctx context.Context, uname string) bool
-	ExistByEmail(ctx context.Context, email string) bool
+	ExistByUname(ctx context.Context, uname string) (bool, error)
+	ExistByEmail(ctx context.Context, email string) (bool, error)
+	CountByRole(ctx context.Context, ro Role) (int64, error)
+	CountByPermission(ctx context.Context, perm string) (int64, error)
+	CountByState(ctx context.Context, st UserState) (int64, error)
 	Store(ctx context.Context, u *User) error
-	Delete(ctx context.Context, uuid string) error
-	Login(ctx context.Context, uname string, passwd string) (User, error)
+	Delete(ctx context.Context, uname string, keep string) error
 	ChgEmail(ctx context.Context, uname string, email string) error
 	ChgName(ctx context.Context, uname string, nName string) error
 	ChgLstname(ctx context.Context, uname string, nLname string) error
-	ChgRole(ctx context.Context, uname string, ro Role) error
-	ChgState(ctx context.Context, uname string, st UserState) error
+	ChgRole(ctx context.Context, uname string, ro Role, keep string) error
+	ChgState(ctx context.Context, uname string, st UserState, keep string) error
+	ChgPasswd(ctx context.Context, uname string, hash string) error
 }
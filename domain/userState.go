@@ -2,10 +2,26 @@ package domain
 
 import "context"
 
+// Base user states seeded in the user_state table
+const (
+	UserStateInactive = `inactivo`
+	UserStateActive   = `activo`
+)
+
 // UserState is representing the userState data struct
 type UserState struct {
 	Code        int64  `json:"code"`
-	Description string `json:"description"`
+	Description string `json:"description" validate:"required,ascii,max=32"`
+}
+
+// UserStateUsecase represents the userState's usecases
+type UserStateUsecase interface {
+	Fetch(c context.Context) ([]UserState, RequestErr)
+	GetByCode(c context.Context, code int64) (UserState, RequestErr)
+	GetByDescription(c context.Context, desc string) (UserState, RequestErr)
+	Store(c context.Context, s *UserState) RequestErr
+	Rename(c context.Context, code int64, desc string) RequestErr
+	Delete(c context.Context, code int64) RequestErr
 }
 
 // UserStateRepository represents the userStates's repository contract
@@ -13,4 +29,7 @@ type UserStateRepository interface {
 	GetByCode(ctx context.Context, code int64) (UserState, error)
 	GetAll(ctx context.Context) ([]UserState, error)
 	GetByDescription(ctx context.Context, desc string) (UserState, error)
+	Store(ctx context.Context, s *UserState) error
+	Rename(ctx context.Context, code int64, desc string) error
+	Delete(ctx context.Context, code int64) error
 }
@@ -0,0 +1,30 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Purposes of single use user tokens
+const (
+	TokenPasswdReset = `passwd_reset`
+	TokenVerifyEmail = `verify_email`
+)
+
+// UserToken is representing a single use, expiring token issued to a user
+type UserToken struct {
+	Uuid      string
+	UserUuid  string
+	Purpose   string
+	TokenHash string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// UserTokenRepository represents the user token's repository contract
+type UserTokenRepository interface {
+	Store(ctx context.Context, t *UserToken) error
+	// Consume marks an unused, unexpired token as used and returns it
+	Consume(ctx context.Context, purpose string, hash string, now time.Time) (UserToken, error)
+	DeleteByUser(ctx context.Context, userUuid string, purpose string) error
+}
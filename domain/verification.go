@@ -0,0 +1,10 @@
+package domain
+
+import "context"
+
+// VerificationUsecase represents the email verification usecases
+type VerificationUsecase interface {
+	Send(c context.Context, uname string) RequestErr
+	Verify(c context.Context, token string) RequestErr
+	Bypass(c context.Context, uname string) RequestErr
+}
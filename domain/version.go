@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// FileVersion is representing an immutable revision of the content of a file
+type FileVersion struct {
+	Uuid     string    `json:"uuid"`
+	File     string    `json:"file"`
+	Number   int       `json:"number"`
+	Path     string    `json:"-"`
+	Date     time.Time `json:"date"`
+	Name     string    `json:"name"`
+	Size     int64     `json:"size"`
+	MimeType string    `json:"mime_type"`
+	Sha256   string    `json:"sha256"`
+	User     string    `json:"user"`
+	Username string    `json:"username"`
+	Note     string    `json:"note"`
+	// RestoredFrom is the number of the version a restore copied, 0 otherwise
+	RestoredFrom int `json:"restored_from,omitempty"`
+}
+
+// VersionMeta is representing what an uploader tells about a new version
+type VersionMeta struct {
+	Name     string `json:"name" validate:"required,max=256"`
+	MimeType string `json:"mime_type" validate:"max=255"`
+	Note     string `json:"note" validate:"max=1024"`
+}
+
+// FieldChange is representing a metadata field that differs between versions
+type FieldChange struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+// VersionDiff is representing the metadata changes from one version to another
+type VersionDiff struct {
+	From        int           `json:"from"`
+	To          int           `json:"to"`
+	SameContent bool          `json:"same_content"`
+	Changes     []FieldChange `json:"changes"`
+}
+
+// VersionRepository represents the file version's repository contract
+type VersionRepository interface {
+	GetByFile(ctx context.Context, file string) ([]FileVersion, error)
+	GetByNumber(ctx context.Context, file string, number int) (FileVersion, error)
+	/*
+	* Store records v as the next head of its file, which mirrors it and goes
+	* back to loaded. False when the file is not active
+	 */
+	Store(ctx context.Context, v *FileVersion) (bool, error)
+}
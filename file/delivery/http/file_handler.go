@@ -0,0 +1,595 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/domain/dtos"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+	"github.com/sicozz/papyrus/utils/delivery"
+)
+
+const (
+	uploadOffsetHeader = "Upload-Offset"
+	uploadLengthHeader = "Upload-Length"
+	// maxFieldSize bounds the metadata fields of a multipart upload
+	maxFieldSize = 1024
+)
+
+// FileHandler will initialize the file/ and upload/ resources endpoint
+type FileHandler struct {
+	FUsecase domain.FileUsecase
+	log      utils.AggregatedLogger
+}
+
+/*
+* NewFileHandler registers the file routes behind authentication only, the
+* usecase resolves the access of the user on every file it touches
+ */
+func NewFileHandler(e *echo.Echo, fu domain.FileUsecase, authMw echo.MiddlewareFunc) {
+	logger := utils.NewAggregatedLogger(constants.Delivery, constants.File)
+	handler := &FileHandler{fu, logger}
+	// dir/ is grouped by the dir handler, these routes are registered one by one
+	e.GET("/dir/:uuid/file", handler.FetchByDir, authMw)
+	e.POST("/dir/:uuid/file", handler.Upload, authMw)
+	e.POST("/dir/:uuid/upload", handler.CreateUpload, authMw)
+	g := e.Group("/file", authMw)
+	g.GET("/:uuid", handler.GetByUuid)
+	g.GET("/:uuid/content", handler.Download)
+	g.GET("/:uuid/history", handler.GetHistory)
+	g.POST("/:uuid/review", handler.Review)
+	g.POST("/:uuid/approve", handler.Approve)
+	g.POST("/:uuid/reject", handler.Reject)
+	g.GET("/:uuid/version", handler.GetVersions)
+	g.POST("/:uuid/version", handler.UploadVersion)
+	g.GET("/:uuid/version/diff", handler.DiffVersions)
+	g.GET("/:uuid/version/:number", handler.GetVersion)
+	g.GET("/:uuid/version/:number/content", handler.DownloadVersion)
+	g.POST("/:uuid/version/:number/restore", handler.RestoreVersion)
+	ug := e.Group("/upload", authMw)
+	ug.HEAD("/:uuid", handler.GetUpload)
+	ug.PATCH("/:uuid", handler.AppendUpload)
+	ug.DELETE("/:uuid", handler.AbortUpload)
+}
+
+func validationErr(c echo.Context, err error) error {
+	errBody, err := dtos.NewValidationErrDto(err.Error())
+	if err != nil {
+		errValid := dtos.NewErrDto(fmt.Sprint("Req body validation failed: ", err))
+		return c.JSON(http.StatusBadRequest, errValid)
+	}
+	return c.JSON(http.StatusBadRequest, errBody)
+}
+
+func (h *FileHandler) FetchByDir(c echo.Context) error {
+	h.log.Info("REQ: fetch by dir")
+	ctx := c.Request().Context()
+	dir := c.Param("uuid")
+	files, rErr := h.FUsecase.FetchByDir(ctx, dir)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.JSON(http.StatusOK, files)
+}
+
+func (h *FileHandler) GetByUuid(c echo.Context) error {
+	h.log.Info("REQ: get by uuid")
+	ctx := c.Request().Context()
+	uuid := c.Param("uuid")
+	file, rErr := h.FUsecase.GetByUuid(ctx, uuid)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.JSON(http.StatusOK, file)
+}
+
+/*
+* Upload reads a multipart/form-data body without buffering it: the metadata
+* fields must come before the "file" part, whose content is streamed straight
+* to the blob store
+ */
+func (h *FileHandler) Upload(c echo.Context) error {
+	h.log.Info("REQ: upload")
+	ctx := c.Request().Context()
+	reader, err := c.Request().MultipartReader()
+	if err != nil {
+		errBody := dtos.NewErrDto(fmt.Sprint("Req body is not multipart: ", err))
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	fields, content, err := readMultipart(reader)
+	if err != nil {
+		errBody := dtos.NewErrDto(fmt.Sprint("Req body reading failed: ", err))
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	meta := domain.FileMeta{
+		Code:         fields["code"],
+		Name:         fields["name"],
+		Type:         fields["type"],
+		Dir:          c.Param("uuid"),
+		RevisionUser: fields["revision_user"],
+		ApprovalUser: fields["approval_user"],
+		MimeType:     fields["mime_type"],
+	}
+	if meta.Name == "" {
+		meta.Name = content.FileName()
+	}
+	if meta.MimeType == "" {
+		meta.MimeType = content.Header.Get(echo.HeaderContentType)
+	}
+
+	if ok, err := delivery.IsRequestValid(&meta); !ok {
+		return validationErr(c, err)
+	}
+
+	file, rErr := h.FUsecase.Upload(ctx, meta, content)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.JSON(http.StatusCreated, file)
+}
+
+// readMultipart reads the fields that precede the "file" part and returns that part
+func readMultipart(reader *multipart.Reader) (fields map[string]string, content *multipart.Part, err error) {
+	fields = make(map[string]string)
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil, nil, errors.New("no file part")
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if part.FormName() == "file" {
+			return fields, part, nil
+		}
+
+		value, err := io.ReadAll(io.LimitReader(part, maxFieldSize))
+		if err != nil {
+			return nil, nil, err
+		}
+		fields[part.FormName()] = string(value)
+	}
+}
+
+/*
+* parseRange resolves a single "bytes=" range against size. Multiple ranges
+* and units other than bytes are not supported and yield ok false so the whole
+* content is served
+ */
+func parseRange(header string, size int64) (offset int64, length int64, ok bool, err error) {
+	spec, found := strings.CutPrefix(header, "bytes=")
+	if !found {
+		return 0, 0, false, nil
+	}
+	if strings.Contains(spec, ",") {
+		return 0, 0, false, nil
+	}
+
+	first, last, found := strings.Cut(strings.TrimSpace(spec), "-")
+	if !found {
+		return 0, 0, false, errors.New("malformed range")
+	}
+
+	if first == "" {
+		// Suffix range, the last n bytes
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n <= 0 || size == 0 {
+			return 0, 0, false, errors.New("unsatisfiable range")
+		}
+		if n > size {
+			n = size
+		}
+		return size - n, n, true, nil
+	}
+
+	offset, err = strconv.ParseInt(first, 10, 64)
+	if err != nil || offset < 0 || offset >= size {
+		return 0, 0, false, errors.New("unsatisfiable range")
+	}
+	end := size - 1
+	if last != "" {
+		end, err = strconv.ParseInt(last, 10, 64)
+		if err != nil || end < offset {
+			return 0, 0, false, errors.New("unsatisfiable range")
+		}
+		if end > size-1 {
+			end = size - 1
+		}
+	}
+
+	return offset, end - offset + 1, true, nil
+}
+
+func (h *FileHandler) Download(c echo.Context) error {
+	h.log.Info("REQ: download")
+	ctx := c.Request().Context()
+	uuid := c.Param("uuid")
+	file, rErr := h.FUsecase.GetByUuid(ctx, uuid)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return serveContent(c, file.Name, file.Size, file.MimeType, file.Sha256,
+		func(offset int64, length int64) (io.ReadCloser, domain.RequestErr) {
+			return h.FUsecase.Open(ctx, file, offset, length)
+		})
+}
+
+/*
+* serveContent streams size bytes of content as an attachment, honouring a
+* single byte range and conditional requests on its sha256 as the ETag
+ */
+func serveContent(
+	c echo.Context,
+	name string,
+	size int64,
+	mimeType string,
+	sha string,
+	open func(offset int64, length int64) (io.ReadCloser, domain.RequestErr),
+) error {
+	header := c.Response().Header()
+	etag := fmt.Sprintf("%q", sha)
+	header.Set(echo.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	header.Set("Accept-Ranges", "bytes")
+	header.Set("ETag", etag)
+	header.Set(echo.HeaderXContentTypeOptions, "nosniff")
+	if c.Request().Header.Get("If-None-Match") == etag {
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	status := http.StatusOK
+	offset, length := int64(0), size
+	if rng := c.Request().Header.Get("Range"); rng != "" {
+		o, l, ok, err := parseRange(rng, size)
+		if err != nil {
+			header.Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+			errBody := dtos.NewErrDto(fmt.Sprint("Invalid range: ", err))
+			return c.JSON(http.StatusRequestedRangeNotSatisfiable, errBody)
+		}
+		if ok {
+			status = http.StatusPartialContent
+			offset, length = o, l
+			header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", o, o+l-1, size))
+		}
+	}
+
+	if length == 0 {
+		return c.Blob(status, mimeType, nil)
+	}
+
+	content, rErr := open(offset, length)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+	defer content.Close()
+
+	header.Set(echo.HeaderContentLength, strconv.FormatInt(length, 10))
+	return c.Stream(status, mimeType, content)
+}
+
+func (h *FileHandler) CreateUpload(c echo.Context) error {
+	h.log.Info("REQ: create upload")
+	ctx := c.Request().Context()
+	var cDto dtos.UploadCreateDto
+	err := c.Bind(&cDto)
+	if err != nil {
+		errBody := dtos.NewErrDto(fmt.Sprint("Req body binding failed: ", err))
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	if ok, err := delivery.IsRequestValid(&cDto); !ok {
+		return validationErr(c, err)
+	}
+
+	meta := domain.FileMeta{
+		Code:         cDto.Code,
+		Name:         cDto.Name,
+		Type:         cDto.Type,
+		Dir:          c.Param("uuid"),
+		RevisionUser: cDto.RevisionUser,
+		ApprovalUser: cDto.ApprovalUser,
+		MimeType:     cDto.MimeType,
+	}
+	if ok, err := delivery.IsRequestValid(&meta); !ok {
+		return validationErr(c, err)
+	}
+
+	session, rErr := h.FUsecase.CreateUpload(ctx, meta, cDto.Size)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	c.Response().Header().Set(echo.HeaderLocation, "/upload/"+session.Uuid)
+	return c.JSON(http.StatusCreated, session)
+}
+
+func (h *FileHandler) GetUpload(c echo.Context) error {
+	h.log.Info("REQ: get upload")
+	ctx := c.Request().Context()
+	uuid := c.Param("uuid")
+	session, rErr := h.FUsecase.GetUpload(ctx, uuid)
+	if rErr != nil {
+		return c.NoContent(rErr.GetStatus())
+	}
+
+	header := c.Response().Header()
+	header.Set(uploadOffsetHeader, strconv.FormatInt(session.Offset, 10))
+	header.Set(uploadLengthHeader, strconv.FormatInt(session.Size, 10))
+	header.Set(echo.HeaderCacheControl, "no-store")
+	return c.NoContent(http.StatusOK)
+}
+
+func (h *FileHandler) AppendUpload(c echo.Context) error {
+	h.log.Info("REQ: append upload")
+	ctx := c.Request().Context()
+	uuid := c.Param("uuid")
+	offset, err := strconv.ParseInt(c.Request().Header.Get(uploadOffsetHeader), 10, 64)
+	if err != nil || offset < 0 {
+		errBody := dtos.NewErrDto(fmt.Sprint("Invalid ", uploadOffsetHeader, " header"))
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	session, file, rErr := h.FUsecase.AppendUpload(ctx, uuid, offset, c.Request().Body)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	c.Response().Header().Set(uploadOffsetHeader, strconv.FormatInt(session.Offset, 10))
+	if file != nil {
+		return c.JSON(http.StatusCreated, file)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *FileHandler) AbortUpload(c echo.Context) error {
+	h.log.Info("REQ: abort upload")
+	ctx := c.Request().Context()
+	uuid := c.Param("uuid")
+	rErr := h.FUsecase.AbortUpload(ctx, uuid)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+func (h *FileHandler) GetHistory(c echo.Context) error {
+	h.log.Info("REQ: get history")
+	ctx := c.Request().Context()
+	uuid := c.Param("uuid")
+	history, rErr := h.FUsecase.GetHistory(ctx, uuid)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.JSON(http.StatusOK, history)
+}
+
+func (h *FileHandler) Review(c echo.Context) error {
+	h.log.Info("REQ: review")
+	return h.advance(c, h.FUsecase.Review)
+}
+
+func (h *FileHandler) Approve(c echo.Context) error {
+	h.log.Info("REQ: approve")
+	return h.advance(c, h.FUsecase.Approve)
+}
+
+// advance runs a forward transition, whose comment is optional
+func (h *FileHandler) advance(c echo.Context, action func(context.Context, string, int, string) (domain.File, domain.RequestErr)) error {
+	ctx := c.Request().Context()
+	uuid := c.Param("uuid")
+	var tDto dtos.FileTransitionDto
+	err := c.Bind(&tDto)
+	if err != nil {
+		errBody := dtos.NewErrDto(fmt.Sprint("Req body binding failed: ", err))
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	if ok, err := delivery.IsRequestValid(&tDto); !ok {
+		return validationErr(c, err)
+	}
+
+	file, rErr := action(ctx, uuid, tDto.Version, tDto.Comment)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.JSON(http.StatusOK, file)
+}
+
+func (h *FileHandler) Reject(c echo.Context) error {
+	h.log.Info("REQ: reject")
+	ctx := c.Request().Context()
+	uuid := c.Param("uuid")
+	var rDto dtos.FileRejectDto
+	err := c.Bind(&rDto)
+	if err != nil {
+		errBody := dtos.NewErrDto(fmt.Sprint("Req body binding failed: ", err))
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	if ok, err := delivery.IsRequestValid(&rDto); !ok {
+		return validationErr(c, err)
+	}
+
+	file, rErr := h.FUsecase.Reject(ctx, uuid, rDto.Comment)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.JSON(http.StatusOK, file)
+}
+
+func (h *FileHandler) GetVersions(c echo.Context) error {
+	h.log.Info("REQ: get versions")
+	ctx := c.Request().Context()
+	uuid := c.Param("uuid")
+	versions, rErr := h.FUsecase.GetVersions(ctx, uuid)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.JSON(http.StatusOK, versions)
+}
+
+func (h *FileHandler) GetVersion(c echo.Context) error {
+	h.log.Info("REQ: get version")
+	ctx := c.Request().Context()
+	uuid := c.Param("uuid")
+	number, err := strconv.Atoi(c.Param("number"))
+	if err != nil || number < 1 {
+		errBody := dtos.NewErrDto(fmt.Sprint("Invalid version number: ", c.Param("number")))
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	version, rErr := h.FUsecase.GetVersion(ctx, uuid, number)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.JSON(http.StatusOK, version)
+}
+
+func (h *FileHandler) UploadVersion(c echo.Context) error {
+	h.log.Info("REQ: upload version")
+	ctx := c.Request().Context()
+	uuid := c.Param("uuid")
+	reader, err := c.Request().MultipartReader()
+	if err != nil {
+		errBody := dtos.NewErrDto(fmt.Sprint("Req body is not multipart: ", err))
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	fields, content, err := readMultipart(reader)
+	if err != nil {
+		errBody := dtos.NewErrDto(fmt.Sprint("Req body reading failed: ", err))
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	meta := domain.VersionMeta{
+		Name:     fields["name"],
+		MimeType: fields["mime_type"],
+		Note:     fields["note"],
+	}
+	if meta.Name == "" {
+		meta.Name = content.FileName()
+	}
+	if meta.MimeType == "" {
+		meta.MimeType = content.Header.Get(echo.HeaderContentType)
+	}
+
+	if ok, err := delivery.IsRequestValid(&meta); !ok {
+		return validationErr(c, err)
+	}
+
+	version, rErr := h.FUsecase.UploadVersion(ctx, uuid, meta, content)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.JSON(http.StatusCreated, version)
+}
+
+func (h *FileHandler) DownloadVersion(c echo.Context) error {
+	h.log.Info("REQ: download version")
+	ctx := c.Request().Context()
+	uuid := c.Param("uuid")
+	number, err := strconv.Atoi(c.Param("number"))
+	if err != nil || number < 1 {
+		errBody := dtos.NewErrDto(fmt.Sprint("Invalid version number: ", c.Param("number")))
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	version, rErr := h.FUsecase.GetVersion(ctx, uuid, number)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return serveContent(c, version.Name, version.Size, version.MimeType, version.Sha256,
+		func(offset int64, length int64) (io.ReadCloser, domain.RequestErr) {
+			return h.FUsecase.OpenVersion(ctx, version, offset, length)
+		})
+}
+
+func (h *FileHandler) RestoreVersion(c echo.Context) error {
+	h.log.Info("REQ: restore version")
+	ctx := c.Request().Context()
+	uuid := c.Param("uuid")
+	number, err := strconv.Atoi(c.Param("number"))
+	if err != nil || number < 1 {
+		errBody := dtos.NewErrDto(fmt.Sprint("Invalid version number: ", c.Param("number")))
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	var rDto dtos.VersionRestoreDto
+	err = c.Bind(&rDto)
+	if err != nil {
+		errBody := dtos.NewErrDto(fmt.Sprint("Req body binding failed: ", err))
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	if ok, err := delivery.IsRequestValid(&rDto); !ok {
+		return validationErr(c, err)
+	}
+
+	version, rErr := h.FUsecase.RestoreVersion(ctx, uuid, number, rDto.Note)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.JSON(http.StatusCreated, version)
+}
+
+func (h *FileHandler) DiffVersions(c echo.Context) error {
+	h.log.Info("REQ: diff versions")
+	ctx := c.Request().Context()
+	uuid := c.Param("uuid")
+	from, errFrom := strconv.Atoi(c.QueryParam("from"))
+	to, errTo := strconv.Atoi(c.QueryParam("to"))
+	if errFrom != nil || errTo != nil || from < 1 || to < 1 {
+		errBody := dtos.NewErrDto("Query params from and to must be version numbers")
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	diff, rErr := h.FUsecase.DiffVersions(ctx, uuid, from, to)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.JSON(http.StatusOK, diff)
+}
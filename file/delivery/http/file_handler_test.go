@@ -0,0 +1,123 @@
+package http
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sicozz/papyrus/domain"
+)
+
+func TestParseRange(t *testing.T) {
+	cases := []struct {
+		header         string
+		offset, length int64
+		ok, invalid    bool
+	}{
+		{header: "bytes=0-9", offset: 0, length: 10, ok: true},
+		{header: "bytes=5-", offset: 5, length: 95, ok: true},
+		{header: "bytes=90-200", offset: 90, length: 10, ok: true},
+		{header: "bytes=-10", offset: 90, length: 10, ok: true},
+		{header: "bytes=-500", offset: 0, length: 100, ok: true},
+		{header: "bytes= 3-4", offset: 3, length: 2, ok: true},
+		// Served whole
+		{header: "bytes=0-1,5-6"},
+		{header: "items=0-9"},
+		{header: "Bytes=0-9"},
+		{header: "0-9"},
+		// Answered 416
+		{header: "bytes=100-", invalid: true},
+		{header: "bytes=9-3", invalid: true},
+		{header: "bytes=-0", invalid: true},
+		{header: "bytes=a-b", invalid: true},
+		{header: "bytes=7", invalid: true},
+	}
+	for _, c := range cases {
+		offset, length, ok, err := parseRange(c.header, 100)
+		if (err != nil) != c.invalid {
+			t.Errorf("%q: got error %v, want invalid %v", c.header, err, c.invalid)
+			continue
+		}
+		if ok != c.ok || offset != c.offset || length != c.length {
+			t.Errorf("%q: got %d+%d ok %v, want %d+%d ok %v", c.header, offset, length, ok, c.offset, c.length, c.ok)
+		}
+	}
+}
+
+func serve(t *testing.T, rng string, ifNoneMatch string) *httptest.ResponseRecorder {
+	const content = "0123456789"
+	req := httptest.NewRequest(http.MethodGet, "/file/x/download", nil)
+	if rng != "" {
+		req.Header.Set("Range", rng)
+	}
+	if ifNoneMatch != "" {
+		req.Header.Set("If-None-Match", ifNoneMatch)
+	}
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+
+	err := serveContent(c, "a.txt", int64(len(content)), "text/plain", "abc",
+		func(offset int64, length int64) (io.ReadCloser, domain.RequestErr) {
+			return io.NopCloser(strings.NewReader(content[offset : offset+length])), nil
+		})
+	if err != nil {
+		t.Fatalf("serving %q: %v", rng, err)
+	}
+	return rec
+}
+
+func TestServeContentRanges(t *testing.T) {
+	rec := serve(t, "", "")
+	if rec.Code != http.StatusOK || rec.Body.String() != "0123456789" {
+		t.Fatalf("whole content: got %d %q", rec.Code, rec.Body.String())
+	}
+
+	rec = serve(t, "bytes=2-4", "")
+	if rec.Code != http.StatusPartialContent || rec.Body.String() != "234" {
+		t.Fatalf("range: got %d %q", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Range"); got != "bytes 2-4/10" {
+		t.Fatalf("range: Content-Range %q", got)
+	}
+
+	rec = serve(t, "items=2-4", "")
+	if rec.Code != http.StatusOK || rec.Body.String() != "0123456789" {
+		t.Fatalf("unknown unit: got %d %q, want the whole content", rec.Code, rec.Body.String())
+	}
+
+	rec = serve(t, "bytes=20-", "")
+	if rec.Code != http.StatusRequestedRangeNotSatisfiable {
+		t.Fatalf("unsatisfiable range: got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Content-Range"); got != "bytes */10" {
+		t.Fatalf("unsatisfiable range: Content-Range %q", got)
+	}
+
+	rec = serve(t, "bytes=2-4", `"abc"`)
+	if rec.Code != http.StatusNotModified {
+		t.Fatalf("matching etag: got %d", rec.Code)
+	}
+}
+
+func TestServeContentFilename(t *testing.T) {
+	for _, name := range []string{"a.txt", "informe final.pdf", "año \"2026\".pdf", "年度報告.docx"} {
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/file/x/download", nil), rec)
+		err := serveContent(c, name, 1, "text/plain", "abc",
+			func(offset int64, length int64) (io.ReadCloser, domain.RequestErr) {
+				return io.NopCloser(strings.NewReader("x")), nil
+			})
+		if err != nil {
+			t.Fatalf("serving %q: %v", name, err)
+		}
+
+		disposition, params, err := mime.ParseMediaType(rec.Header().Get(echo.HeaderContentDisposition))
+		if err != nil || disposition != "attachment" || params["filename"] != name {
+			t.Errorf("%q: got %q, %v, %v", name, disposition, params, err)
+		}
+	}
+}
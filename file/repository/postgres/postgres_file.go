@@ -0,0 +1,287 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+	"github.com/sicozz/papyrus/utils/repository"
+)
+
+// fileSelect reads files with their type, state and stage descriptions
+const fileSelect = `SELECT f.uuid, f.code, f.name, f.path, f.creation_date, f.input_date,
+		ft.description, fs.description, fg.description, f.dir, f.revision_user,
+		f.approval_user, f.size, f.mime_type, COALESCE(f.sha256, ''), f.version,
+		COALESCE(f.approved_version, 0)
+	FROM file f
+	JOIN file_type ft ON ft.code = f.type
+	JOIN file_state fs ON fs.code = f.state
+	JOIN file_stage fg ON fg.code = f.stage`
+
+type postgresFileRepository struct {
+	Conn *sql.DB
+	log  utils.AggregatedLogger
+}
+
+// NewPostgresFileRepository will create an object that represent the FileRepository interface
+func NewPostgresFileRepository(conn *sql.DB) domain.FileRepository {
+	logger := utils.NewAggregatedLogger(constants.Repository, constants.File)
+	return &postgresFileRepository{conn, logger}
+}
+
+func (r *postgresFileRepository) fetch(ctx context.Context, query string, args ...interface{}) (res []domain.File, err error) {
+	rows, err := r.Conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		r.log.Error(err)
+		return nil, repository.MapErr(err)
+	}
+
+	defer func() {
+		errRow := rows.Close()
+		if errRow != nil {
+			r.log.Error(errRow)
+		}
+	}()
+
+	res = make([]domain.File, 0)
+	for rows.Next() {
+		t := domain.File{}
+		// Get from db
+		err = rows.Scan(
+			&t.Uuid,
+			&t.Code,
+			&t.Name,
+			&t.Path,
+			&t.CreationDate,
+			&t.InputDate,
+			&t.Type,
+			&t.State,
+			&t.Stage,
+			&t.Dir,
+			&t.RevisionUser,
+			&t.ApprovalUser,
+			&t.Size,
+			&t.MimeType,
+			&t.Sha256,
+			&t.Version,
+			&t.ApprovedVersion,
+		)
+
+		if err != nil {
+			r.log.Error("IN [fetch]:", err)
+			return nil, err
+		}
+		res = append(res, t)
+	}
+
+	return res, nil
+}
+
+// Get file by uuid
+func (r *postgresFileRepository) GetByUuid(ctx context.Context, uuid string) (res domain.File, err error) {
+	query := fileSelect + ` WHERE f.uuid = $1`
+
+	files, err := r.fetch(ctx, query, uuid)
+	if err != nil {
+		return domain.File{}, err
+	}
+
+	if len(files) < 1 {
+		return domain.File{}, domain.ErrNotFound
+	}
+
+	res = files[0]
+
+	return
+}
+
+// Get the files of a dir
+func (r *postgresFileRepository) GetByDir(ctx context.Context, dir string) (res []domain.File, err error) {
+	query := fileSelect + ` WHERE f.dir = $1 ORDER BY f.code, f.name`
+
+	res, err = r.fetch(ctx, query, dir)
+	if err != nil {
+		r.log.Error("IN [GetByDir]: could not fetch files ->", err)
+	}
+
+	return
+}
+
+/*
+* Store a new file with its first version and logs its upload, type, state
+* and stage are given by description
+ */
+func (r *postgresFileRepository) Store(ctx context.Context, f *domain.File, v *domain.FileVersion) (err error) {
+	tx, err := r.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		r.log.Error("IN [Store]: could not begin transaction ->", err)
+		return
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	query :=
+		`INSERT INTO file (code, name, path, creation_date, input_date, type, state, stage,
+			dir, revision_user, approval_user, size, mime_type, sha256, version)
+		VALUES ($1, $2, $3, $4, $5,
+			(SELECT code FROM file_type WHERE description = $6),
+			(SELECT code FROM file_state WHERE description = $7),
+			(SELECT code FROM file_stage WHERE description = $8),
+			$9, $10, $11, $12, $13, $14, 1)
+		RETURNING uuid`
+	err = tx.QueryRowContext(
+		ctx,
+		query,
+		f.Code,
+		f.Name,
+		f.Path,
+		f.CreationDate,
+		f.InputDate,
+		f.Type,
+		f.State,
+		f.Stage,
+		f.Dir,
+		f.RevisionUser,
+		f.ApprovalUser,
+		f.Size,
+		f.MimeType,
+		f.Sha256,
+	).Scan(&f.Uuid)
+	if err != nil {
+		r.log.Error("IN [Store]: could not store file ->", err)
+		return
+	}
+	f.Version = 1
+
+	query =
+		`INSERT INTO version (date, file, number, name, path, size, mime_type, sha256, user_, note)
+		VALUES ($1, $2, 1, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING uuid`
+	err = tx.QueryRowContext(
+		ctx,
+		query,
+		v.Date,
+		f.Uuid,
+		v.Name,
+		v.Path,
+		v.Size,
+		v.MimeType,
+		v.Sha256,
+		v.User,
+		v.Note,
+	).Scan(&v.Uuid)
+	if err != nil {
+		r.log.Error("IN [Store]: could not store first version ->", err)
+		return
+	}
+	v.File, v.Number = f.Uuid, 1
+
+	_, err = tx.ExecContext(
+		ctx,
+		`INSERT INTO upload (date, user_, file, version, bytes) VALUES ($1, $2, $3, 1, $4)`,
+		v.Date,
+		v.User,
+		f.Uuid,
+		v.Size,
+	)
+	if err != nil {
+		r.log.Error("IN [Store]: could not log upload ->", err)
+		return
+	}
+
+	return tx.Commit()
+}
+
+/*
+* Transition moves the stage of a file and records the move in one transaction.
+* The update only matches while the file is still at t.From and t.Version, so
+* two concurrent transitions, or one and a new version, cannot both apply
+ */
+func (r *postgresFileRepository) Transition(ctx context.Context, t *domain.FileTransition) (ok bool, err error) {
+	tx, err := r.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		r.log.Error("IN [Transition]: could not begin transaction ->", err)
+		return
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	res, err := tx.ExecContext(
+		ctx,
+		`UPDATE file SET
+			stage = (SELECT code FROM file_stage WHERE description = $3),
+			approved_version = CASE WHEN $5 THEN version ELSE approved_version END
+		WHERE uuid = $1
+			AND stage = (SELECT code FROM file_stage WHERE description = $2)
+			AND version = $4`,
+		t.File,
+		t.From,
+		t.To,
+		t.Version,
+		t.To == domain.FileStageApproved,
+	)
+	if err != nil {
+		r.log.Error("IN [Transition]: could not update stage ->", err)
+		return
+	}
+	affect, err := res.RowsAffected()
+	if err != nil || affect != 1 {
+		return false, err
+	}
+
+	err = tx.QueryRowContext(
+		ctx,
+		`INSERT INTO file_transition (file, action, from_stage, to_stage, user_, comment, date, version)
+		VALUES ($1, $2,
+			(SELECT code FROM file_stage WHERE description = $3),
+			(SELECT code FROM file_stage WHERE description = $4),
+			$5, $6, $7, $8)
+		RETURNING uuid`,
+		t.File,
+		t.Action,
+		t.From,
+		t.To,
+		t.User,
+		t.Comment,
+		t.Date,
+		t.Version,
+	).Scan(&t.Uuid)
+	if err != nil {
+		r.log.Error("IN [Transition]: could not record transition ->", err)
+		return
+	}
+
+	return true, tx.Commit()
+}
+
+// Get the transitions of a file, oldest first
+func (r *postgresFileRepository) GetHistory(ctx context.Context, file string) (res []domain.FileTransition, err error) {
+	query :=
+		`SELECT t.uuid, t.file, t.action, sf.description, st.description, t.user_,
+			u.username, t.version, t.comment, t.date
+		FROM file_transition t
+		JOIN file_stage sf ON sf.code = t.from_stage
+		JOIN file_stage st ON st.code = t.to_stage
+		JOIN user_ u ON u.uuid = t.user_
+		WHERE t.file = $1
+		ORDER BY t.date, t.uuid`
+	rows, err := r.Conn.QueryContext(ctx, query, file)
+	if err != nil {
+		r.log.Error("IN [GetHistory]: could not query history ->", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	res = make([]domain.FileTransition, 0)
+	for rows.Next() {
+		t := domain.FileTransition{}
+		err = rows.Scan(&t.Uuid, &t.File, &t.Action, &t.From, &t.To, &t.User, &t.Username, &t.Version, &t.Comment, &t.Date)
+		if err != nil {
+			r.log.Error("IN [GetHistory]:", err)
+			return nil, err
+		}
+		res = append(res, t)
+	}
+
+	return res, rows.Err()
+}
@@ -0,0 +1,902 @@
+package usecase
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+)
+
+const (
+	octetStream = `application/octet-stream`
+	sniffLen    = 512
+)
+
+type fileUsecase struct {
+	fileRepo       domain.FileRepository
+	uploadRepo     domain.UploadSessionRepository
+	versionRepo    domain.VersionRepository
+	access         domain.FileAccess
+	auditRepo      domain.AuditRepository
+	dirRepo        domain.DirRepository
+	userRepo       domain.UserRepository
+	blobStore      domain.BlobStore
+	notifier       domain.Notifier
+	clock          domain.Clock
+	stagingDir     string
+	maxSize        int64
+	uploadLocks    sync.Map
+	contextTimeout time.Duration
+	log            utils.AggregatedLogger
+}
+
+/*
+* NewFileUsecase will create a new fileUsecase object representation of
+* domain.FileUsecase interface. Resumable uploads are staged below stagingDir,
+* so every chunk of an upload must reach the same instance
+ */
+func NewFileUsecase(
+	fr domain.FileRepository,
+	usr domain.UploadSessionRepository,
+	vr domain.VersionRepository,
+	fa domain.FileAccess,
+	ar domain.AuditRepository,
+	dr domain.DirRepository,
+	ur domain.UserRepository,
+	bs domain.BlobStore,
+	n domain.Notifier,
+	clk domain.Clock,
+	stagingDir string,
+	maxSize int64,
+	timeout time.Duration,
+) (domain.FileUsecase, error) {
+	if err := os.MkdirAll(stagingDir, 0o750); err != nil {
+		return nil, err
+	}
+
+	logger := utils.NewAggregatedLogger(constants.Usecase, constants.File)
+	return &fileUsecase{
+		fileRepo:       fr,
+		uploadRepo:     usr,
+		versionRepo:    vr,
+		access:         fa,
+		auditRepo:      ar,
+		dirRepo:        dr,
+		userRepo:       ur,
+		blobStore:      bs,
+		notifier:       n,
+		clock:          clk,
+		stagingDir:     stagingDir,
+		maxSize:        maxSize,
+		contextTimeout: timeout,
+		log:            logger,
+	}, nil
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// newBlobKey returns a random key, sharded by its first byte
+func newBlobKey() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	h := hex.EncodeToString(b)
+
+	return h[:2] + "/" + h, nil
+}
+
+/*
+* detectMime prefers the type declared by the client, then the one of the file
+* extension and finally sniffs the first bytes of the content
+ */
+func detectMime(declared string, name string, head []byte) string {
+	if declared != "" && declared != octetStream {
+		return declared
+	}
+	if byExt := mime.TypeByExtension(filepath.Ext(name)); byExt != "" {
+		return byExt
+	}
+	return http.DetectContentType(head)
+}
+
+func (u *fileUsecase) stagingPath(uuid string) string {
+	return filepath.Join(u.stagingDir, uuid+".part")
+}
+
+// fileMetaRef carries a FileMeta along with the uuids its usernames resolve to
+type fileMetaRef struct {
+	domain.FileMeta
+	reviewer string
+	approver string
+}
+
+// checkMeta resolves the reviewer and approver of meta and checks its dir exists
+func (u *fileUsecase) checkMeta(ctx context.Context, meta *fileMetaRef) (rErr domain.RequestErr) {
+	if _, err := u.dirRepo.GetByUuid(ctx, meta.Dir); err != nil {
+		return domain.NewRepoErr(err, fmt.Sprint("Directory not found. uuid: ", meta.Dir))
+	}
+
+	reviewer, err := u.userRepo.GetByUsername(ctx, meta.RevisionUser)
+	if errors.Is(err, domain.ErrNotFound) {
+		err = errors.New(fmt.Sprint("Reviewer not found. username: ", meta.RevisionUser))
+		return domain.NewUCaseErr(http.StatusBadRequest, err)
+	}
+	if err != nil {
+		return domain.NewRepoErr(err, "")
+	}
+
+	approver, err := u.userRepo.GetByUsername(ctx, meta.ApprovalUser)
+	if errors.Is(err, domain.ErrNotFound) {
+		err = errors.New(fmt.Sprint("Approver not found. username: ", meta.ApprovalUser))
+		return domain.NewUCaseErr(http.StatusBadRequest, err)
+	}
+	if err != nil {
+		return domain.NewRepoErr(err, "")
+	}
+
+	meta.reviewer, meta.approver = reviewer.Uuid, approver.Uuid
+	return
+}
+
+// blobContent is representing content written to the blob store
+type blobContent struct {
+	key      string
+	size     int64
+	mimeType string
+	sha256   string
+}
+
+/*
+* putContent streams content to the blob store, hashing and measuring it on
+* the way. The transfer is bound by c only, not by the usecase timeout
+ */
+func (u *fileUsecase) putContent(c context.Context, name string, declared string, content io.Reader) (res blobContent, rErr domain.RequestErr) {
+	key, err := newBlobKey()
+	if err != nil {
+		return res, domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Upload failed"))
+	}
+
+	br := bufio.NewReaderSize(content, sniffLen)
+	head, _ := br.Peek(sniffLen)
+	mimeType := detectMime(declared, name, head)
+
+	hash := sha256.New()
+	counter := &countingWriter{}
+	body := io.TeeReader(io.LimitReader(br, u.maxSize+1), io.MultiWriter(hash, counter))
+	if err = u.blobStore.Put(c, key, body, -1, mimeType); err != nil {
+		u.log.Error("IN [putContent]: could not put blob ->", err)
+		return res, domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Upload failed"))
+	}
+
+	if counter.n > u.maxSize {
+		u.discardBlob(key)
+		err = errors.New(fmt.Sprint("File exceeds the maximum size of ", u.maxSize, " bytes"))
+		return res, domain.NewUCaseErr(http.StatusRequestEntityTooLarge, err)
+	}
+
+	return blobContent{key, counter.n, mimeType, hex.EncodeToString(hash.Sum(nil))}, nil
+}
+
+// store writes content and records it as a new file uploaded by uploader
+func (u *fileUsecase) store(c context.Context, meta *fileMetaRef, uploader string, content io.Reader) (res domain.File, rErr domain.RequestErr) {
+	blob, rErr := u.putContent(c, meta.Name, meta.MimeType, content)
+	if rErr != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	now := u.clock.Now()
+	res = domain.File{
+		Code:         meta.Code,
+		Name:         meta.Name,
+		Path:         blob.key,
+		CreationDate: now,
+		InputDate:    now,
+		Type:         meta.Type,
+		State:        domain.FileStateActive,
+		Stage:        domain.FileStageLoaded,
+		Dir:          meta.Dir,
+		RevisionUser: meta.reviewer,
+		ApprovalUser: meta.approver,
+		Size:         blob.size,
+		MimeType:     blob.mimeType,
+		Sha256:       blob.sha256,
+	}
+	v := domain.FileVersion{
+		Path:     blob.key,
+		Date:     now,
+		Name:     meta.Name,
+		Size:     blob.size,
+		MimeType: blob.mimeType,
+		Sha256:   blob.sha256,
+		User:     uploader,
+	}
+	if err := u.fileRepo.Store(ctx, &res, &v); err != nil {
+		u.discardBlob(blob.key)
+		return domain.File{}, domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Upload failed"))
+	}
+
+	u.log.Info("IN [store]: stored file {", res.Uuid, "}", res.Size, "bytes", res.MimeType)
+	u.notifyReview(ctx, res)
+	return
+}
+
+// notify delivers n, a failed delivery never fails the operation that caused it
+func (u *fileUsecase) notify(ctx context.Context, n domain.Notification) {
+	n.Date = u.clock.Now()
+	if err := u.notifier.Notify(ctx, n); err != nil {
+		u.log.Error("IN [notify]: could not notify {", n.User, "} of", n.Event, "->", err)
+	}
+}
+
+// notifyReview tells the reviewer of f that its head awaits review
+func (u *fileUsecase) notifyReview(ctx context.Context, f domain.File) {
+	u.notify(ctx, domain.Notification{
+		User:    f.RevisionUser,
+		Event:   domain.EventFileReview,
+		Subject: fmt.Sprint("File ", f.Code, " awaits your review"),
+		Body:    fmt.Sprint("Version ", f.Version, " of the file ", f.Code, " - ", f.Name, " awaits your review."),
+		Ref:     f.Uuid,
+	})
+}
+
+// discardBlob removes a blob whose file could not be recorded
+func (u *fileUsecase) discardBlob(key string) {
+	ctx, cancel := context.WithTimeout(context.Background(), u.contextTimeout)
+	defer cancel()
+
+	if err := u.blobStore.Delete(ctx, key); err != nil {
+		u.log.Error("IN [discardBlob]: could not delete orphan blob {", key, "} ->", err)
+	}
+}
+
+// authorize checks the authenticated user can access file, or dir itself when file is empty
+func (u *fileUsecase) authorize(ctx context.Context, access string, dir string, file string) (rErr domain.RequestErr) {
+	user, ok := domain.UserFromContext(ctx)
+	if !ok {
+		return domain.NewUCaseErr(http.StatusUnauthorized, errors.New("Authentication required"))
+	}
+
+	allowed, err := u.access.Allowed(ctx, user, access, dir, file)
+	if err != nil {
+		u.log.Error("IN [authorize]: could not resolve access ->", err)
+		return domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Access check failed"))
+	}
+	if !allowed {
+		target := "file " + file
+		if file == "" {
+			target = "directory " + dir
+		}
+		return domain.NewUCaseErr(http.StatusForbidden, errors.New(fmt.Sprint("No ", access, " access to ", target)))
+	}
+
+	return
+}
+
+// getAuthorized returns the file uuid when the authenticated user has access on it
+func (u *fileUsecase) getAuthorized(ctx context.Context, uuid string, access string) (res domain.File, rErr domain.RequestErr) {
+	res, err := u.fileRepo.GetByUuid(ctx, uuid)
+	if err != nil {
+		return res, domain.NewRepoErr(err, fmt.Sprint("File not found. uuid: ", uuid))
+	}
+
+	rErr = u.authorize(ctx, access, res.Dir, res.Uuid)
+	return
+}
+
+func (u *fileUsecase) GetByUuid(c context.Context, uuid string) (res domain.File, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	return u.getAuthorized(ctx, uuid, domain.AccessRead)
+}
+
+func (u *fileUsecase) FetchByDir(c context.Context, dir string) (res []domain.File, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	if _, err := u.dirRepo.GetByUuid(ctx, dir); err != nil {
+		rErr = domain.NewRepoErr(err, fmt.Sprint("Directory not found. uuid: ", dir))
+		return
+	}
+
+	user, ok := domain.UserFromContext(ctx)
+	if !ok {
+		return res, domain.NewUCaseErr(http.StatusUnauthorized, errors.New("Authentication required"))
+	}
+
+	files, err := u.fileRepo.GetByDir(ctx, dir)
+	if err == nil {
+		res, err = u.access.Filter(ctx, user, domain.AccessRead, dir, files)
+	}
+	if err != nil {
+		rErr = domain.NewUCaseErr(http.StatusInternalServerError, errors.New("File fetch failed"))
+		return
+	}
+
+	return
+}
+
+func (u *fileUsecase) Upload(c context.Context, meta domain.FileMeta, content io.Reader) (res domain.File, rErr domain.RequestErr) {
+	user, ok := domain.UserFromContext(c)
+	if !ok {
+		return res, domain.NewUCaseErr(http.StatusUnauthorized, errors.New("Authentication required"))
+	}
+
+	ref := &fileMetaRef{FileMeta: meta}
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	rErr = u.checkMeta(ctx, ref)
+	if rErr == nil {
+		rErr = u.authorize(ctx, domain.AccessWrite, meta.Dir, "")
+	}
+	cancel()
+	if rErr != nil {
+		return
+	}
+
+	return u.store(c, ref, user.Uuid, content)
+}
+
+func (u *fileUsecase) Open(c context.Context, f domain.File, offset int64, length int64) (res io.ReadCloser, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	rErr = u.authorize(ctx, domain.AccessRead, f.Dir, f.Uuid)
+	cancel()
+	if rErr != nil {
+		return
+	}
+
+	return u.openContent(c, f.Uuid, f.Version, f.Path, f.Size, offset, length)
+}
+
+/*
+* openContent opens the blob key of a version of file and logs the download
+* before handing it out, so no content leaves without a trace
+ */
+func (u *fileUsecase) openContent(c context.Context, file string, version int, key string, size int64, offset int64, length int64) (res io.ReadCloser, rErr domain.RequestErr) {
+	res, err := u.blobStore.Get(c, key, offset, length)
+	if err != nil {
+		u.log.Error("IN [openContent]: could not read blob of file {", file, "} ->", err)
+		rErr = domain.NewUCaseErr(http.StatusInternalServerError, errors.New("File content unavailable"))
+		return
+	}
+
+	if length < 0 {
+		length = size - offset
+	}
+	user, _ := domain.UserFromContext(c)
+	t := domain.Transfer{
+		Date:    u.clock.Now(),
+		User:    user.Uuid,
+		File:    file,
+		Version: version,
+		Bytes:   length,
+	}
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	if err = u.auditRepo.RecordDownload(ctx, &t); err != nil {
+		res.Close()
+		return nil, domain.NewUCaseErr(http.StatusInternalServerError, errors.New("File download could not be logged"))
+	}
+
+	return
+}
+
+// getOwnUpload returns a session of the authenticated user, hiding the others'
+func (u *fileUsecase) getOwnUpload(ctx context.Context, uuid string) (res domain.UploadSession, rErr domain.RequestErr) {
+	notFound := domain.NewUCaseErr(http.StatusNotFound, errors.New(fmt.Sprint("Upload not found. uuid: ", uuid)))
+	user, ok := domain.UserFromContext(ctx)
+	if !ok {
+		return res, domain.NewUCaseErr(http.StatusUnauthorized, errors.New("Authentication required"))
+	}
+
+	res, err := u.uploadRepo.GetByUuid(ctx, uuid)
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		u.log.Error("IN [getOwnUpload]: could not get upload ->", err)
+		return domain.UploadSession{}, domain.NewRepoErr(err, "")
+	}
+	if err != nil || res.UserUuid != user.Uuid {
+		return domain.UploadSession{}, notFound
+	}
+
+	return
+}
+
+func (u *fileUsecase) CreateUpload(c context.Context, meta domain.FileMeta, size int64) (res domain.UploadSession, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	user, ok := domain.UserFromContext(ctx)
+	if !ok {
+		return res, domain.NewUCaseErr(http.StatusUnauthorized, errors.New("Authentication required"))
+	}
+
+	if size > u.maxSize {
+		err := errors.New(fmt.Sprint("File exceeds the maximum size of ", u.maxSize, " bytes"))
+		return res, domain.NewUCaseErr(http.StatusRequestEntityTooLarge, err)
+	}
+
+	if rErr = u.checkMeta(ctx, &fileMetaRef{FileMeta: meta}); rErr != nil {
+		return
+	}
+	if rErr = u.authorize(ctx, domain.AccessWrite, meta.Dir, ""); rErr != nil {
+		return
+	}
+
+	res = domain.UploadSession{
+		UserUuid: user.Uuid,
+		Meta:     meta,
+		Size:     size,
+	}
+	if err := u.uploadRepo.Store(ctx, &res); err != nil {
+		return res, domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Upload creation failed"))
+	}
+
+	f, err := os.Create(u.stagingPath(res.Uuid))
+	if err == nil {
+		err = f.Close()
+	}
+	if err != nil {
+		u.log.Error("IN [CreateUpload]: could not create staging file ->", err)
+		_ = u.uploadRepo.Delete(ctx, res.Uuid)
+		return res, domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Upload creation failed"))
+	}
+
+	return
+}
+
+func (u *fileUsecase) GetUpload(c context.Context, uuid string) (res domain.UploadSession, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	return u.getOwnUpload(ctx, uuid)
+}
+
+func (u *fileUsecase) AppendUpload(c context.Context, uuid string, offset int64, chunk io.Reader) (res domain.UploadSession, file *domain.File, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	res, rErr = u.getOwnUpload(ctx, uuid)
+	cancel()
+	if rErr != nil {
+		return
+	}
+
+	// One writer per session at a time, later chunks must wait for the offset
+	mu, _ := u.uploadLocks.LoadOrStore(uuid, &sync.Mutex{})
+	if !mu.(*sync.Mutex).TryLock() {
+		return res, nil, domain.NewUCaseErr(http.StatusConflict, errors.New("Another chunk of this upload is in progress"))
+	}
+	defer mu.(*sync.Mutex).Unlock()
+
+	if offset != res.Offset {
+		err := errors.New(fmt.Sprint("Offset mismatch, the upload is at ", res.Offset))
+		return res, nil, domain.NewUCaseErr(http.StatusConflict, err)
+	}
+
+	f, err := os.OpenFile(u.stagingPath(uuid), os.O_WRONLY, 0)
+	if err != nil {
+		u.log.Error("IN [AppendUpload]: could not open staging file ->", err)
+		return res, nil, domain.NewUCaseErr(http.StatusGone, errors.New("Upload data is no longer available"))
+	}
+
+	remaining := res.Size - offset
+	n, err := io.Copy(io.NewOffsetWriter(f, offset), io.LimitReader(chunk, remaining))
+	if errClose := f.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		u.log.Error("IN [AppendUpload]: could not write chunk ->", err)
+		return res, nil, domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Chunk write failed"))
+	}
+
+	// Probe for a byte past the declared size without staging it, the offset stays put on a rejection
+	if n == remaining {
+		if _, err = io.ReadFull(chunk, make([]byte, 1)); err == nil {
+			err = errors.New(fmt.Sprint("Chunk exceeds the declared size, ", remaining, " bytes remain"))
+			return res, nil, domain.NewUCaseErr(http.StatusRequestEntityTooLarge, err)
+		}
+	}
+
+	// The chunk streams for as long as the request lasts, the bookkeeping after it gets its own timeout
+	ctx, cancel = context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	advanced, err := u.uploadRepo.Advance(ctx, uuid, offset, offset+n)
+	if err != nil {
+		u.log.Error("IN [AppendUpload]: could not advance upload ->", err)
+		return res, nil, domain.NewRepoErr(err, fmt.Sprint("Upload not found. uuid: ", uuid))
+	}
+	if !advanced {
+		return res, nil, domain.NewUCaseErr(http.StatusConflict, errors.New("Upload offset changed concurrently"))
+	}
+	res.Offset = offset + n
+
+	if res.Offset < res.Size {
+		return
+	}
+
+	staged, err := os.Open(u.stagingPath(uuid))
+	if err != nil {
+		u.log.Error("IN [AppendUpload]: could not reopen staging file ->", err)
+		return res, nil, domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Upload failed"))
+	}
+	defer staged.Close()
+
+	ref := &fileMetaRef{FileMeta: res.Meta}
+	if rErr = u.checkMeta(ctx, ref); rErr != nil {
+		return
+	}
+
+	stored, rErr := u.store(c, ref, res.UserUuid, io.LimitReader(staged, res.Size))
+	if rErr != nil {
+		return
+	}
+	file = &stored
+
+	u.cleanUpload(uuid)
+	return
+}
+
+// cleanUpload forgets a finished or aborted upload
+func (u *fileUsecase) cleanUpload(uuid string) {
+	ctx, cancel := context.WithTimeout(context.Background(), u.contextTimeout)
+	defer cancel()
+
+	if err := u.uploadRepo.Delete(ctx, uuid); err != nil {
+		u.log.Error("IN [cleanUpload]: could not delete upload session {", uuid, "} ->", err)
+	}
+	if err := os.Remove(u.stagingPath(uuid)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		u.log.Error("IN [cleanUpload]: could not remove staging file {", uuid, "} ->", err)
+	}
+	u.uploadLocks.Delete(uuid)
+}
+
+func (u *fileUsecase) AbortUpload(c context.Context, uuid string) (rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	if _, rErr = u.getOwnUpload(ctx, uuid); rErr != nil {
+		return
+	}
+
+	u.cleanUpload(uuid)
+	return
+}
+
+/*
+* transition applies action to a file on behalf of the authenticated user:
+* cargado -> revisado by the reviewer, revisado -> aprobado by the approver and
+* back to cargado on a rejection by whoever holds the file at its stage
+ */
+func (u *fileUsecase) transition(c context.Context, uuid string, action string, version int, comment string) (res domain.File, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	user, ok := domain.UserFromContext(ctx)
+	if !ok {
+		return res, domain.NewUCaseErr(http.StatusUnauthorized, errors.New("Authentication required"))
+	}
+
+	res, rErr = u.getAuthorized(ctx, uuid, domain.AccessWrite)
+	if rErr != nil {
+		return
+	}
+
+	var err error
+	if res.State != domain.FileStateActive {
+		err = errors.New(fmt.Sprint("File is ", res.State, ", only active files follow the workflow"))
+		return res, domain.NewUCaseErr(http.StatusConflict, err)
+	}
+
+	if version != 0 && version != res.Version {
+		err = errors.New(fmt.Sprint("Version ", version, " is not the head of the file, version ", res.Version, " is"))
+		return res, domain.NewUCaseErr(http.StatusConflict, err)
+	}
+
+	var to, holder, role string
+	switch {
+	case action == domain.FileActionReview && res.Stage == domain.FileStageLoaded:
+		to, holder, role = domain.FileStageReviewed, res.RevisionUser, "reviewer"
+	case action == domain.FileActionApprove && res.Stage == domain.FileStageReviewed:
+		to, holder, role = domain.FileStageApproved, res.ApprovalUser, "approver"
+	case action == domain.FileActionReject && res.Stage == domain.FileStageLoaded:
+		to, holder, role = domain.FileStageLoaded, res.RevisionUser, "reviewer"
+	case action == domain.FileActionReject && res.Stage == domain.FileStageReviewed:
+		to, holder, role = domain.FileStageLoaded, res.ApprovalUser, "approver"
+	default:
+		err = errors.New(fmt.Sprint("Cannot ", action, " a file at stage ", res.Stage))
+		return res, domain.NewUCaseErr(http.StatusConflict, err)
+	}
+
+	if user.Uuid != holder {
+		err = errors.New(fmt.Sprint("Only the assigned ", role, " may ", action, " this file"))
+		return res, domain.NewUCaseErr(http.StatusForbidden, err)
+	}
+
+	t := domain.FileTransition{
+		File:     res.Uuid,
+		Action:   action,
+		From:     res.Stage,
+		To:       to,
+		User:     user.Uuid,
+		Username: user.Username,
+		Version:  res.Version,
+		Comment:  comment,
+		Date:     u.clock.Now(),
+	}
+	ok, err = u.fileRepo.Transition(ctx, &t)
+	if err != nil {
+		return res, domain.NewUCaseErr(http.StatusInternalServerError, errors.New("File transition failed"))
+	}
+	if !ok {
+		return res, domain.NewUCaseErr(http.StatusConflict, errors.New("File stage changed concurrently"))
+	}
+
+	u.log.Info("IN [transition]:", user.Username, action, "file {", res.Uuid, "} version", res.Version, res.Stage, "->", to)
+	res.Stage = to
+	switch {
+	case to == domain.FileStageApproved:
+		res.ApprovedVersion = res.Version
+	case to == domain.FileStageReviewed:
+		u.notify(ctx, domain.Notification{
+			User:    res.ApprovalUser,
+			Event:   domain.EventFileApproval,
+			Subject: fmt.Sprint("File ", res.Code, " awaits your approval"),
+			Body:    fmt.Sprint("Version ", res.Version, " of the file ", res.Code, " - ", res.Name, " was reviewed by ", user.Username, " and awaits your approval."),
+			Ref:     res.Uuid,
+		})
+	case t.From == domain.FileStageReviewed:
+		u.notifyReview(ctx, res)
+	}
+	return
+}
+
+func (u *fileUsecase) Review(c context.Context, uuid string, version int, comment string) (res domain.File, rErr domain.RequestErr) {
+	return u.transition(c, uuid, domain.FileActionReview, version, comment)
+}
+
+func (u *fileUsecase) Approve(c context.Context, uuid string, version int, comment string) (res domain.File, rErr domain.RequestErr) {
+	return u.transition(c, uuid, domain.FileActionApprove, version, comment)
+}
+
+func (u *fileUsecase) Reject(c context.Context, uuid string, comment string) (res domain.File, rErr domain.RequestErr) {
+	if strings.TrimSpace(comment) == "" {
+		return res, domain.NewUCaseErr(http.StatusBadRequest, errors.New("A rejection requires a comment"))
+	}
+
+	return u.transition(c, uuid, domain.FileActionReject, 0, comment)
+}
+
+func (u *fileUsecase) GetHistory(c context.Context, uuid string) (res []domain.FileTransition, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	if _, rErr = u.getAuthorized(ctx, uuid, domain.AccessRead); rErr != nil {
+		return
+	}
+
+	res, err := u.fileRepo.GetHistory(ctx, uuid)
+	if err != nil {
+		rErr = domain.NewUCaseErr(http.StatusInternalServerError, errors.New("File history fetch failed"))
+		return
+	}
+
+	return
+}
+
+func (u *fileUsecase) GetVersions(c context.Context, uuid string) (res []domain.FileVersion, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	if _, rErr = u.getAuthorized(ctx, uuid, domain.AccessRead); rErr != nil {
+		return
+	}
+
+	res, err := u.versionRepo.GetByFile(ctx, uuid)
+	if err != nil {
+		rErr = domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Version fetch failed"))
+		return
+	}
+
+	return
+}
+
+func (u *fileUsecase) GetVersion(c context.Context, uuid string, number int) (res domain.FileVersion, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	if _, rErr = u.getAuthorized(ctx, uuid, domain.AccessRead); rErr != nil {
+		return
+	}
+
+	res, err := u.versionRepo.GetByNumber(ctx, uuid, number)
+	if err != nil {
+		rErr = domain.NewRepoErr(err, fmt.Sprint("Version ", number, " of file ", uuid, " not found"))
+	}
+
+	return
+}
+
+// storeVersion records v as the new head of its file on behalf of the authenticated user
+func (u *fileUsecase) storeVersion(ctx context.Context, v *domain.FileVersion) (rErr domain.RequestErr) {
+	ok, err := u.versionRepo.Store(ctx, v)
+	if err != nil {
+		return domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Version creation failed"))
+	}
+	if !ok {
+		err = errors.New(fmt.Sprint("File ", v.File, " is not active, it takes no new versions"))
+		return domain.NewUCaseErr(http.StatusConflict, err)
+	}
+
+	u.log.Info("IN [storeVersion]:", v.Username, "stored version", v.Number, "of file {", v.File, "}")
+	return
+}
+
+func (u *fileUsecase) UploadVersion(c context.Context, uuid string, meta domain.VersionMeta, content io.Reader) (res domain.FileVersion, rErr domain.RequestErr) {
+	user, ok := domain.UserFromContext(c)
+	if !ok {
+		return res, domain.NewUCaseErr(http.StatusUnauthorized, errors.New("Authentication required"))
+	}
+
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	f, rErr := u.getAuthorized(ctx, uuid, domain.AccessWrite)
+	cancel()
+	if rErr != nil {
+		return
+	}
+	if f.State != domain.FileStateActive {
+		err := errors.New(fmt.Sprint("File ", uuid, " is not active, it takes no new versions"))
+		return res, domain.NewUCaseErr(http.StatusConflict, err)
+	}
+
+	blob, rErr := u.putContent(c, meta.Name, meta.MimeType, content)
+	if rErr != nil {
+		return
+	}
+
+	ctx, cancel = context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	res = domain.FileVersion{
+		File:     uuid,
+		Path:     blob.key,
+		Date:     u.clock.Now(),
+		Name:     meta.Name,
+		Size:     blob.size,
+		MimeType: blob.mimeType,
+		Sha256:   blob.sha256,
+		User:     user.Uuid,
+		Username: user.Username,
+		Note:     meta.Note,
+	}
+	if rErr = u.storeVersion(ctx, &res); rErr != nil {
+		u.discardBlob(blob.key)
+		return domain.FileVersion{}, rErr
+	}
+
+	f.Version, f.Name = res.Number, res.Name
+	u.notifyReview(ctx, f)
+	return
+}
+
+func (u *fileUsecase) OpenVersion(c context.Context, v domain.FileVersion, offset int64, length int64) (res io.ReadCloser, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	_, rErr = u.getAuthorized(ctx, v.File, domain.AccessRead)
+	cancel()
+	if rErr != nil {
+		return
+	}
+
+	return u.openContent(c, v.File, v.Number, v.Path, v.Size, offset, length)
+}
+
+/*
+* RestoreVersion records a new head sharing the blob of an older version.
+* Blobs are immutable, so versions never need their own copy
+ */
+func (u *fileUsecase) RestoreVersion(c context.Context, uuid string, number int, note string) (res domain.FileVersion, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	user, ok := domain.UserFromContext(ctx)
+	if !ok {
+		return res, domain.NewUCaseErr(http.StatusUnauthorized, errors.New("Authentication required"))
+	}
+
+	f, rErr := u.getAuthorized(ctx, uuid, domain.AccessWrite)
+	if rErr != nil {
+		return
+	}
+
+	old, err := u.versionRepo.GetByNumber(ctx, uuid, number)
+	if err != nil {
+		return res, domain.NewRepoErr(err, fmt.Sprint("Version ", number, " of file ", uuid, " not found"))
+	}
+
+	if note == "" {
+		note = fmt.Sprint("Restored from version ", number)
+	}
+	res = old
+	res.Uuid = ""
+	res.Date = u.clock.Now()
+	res.User = user.Uuid
+	res.Username = user.Username
+	res.Note = note
+	res.RestoredFrom = number
+	if rErr = u.storeVersion(ctx, &res); rErr != nil {
+		return domain.FileVersion{}, rErr
+	}
+
+	f.Version, f.Name = res.Number, res.Name
+	u.notifyReview(ctx, f)
+	return
+}
+
+func (u *fileUsecase) DiffVersions(c context.Context, uuid string, from int, to int) (res domain.VersionDiff, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	if _, rErr = u.getAuthorized(ctx, uuid, domain.AccessRead); rErr != nil {
+		return
+	}
+
+	a, err := u.versionRepo.GetByNumber(ctx, uuid, from)
+	if err != nil {
+		return res, domain.NewRepoErr(err, fmt.Sprint("Version ", from, " of file ", uuid, " not found"))
+	}
+	b, err := u.versionRepo.GetByNumber(ctx, uuid, to)
+	if err != nil {
+		return res, domain.NewRepoErr(err, fmt.Sprint("Version ", to, " of file ", uuid, " not found"))
+	}
+
+	res = domain.VersionDiff{
+		From:        from,
+		To:          to,
+		SameContent: a.Sha256 == b.Sha256,
+		Changes:     make([]domain.FieldChange, 0),
+	}
+	fields := []struct {
+		name string
+		a, b string
+	}{
+		{"name", a.Name, b.Name},
+		{"size", fmt.Sprint(a.Size), fmt.Sprint(b.Size)},
+		{"mime_type", a.MimeType, b.MimeType},
+		{"sha256", a.Sha256, b.Sha256},
+		{"username", a.Username, b.Username},
+		{"date", a.Date.Format(time.RFC3339), b.Date.Format(time.RFC3339)},
+		{"note", a.Note, b.Note},
+	}
+	for _, f := range fields {
+		if f.a != f.b {
+			res.Changes = append(res.Changes, domain.FieldChange{Field: f.name, From: f.a, To: f.b})
+		}
+	}
+
+	return
+}
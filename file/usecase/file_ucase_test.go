@@ -0,0 +1,323 @@
+package usecase_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/file/usecase"
+	"github.com/sicozz/papyrus/utils/blob"
+	"github.com/sicozz/papyrus/utils/clock"
+)
+
+const dirUuid = "3f1c2a9e-5b7d-4e8f-9a6b-1c2d3e4f5a6b"
+
+var uploader = domain.User{Uuid: "u-ana", Username: "ana"}
+
+type fakeUploadRepo struct {
+	sessions map[string]domain.UploadSession
+	err      error
+}
+
+func (r *fakeUploadRepo) GetByUuid(ctx context.Context, uuid string) (domain.UploadSession, error) {
+	s, ok := r.sessions[uuid]
+	if !ok {
+		return domain.UploadSession{}, domain.ErrNotFound
+	}
+	return s, nil
+}
+
+func (r *fakeUploadRepo) Store(ctx context.Context, s *domain.UploadSession) error {
+	s.Uuid = "7a8b9c0d-1e2f-4a3b-8c4d-5e6f7a8b9c0d"
+	r.sessions[s.Uuid] = *s
+	return nil
+}
+
+func (r *fakeUploadRepo) Advance(ctx context.Context, uuid string, from int64, to int64) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	if r.err != nil {
+		return false, r.err
+	}
+	s := r.sessions[uuid]
+	if s.Offset != from {
+		return false, nil
+	}
+	s.Offset = to
+	r.sessions[uuid] = s
+	return true, nil
+}
+
+func (r *fakeUploadRepo) Delete(ctx context.Context, uuid string) error {
+	delete(r.sessions, uuid)
+	return nil
+}
+
+type fakeFileRepo struct {
+	domain.FileRepository
+	stored []domain.File
+}
+
+func (r *fakeFileRepo) Store(ctx context.Context, f *domain.File, v *domain.FileVersion) error {
+	f.Uuid = "9c8b7a6d-5e4f-4a3b-9c2d-1e0f9a8b7c6d"
+	f.Version = 1
+	r.stored = append(r.stored, *f)
+	return nil
+}
+
+type fakeDirRepo struct{ domain.DirRepository }
+
+func (fakeDirRepo) GetByUuid(ctx context.Context, uuid string) (domain.Dir, error) {
+	if uuid != dirUuid {
+		return domain.Dir{}, domain.ErrNotFound
+	}
+	return domain.Dir{Uuid: dirUuid}, nil
+}
+
+type fakeUserRepo struct{ domain.UserRepository }
+
+func (fakeUserRepo) GetByUsername(ctx context.Context, uname string) (domain.User, error) {
+	return domain.User{Uuid: "u-" + uname, Username: uname}, nil
+}
+
+type allowAll struct{}
+
+func (allowAll) Allowed(ctx context.Context, u domain.User, access string, dir string, file string) (bool, error) {
+	return true, nil
+}
+
+func (allowAll) Filter(ctx context.Context, u domain.User, access string, dir string, files []domain.File) ([]domain.File, error) {
+	return files, nil
+}
+
+type nopNotifier struct{}
+
+func (nopNotifier) Notify(ctx context.Context, n domain.Notification) error {
+	return nil
+}
+
+type fixture struct {
+	fu      domain.FileUsecase
+	files   *fakeFileRepo
+	uploads *fakeUploadRepo
+	blobs   domain.BlobStore
+	staging string
+}
+
+func newFixture(t *testing.T) fixture {
+	return newTimedFixture(t, time.Second)
+}
+
+func newTimedFixture(t *testing.T, timeout time.Duration) fixture {
+	blobs, err := blob.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := fixture{
+		files:   &fakeFileRepo{},
+		uploads: &fakeUploadRepo{sessions: map[string]domain.UploadSession{}},
+		blobs:   blobs,
+		staging: t.TempDir(),
+	}
+	f.fu, err = usecase.NewFileUsecase(
+		f.files,
+		f.uploads,
+		nil,
+		allowAll{},
+		nil,
+		fakeDirRepo{},
+		fakeUserRepo{},
+		blobs,
+		nopNotifier{},
+		&clock.Fake{T: time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)},
+		f.staging,
+		1<<20,
+		timeout,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func (f fixture) create(t *testing.T, size int64) (context.Context, domain.UploadSession) {
+	ctx := domain.NewContextWithUser(context.Background(), uploader)
+	meta := domain.FileMeta{
+		Code:         "DOC-1",
+		Name:         "a.txt",
+		Type:         "documento",
+		Dir:          dirUuid,
+		RevisionUser: "bob",
+		ApprovalUser: "eve",
+	}
+	s, rErr := f.fu.CreateUpload(ctx, meta, size)
+	if rErr != nil {
+		t.Fatalf("creating upload: %v", rErr)
+	}
+	return ctx, s
+}
+
+func (f fixture) stagedSize(t *testing.T, uuid string) int64 {
+	fi, err := os.Stat(filepath.Join(f.staging, uuid+".part"))
+	if err != nil {
+		t.Fatalf("stat of staging file: %v", err)
+	}
+	return fi.Size()
+}
+
+func (f fixture) content(t *testing.T, file *domain.File) string {
+	rc, err := f.blobs.Get(context.Background(), file.Path, 0, -1)
+	if err != nil {
+		t.Fatalf("reading stored blob: %v", err)
+	}
+	defer rc.Close()
+	b, _ := io.ReadAll(rc)
+	return string(b)
+}
+
+func wantStatus(t *testing.T, what string, rErr domain.RequestErr, status int) {
+	t.Helper()
+	if rErr == nil || rErr.GetStatus() != status {
+		t.Fatalf("%s: got %v, want %d", what, rErr, status)
+	}
+}
+
+func TestResumableUpload(t *testing.T) {
+	f := newFixture(t)
+	ctx, s := f.create(t, 10)
+
+	res, file, rErr := f.fu.AppendUpload(ctx, s.Uuid, 0, strings.NewReader("01234"))
+	if rErr != nil || file != nil || res.Offset != 5 {
+		t.Fatalf("first chunk: got offset %d, file %v, %v", res.Offset, file, rErr)
+	}
+
+	_, _, rErr = f.fu.AppendUpload(ctx, s.Uuid, 3, strings.NewReader("34567"))
+	wantStatus(t, "chunk at a stale offset", rErr, http.StatusConflict)
+
+	got, rErr := f.fu.GetUpload(ctx, s.Uuid)
+	if rErr != nil || got.Offset != 5 {
+		t.Fatalf("resuming: got offset %d, %v", got.Offset, rErr)
+	}
+
+	res, file, rErr = f.fu.AppendUpload(ctx, s.Uuid, 5, strings.NewReader("56789"))
+	if rErr != nil || file == nil {
+		t.Fatalf("last chunk: got file %v, %v", file, rErr)
+	}
+	sum := sha256.Sum256([]byte("0123456789"))
+	if file.Size != 10 || file.Sha256 != hex.EncodeToString(sum[:]) {
+		t.Fatalf("stored file: got size %d sha %s", file.Size, file.Sha256)
+	}
+	if c := f.content(t, file); c != "0123456789" {
+		t.Fatalf("stored content: got %q", c)
+	}
+
+	if _, ok := f.uploads.sessions[s.Uuid]; ok {
+		t.Fatal("finished upload session kept")
+	}
+	if _, err := os.Stat(filepath.Join(f.staging, s.Uuid+".part")); !os.IsNotExist(err) {
+		t.Fatalf("finished staging file kept: %v", err)
+	}
+}
+
+// slowReader hands out its data only after a delay, as a slow client would
+type slowReader struct {
+	delay time.Duration
+	r     io.Reader
+}
+
+func (s *slowReader) Read(p []byte) (int, error) {
+	time.Sleep(s.delay)
+	s.delay = 0
+	return s.r.Read(p)
+}
+
+func TestSlowChunkOutlivesTheTimeout(t *testing.T) {
+	f := newTimedFixture(t, 50*time.Millisecond)
+	ctx, s := f.create(t, 10)
+
+	chunk := &slowReader{delay: 100 * time.Millisecond, r: strings.NewReader("01234")}
+	res, _, rErr := f.fu.AppendUpload(ctx, s.Uuid, 0, chunk)
+	if rErr != nil || res.Offset != 5 {
+		t.Fatalf("slow chunk: got offset %d, %v", res.Offset, rErr)
+	}
+
+	// Only a moved offset is a conflict, a failing store is not
+	f.uploads.err = errors.New("connection reset")
+	_, _, rErr = f.fu.AppendUpload(ctx, s.Uuid, 5, strings.NewReader("56789"))
+	wantStatus(t, "chunk while the store fails", rErr, http.StatusInternalServerError)
+}
+
+func TestOversizedChunkStagesNothingPastTheSize(t *testing.T) {
+	f := newFixture(t)
+	ctx, s := f.create(t, 10)
+
+	if _, _, rErr := f.fu.AppendUpload(ctx, s.Uuid, 0, strings.NewReader("01234")); rErr != nil {
+		t.Fatalf("first chunk: %v", rErr)
+	}
+
+	_, _, rErr := f.fu.AppendUpload(ctx, s.Uuid, 5, strings.NewReader("56789XYZ"))
+	wantStatus(t, "chunk past the declared size", rErr, http.StatusRequestEntityTooLarge)
+	if n := f.stagedSize(t, s.Uuid); n > 10 {
+		t.Fatalf("staging file grew to %d bytes past the declared 10", n)
+	}
+	if got, _ := f.fu.GetUpload(ctx, s.Uuid); got.Offset != 5 {
+		t.Fatalf("rejected chunk moved the offset to %d", got.Offset)
+	}
+
+	_, file, rErr := f.fu.AppendUpload(ctx, s.Uuid, 5, strings.NewReader("56789"))
+	if rErr != nil || file == nil {
+		t.Fatalf("retried chunk: got file %v, %v", file, rErr)
+	}
+	if c := f.content(t, file); c != "0123456789" {
+		t.Fatalf("stored content: got %q", c)
+	}
+}
+
+func TestFinishedUploadReadsOnlyTheDeclaredSize(t *testing.T) {
+	f := newFixture(t)
+	ctx, s := f.create(t, 10)
+
+	if _, _, rErr := f.fu.AppendUpload(ctx, s.Uuid, 0, strings.NewReader("01234")); rErr != nil {
+		t.Fatalf("first chunk: %v", rErr)
+	}
+
+	// Leftovers past the size, as a crashed writer could leave them
+	staged, err := os.OpenFile(filepath.Join(f.staging, s.Uuid+".part"), os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = staged.WriteAt([]byte("garbage"), 10); err != nil {
+		t.Fatal(err)
+	}
+	staged.Close()
+
+	_, file, rErr := f.fu.AppendUpload(ctx, s.Uuid, 5, strings.NewReader("56789"))
+	if rErr != nil || file == nil {
+		t.Fatalf("last chunk: got file %v, %v", file, rErr)
+	}
+	if file.Size != 10 {
+		t.Fatalf("stored %d bytes, want the declared 10", file.Size)
+	}
+	if c := f.content(t, file); c != "0123456789" {
+		t.Fatalf("stored content: got %q", c)
+	}
+}
+
+func TestUploadBelongsToItsCreator(t *testing.T) {
+	f := newFixture(t)
+	_, s := f.create(t, 10)
+
+	other := domain.NewContextWithUser(context.Background(), domain.User{Uuid: "u-bob", Username: "bob"})
+	_, _, rErr := f.fu.AppendUpload(other, s.Uuid, 0, strings.NewReader("01234"))
+	wantStatus(t, "appending to someone else's upload", rErr, http.StatusNotFound)
+}
@@ -0,0 +1,122 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sicozz/papyrus/auth/delivery/http/middleware"
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/domain/dtos"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+	"github.com/sicozz/papyrus/utils/delivery"
+)
+
+// FileGrantHandler will initialize the permissions resources of files and dirs
+type FileGrantHandler struct {
+	GUsecase domain.FileGrantUsecase
+	log      utils.AggregatedLogger
+}
+
+func NewFileGrantHandler(e *echo.Echo, gu domain.FileGrantUsecase, authMw echo.MiddlewareFunc, authz *middleware.AuthzMiddleware) {
+	logger := utils.NewAggregatedLogger(constants.Delivery, constants.FileGrant)
+	handler := &FileGrantHandler{gu, logger}
+	canGrant := authz.Require(middleware.Can(domain.PermFileGrant))
+	e.GET("/file/:uuid/permissions", handler.GetByFile, authMw, canGrant)
+	e.PATCH("/file/:uuid/permissions", handler.SetOnFile, authMw, canGrant)
+	e.GET("/dir/:uuid/permissions", handler.GetByDir, authMw, canGrant)
+	e.PATCH("/dir/:uuid/permissions", handler.SetOnDir, authMw, canGrant)
+}
+
+func toChanges(gDto dtos.GrantChangesDto) []domain.GrantChange {
+	changes := make([]domain.GrantChange, 0, len(gDto.Grants))
+	for _, g := range gDto.Grants {
+		changes = append(changes, domain.GrantChange{Username: g.Username, Access: g.Access, Allowed: g.Allowed})
+	}
+	return changes
+}
+
+func (h *FileGrantHandler) GetByFile(c echo.Context) error {
+	h.log.Info("REQ: get by file")
+	ctx := c.Request().Context()
+	uuid := c.Param("uuid")
+	perms, rErr := h.GUsecase.GetByFile(ctx, uuid, c.QueryParam("user"))
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.JSON(http.StatusOK, perms)
+}
+
+func (h *FileGrantHandler) SetOnFile(c echo.Context) error {
+	h.log.Info("REQ: set on file")
+	ctx := c.Request().Context()
+	uuid := c.Param("uuid")
+	var gDto dtos.GrantChangesDto
+	err := c.Bind(&gDto)
+	if err != nil {
+		errBody := dtos.NewErrDto(fmt.Sprint("Req body binding failed: ", err))
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	if ok, err := delivery.IsRequestValid(&gDto); !ok {
+		errBody, err := dtos.NewValidationErrDto(err.Error())
+		if err != nil {
+			errValid := dtos.NewErrDto(fmt.Sprint("Req body validation failed: ", err))
+			return c.JSON(http.StatusBadRequest, errValid)
+		}
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	rErr := h.GUsecase.SetOnFile(ctx, uuid, toChanges(gDto))
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+func (h *FileGrantHandler) GetByDir(c echo.Context) error {
+	h.log.Info("REQ: get by dir")
+	ctx := c.Request().Context()
+	uuid := c.Param("uuid")
+	grants, rErr := h.GUsecase.GetByDir(ctx, uuid)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.JSON(http.StatusOK, grants)
+}
+
+func (h *FileGrantHandler) SetOnDir(c echo.Context) error {
+	h.log.Info("REQ: set on dir")
+	ctx := c.Request().Context()
+	uuid := c.Param("uuid")
+	var gDto dtos.GrantChangesDto
+	err := c.Bind(&gDto)
+	if err != nil {
+		errBody := dtos.NewErrDto(fmt.Sprint("Req body binding failed: ", err))
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	if ok, err := delivery.IsRequestValid(&gDto); !ok {
+		errBody, err := dtos.NewValidationErrDto(err.Error())
+		if err != nil {
+			errValid := dtos.NewErrDto(fmt.Sprint("Req body validation failed: ", err))
+			return c.JSON(http.StatusBadRequest, errValid)
+		}
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	rErr := h.GUsecase.SetOnDir(ctx, uuid, toChanges(gDto))
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.NoContent(http.StatusOK)
+}
@@ -0,0 +1,211 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+	"github.com/sicozz/papyrus/utils/repository"
+)
+
+// grantTables maps every kind of access to the table holding its grants
+var grantTables = map[string]string{
+	domain.AccessRead:  "read_permission",
+	domain.AccessWrite: "write_permission",
+}
+
+// ancestorsCte walks up from the dir given by the placeholder param, itself included
+func ancestorsCte(param string) string {
+	return `WITH RECURSIVE up AS (
+		SELECT uuid, parent_dir FROM dir WHERE uuid = ` + param + `
+		UNION ALL
+		SELECT d.uuid, d.parent_dir FROM dir d JOIN up ON d.uuid = up.parent_dir
+	) `
+}
+
+// grantSelect reads the grants of both tables, $1 filters them
+const grantSelect = `SELECT g.access, g.user_, u.username, COALESCE(g.file::text, ''),
+		COALESCE(g.dir::text, ''), g.allowed
+	FROM (
+		SELECT 'read' AS access, user_, file, dir, allowed FROM read_permission
+		UNION ALL
+		SELECT 'write' AS access, user_, file, dir, allowed FROM write_permission
+	) g
+	JOIN user_ u ON u.uuid = g.user_`
+
+type postgresFileGrantRepository struct {
+	Conn *sql.DB
+	log  utils.AggregatedLogger
+}
+
+// NewPostgresFileGrantRepository will create an object that represent the FileGrantRepository interface
+func NewPostgresFileGrantRepository(conn *sql.DB) domain.FileGrantRepository {
+	logger := utils.NewAggregatedLogger(constants.Repository, constants.FileGrant)
+	return &postgresFileGrantRepository{conn, logger}
+}
+
+func grantTable(access string) (string, error) {
+	table, ok := grantTables[access]
+	if !ok {
+		return "", errors.New(fmt.Sprint("unknown access: ", access))
+	}
+	return table, nil
+}
+
+func (r *postgresFileGrantRepository) fetch(ctx context.Context, query string, args ...interface{}) (res []domain.FileGrant, err error) {
+	rows, err := r.Conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		r.log.Error(err)
+		return nil, repository.MapErr(err)
+	}
+
+	defer func() {
+		errRow := rows.Close()
+		if errRow != nil {
+			r.log.Error(errRow)
+		}
+	}()
+
+	res = make([]domain.FileGrant, 0)
+	for rows.Next() {
+		t := domain.FileGrant{}
+		err = rows.Scan(&t.Access, &t.User, &t.Username, &t.File, &t.Dir, &t.Allowed)
+		if err != nil {
+			r.log.Error("IN [fetch]:", err)
+			return nil, err
+		}
+		res = append(res, t)
+	}
+
+	return res, nil
+}
+
+func (r *postgresFileGrantRepository) GetDirDecision(ctx context.Context, access string, user string, dir string) (allowed bool, found bool, err error) {
+	table, err := grantTable(access)
+	if err != nil {
+		return
+	}
+
+	query := ancestorsCte("$2") + fmt.Sprintf(
+		`SELECT COALESCE(bool_and(allowed), false), count(*) > 0
+		FROM %s
+		WHERE user_ = $1 AND dir IN (SELECT uuid FROM up)`,
+		table,
+	)
+	err = r.Conn.QueryRowContext(ctx, query, user, dir).Scan(&allowed, &found)
+	if err != nil {
+		r.log.Error("IN [GetDirDecision]: could not fold dir grants ->", err)
+	}
+
+	return
+}
+
+func (r *postgresFileGrantRepository) GetFileDecisions(ctx context.Context, access string, user string, files []string) (res map[string]bool, err error) {
+	table, err := grantTable(access)
+	if err != nil {
+		return
+	}
+
+	query := fmt.Sprintf(`SELECT file, allowed FROM %s WHERE user_ = $1 AND file = ANY($2::uuid[])`, table)
+	rows, err := r.Conn.QueryContext(ctx, query, user, pq.Array(files))
+	if err != nil {
+		r.log.Error("IN [GetFileDecisions]: could not query file grants ->", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	res = make(map[string]bool)
+	for rows.Next() {
+		var file string
+		var allowed bool
+		if err = rows.Scan(&file, &allowed); err != nil {
+			r.log.Error("IN [GetFileDecisions]:", err)
+			return nil, err
+		}
+		res[file] = allowed
+	}
+
+	return res, rows.Err()
+}
+
+// Get the grants on a file
+func (r *postgresFileGrantRepository) GetByFile(ctx context.Context, file string) (res []domain.FileGrant, err error) {
+	query := grantSelect + ` WHERE g.file = $1 ORDER BY u.username, g.access`
+
+	res, err = r.fetch(ctx, query, file)
+	if err != nil {
+		r.log.Error("IN [GetByFile]: could not fetch grants ->", err)
+	}
+
+	return
+}
+
+// Get the grants on a dir and its ancestors
+func (r *postgresFileGrantRepository) GetByDir(ctx context.Context, dir string) (res []domain.FileGrant, err error) {
+	query := ancestorsCte("$1") + grantSelect +
+		` WHERE g.dir IN (SELECT uuid FROM up) ORDER BY u.username, g.access`
+
+	res, err = r.fetch(ctx, query, dir)
+	if err != nil {
+		r.log.Error("IN [GetByDir]: could not fetch grants ->", err)
+	}
+
+	return
+}
+
+// Apply a bulk grant update in one transaction
+func (r *postgresFileGrantRepository) Apply(ctx context.Context, set []domain.FileGrant, unset []domain.FileGrant) (err error) {
+	tx, err := r.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		r.log.Error("IN [Apply]: could not begin transaction ->", err)
+		return
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	for _, g := range set {
+		table, err := grantTable(g.Access)
+		if err != nil {
+			return err
+		}
+
+		target, value := "file", g.File
+		if g.File == "" {
+			target, value = "dir", g.Dir
+		}
+		query := fmt.Sprintf(
+			`INSERT INTO %[1]s (allowed, user_, %[2]s) VALUES ($1, $2, $3)
+			ON CONFLICT (user_, %[2]s) WHERE %[2]s IS NOT NULL
+			DO UPDATE SET allowed = EXCLUDED.allowed`,
+			table,
+			target,
+		)
+		if _, err = tx.ExecContext(ctx, query, g.Allowed, g.User, value); err != nil {
+			r.log.Error("IN [Apply]: could not set grant ->", err)
+			return err
+		}
+	}
+
+	for _, g := range unset {
+		table, err := grantTable(g.Access)
+		if err != nil {
+			return err
+		}
+
+		target, value := "file", g.File
+		if g.File == "" {
+			target, value = "dir", g.Dir
+		}
+		query := fmt.Sprintf(`DELETE FROM %s WHERE user_ = $1 AND %s = $2`, table, target)
+		if _, err = tx.ExecContext(ctx, query, g.User, value); err != nil {
+			r.log.Error("IN [Apply]: could not clear grant ->", err)
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
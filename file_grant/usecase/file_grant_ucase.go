@@ -0,0 +1,166 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+)
+
+type fileGrantUsecase struct {
+	grantRepo      domain.FileGrantRepository
+	fileRepo       domain.FileRepository
+	dirRepo        domain.DirRepository
+	userRepo       domain.UserRepository
+	access         domain.FileAccess
+	contextTimeout time.Duration
+	log            utils.AggregatedLogger
+}
+
+// NewFileGrantUsecase will create a new fileGrantUsecase object representation of domain.FileGrantUsecase interface
+func NewFileGrantUsecase(
+	gr domain.FileGrantRepository,
+	fr domain.FileRepository,
+	dr domain.DirRepository,
+	ur domain.UserRepository,
+	fa domain.FileAccess,
+	timeout time.Duration,
+) domain.FileGrantUsecase {
+	logger := utils.NewAggregatedLogger(constants.Usecase, constants.FileGrant)
+	return &fileGrantUsecase{
+		grantRepo:      gr,
+		fileRepo:       fr,
+		dirRepo:        dr,
+		userRepo:       ur,
+		access:         fa,
+		contextTimeout: timeout,
+		log:            logger,
+	}
+}
+
+func (u *fileGrantUsecase) GetByFile(c context.Context, uuid string, uname string) (res domain.FilePermissions, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	f, err := u.fileRepo.GetByUuid(ctx, uuid)
+	if err != nil {
+		return res, domain.NewRepoErr(err, fmt.Sprint("File not found. uuid: ", uuid))
+	}
+
+	user, ok := domain.UserFromContext(ctx)
+	if !ok {
+		return res, domain.NewUCaseErr(http.StatusUnauthorized, errors.New("Authentication required"))
+	}
+	if uname != "" && uname != user.Username {
+		user, err = u.userRepo.GetByUsername(ctx, uname)
+		if err != nil {
+			return res, domain.NewRepoErr(err, fmt.Sprint("User not found. username: ", uname))
+		}
+		// The access of someone else is resolved without the scopes of the caller
+		var cancelUser context.CancelFunc
+		ctx, cancelUser = context.WithTimeout(context.Background(), u.contextTimeout)
+		defer cancelUser()
+	}
+
+	res = domain.FilePermissions{File: uuid, Access: domain.Access{Username: user.Username}}
+	if res.Grants, err = u.grantRepo.GetByFile(ctx, uuid); err == nil {
+		res.Inherited, err = u.grantRepo.GetByDir(ctx, f.Dir)
+	}
+	if err == nil {
+		res.Access.Read, err = u.access.Allowed(ctx, user, domain.AccessRead, f.Dir, f.Uuid)
+	}
+	if err == nil {
+		res.Access.Write, err = u.access.Allowed(ctx, user, domain.AccessWrite, f.Dir, f.Uuid)
+	}
+	if err != nil {
+		u.log.Error("IN [GetByFile]: could not resolve grants ->", err)
+		return res, domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Permission fetch failed"))
+	}
+
+	return
+}
+
+/*
+* apply resolves the usernames of changes into grants on file or dir and stores
+* them at once, so a bulk update either fully applies or not at all
+ */
+func (u *fileGrantUsecase) apply(ctx context.Context, file string, dir string, changes []domain.GrantChange) (rErr domain.RequestErr) {
+	set := make([]domain.FileGrant, 0, len(changes))
+	unset := make([]domain.FileGrant, 0)
+	users := make(map[string]string)
+	for _, ch := range changes {
+		uuid, ok := users[ch.Username]
+		if !ok {
+			user, err := u.userRepo.GetByUsername(ctx, ch.Username)
+			if errors.Is(err, domain.ErrNotFound) {
+				err = errors.New(fmt.Sprint("User not found. username: ", ch.Username))
+				return domain.NewUCaseErr(http.StatusBadRequest, err)
+			}
+			if err != nil {
+				return domain.NewRepoErr(err, "")
+			}
+			uuid = user.Uuid
+			users[ch.Username] = uuid
+		}
+
+		g := domain.FileGrant{Access: ch.Access, User: uuid, File: file, Dir: dir}
+		if ch.Allowed == nil {
+			unset = append(unset, g)
+			continue
+		}
+		g.Allowed = *ch.Allowed
+		set = append(set, g)
+	}
+
+	if err := u.grantRepo.Apply(ctx, set, unset); err != nil {
+		return domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Permission update failed"))
+	}
+
+	u.log.Info("IN [apply]: set", len(set), "and cleared", len(unset), "grants on {", file+dir, "}")
+	return
+}
+
+func (u *fileGrantUsecase) SetOnFile(c context.Context, uuid string, changes []domain.GrantChange) (rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	if _, err := u.fileRepo.GetByUuid(ctx, uuid); err != nil {
+		return domain.NewRepoErr(err, fmt.Sprint("File not found. uuid: ", uuid))
+	}
+
+	return u.apply(ctx, uuid, "", changes)
+}
+
+func (u *fileGrantUsecase) GetByDir(c context.Context, uuid string) (res []domain.FileGrant, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	if _, err := u.dirRepo.GetByUuid(ctx, uuid); err != nil {
+		rErr = domain.NewRepoErr(err, fmt.Sprint("Directory not found. uuid: ", uuid))
+		return
+	}
+
+	res, err := u.grantRepo.GetByDir(ctx, uuid)
+	if err != nil {
+		rErr = domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Permission fetch failed"))
+		return
+	}
+
+	return
+}
+
+func (u *fileGrantUsecase) SetOnDir(c context.Context, uuid string, changes []domain.GrantChange) (rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	if _, err := u.dirRepo.GetByUuid(ctx, uuid); err != nil {
+		return domain.NewRepoErr(err, fmt.Sprint("Directory not found. uuid: ", uuid))
+	}
+
+	return u.apply(ctx, "", uuid, changes)
+}
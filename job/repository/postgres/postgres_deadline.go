@@ -0,0 +1,107 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+	"github.com/sicozz/papyrus/utils/repository"
+)
+
+/*
+* unnoticedQuery lists the open tasks and plans due before $3 lacking a notice
+* of level $4, keyed past the deadline $8 and uuid $9 of the previous batch
+ */
+const unnoticedQuery = `SELECT kind, uuid, title, due, issuer, assignee
+	FROM (
+		SELECT $6::text AS kind, t.uuid, t.title, t.deadline AS due, t.issuing_user AS issuer,
+			t.assigned_user AS assignee
+		FROM task t
+		JOIN task_state ts ON ts.code = t.state
+		WHERE ts.description = $1
+			AND t.deadline < $3::timestamp
+			AND NOT EXISTS (SELECT 1 FROM deadline_notice n WHERE n.target = t.uuid AND n.level = $4)
+		UNION ALL
+		SELECT $7::text, pl.uuid, pl.title, pl.termination_date, pl.issuing_user_, pl.assigned_user_
+		FROM plan pl
+		JOIN plan_state ps ON ps.code = pl.state
+		WHERE ps.description = $2
+			AND pl.termination_date < $3::timestamp
+			AND NOT EXISTS (SELECT 1 FROM deadline_notice n WHERE n.target = pl.uuid AND n.level = $4)
+	) d
+	WHERE (due, uuid) > ($8::timestamp, $9::uuid)
+	ORDER BY due, uuid
+	LIMIT $5`
+
+// nilUuid sorts before every uuid, it keys the first batch
+const nilUuid = "00000000-0000-0000-0000-000000000000"
+
+type postgresDeadlineRepository struct {
+	Conn *sql.DB
+	log  utils.AggregatedLogger
+}
+
+// NewPostgresDeadlineRepository will create an object that represent the DeadlineRepository interface
+func NewPostgresDeadlineRepository(conn *sql.DB) domain.DeadlineRepository {
+	logger := utils.NewAggregatedLogger(constants.Repository, constants.Job)
+	return &postgresDeadlineRepository{conn, logger}
+}
+
+func (r *postgresDeadlineRepository) GetUnnoticed(ctx context.Context, level string, due time.Time, after domain.Deadline, limit int64) (res []domain.Deadline, err error) {
+	afterUuid := after.Uuid
+	if afterUuid == "" {
+		afterUuid = nilUuid
+	}
+
+	rows, err := r.Conn.QueryContext(
+		ctx,
+		unnoticedQuery,
+		domain.TaskStateOpen,
+		domain.PlanStateOpen,
+		repository.FormatCursorTime(due.UTC()),
+		level,
+		limit,
+		domain.DeadlineTask,
+		domain.DeadlinePlan,
+		repository.FormatCursorTime(after.Deadline.UTC()),
+		afterUuid,
+	)
+	if err != nil {
+		r.log.Error("IN [GetUnnoticed]: could not fetch deadlines ->", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	res = make([]domain.Deadline, 0)
+	for rows.Next() {
+		t := domain.Deadline{}
+		assignee := sql.NullString{}
+		err = rows.Scan(&t.Kind, &t.Uuid, &t.Title, &t.Deadline, &t.Issuer, &assignee)
+		if err != nil {
+			r.log.Error("IN [GetUnnoticed]:", err)
+			return nil, err
+		}
+		t.Assignee = assignee.String
+		res = append(res, t)
+	}
+
+	return res, rows.Err()
+}
+
+func (r *postgresDeadlineRepository) MarkNoticed(ctx context.Context, target string, level string, date time.Time) (err error) {
+	_, err = r.Conn.ExecContext(
+		ctx,
+		`INSERT INTO deadline_notice (target, level, date) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`,
+		target,
+		level,
+		date,
+	)
+	if err != nil {
+		r.log.Error("IN [MarkNoticed]: could not mark notice ->", err)
+	}
+
+	return
+}
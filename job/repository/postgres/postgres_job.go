@@ -0,0 +1,113 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"hash/fnv"
+	"time"
+
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+)
+
+// jobLockSpace is the first key of every job advisory lock, the second one hashes the job name
+const jobLockSpace int32 = 0x6a6f62
+
+// unlockTimeout bounds the release of a lock, which must happen even after the job context is done
+const unlockTimeout = 5 * time.Second
+
+type postgresJobRepository struct {
+	Conn *sql.DB
+	log  utils.AggregatedLogger
+}
+
+// NewPostgresJobRepository will create an object that represent the JobRepository interface
+func NewPostgresJobRepository(conn *sql.DB) domain.JobRepository {
+	logger := utils.NewAggregatedLogger(constants.Repository, constants.Job)
+	return &postgresJobRepository{conn, logger}
+}
+
+func lockKey(job string) int32 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(job))
+	return int32(h.Sum32())
+}
+
+/*
+* TryLock pins a connection: advisory locks belong to the session, so the
+* unlock has to go through the same one. When the unlock fails the connection
+* is discarded, which ends the session and frees the lock anyway
+ */
+func (r *postgresJobRepository) TryLock(ctx context.Context, job string) (release func(), ok bool, err error) {
+	conn, err := r.Conn.Conn(ctx)
+	if err != nil {
+		r.log.Error("IN [TryLock]: could not get a connection ->", err)
+		return nil, false, err
+	}
+
+	key := lockKey(job)
+	err = conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1, $2)`, jobLockSpace, key).Scan(&ok)
+	if err != nil || !ok {
+		if err != nil {
+			r.log.Error("IN [TryLock]: could not try lock ->", err)
+		}
+		conn.Close()
+		return nil, false, err
+	}
+
+	release = func() {
+		uctx, cancel := context.WithTimeout(context.Background(), unlockTimeout)
+		defer cancel()
+		_, err := conn.ExecContext(uctx, `SELECT pg_advisory_unlock($1, $2)`, jobLockSpace, key)
+		if err != nil {
+			r.log.Error("IN [TryLock]: could not unlock", job, "->", err)
+			_ = conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+		}
+		conn.Close()
+	}
+
+	return release, true, nil
+}
+
+func (r *postgresJobRepository) GetLastRun(ctx context.Context, job string) (res domain.JobRun, found bool, err error) {
+	err = r.Conn.QueryRowContext(
+		ctx,
+		`SELECT uuid, job, node, started, finished, status, error
+		FROM job_run
+		WHERE job = $1
+		ORDER BY started DESC
+		LIMIT 1`,
+		job,
+	).Scan(&res.Uuid, &res.Job, &res.Node, &res.Started, &res.Finished, &res.Status, &res.Error)
+	if err == sql.ErrNoRows {
+		return domain.JobRun{}, false, nil
+	}
+	if err != nil {
+		r.log.Error("IN [GetLastRun]: could not get last run ->", err)
+		return domain.JobRun{}, false, err
+	}
+
+	return res, true, nil
+}
+
+func (r *postgresJobRepository) StoreRun(ctx context.Context, run *domain.JobRun) (err error) {
+	err = r.Conn.QueryRowContext(
+		ctx,
+		`INSERT INTO job_run (job, node, started, finished, status, error)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING uuid`,
+		run.Job,
+		run.Node,
+		run.Started,
+		run.Finished,
+		run.Status,
+		run.Error,
+	).Scan(&run.Uuid)
+	if err != nil {
+		r.log.Error("IN [StoreRun]: could not store run ->", err)
+	}
+
+	return
+}
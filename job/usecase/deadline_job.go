@@ -0,0 +1,124 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+)
+
+// DeadlineJobName names the job in the job_run table and its advisory lock
+const DeadlineJobName = "deadlines"
+
+// deadlineBatch bounds the deadlines read at once
+const deadlineBatch = 100
+
+// deadlineEvents maps every kind of deadline and level of notice to its event
+var deadlineEvents = map[string]map[string]string{
+	domain.DeadlineTask: {
+		domain.DeadlineReminder:   domain.EventTaskOverdue,
+		domain.DeadlineEscalation: domain.EventTaskEscalated,
+	},
+	domain.DeadlinePlan: {
+		domain.DeadlineReminder:   domain.EventPlanOverdue,
+		domain.DeadlineEscalation: domain.EventPlanEscalated,
+	},
+}
+
+type deadlineJob struct {
+	deadlineRepo  domain.DeadlineRepository
+	notifier      domain.Notifier
+	clock         domain.Clock
+	escalateAfter time.Duration
+	log           utils.AggregatedLogger
+}
+
+/*
+* NewDeadlineJob will create the job noticing the open tasks and plans past
+* their deadline. The assignee, or the issuer of an unassigned one, gets a
+* reminder, and escalateAfter later the issuer gets an escalation
+ */
+func NewDeadlineJob(dr domain.DeadlineRepository, n domain.Notifier, clk domain.Clock, interval time.Duration, escalateAfter time.Duration) domain.Job {
+	logger := utils.NewAggregatedLogger(constants.Usecase, constants.Job)
+	j := &deadlineJob{
+		deadlineRepo:  dr,
+		notifier:      n,
+		clock:         clk,
+		escalateAfter: escalateAfter,
+		log:           logger,
+	}
+
+	return domain.Job{Name: DeadlineJobName, Interval: interval, Run: j.run}
+}
+
+// run sends the escalations even when some reminders failed, they go to other users
+func (j *deadlineJob) run(ctx context.Context) error {
+	now := j.clock.Now()
+	reminders := j.notice(ctx, domain.DeadlineReminder, now)
+	escalations := j.notice(ctx, domain.DeadlineEscalation, now.Add(-j.escalateAfter))
+
+	return errors.Join(reminders, escalations)
+}
+
+// recipient returns who gets the notice of level about d
+func recipient(d domain.Deadline, level string) string {
+	if level == domain.DeadlineEscalation || d.Assignee == "" {
+		return d.Issuer
+	}
+	return d.Assignee
+}
+
+/*
+* notice sends the notices of level for every deadline before due. A deadline
+* is marked once its recipient got it, so a failed one is retried on the next
+* run. The batches are keyed past the last deadline read, so failing ones do
+* not hide the ones after them
+ */
+func (j *deadlineJob) notice(ctx context.Context, level string, due time.Time) error {
+	var failed error
+	after := domain.Deadline{}
+	for {
+		deadlines, err := j.deadlineRepo.GetUnnoticed(ctx, level, due, after, deadlineBatch)
+		if err != nil {
+			return err
+		}
+
+		for _, d := range deadlines {
+			if err = j.send(ctx, d, level); err != nil {
+				j.log.Error("IN [notice]: could not notify", d.Kind, d.Uuid, "->", err)
+				failed = err
+				continue
+			}
+			if err = j.deadlineRepo.MarkNoticed(ctx, d.Uuid, level, j.clock.Now()); err != nil {
+				return err
+			}
+		}
+
+		if len(deadlines) < deadlineBatch {
+			return failed
+		}
+		after = deadlines[len(deadlines)-1]
+	}
+}
+
+func (j *deadlineJob) send(ctx context.Context, d domain.Deadline, level string) error {
+	subject := fmt.Sprintf("Overdue %s: %s", d.Kind, d.Title)
+	body := fmt.Sprintf("The %s %q was due on %s.", d.Kind, d.Title, d.Deadline.Format(time.RFC1123))
+	if level == domain.DeadlineEscalation {
+		subject = fmt.Sprintf("Still overdue %s: %s", d.Kind, d.Title)
+		body += fmt.Sprintf(" It is still open %s past its deadline.", j.escalateAfter)
+	}
+
+	return j.notifier.Notify(ctx, domain.Notification{
+		User:    recipient(d, level),
+		Event:   deadlineEvents[d.Kind][level],
+		Subject: subject,
+		Body:    body,
+		Ref:     d.Uuid,
+		Date:    j.clock.Now(),
+	})
+}
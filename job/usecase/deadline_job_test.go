@@ -0,0 +1,159 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/job/usecase"
+	"github.com/sicozz/papyrus/utils/clock"
+)
+
+// fakeDeadlineRepo keeps the open deadlines and their notices, read in keyed batches
+type fakeDeadlineRepo struct {
+	deadlines []domain.Deadline
+	noticed   map[string]time.Time
+}
+
+func newFakeDeadlineRepo(deadlines ...domain.Deadline) *fakeDeadlineRepo {
+	sort.Slice(deadlines, func(i, j int) bool {
+		a, b := deadlines[i], deadlines[j]
+		return a.Deadline.Before(b.Deadline) || (a.Deadline.Equal(b.Deadline) && a.Uuid < b.Uuid)
+	})
+	return &fakeDeadlineRepo{deadlines: deadlines, noticed: map[string]time.Time{}}
+}
+
+func (r *fakeDeadlineRepo) GetUnnoticed(ctx context.Context, level string, due time.Time, after domain.Deadline, limit int64) ([]domain.Deadline, error) {
+	res := make([]domain.Deadline, 0)
+	for _, d := range r.deadlines {
+		if int64(len(res)) == limit {
+			break
+		}
+		if _, ok := r.noticed[level+d.Uuid]; ok || !d.Deadline.Before(due) {
+			continue
+		}
+		if d.Deadline.Before(after.Deadline) || (d.Deadline.Equal(after.Deadline) && d.Uuid <= after.Uuid) {
+			continue
+		}
+		res = append(res, d)
+	}
+	return res, nil
+}
+
+func (r *fakeDeadlineRepo) MarkNoticed(ctx context.Context, target string, level string, date time.Time) error {
+	r.noticed[level+target] = date
+	return nil
+}
+
+// fakeNotifier records the notifications and fails the ones of the users in failing
+type fakeNotifier struct {
+	sent    []domain.Notification
+	failing map[string]bool
+}
+
+func (n *fakeNotifier) Notify(ctx context.Context, notif domain.Notification) error {
+	if n.failing[notif.User] {
+		return errors.New("mail server down")
+	}
+	n.sent = append(n.sent, notif)
+	return nil
+}
+
+func newDeadlineJob(dr domain.DeadlineRepository, n domain.Notifier, clk domain.Clock) domain.Job {
+	return usecase.NewDeadlineJob(dr, n, clk, time.Hour, 48*time.Hour)
+}
+
+func TestDeadlineJobRemindsThenEscalates(t *testing.T) {
+	clk := &clock.Fake{T: epoch}
+	dr := newFakeDeadlineRepo(
+		domain.Deadline{Kind: domain.DeadlineTask, Uuid: "t-1", Title: "Audit", Deadline: epoch.Add(-time.Hour), Issuer: "u-boss", Assignee: "u-ana"},
+		domain.Deadline{Kind: domain.DeadlinePlan, Uuid: "p-1", Title: "Fix", Deadline: epoch.Add(-2 * time.Hour), Issuer: "u-boss"},
+		domain.Deadline{Kind: domain.DeadlineTask, Uuid: "t-2", Title: "Later", Deadline: epoch.Add(time.Hour), Issuer: "u-boss", Assignee: "u-ana"},
+	)
+	n := &fakeNotifier{}
+	job := newDeadlineJob(dr, n, clk)
+	ctx := context.Background()
+
+	if err := job.Run(ctx); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	got := make([]string, 0)
+	for _, s := range n.sent {
+		got = append(got, s.User+" "+s.Event+" "+s.Ref)
+	}
+	want := fmt.Sprint([]string{
+		"u-boss " + domain.EventPlanOverdue + " p-1",
+		"u-ana " + domain.EventTaskOverdue + " t-1",
+	})
+	if fmt.Sprint(got) != want {
+		t.Fatalf("reminders: got %v, want %v", got, want)
+	}
+
+	// Nothing is sent twice
+	if err := job.Run(ctx); err != nil || len(n.sent) != 2 {
+		t.Fatalf("second run: sent %d, %v", len(n.sent), err)
+	}
+
+	clk.Advance(47 * time.Hour)
+	n.sent = nil
+	if err := job.Run(ctx); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if len(n.sent) != 2 || n.sent[0].Ref != "t-2" || n.sent[1].Event != domain.EventPlanEscalated {
+		t.Fatalf("before the task escalation: got %+v", n.sent)
+	}
+
+	clk.Advance(time.Hour)
+	n.sent = nil
+	if err := job.Run(ctx); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if len(n.sent) != 1 || n.sent[0].User != "u-boss" || n.sent[0].Event != domain.EventTaskEscalated {
+		t.Fatalf("task escalation: got %+v", n.sent)
+	}
+}
+
+func TestDeadlineJobGoesPastFailingNotices(t *testing.T) {
+	clk := &clock.Fake{T: epoch}
+	deadlines := make([]domain.Deadline, 0)
+	// More failing notices than a batch, all due before the one that works
+	for i := 0; i < 250; i++ {
+		deadlines = append(deadlines, domain.Deadline{
+			Kind:     domain.DeadlineTask,
+			Uuid:     fmt.Sprintf("t-%03d", i),
+			Deadline: epoch.Add(-72 * time.Hour),
+			Issuer:   "u-boss",
+			Assignee: "u-gone",
+		})
+	}
+	deadlines = append(deadlines, domain.Deadline{Kind: domain.DeadlineTask, Uuid: "t-ok", Deadline: epoch.Add(-time.Hour), Issuer: "u-boss", Assignee: "u-ana"})
+	dr := newFakeDeadlineRepo(deadlines...)
+	n := &fakeNotifier{failing: map[string]bool{"u-gone": true}}
+	job := newDeadlineJob(dr, n, clk)
+
+	if err := job.Run(context.Background()); err == nil {
+		t.Fatal("run hid the failed notices")
+	}
+	if _, ok := dr.noticed[domain.DeadlineReminder+"t-ok"]; !ok {
+		t.Fatal("failing notices blocked the reminder after them")
+	}
+	if _, ok := dr.noticed[domain.DeadlineReminder+"t-000"]; ok {
+		t.Fatal("failed reminder marked")
+	}
+	if _, ok := dr.noticed[domain.DeadlineEscalation+"t-249"]; !ok {
+		t.Fatal("failing reminders blocked the escalations to the issuer")
+	}
+
+	// Failed ones are retried on the next run
+	delete(n.failing, "u-gone")
+	if err := job.Run(context.Background()); err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	if _, ok := dr.noticed[domain.DeadlineReminder+"t-249"]; !ok {
+		t.Fatal("failed reminder not retried")
+	}
+}
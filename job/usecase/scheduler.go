@@ -0,0 +1,120 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+)
+
+type scheduler struct {
+	jobRepo domain.JobRepository
+	clock   domain.Clock
+	node    string
+	tick    time.Duration
+	mu      sync.Mutex
+	jobs    []domain.Job
+	log     utils.AggregatedLogger
+}
+
+/*
+* NewScheduler will create a new scheduler object representation of
+* domain.Scheduler interface. Every tick each node competes for the lock of
+* each job, and the winner runs it when its interval elapsed since the last
+* run recorded by any node
+ */
+func NewScheduler(jr domain.JobRepository, clk domain.Clock, node string, tick time.Duration) domain.Scheduler {
+	logger := utils.NewAggregatedLogger(constants.Usecase, constants.Job)
+	return &scheduler{
+		jobRepo: jr,
+		clock:   clk,
+		node:    node,
+		tick:    tick,
+		log:     logger,
+	}
+}
+
+func (s *scheduler) Register(j domain.Job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs = append(s.jobs, j)
+}
+
+func (s *scheduler) Start(ctx context.Context) {
+	t := time.NewTicker(s.tick)
+	defer t.Stop()
+
+	s.log.Info("IN [Start]: scheduler running on node", s.node, "every", s.tick)
+	for {
+		s.RunDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+func (s *scheduler) RunDue(ctx context.Context) {
+	s.mu.Lock()
+	jobs := append([]domain.Job(nil), s.jobs...)
+	s.mu.Unlock()
+
+	for _, j := range jobs {
+		if ctx.Err() != nil {
+			return
+		}
+		s.runIfDue(ctx, j)
+	}
+}
+
+// runIfDue runs j under its lock, unless another node holds it or ran j recently
+func (s *scheduler) runIfDue(ctx context.Context, j domain.Job) {
+	release, ok, err := s.jobRepo.TryLock(ctx, j.Name)
+	if err != nil {
+		s.log.Error("IN [runIfDue]: could not lock job", j.Name, "->", err)
+		return
+	}
+	if !ok {
+		return
+	}
+	defer release()
+
+	last, found, err := s.jobRepo.GetLastRun(ctx, j.Name)
+	if err != nil {
+		s.log.Error("IN [runIfDue]: could not get last run of", j.Name, "->", err)
+		return
+	}
+	if found && s.clock.Now().Sub(last.Started) < j.Interval {
+		return
+	}
+
+	run := domain.JobRun{Job: j.Name, Node: s.node, Started: s.clock.Now(), Status: domain.JobRunOk}
+	if err = s.run(ctx, j); err != nil {
+		s.log.Error("IN [runIfDue]: job", j.Name, "failed ->", err)
+		run.Status = domain.JobRunFailed
+		run.Error = err.Error()
+	}
+	run.Finished = s.clock.Now()
+
+	if err = s.jobRepo.StoreRun(ctx, &run); err != nil {
+		s.log.Error("IN [runIfDue]: could not record run of", j.Name, "->", err)
+	}
+}
+
+// run bounds j by its interval and turns a panic into an error, so one job cannot stop the others
+func (s *scheduler) run(ctx context.Context, j domain.Job) (err error) {
+	jctx, cancel := context.WithTimeout(ctx, j.Interval)
+	defer cancel()
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+
+	return j.Run(jctx)
+}
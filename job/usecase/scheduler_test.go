@@ -0,0 +1,166 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/job/usecase"
+	"github.com/sicozz/papyrus/utils/clock"
+)
+
+var epoch = time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)
+
+// fakeJobRepo is the job_run table and advisory locks shared by every node of a test
+type fakeJobRepo struct {
+	mu     sync.Mutex
+	locked map[string]bool
+	runs   []domain.JobRun
+}
+
+func newFakeJobRepo() *fakeJobRepo {
+	return &fakeJobRepo{locked: map[string]bool{}}
+}
+
+func (r *fakeJobRepo) TryLock(ctx context.Context, job string) (func(), bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.locked[job] {
+		return nil, false, nil
+	}
+	r.locked[job] = true
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.locked, job)
+	}, true, nil
+}
+
+func (r *fakeJobRepo) GetLastRun(ctx context.Context, job string) (res domain.JobRun, found bool, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, run := range r.runs {
+		if run.Job == job && (!found || run.Started.After(res.Started)) {
+			res, found = run, true
+		}
+	}
+	return
+}
+
+func (r *fakeJobRepo) StoreRun(ctx context.Context, run *domain.JobRun) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.runs = append(r.runs, *run)
+	return nil
+}
+
+// counter returns a job run of the given interval counting its runs in n
+func counter(name string, interval time.Duration, n *int) domain.Job {
+	return domain.Job{Name: name, Interval: interval, Run: func(ctx context.Context) error {
+		*n++
+		return nil
+	}}
+}
+
+func TestSchedulerRunsJobsOnceEveryInterval(t *testing.T) {
+	clk := &clock.Fake{T: epoch}
+	jr := newFakeJobRepo()
+	sch := usecase.NewScheduler(jr, clk, "node-a", time.Minute)
+	runs := 0
+	sch.Register(counter("count", time.Hour, &runs))
+	ctx := context.Background()
+
+	sch.RunDue(ctx)
+	if runs != 1 {
+		t.Fatalf("first tick: %d runs, want 1", runs)
+	}
+
+	clk.Advance(59 * time.Minute)
+	sch.RunDue(ctx)
+	if runs != 1 {
+		t.Fatalf("before the interval elapsed: %d runs, want 1", runs)
+	}
+
+	clk.Advance(time.Minute)
+	sch.RunDue(ctx)
+	if runs != 2 {
+		t.Fatalf("once the interval elapsed: %d runs, want 2", runs)
+	}
+
+	last, found, _ := jr.GetLastRun(ctx, "count")
+	if !found || last.Node != "node-a" || last.Status != domain.JobRunOk || !last.Started.Equal(clk.T) {
+		t.Fatalf("last run recorded as %+v", last)
+	}
+}
+
+func TestSchedulerNodesShareTheRuns(t *testing.T) {
+	clk := &clock.Fake{T: epoch}
+	jr := newFakeJobRepo()
+	runsA, runsB := 0, 0
+	a := usecase.NewScheduler(jr, clk, "node-a", time.Minute)
+	a.Register(counter("count", time.Hour, &runsA))
+	b := usecase.NewScheduler(jr, clk, "node-b", time.Minute)
+	b.Register(counter("count", time.Hour, &runsB))
+	ctx := context.Background()
+
+	a.RunDue(ctx)
+	b.RunDue(ctx)
+	if runsA != 1 || runsB != 0 {
+		t.Fatalf("same tick: node-a ran %d, node-b ran %d, want 1 and 0", runsA, runsB)
+	}
+
+	clk.Advance(time.Hour)
+	release, _, _ := jr.TryLock(ctx, "count")
+	a.RunDue(ctx)
+	if runsA != 1 {
+		t.Fatalf("ran while another node held the lock")
+	}
+	release()
+
+	b.RunDue(ctx)
+	a.RunDue(ctx)
+	if runsA != 1 || runsB != 1 {
+		t.Fatalf("after the interval: node-a ran %d, node-b ran %d, want 1 and 1", runsA, runsB)
+	}
+}
+
+func TestSchedulerRecordsFailuresAndPanics(t *testing.T) {
+	clk := &clock.Fake{T: epoch}
+	jr := newFakeJobRepo()
+	sch := usecase.NewScheduler(jr, clk, "node-a", time.Minute)
+	runs := 0
+	sch.Register(domain.Job{Name: "fail", Interval: time.Hour, Run: func(ctx context.Context) error {
+		return errors.New("boom")
+	}})
+	sch.Register(domain.Job{Name: "panic", Interval: time.Hour, Run: func(ctx context.Context) error {
+		panic("boom")
+	}})
+	sch.Register(counter("count", time.Hour, &runs))
+	ctx := context.Background()
+
+	sch.RunDue(ctx)
+	if runs != 1 {
+		t.Fatalf("jobs after failing ones: %d runs, want 1", runs)
+	}
+	for _, name := range []string{"fail", "panic"} {
+		last, found, _ := jr.GetLastRun(ctx, name)
+		if !found || last.Status != domain.JobRunFailed || last.Error == "" {
+			t.Errorf("%s recorded as %+v, want a failed run", name, last)
+		}
+		if release, ok, _ := jr.TryLock(ctx, name); !ok {
+			t.Errorf("%s kept its lock", name)
+		} else {
+			release()
+		}
+	}
+
+	// A failed run counts as a run, it is retried on the next interval
+	clk.Advance(time.Minute)
+	sch.RunDue(ctx)
+	if len(jr.runs) != 3 {
+		t.Fatalf("%d runs recorded before the interval elapsed, want 3", len(jr.runs))
+	}
+}
@@ -0,0 +1,164 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+)
+
+type postgresLoginThrottleRepository struct {
+	Conn *sql.DB
+	log  utils.AggregatedLogger
+}
+
+/*
+* NewPostgresLoginThrottleRepository will create an object that represent the
+* LoginThrottleRepository interface
+ */
+func NewPostgresLoginThrottleRepository(conn *sql.DB) domain.LoginThrottleRepository {
+	logger := utils.NewAggregatedLogger(constants.Repository, constants.Throttle)
+	return &postgresLoginThrottleRepository{conn, logger}
+}
+
+// Get the failure counter of a username or an ip, zero valued when there is none
+func (r *postgresLoginThrottleRepository) GetLock(ctx context.Context, kind string, key string) (res domain.LoginLock, err error) {
+	query :=
+		`SELECT kind, key, failures, last_failure, locked_until
+		FROM login_throttle
+		WHERE kind = $1 AND key = $2`
+
+	err = r.Conn.QueryRowContext(ctx, query, kind, key).Scan(
+		&res.Kind,
+		&res.Key,
+		&res.Failures,
+		&res.LastFailure,
+		&res.LockedUntil,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.LoginLock{Kind: kind, Key: key}, nil
+	}
+	if err != nil {
+		r.log.Error("IN [GetLock]: could not get lock ->", err)
+	}
+
+	return
+}
+
+/*
+* Atomically increment the failure counter of a username or an ip and lock it
+* for locks[n-1] after its n-th failure, or for the last lock past the end of
+* locks. Counters whose last failure happened before window start over
+ */
+func (r *postgresLoginThrottleRepository) RegisterFailure(
+	ctx context.Context,
+	kind string,
+	key string,
+	date time.Time,
+	window time.Time,
+	locks []time.Duration,
+) (res int64, err error) {
+	query :=
+		`INSERT INTO login_throttle (kind, key, failures, last_failure, locked_until)
+		VALUES ($1, $2, 1, $3, $3 + ($5::BIGINT[])[1] * INTERVAL '1 microsecond')
+		ON CONFLICT (kind, key) DO UPDATE SET
+			failures = CASE
+				WHEN login_throttle.last_failure < $4 THEN 1
+				ELSE login_throttle.failures + 1
+			END,
+			last_failure = $3,
+			locked_until = $3 + ($5::BIGINT[])[LEAST(
+				CASE
+					WHEN login_throttle.last_failure < $4 THEN 1
+					ELSE login_throttle.failures + 1
+				END,
+				cardinality($5::BIGINT[])
+			)] * INTERVAL '1 microsecond'
+		RETURNING failures`
+
+	us := make([]int64, len(locks))
+	for i, l := range locks {
+		us[i] = l.Microseconds()
+	}
+
+	err = r.Conn.QueryRowContext(ctx, query, kind, key, date, window, pq.Array(us)).Scan(&res)
+	if err != nil {
+		r.log.Error("IN [RegisterFailure]: could not register failure ->", err)
+	}
+
+	return
+}
+
+// Clear the failure counter of a username or an ip
+func (r *postgresLoginThrottleRepository) Clear(ctx context.Context, kind string, key string) (err error) {
+	query := `DELETE FROM login_throttle WHERE kind=$1 AND key=$2`
+	_, err = r.Conn.ExecContext(ctx, query, kind, key)
+	if err != nil {
+		r.log.Error("IN [Clear]: could not clear lock ->", err)
+	}
+
+	return
+}
+
+// Record a login attempt
+func (r *postgresLoginThrottleRepository) StoreAttempt(ctx context.Context, a *domain.LoginAttempt) (err error) {
+	query :=
+		`INSERT INTO login_attempt (username, ip, success, date)
+		VALUES ($1, $2, $3, $4)
+		RETURNING uuid`
+
+	err = r.Conn.QueryRowContext(ctx, query, a.Username, a.Ip, a.Success, a.Date).Scan(&a.Uuid)
+	if err != nil {
+		r.log.Error("IN [StoreAttempt]: could not store attempt ->", err)
+	}
+
+	return
+}
+
+// Get the latest login attempts on a username
+func (r *postgresLoginThrottleRepository) GetAttempts(ctx context.Context, uname string, limit int64) (res []domain.LoginAttempt, err error) {
+	query :=
+		`SELECT uuid, username, ip, success, date
+		FROM login_attempt
+		WHERE username = $1
+		ORDER BY date DESC
+		LIMIT $2`
+
+	rows, err := r.Conn.QueryContext(ctx, query, uname, limit)
+	if err != nil {
+		r.log.Error(err)
+		return nil, err
+	}
+
+	defer func() {
+		errRow := rows.Close()
+		if errRow != nil {
+			r.log.Error(errRow)
+		}
+	}()
+
+	res = make([]domain.LoginAttempt, 0)
+	for rows.Next() {
+		t := domain.LoginAttempt{}
+		err = rows.Scan(
+			&t.Uuid,
+			&t.Username,
+			&t.Ip,
+			&t.Success,
+			&t.Date,
+		)
+
+		if err != nil {
+			r.log.Error("IN [GetAttempts]:", err)
+			return nil, err
+		}
+		res = append(res, t)
+	}
+
+	return res, rows.Err()
+}
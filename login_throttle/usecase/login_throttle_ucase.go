@@ -0,0 +1,186 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+)
+
+const maxAttemptsLimit = 100
+
+type loginThrottleUsecase struct {
+	throttleRepo   domain.LoginThrottleRepository
+	policy         domain.LoginPolicy
+	clock          domain.Clock
+	contextTimeout time.Duration
+	log            utils.AggregatedLogger
+}
+
+// NewLoginThrottleUsecase will create a new loginThrottleUsecase object representation of domain.LoginThrottleUsecase interface
+func NewLoginThrottleUsecase(
+	tr domain.LoginThrottleRepository,
+	p domain.LoginPolicy,
+	clk domain.Clock,
+	timeout time.Duration,
+) domain.LoginThrottleUsecase {
+	logger := utils.NewAggregatedLogger(constants.Usecase, constants.Throttle)
+	return &loginThrottleUsecase{
+		throttleRepo:   tr,
+		policy:         p,
+		clock:          clk,
+		contextTimeout: timeout,
+		log:            logger,
+	}
+}
+
+/*
+* delay computes how long a key stays locked after its n-th consecutive
+* failure: exponential backoff until max failures, then a full lock
+ */
+func (u *loginThrottleUsecase) delay(n int64, max int64) time.Duration {
+	if n >= max {
+		return u.policy.LockDuration
+	}
+
+	d := time.Duration(float64(u.policy.BackoffBase) * math.Pow(2, float64(n-1)))
+	if d > u.policy.BackoffMax {
+		d = u.policy.BackoffMax
+	}
+
+	return d
+}
+
+// locks lists how long a key stays locked after each of its first max failures
+func (u *loginThrottleUsecase) locks(max int64) []time.Duration {
+	if max < 1 {
+		max = 1
+	}
+
+	res := make([]time.Duration, max)
+	for i := range res {
+		res[i] = u.delay(int64(i+1), max)
+	}
+
+	return res
+}
+
+func (u *loginThrottleUsecase) Check(c context.Context, uname string, ip string) (rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	now := u.clock.Now()
+	for _, k := range [][2]string{{domain.ThrottleByUser, uname}, {domain.ThrottleByIp, ip}} {
+		l, err := u.throttleRepo.GetLock(ctx, k[0], k[1])
+		if err != nil {
+			u.log.Error("IN [Check]: could not get lock ->", err)
+			rErr = domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Login failed"))
+			return
+		}
+
+		if now.Before(l.LockedUntil) {
+			wait := l.LockedUntil.Sub(now).Round(time.Second)
+			err = errors.New(fmt.Sprint("Too many failed login attempts, retry in ", wait))
+			rErr = domain.NewUCaseErr(http.StatusTooManyRequests, err)
+			return
+		}
+	}
+
+	return
+}
+
+func (u *loginThrottleUsecase) record(ctx context.Context, uname string, ip string, success bool, now time.Time) {
+	a := domain.LoginAttempt{
+		Username: uname,
+		Ip:       ip,
+		Success:  success,
+		Date:     now,
+	}
+	if err := u.throttleRepo.StoreAttempt(ctx, &a); err != nil {
+		u.log.Error("IN [record]: could not record login attempt ->", err)
+	}
+}
+
+func (u *loginThrottleUsecase) RecordFailure(c context.Context, uname string, ip string) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	now := u.clock.Now()
+	u.record(ctx, uname, ip, false, now)
+
+	window := now.Add(-u.policy.LockDuration)
+	keys := [][2]string{{domain.ThrottleByUser, uname}, {domain.ThrottleByIp, ip}}
+	maxs := []int64{u.policy.MaxFailures, u.policy.IpMaxFailures}
+	for i, k := range keys {
+		// Counting the failure and locking are one write, so concurrent failures can not undo a lock
+		n, err := u.throttleRepo.RegisterFailure(ctx, k[0], k[1], now, window, u.locks(maxs[i]))
+		if err != nil {
+			u.log.Error("IN [RecordFailure]: could not register failure ->", err)
+			continue
+		}
+
+		if n >= maxs[i] {
+			u.log.Warn("IN [RecordFailure]: locking", k[0], "{", k[1], "} after", n, "failures")
+		}
+	}
+}
+
+func (u *loginThrottleUsecase) RecordSuccess(c context.Context, uname string, ip string) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	u.record(ctx, uname, ip, true, u.clock.Now())
+
+	// The ip counter only decays, or one owned account would reset it between guesses
+	if err := u.throttleRepo.Clear(ctx, domain.ThrottleByUser, uname); err != nil {
+		u.log.Error("IN [RecordSuccess]: could not clear user lock ->", err)
+	}
+}
+
+func (u *loginThrottleUsecase) GetLock(c context.Context, uname string) (res domain.LoginLock, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	res, err := u.throttleRepo.GetLock(ctx, domain.ThrottleByUser, uname)
+	if err != nil {
+		rErr = domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Lock fetch failed"))
+		return
+	}
+
+	return
+}
+
+func (u *loginThrottleUsecase) Unlock(c context.Context, uname string) (rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	if err := u.throttleRepo.Clear(ctx, domain.ThrottleByUser, uname); err != nil {
+		rErr = domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Unlock failed"))
+		return
+	}
+
+	return
+}
+
+func (u *loginThrottleUsecase) FetchAttempts(c context.Context, uname string, limit int64) (res []domain.LoginAttempt, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	if limit < 1 || limit > maxAttemptsLimit {
+		limit = maxAttemptsLimit
+	}
+
+	res, err := u.throttleRepo.GetAttempts(ctx, uname, limit)
+	if err != nil {
+		rErr = domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Login attempts fetch failed"))
+		return
+	}
+
+	return
+}
@@ -0,0 +1,86 @@
+package usecase_test
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/login_throttle/usecase"
+	"github.com/sicozz/papyrus/utils/clock"
+)
+
+// fakeThrottleRepo keeps the failures in memory the way the postgres repository counts them
+type fakeThrottleRepo struct {
+	domain.LoginThrottleRepository
+	locks map[[2]string]domain.LoginLock
+}
+
+func (r *fakeThrottleRepo) GetLock(ctx context.Context, kind string, key string) (domain.LoginLock, error) {
+	return r.locks[[2]string{kind, key}], nil
+}
+
+func (r *fakeThrottleRepo) RegisterFailure(
+	ctx context.Context,
+	kind string,
+	key string,
+	date time.Time,
+	window time.Time,
+	locks []time.Duration,
+) (int64, error) {
+	l := r.locks[[2]string{kind, key}]
+	if l.LastFailure.Before(window) {
+		l.Failures = 0
+	}
+	l.Failures++
+	l.LastFailure = date
+	if l.Failures < int64(len(locks)) {
+		l.LockedUntil = date.Add(locks[l.Failures-1])
+	} else {
+		l.LockedUntil = date.Add(locks[len(locks)-1])
+	}
+	r.locks[[2]string{kind, key}] = l
+
+	return l.Failures, nil
+}
+
+func (r *fakeThrottleRepo) StoreAttempt(ctx context.Context, a *domain.LoginAttempt) error {
+	return nil
+}
+
+func TestRecordFailureLocksWithBackoff(t *testing.T) {
+	clk := &clock.Fake{T: time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)}
+	repo := &fakeThrottleRepo{locks: map[[2]string]domain.LoginLock{}}
+	lu := usecase.NewLoginThrottleUsecase(repo, domain.LoginPolicy{
+		MaxFailures:   3,
+		IpMaxFailures: 10,
+		LockDuration:  time.Hour,
+		BackoffBase:   time.Second,
+		BackoffMax:    time.Minute,
+	}, clk, time.Second)
+	ctx := context.Background()
+
+	// Each failure waits out the lock of the previous one
+	waits := []time.Duration{time.Second, 2 * time.Second, time.Hour}
+	var got []time.Duration
+	for range waits {
+		if rErr := lu.Check(ctx, "ana", "10.0.0.1"); rErr != nil {
+			t.Fatalf("check after %d failures: %v", len(got), rErr)
+		}
+		lu.RecordFailure(ctx, "ana", "10.0.0.1")
+		l := repo.locks[[2]string{domain.ThrottleByUser, "ana"}]
+		got = append(got, l.LockedUntil.Sub(clk.Now()))
+		clk.Advance(l.LockedUntil.Sub(clk.Now()))
+	}
+	if !reflect.DeepEqual(got, waits) {
+		t.Fatalf("locks %v, want %v", got, waits)
+	}
+
+	lu.RecordFailure(ctx, "ana", "10.0.0.1")
+	clk.Advance(time.Minute)
+	if rErr := lu.Check(ctx, "ana", "10.0.0.2"); rErr == nil || rErr.GetStatus() != http.StatusTooManyRequests {
+		t.Fatalf("check of a locked user: got %v, want 429", rErr)
+	}
+}
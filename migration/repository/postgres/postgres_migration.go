@@ -0,0 +1,198 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"time"
+
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+)
+
+// migrationLockKey is the advisory lock serializing migrators, "mig" in ascii
+const migrationLockKey int64 = 0x6d6967
+
+// unlockTimeout bounds the release of the lock, which must happen even after the migration context is done
+const unlockTimeout = 5 * time.Second
+
+type postgresMigrationRepository struct {
+	Conn *sql.DB
+	log  utils.AggregatedLogger
+}
+
+// NewPostgresMigrationRepository will create an object that represent the MigrationRepository interface
+func NewPostgresMigrationRepository(conn *sql.DB) domain.MigrationRepository {
+	logger := utils.NewAggregatedLogger(constants.Repository, constants.Migration)
+	return &postgresMigrationRepository{conn, logger}
+}
+
+/*
+* Lock pins a connection: advisory locks belong to the session, so the unlock
+* has to go through the same one. When the unlock fails the connection is
+* discarded, which ends the session and frees the lock anyway
+ */
+func (r *postgresMigrationRepository) Lock(ctx context.Context) (release func(), err error) {
+	conn, err := r.Conn.Conn(ctx)
+	if err != nil {
+		r.log.Error("IN [Lock]: could not get a connection ->", err)
+		return nil, err
+	}
+
+	if _, err = conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockKey); err != nil {
+		r.log.Error("IN [Lock]: could not lock ->", err)
+		conn.Close()
+		return nil, err
+	}
+
+	release = func() {
+		uctx, cancel := context.WithTimeout(context.Background(), unlockTimeout)
+		defer cancel()
+		_, err := conn.ExecContext(uctx, `SELECT pg_advisory_unlock($1)`, migrationLockKey)
+		if err != nil {
+			r.log.Error("IN [Lock]: could not unlock ->", err)
+			_ = conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+		}
+		conn.Close()
+	}
+
+	return release, nil
+}
+
+func (r *postgresMigrationRepository) Init(ctx context.Context) (err error) {
+	_, err = r.Conn.ExecContext(
+		ctx,
+		`CREATE TABLE IF NOT EXISTS schema_migration (
+			version  BIGINT        PRIMARY KEY,
+			name     VARCHAR(255)  NOT NULL,
+			date     TIMESTAMP     NOT NULL
+		)`,
+	)
+	if err != nil {
+		r.log.Error("IN [Init]: could not create the tracking table ->", err)
+	}
+
+	return
+}
+
+func (r *postgresMigrationRepository) LegacyVersion(ctx context.Context) (version int64, dirty bool, err error) {
+	var exists bool
+	err = r.Conn.QueryRowContext(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists)
+	if err != nil {
+		r.log.Error("IN [LegacyVersion]: could not look for the legacy table ->", err)
+		return 0, false, err
+	}
+	if !exists {
+		return 0, false, domain.ErrNotFound
+	}
+
+	err = r.Conn.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if err == sql.ErrNoRows {
+		return 0, false, domain.ErrNotFound
+	}
+	if err != nil {
+		r.log.Error("IN [LegacyVersion]: could not read the legacy version ->", err)
+	}
+
+	return
+}
+
+func (r *postgresMigrationRepository) HasSchema(ctx context.Context) (res bool, err error) {
+	err = r.Conn.QueryRowContext(ctx, `SELECT to_regclass('user_') IS NOT NULL`).Scan(&res)
+	if err != nil {
+		r.log.Error("IN [HasSchema]: could not look for the schema ->", err)
+	}
+
+	return
+}
+
+func (r *postgresMigrationRepository) GetApplied(ctx context.Context) (res []domain.AppliedMigration, err error) {
+	rows, err := r.Conn.QueryContext(ctx, `SELECT version, name, date FROM schema_migration ORDER BY version`)
+	if err != nil {
+		r.log.Error("IN [GetApplied]: could not fetch migrations ->", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	res = make([]domain.AppliedMigration, 0)
+	for rows.Next() {
+		m := domain.AppliedMigration{}
+		if err = rows.Scan(&m.Version, &m.Name, &m.Date); err != nil {
+			r.log.Error("IN [GetApplied]:", err)
+			return nil, err
+		}
+		res = append(res, m)
+	}
+
+	return res, rows.Err()
+}
+
+// Scripts run in the transaction recording them, so a failed one leaves no trace
+func (r *postgresMigrationRepository) Apply(ctx context.Context, m domain.Migration, date time.Time) (err error) {
+	tx, err := r.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		r.log.Error("IN [Apply]: could not begin transaction ->", err)
+		return
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if _, err = tx.ExecContext(ctx, m.Up); err != nil {
+		r.log.Error("IN [Apply]: migration", m.Version, m.Name, "failed ->", err)
+		return
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`INSERT INTO schema_migration (version, name, date) VALUES ($1, $2, $3)`,
+		m.Version, m.Name, date.UTC(),
+	)
+	if err != nil {
+		r.log.Error("IN [Apply]: could not record migration ->", err)
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.log.Error("IN [Apply]: could not commit ->", err)
+	}
+
+	return
+}
+
+func (r *postgresMigrationRepository) Revert(ctx context.Context, m domain.Migration) (err error) {
+	tx, err := r.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		r.log.Error("IN [Revert]: could not begin transaction ->", err)
+		return
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if _, err = tx.ExecContext(ctx, m.Down); err != nil {
+		r.log.Error("IN [Revert]: migration", m.Version, m.Name, "failed ->", err)
+		return
+	}
+
+	if _, err = tx.ExecContext(ctx, `DELETE FROM schema_migration WHERE version = $1`, m.Version); err != nil {
+		r.log.Error("IN [Revert]: could not forget migration ->", err)
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.log.Error("IN [Revert]: could not commit ->", err)
+	}
+
+	return
+}
+
+func (r *postgresMigrationRepository) Record(ctx context.Context, m domain.Migration, date time.Time) (err error) {
+	_, err = r.Conn.ExecContext(
+		ctx,
+		`INSERT INTO schema_migration (version, name, date) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`,
+		m.Version, m.Name, date.UTC(),
+	)
+	if err != nil {
+		r.log.Error("IN [Record]: could not record migration ->", err)
+	}
+
+	return
+}
@@ -0,0 +1,278 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+)
+
+// migrationFile matches <version>_<name>.<up|down>.sql
+var migrationFile = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+/*
+* LoadMigrations reads the migrations of fsys, sorted by version. Every
+* version needs an up script, a missing down script makes it irreversible
+ */
+func LoadMigrations(fsys fs.FS) ([]domain.Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*domain.Migration)
+	for _, e := range entries {
+		parts := migrationFile.FindStringSubmatch(e.Name())
+		if e.IsDir() || parts == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %w", e.Name(), err)
+		}
+		script, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &domain.Migration{Version: version, Name: parts[2]}
+			byVersion[version] = m
+		}
+		if m.Name != parts[2] {
+			return nil, fmt.Errorf("migration %d is named both %s and %s", version, m.Name, parts[2])
+		}
+		if parts[3] == "up" {
+			m.Up = string(script)
+		} else {
+			m.Down = string(script)
+		}
+	}
+
+	res := make([]domain.Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up script", m.Version, m.Name)
+		}
+		res = append(res, *m)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Version < res[j].Version })
+
+	return res, nil
+}
+
+type migrator struct {
+	migrationRepo domain.MigrationRepository
+	migrations    []domain.Migration
+	clock         domain.Clock
+	log           utils.AggregatedLogger
+}
+
+// NewMigrator will create a new migrator object representation of domain.Migrator interface
+func NewMigrator(mr domain.MigrationRepository, ms []domain.Migration, clk domain.Clock) domain.Migrator {
+	logger := utils.NewAggregatedLogger(constants.Usecase, constants.Migration)
+	return &migrator{
+		migrationRepo: mr,
+		migrations:    ms,
+		clock:         clk,
+		log:           logger,
+	}
+}
+
+func (u *migrator) known(version int64) (domain.Migration, bool) {
+	i := sort.Search(len(u.migrations), func(i int) bool { return u.migrations[i].Version >= version })
+	if i < len(u.migrations) && u.migrations[i].Version == version {
+		return u.migrations[i], true
+	}
+	return domain.Migration{}, false
+}
+
+/*
+* baseline adopts a database nobody migrated through this tracker yet. The
+* versions the migrate cli applied are recorded as they are, and a schema
+* created by the old init.sql alone counts as the first migration
+ */
+func (u *migrator) baseline(ctx context.Context) (err error) {
+	legacy, dirty, err := u.migrationRepo.LegacyVersion(ctx)
+	if errors.Is(err, domain.ErrNotFound) {
+		var has bool
+		if has, err = u.migrationRepo.HasSchema(ctx); err != nil || !has || len(u.migrations) == 0 {
+			return
+		}
+		legacy = u.migrations[0].Version
+	} else if err != nil {
+		return
+	} else if dirty {
+		return fmt.Errorf("the migrate cli left version %d dirty, fix it before migrating", legacy)
+	}
+
+	now := u.clock.Now()
+	for _, m := range u.migrations {
+		if m.Version > legacy {
+			break
+		}
+		if err = u.migrationRepo.Record(ctx, m, now); err != nil {
+			return
+		}
+		u.log.Info("IN [baseline]: adopted migration", m.Version, m.Name)
+	}
+
+	return
+}
+
+// track brings the tracker up and returns the applied migrations
+func (u *migrator) track(ctx context.Context) (res []domain.AppliedMigration, err error) {
+	if err = u.migrationRepo.Init(ctx); err != nil {
+		return
+	}
+
+	res, err = u.migrationRepo.GetApplied(ctx)
+	if err != nil || len(res) > 0 {
+		return
+	}
+
+	if err = u.baseline(ctx); err != nil {
+		return
+	}
+	return u.migrationRepo.GetApplied(ctx)
+}
+
+/*
+* prepare returns the applied migrations, failing with ErrUnknownMigration
+* when one of them is newer than every migration of this build, which means
+* an older binary is running against a newer schema
+ */
+func (u *migrator) prepare(ctx context.Context) (res []domain.AppliedMigration, err error) {
+	res, err = u.track(ctx)
+	if err != nil {
+		return
+	}
+
+	var latest int64
+	if len(u.migrations) > 0 {
+		latest = u.migrations[len(u.migrations)-1].Version
+	}
+	for _, a := range res {
+		if a.Version > latest {
+			return nil, fmt.Errorf("%w: %d_%s", domain.ErrUnknownMigration, a.Version, a.Name)
+		}
+		if _, ok := u.known(a.Version); !ok {
+			u.log.Warn("IN [prepare]: applied migration", a.Version, a.Name, "is unknown to this build")
+		}
+	}
+
+	return
+}
+
+func (u *migrator) Up(ctx context.Context, n int) (res []domain.Migration, err error) {
+	release, err := u.migrationRepo.Lock(ctx)
+	if err != nil {
+		return
+	}
+	defer release()
+
+	applied, err := u.prepare(ctx)
+	if err != nil {
+		return
+	}
+	done := make(map[int64]bool, len(applied))
+	for _, a := range applied {
+		done[a.Version] = true
+	}
+
+	res = make([]domain.Migration, 0)
+	for _, m := range u.migrations {
+		if done[m.Version] {
+			continue
+		}
+		if n > 0 && len(res) == n {
+			break
+		}
+		if err = u.migrationRepo.Apply(ctx, m, u.clock.Now()); err != nil {
+			return res, fmt.Errorf("migration %d_%s: %w", m.Version, m.Name, err)
+		}
+		u.log.Info("IN [Up]: applied migration", m.Version, m.Name)
+		res = append(res, m)
+	}
+
+	return
+}
+
+func (u *migrator) Down(ctx context.Context, n int) (res []domain.Migration, err error) {
+	if n <= 0 {
+		return nil, domain.ErrBadParamInput
+	}
+
+	release, err := u.migrationRepo.Lock(ctx)
+	if err != nil {
+		return
+	}
+	defer release()
+
+	applied, err := u.prepare(ctx)
+	if err != nil {
+		return
+	}
+
+	res = make([]domain.Migration, 0)
+	for i := len(applied) - 1; i >= 0 && len(res) < n; i-- {
+		m, ok := u.known(applied[i].Version)
+		if !ok {
+			return res, fmt.Errorf("%w: %d_%s", domain.ErrUnknownMigration, applied[i].Version, applied[i].Name)
+		}
+		if m.Down == "" {
+			return res, fmt.Errorf("migration %d_%s can not be reverted", m.Version, m.Name)
+		}
+		if err = u.migrationRepo.Revert(ctx, m); err != nil {
+			return res, fmt.Errorf("migration %d_%s: %w", m.Version, m.Name, err)
+		}
+		u.log.Info("IN [Down]: reverted migration", m.Version, m.Name)
+		res = append(res, m)
+	}
+
+	return
+}
+
+// Status lists every known and every applied migration, in version order
+func (u *migrator) Status(ctx context.Context) (res []domain.MigrationStatus, err error) {
+	release, err := u.migrationRepo.Lock(ctx)
+	if err != nil {
+		return
+	}
+	defer release()
+
+	applied, err := u.track(ctx)
+	if err != nil {
+		return
+	}
+
+	byVersion := make(map[int64]*domain.MigrationStatus)
+	for _, m := range u.migrations {
+		byVersion[m.Version] = &domain.MigrationStatus{Version: m.Version, Name: m.Name, Known: true}
+	}
+	for _, a := range applied {
+		s, ok := byVersion[a.Version]
+		if !ok {
+			s = &domain.MigrationStatus{Version: a.Version, Name: a.Name}
+			byVersion[a.Version] = s
+		}
+		s.Applied, s.Date = true, a.Date
+	}
+
+	res = make([]domain.MigrationStatus, 0, len(byVersion))
+	for _, s := range byVersion {
+		res = append(res, *s)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Version < res[j].Version })
+
+	return res, nil
+}
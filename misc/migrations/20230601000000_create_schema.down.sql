@@ -0,0 +1,19 @@
+DROP TABLE IF EXISTS task;
+DROP TABLE IF EXISTS task_state;
+DROP TABLE IF EXISTS plan;
+DROP TABLE IF EXISTS plan_state;
+DROP TABLE IF EXISTS project;
+DROP TABLE IF EXISTS project_state;
+DROP TABLE IF EXISTS write_permission;
+DROP TABLE IF EXISTS read_permission;
+DROP TABLE IF EXISTS upload;
+DROP TABLE IF EXISTS download;
+DROP TABLE IF EXISTS version;
+DROP TABLE IF EXISTS file;
+DROP TABLE IF EXISTS file_stage;
+DROP TABLE IF EXISTS file_state;
+DROP TABLE IF EXISTS file_type;
+DROP TABLE IF EXISTS user_;
+DROP TABLE IF EXISTS user_state;
+DROP TABLE IF EXISTS role;
+DROP TABLE IF EXISTS dir;
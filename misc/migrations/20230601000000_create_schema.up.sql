@@ -1,7 +1,3 @@
-CREATE DATABASE papyrus;
-
-\connect papyrus;
-
 CREATE TABLE dir (
     uuid        UUID          PRIMARY KEY DEFAULT gen_random_uuid(),
     name        VARCHAR(256)  NOT NULL,
@@ -0,0 +1,10 @@
+-- Irreversible, so it has no down script: hashed passwords can not be turned
+-- back into plaintext and do not fit in the former VARCHAR(32) column.
+CREATE EXTENSION IF NOT EXISTS pgcrypto;
+
+ALTER TABLE user_ ALTER COLUMN password TYPE VARCHAR(255);
+
+-- Rehash every plaintext password (bcrypt hashes start with $2a$, $2b$ or $2y$)
+UPDATE user_
+SET password = crypt(password, gen_salt('bf', 10))
+WHERE password !~ '^\$2[aby]\$';
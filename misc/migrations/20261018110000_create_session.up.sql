@@ -0,0 +1,8 @@
+CREATE TABLE session (
+    uuid        UUID         PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_       UUID         REFERENCES user_ ON DELETE CASCADE NOT NULL,
+    token_hash  CHAR(64)     UNIQUE NOT NULL,
+    created_at  TIMESTAMP    NOT NULL DEFAULT now(),
+    expires_at  TIMESTAMP    NOT NULL,
+    revoked     BOOLEAN      NOT NULL DEFAULT false
+);
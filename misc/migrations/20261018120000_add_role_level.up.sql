@@ -0,0 +1,5 @@
+ALTER TABLE role ADD COLUMN level INT NOT NULL DEFAULT 0;
+
+UPDATE role SET level = 0 WHERE description = 'estandar';
+UPDATE role SET level = 10 WHERE description = 'admin';
+UPDATE role SET level = 20 WHERE description = 'super';
@@ -0,0 +1,28 @@
+CREATE TABLE permission (
+    code         SERIAL        PRIMARY KEY,
+    name         VARCHAR(64)   UNIQUE NOT NULL,
+    description  VARCHAR(256)  NOT NULL
+);
+
+CREATE TABLE role_permission (
+    role        INT  REFERENCES role ON DELETE CASCADE NOT NULL,
+    permission  INT  REFERENCES permission ON DELETE CASCADE NOT NULL,
+    PRIMARY KEY (role, permission)
+);
+
+INSERT INTO permission (name, description)
+VALUES
+    ('user.read', 'List and read users'),
+    ('user.write', 'Create, update and delete users'),
+    ('role.manage', 'Manage roles and their permissions'),
+    ('file.read', 'Read and download files'),
+    ('file.write', 'Upload and edit files'),
+    ('file.approve', 'Review and approve files'),
+    ('project.manage', 'Create and manage projects');
+
+INSERT INTO role_permission (role, permission)
+SELECT r.code, p.code
+FROM role r, permission p
+WHERE (r.description = 'estandar' AND p.name IN ('file.read', 'file.write'))
+    OR (r.description = 'admin' AND p.name <> 'role.manage')
+    OR r.description = 'super';
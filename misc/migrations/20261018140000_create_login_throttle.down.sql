@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS login_throttle;
+
+DROP TABLE IF EXISTS login_attempt;
@@ -0,0 +1,19 @@
+-- Attempted usernames are unbounded user input, recording them must not fail
+CREATE TABLE login_attempt (
+    uuid      UUID         PRIMARY KEY DEFAULT gen_random_uuid(),
+    username  TEXT         NOT NULL,
+    ip        VARCHAR(45)  NOT NULL,
+    success   BOOLEAN      NOT NULL,
+    date      TIMESTAMP    NOT NULL
+);
+
+CREATE INDEX login_attempt_username_idx ON login_attempt (username, date DESC);
+
+CREATE TABLE login_throttle (
+    kind          VARCHAR(8)   NOT NULL,
+    key           TEXT         NOT NULL,
+    failures      INT          NOT NULL DEFAULT 0,
+    last_failure  TIMESTAMP    NOT NULL,
+    locked_until  TIMESTAMP    NOT NULL,
+    PRIMARY KEY (kind, key)
+);
@@ -0,0 +1,9 @@
+CREATE TABLE user_token (
+    uuid        UUID         PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_       UUID         REFERENCES user_ ON DELETE CASCADE NOT NULL,
+    purpose     VARCHAR(16)  NOT NULL,
+    token_hash  CHAR(64)     UNIQUE NOT NULL,
+    created_at  TIMESTAMP    NOT NULL,
+    expires_at  TIMESTAMP    NOT NULL,
+    used_at     TIMESTAMP
+);
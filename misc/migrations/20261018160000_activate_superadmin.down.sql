@@ -0,0 +1,3 @@
+UPDATE user_
+SET state = (SELECT code FROM user_state WHERE description = 'inactivo')
+WHERE username = 'pps_admin';
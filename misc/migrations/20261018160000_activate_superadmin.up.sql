@@ -0,0 +1,4 @@
+-- The seeded super admin has no mailbox to verify, activate it right away
+UPDATE user_
+SET state = (SELECT code FROM user_state WHERE description = 'activo')
+WHERE username = 'pps_admin';
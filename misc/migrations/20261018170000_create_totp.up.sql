@@ -0,0 +1,15 @@
+CREATE TABLE user_totp (
+    user_       UUID         PRIMARY KEY REFERENCES user_ ON DELETE CASCADE,
+    secret      VARCHAR(64)  NOT NULL,
+    confirmed   BOOLEAN      NOT NULL DEFAULT false,
+    last_step   BIGINT       NOT NULL DEFAULT 0,
+    created_at  TIMESTAMP    NOT NULL
+);
+
+CREATE TABLE recovery_code (
+    uuid       UUID       PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_      UUID       REFERENCES user_ ON DELETE CASCADE NOT NULL,
+    code_hash  CHAR(64)   NOT NULL,
+    used_at    TIMESTAMP,
+    UNIQUE (user_, code_hash)
+);
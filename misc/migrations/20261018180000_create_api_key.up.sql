@@ -0,0 +1,14 @@
+CREATE TABLE api_key (
+    uuid          UUID         PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_         UUID         REFERENCES user_ ON DELETE CASCADE NOT NULL,
+    name          VARCHAR(64)  NOT NULL,
+    prefix        VARCHAR(16)  NOT NULL,
+    key_hash      CHAR(64)     UNIQUE NOT NULL,
+    scopes        TEXT[]       NOT NULL DEFAULT '{}',
+    expires_at    TIMESTAMP,
+    last_used_at  TIMESTAMP,
+    created_at    TIMESTAMP    NOT NULL DEFAULT now(),
+    revoked       BOOLEAN      NOT NULL DEFAULT false
+);
+
+CREATE INDEX api_key_user_idx ON api_key (user_);
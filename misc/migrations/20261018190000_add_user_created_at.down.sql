@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS user_created_at_idx;
+
+ALTER TABLE user_ DROP COLUMN IF EXISTS created_at;
@@ -0,0 +1,3 @@
+ALTER TABLE user_ ADD COLUMN created_at TIMESTAMP NOT NULL DEFAULT now();
+
+CREATE INDEX user_created_at_idx ON user_ (created_at, uuid);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS dir_parent_idx;
+
+DROP INDEX IF EXISTS dir_sibling_name_idx;
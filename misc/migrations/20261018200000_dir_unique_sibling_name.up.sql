@@ -0,0 +1,5 @@
+-- Roots share the nil uuid so their names are unique too
+CREATE UNIQUE INDEX dir_sibling_name_idx
+    ON dir (COALESCE(parent_dir, '00000000-0000-0000-0000-000000000000'::uuid), name);
+
+CREATE INDEX dir_parent_idx ON dir (parent_dir);
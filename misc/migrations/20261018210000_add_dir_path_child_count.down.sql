@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS dir_path_idx;
+
+ALTER TABLE dir DROP COLUMN IF EXISTS child_count;
+ALTER TABLE dir DROP COLUMN IF EXISTS path;
@@ -0,0 +1,16 @@
+ALTER TABLE dir ADD COLUMN path TEXT;
+ALTER TABLE dir ADD COLUMN child_count INTEGER NOT NULL DEFAULT 0;
+
+WITH RECURSIVE t AS (
+    SELECT uuid, '/' || name AS path FROM dir WHERE parent_dir IS NULL
+    UNION ALL
+    SELECT d.uuid, t.path || '/' || d.name FROM dir d JOIN t ON d.parent_dir = t.uuid
+)
+UPDATE dir SET path = t.path FROM t WHERE dir.uuid = t.uuid;
+
+UPDATE dir p SET child_count = (SELECT COUNT(*) FROM dir c WHERE c.parent_dir = p.uuid);
+
+ALTER TABLE dir ALTER COLUMN path SET NOT NULL;
+
+-- Serves the prefix lookups of whole subtrees: path LIKE '/Quality/%'
+CREATE INDEX dir_path_idx ON dir (path text_pattern_ops);
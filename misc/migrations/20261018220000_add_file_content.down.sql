@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS upload_session;
+
+DROP INDEX IF EXISTS file_dir_idx;
+
+ALTER TABLE file DROP COLUMN IF EXISTS sha256;
+ALTER TABLE file DROP COLUMN IF EXISTS mime_type;
+ALTER TABLE file DROP COLUMN IF EXISTS size;
+ALTER TABLE file DROP COLUMN IF EXISTS name;
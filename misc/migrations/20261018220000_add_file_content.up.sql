@@ -0,0 +1,21 @@
+ALTER TABLE file ADD COLUMN name VARCHAR(256) NOT NULL DEFAULT '';
+ALTER TABLE file ADD COLUMN size BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE file ADD COLUMN mime_type VARCHAR(255) NOT NULL DEFAULT 'application/octet-stream';
+ALTER TABLE file ADD COLUMN sha256 CHAR(64);
+
+CREATE INDEX file_dir_idx ON file (dir);
+
+CREATE TABLE upload_session (
+    uuid           UUID          PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_          UUID          REFERENCES user_ ON DELETE CASCADE NOT NULL,
+    code           VARCHAR(32)   NOT NULL,
+    name           VARCHAR(256)  NOT NULL,
+    type           VARCHAR(32)   NOT NULL,
+    dir            UUID          REFERENCES dir ON DELETE CASCADE NOT NULL,
+    revision_user  VARCHAR(32)   NOT NULL,
+    approval_user  VARCHAR(32)   NOT NULL,
+    mime_type      VARCHAR(255)  NOT NULL,
+    size           BIGINT        NOT NULL,
+    offset_        BIGINT        NOT NULL DEFAULT 0,
+    created_at     TIMESTAMP     NOT NULL DEFAULT now()
+);
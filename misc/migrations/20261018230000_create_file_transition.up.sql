@@ -0,0 +1,12 @@
+CREATE TABLE file_transition (
+    uuid        UUID           PRIMARY KEY DEFAULT gen_random_uuid(),
+    file        UUID           REFERENCES file ON DELETE CASCADE NOT NULL,
+    action      VARCHAR(16)    NOT NULL,
+    from_stage  INTEGER        REFERENCES file_stage NOT NULL,
+    to_stage    INTEGER        REFERENCES file_stage NOT NULL,
+    user_       UUID           REFERENCES user_ NOT NULL,
+    comment     VARCHAR(1024)  NOT NULL DEFAULT '',
+    date        TIMESTAMP      NOT NULL
+);
+
+CREATE INDEX file_transition_file_idx ON file_transition (file, date);
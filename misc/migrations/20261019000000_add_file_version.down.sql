@@ -0,0 +1,15 @@
+ALTER TABLE file_transition DROP COLUMN IF EXISTS version;
+
+ALTER TABLE file DROP COLUMN IF EXISTS approved_version;
+ALTER TABLE file DROP COLUMN IF EXISTS version;
+
+ALTER TABLE version DROP CONSTRAINT IF EXISTS version_file_number_key;
+ALTER TABLE version DROP COLUMN IF EXISTS restored_from;
+ALTER TABLE version DROP COLUMN IF EXISTS note;
+ALTER TABLE version DROP COLUMN IF EXISTS user_;
+ALTER TABLE version DROP COLUMN IF EXISTS sha256;
+ALTER TABLE version DROP COLUMN IF EXISTS mime_type;
+ALTER TABLE version DROP COLUMN IF EXISTS size;
+ALTER TABLE version DROP COLUMN IF EXISTS path;
+ALTER TABLE version DROP COLUMN IF EXISTS name;
+ALTER TABLE version DROP COLUMN IF EXISTS number;
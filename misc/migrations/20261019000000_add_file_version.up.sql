@@ -0,0 +1,24 @@
+ALTER TABLE version ADD COLUMN number INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE version ADD COLUMN name VARCHAR(256) NOT NULL DEFAULT '';
+ALTER TABLE version ADD COLUMN path VARCHAR(256) NOT NULL DEFAULT '';
+ALTER TABLE version ADD COLUMN size BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE version ADD COLUMN mime_type VARCHAR(255) NOT NULL DEFAULT 'application/octet-stream';
+ALTER TABLE version ADD COLUMN sha256 CHAR(64);
+ALTER TABLE version ADD COLUMN user_ UUID REFERENCES user_;
+ALTER TABLE version ADD COLUMN note VARCHAR(1024) NOT NULL DEFAULT '';
+ALTER TABLE version ADD COLUMN restored_from INTEGER;
+ALTER TABLE version ADD CONSTRAINT version_file_number_key UNIQUE (file, number);
+
+-- file mirrors its head version, approved_version is the number last approved
+ALTER TABLE file ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE file ADD COLUMN approved_version INTEGER;
+
+ALTER TABLE file_transition ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+
+INSERT INTO version (date, file, number, name, path, size, mime_type, sha256)
+SELECT f.input_date, f.uuid, 1, f.name, f.path, f.size, f.mime_type, f.sha256
+FROM file f
+WHERE NOT EXISTS (SELECT 1 FROM version v WHERE v.file = f.uuid);
+
+UPDATE file SET approved_version = 1
+WHERE stage = (SELECT code FROM file_stage WHERE description = 'aprobado');
@@ -0,0 +1,16 @@
+DELETE FROM permission WHERE name = 'file.grant';
+
+DROP INDEX IF EXISTS write_permission_dir_key;
+DROP INDEX IF EXISTS write_permission_file_key;
+DROP INDEX IF EXISTS read_permission_dir_key;
+DROP INDEX IF EXISTS read_permission_file_key;
+
+DELETE FROM write_permission WHERE file IS NULL;
+ALTER TABLE write_permission DROP CONSTRAINT IF EXISTS write_permission_target_check;
+ALTER TABLE write_permission DROP COLUMN IF EXISTS dir;
+ALTER TABLE write_permission ALTER COLUMN file SET NOT NULL;
+
+DELETE FROM read_permission WHERE file IS NULL;
+ALTER TABLE read_permission DROP CONSTRAINT IF EXISTS read_permission_target_check;
+ALTER TABLE read_permission DROP COLUMN IF EXISTS dir;
+ALTER TABLE read_permission ALTER COLUMN file SET NOT NULL;
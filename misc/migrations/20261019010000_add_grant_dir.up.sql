@@ -0,0 +1,30 @@
+ALTER TABLE read_permission ALTER COLUMN file DROP NOT NULL;
+ALTER TABLE read_permission ADD COLUMN dir UUID REFERENCES dir ON DELETE CASCADE;
+ALTER TABLE read_permission ADD CONSTRAINT read_permission_target_check
+    CHECK ((file IS NULL) <> (dir IS NULL));
+
+ALTER TABLE write_permission ALTER COLUMN file DROP NOT NULL;
+ALTER TABLE write_permission ADD COLUMN dir UUID REFERENCES dir ON DELETE CASCADE;
+ALTER TABLE write_permission ADD CONSTRAINT write_permission_target_check
+    CHECK ((file IS NULL) <> (dir IS NULL));
+
+-- One grant per user and target, denies survive the cleanup
+DELETE FROM read_permission a USING read_permission b
+WHERE a.user_ = b.user_ AND a.file = b.file AND a.uuid <> b.uuid
+    AND (a.allowed AND NOT b.allowed OR (a.allowed = b.allowed AND a.uuid > b.uuid));
+DELETE FROM write_permission a USING write_permission b
+WHERE a.user_ = b.user_ AND a.file = b.file AND a.uuid <> b.uuid
+    AND (a.allowed AND NOT b.allowed OR (a.allowed = b.allowed AND a.uuid > b.uuid));
+
+CREATE UNIQUE INDEX read_permission_file_key ON read_permission (user_, file) WHERE file IS NOT NULL;
+CREATE UNIQUE INDEX read_permission_dir_key ON read_permission (user_, dir) WHERE dir IS NOT NULL;
+CREATE UNIQUE INDEX write_permission_file_key ON write_permission (user_, file) WHERE file IS NOT NULL;
+CREATE UNIQUE INDEX write_permission_dir_key ON write_permission (user_, dir) WHERE dir IS NOT NULL;
+
+INSERT INTO permission (name, description)
+VALUES ('file.grant', 'Manage the access grants of files and directories');
+
+INSERT INTO role_permission (role, permission)
+SELECT r.code, p.code
+FROM role r, permission p
+WHERE r.description IN ('admin', 'super') AND p.name = 'file.grant';
@@ -0,0 +1,13 @@
+DELETE FROM permission WHERE name = 'audit.read';
+
+DROP INDEX IF EXISTS upload_file_idx;
+DROP INDEX IF EXISTS upload_user_idx;
+DROP INDEX IF EXISTS upload_date_idx;
+DROP INDEX IF EXISTS download_file_idx;
+DROP INDEX IF EXISTS download_user_idx;
+DROP INDEX IF EXISTS download_date_idx;
+
+ALTER TABLE upload DROP COLUMN IF EXISTS bytes;
+ALTER TABLE upload DROP COLUMN IF EXISTS version;
+ALTER TABLE download DROP COLUMN IF EXISTS bytes;
+ALTER TABLE download DROP COLUMN IF EXISTS version;
@@ -0,0 +1,19 @@
+ALTER TABLE download ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE download ADD COLUMN bytes BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE upload ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE upload ADD COLUMN bytes BIGINT NOT NULL DEFAULT 0;
+
+CREATE INDEX download_date_idx ON download (date DESC, uuid DESC);
+CREATE INDEX download_user_idx ON download (user_, date DESC);
+CREATE INDEX download_file_idx ON download (file, date DESC);
+CREATE INDEX upload_date_idx ON upload (date DESC, uuid DESC);
+CREATE INDEX upload_user_idx ON upload (user_, date DESC);
+CREATE INDEX upload_file_idx ON upload (file, date DESC);
+
+INSERT INTO permission (name, description)
+VALUES ('audit.read', 'Query and export the download and upload audit trail');
+
+INSERT INTO role_permission (role, permission)
+SELECT r.code, p.code
+FROM role r, permission p
+WHERE r.description IN ('admin', 'super') AND p.name = 'audit.read';
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS project_dir_idx;
+
+DROP TABLE IF EXISTS project_member;
@@ -0,0 +1,10 @@
+CREATE TABLE project_member (
+    project  UUID  REFERENCES project ON DELETE CASCADE NOT NULL,
+    user_    UUID  REFERENCES user_ ON DELETE CASCADE NOT NULL,
+    PRIMARY KEY (project, user_)
+);
+
+CREATE INDEX project_member_user_idx ON project_member (user_);
+
+-- A dir roots at most one project
+CREATE UNIQUE INDEX project_dir_idx ON project (dir);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS task_plan_idx;
+DROP INDEX IF EXISTS plan_assigned_idx;
+DROP INDEX IF EXISTS plan_project_idx;
@@ -0,0 +1,3 @@
+CREATE INDEX plan_project_idx ON plan (project, termination_date);
+CREATE INDEX plan_assigned_idx ON plan (assigned_user_);
+CREATE INDEX task_plan_idx ON task (plan);
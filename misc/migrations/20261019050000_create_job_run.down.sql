@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS plan_termination_idx;
+DROP INDEX IF EXISTS task_deadline_idx;
+
+DROP TABLE IF EXISTS deadline_notice;
+DROP TABLE IF EXISTS job_run;
@@ -0,0 +1,22 @@
+CREATE TABLE job_run (
+    uuid      UUID          PRIMARY KEY DEFAULT gen_random_uuid(),
+    job       VARCHAR(64)   NOT NULL,
+    node      VARCHAR(255)  NOT NULL,
+    started   TIMESTAMP     NOT NULL,
+    finished  TIMESTAMP     NOT NULL,
+    status    VARCHAR(16)   NOT NULL,
+    error     TEXT          NOT NULL DEFAULT ''
+);
+
+CREATE INDEX job_run_job_idx ON job_run (job, started DESC);
+
+-- One reminder and one escalation at most per task or plan
+CREATE TABLE deadline_notice (
+    target  UUID         NOT NULL,
+    level   VARCHAR(32)  NOT NULL,
+    date    TIMESTAMP    NOT NULL,
+    PRIMARY KEY (target, level)
+);
+
+CREATE INDEX task_deadline_idx ON task (deadline);
+CREATE INDEX plan_termination_idx ON plan (termination_date);
@@ -0,0 +1,21 @@
+CREATE TABLE notification (
+    uuid     UUID          PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_    UUID          REFERENCES user_ ON DELETE CASCADE NOT NULL,
+    event    VARCHAR(64)   NOT NULL,
+    subject  VARCHAR(255)  NOT NULL,
+    body     TEXT          NOT NULL,
+    ref      UUID,
+    date     TIMESTAMP     NOT NULL,
+    read_at  TIMESTAMP
+);
+
+CREATE INDEX notification_user_idx ON notification (user_, date DESC, uuid DESC);
+
+-- Only deviations from the default are stored: every channel is on unless disabled
+CREATE TABLE notification_preference (
+    user_    UUID         REFERENCES user_ ON DELETE CASCADE NOT NULL,
+    event    VARCHAR(64)  NOT NULL,
+    channel  VARCHAR(16)  NOT NULL,
+    enabled  BOOLEAN      NOT NULL,
+    PRIMARY KEY (user_, event, channel)
+);
@@ -0,0 +1,11 @@
+/*
+* Package migrations holds the schema and seed migrations of the database,
+* embedded in the binary. They are named <version>_<name>.<up|down>.sql and
+* applied in version order
+ */
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
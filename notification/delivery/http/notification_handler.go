@@ -0,0 +1,128 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/domain/dtos"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+	"github.com/sicozz/papyrus/utils/delivery"
+)
+
+// NotificationHandler will initialize the notification/ resources endpoint
+type NotificationHandler struct {
+	NUsecase domain.NotificationUsecase
+	log      utils.AggregatedLogger
+}
+
+func NewNotificationHandler(e *echo.Echo, nu domain.NotificationUsecase, authMw echo.MiddlewareFunc) {
+	logger := utils.NewAggregatedLogger(constants.Delivery, constants.Notify)
+	handler := &NotificationHandler{nu, logger}
+	g := e.Group("/notification", authMw)
+	g.GET("", handler.Fetch)
+	g.POST("/read", handler.MarkAllRead)
+	g.POST("/:uuid/read", handler.MarkRead)
+	g.GET("/preferences", handler.GetPreferences)
+	g.PUT("/preferences", handler.SetPreferences)
+}
+
+func (h *NotificationHandler) Fetch(c echo.Context) error {
+	h.log.Info("REQ: fetch")
+	ctx := c.Request().Context()
+	var qDto dtos.NotificationQueryDto
+	err := c.Bind(&qDto)
+	if err != nil {
+		errBody := dtos.NewErrDto(fmt.Sprint("Req query binding failed: ", err))
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	if ok, err := delivery.IsRequestValid(&qDto); !ok {
+		errBody, err := dtos.NewValidationErrDto(err.Error())
+		if err != nil {
+			errValid := dtos.NewErrDto(fmt.Sprint("Req query validation failed: ", err))
+			return c.JSON(http.StatusBadRequest, errValid)
+		}
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	f := domain.NotificationFilter{
+		Unread: qDto.Unread == "true",
+		Limit:  qDto.Limit,
+		Cursor: qDto.Cursor,
+	}
+	page, rErr := h.NUsecase.Fetch(ctx, f)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.JSON(http.StatusOK, page)
+}
+
+func (h *NotificationHandler) MarkRead(c echo.Context) error {
+	h.log.Info("REQ: mark read")
+	ctx := c.Request().Context()
+	uuid := c.Param("uuid")
+	rErr := h.NUsecase.MarkRead(ctx, uuid)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+func (h *NotificationHandler) MarkAllRead(c echo.Context) error {
+	h.log.Info("REQ: mark all read")
+	ctx := c.Request().Context()
+	rErr := h.NUsecase.MarkAllRead(ctx)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+func (h *NotificationHandler) GetPreferences(c echo.Context) error {
+	h.log.Info("REQ: get preferences")
+	ctx := c.Request().Context()
+	prefs, rErr := h.NUsecase.GetPreferences(ctx)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.JSON(http.StatusOK, prefs)
+}
+
+func (h *NotificationHandler) SetPreferences(c echo.Context) error {
+	h.log.Info("REQ: set preferences")
+	ctx := c.Request().Context()
+	var pDto dtos.NotificationPreferencesDto
+	err := c.Bind(&pDto)
+	if err != nil {
+		errBody := dtos.NewErrDto(fmt.Sprint("Req body binding failed: ", err))
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	if ok, err := delivery.IsRequestValid(&pDto); !ok {
+		errBody, err := dtos.NewValidationErrDto(err.Error())
+		if err != nil {
+			errValid := dtos.NewErrDto(fmt.Sprint("Req body validation failed: ", err))
+			return c.JSON(http.StatusBadRequest, errValid)
+		}
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	rErr := h.NUsecase.SetPreferences(ctx, pDto.Preferences)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return h.GetPreferences(c)
+}
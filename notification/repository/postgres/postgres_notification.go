@@ -0,0 +1,197 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+	"github.com/sicozz/papyrus/utils/repository"
+)
+
+// notificationOrder is the only order the inbox is listed in, newest first
+const notificationOrder = "-date"
+
+type postgresNotificationRepository struct {
+	Conn *sql.DB
+	log  utils.AggregatedLogger
+}
+
+// NewPostgresNotificationRepository will create an object that represent the NotificationRepository interface
+func NewPostgresNotificationRepository(conn *sql.DB) domain.NotificationRepository {
+	logger := utils.NewAggregatedLogger(constants.Repository, constants.Notify)
+	return &postgresNotificationRepository{conn, logger}
+}
+
+func (r *postgresNotificationRepository) fetch(ctx context.Context, query string, args ...interface{}) (res []domain.Notification, err error) {
+	rows, err := r.Conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		r.log.Error("IN [fetch]:", err)
+		return nil, repository.MapErr(err)
+	}
+	defer rows.Close()
+
+	res = make([]domain.Notification, 0)
+	for rows.Next() {
+		n := domain.Notification{}
+		ref := sql.NullString{}
+		err = rows.Scan(&n.Uuid, &n.User, &n.Event, &n.Subject, &n.Body, &ref, &n.Date, &n.Read)
+		if err != nil {
+			r.log.Error("IN [fetch]:", err)
+			return nil, err
+		}
+		n.Ref = ref.String
+		res = append(res, n)
+	}
+
+	return res, rows.Err()
+}
+
+func (r *postgresNotificationRepository) Store(ctx context.Context, n *domain.Notification) (err error) {
+	ref := sql.NullString{String: n.Ref, Valid: n.Ref != ""}
+	query :=
+		`INSERT INTO notification (user_, event, subject, body, ref, date)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING uuid`
+	err = r.Conn.QueryRowContext(ctx, query, n.User, n.Event, n.Subject, n.Body, ref, n.Date.UTC()).Scan(&n.Uuid)
+	if err != nil {
+		r.log.Error("IN [Store]: could not store notification ->", err)
+	}
+
+	return
+}
+
+// Get a page of the inbox of f.User, newest first
+func (r *postgresNotificationRepository) GetPage(ctx context.Context, f domain.NotificationFilter) (res []domain.Notification, next string, err error) {
+	args := []interface{}{f.User}
+	conds := []string{"user_ = $1"}
+	if f.Unread {
+		conds = append(conds, "read_at IS NULL")
+	}
+	if f.Cursor != "" {
+		key, uuid, err := repository.DecodeCursor(f.Cursor, notificationOrder)
+		if err != nil {
+			return nil, "", domain.ErrBadParamInput
+		}
+		if _, err = repository.ParseCursorTime(key); err != nil {
+			return nil, "", domain.ErrBadParamInput
+		}
+		args = append(args, key, uuid)
+		conds = append(conds, fmt.Sprintf("(date, uuid) < ($%d::timestamp, $%d::uuid)", len(args)-1, len(args)))
+	}
+
+	// One extra row tells whether there is a next page
+	args = append(args, f.Limit+1)
+	query := fmt.Sprintf(
+		`SELECT uuid, user_, event, subject, body, ref, date, read_at IS NOT NULL
+		FROM notification
+		WHERE %s
+		ORDER BY date DESC, uuid DESC
+		LIMIT $%d`,
+		strings.Join(conds, " AND "), len(args),
+	)
+
+	res, err = r.fetch(ctx, query, args...)
+	if err != nil {
+		r.log.Error("IN [GetPage]: could not fetch notifications ->", err)
+		return nil, "", err
+	}
+
+	if int64(len(res)) > f.Limit {
+		res = res[:f.Limit]
+		last := res[len(res)-1]
+		next = repository.EncodeCursor(notificationOrder, repository.FormatCursorTime(last.Date), last.Uuid)
+	}
+
+	return
+}
+
+func (r *postgresNotificationRepository) CountUnread(ctx context.Context, user string) (res int64, err error) {
+	query := `SELECT COUNT(*) FROM notification WHERE user_ = $1 AND read_at IS NULL`
+	err = r.Conn.QueryRowContext(ctx, query, user).Scan(&res)
+	if err != nil {
+		r.log.Error("IN [CountUnread]: could not count notifications ->", err)
+	}
+
+	return
+}
+
+func (r *postgresNotificationRepository) MarkRead(ctx context.Context, user string, uuid string, date time.Time) (bool, error) {
+	query := `UPDATE notification SET read_at = $3 WHERE uuid = $1 AND user_ = $2 AND read_at IS NULL`
+	result, err := r.Conn.ExecContext(ctx, query, uuid, user, date.UTC())
+	if err != nil {
+		r.log.Error("IN [MarkRead]: could not mark notification ->", err)
+		return false, err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		r.log.Error("IN [MarkRead]: could not get affected rows ->", err)
+		return false, err
+	}
+
+	return n == 1, nil
+}
+
+func (r *postgresNotificationRepository) MarkAllRead(ctx context.Context, user string, date time.Time) (err error) {
+	query := `UPDATE notification SET read_at = $2 WHERE user_ = $1 AND read_at IS NULL`
+	_, err = r.Conn.ExecContext(ctx, query, user, date.UTC())
+	if err != nil {
+		r.log.Error("IN [MarkAllRead]: could not mark notifications ->", err)
+	}
+
+	return
+}
+
+func (r *postgresNotificationRepository) GetPreferences(ctx context.Context, user string) (res []domain.NotificationPreference, err error) {
+	query := `SELECT event, channel, enabled FROM notification_preference WHERE user_ = $1`
+	rows, err := r.Conn.QueryContext(ctx, query, user)
+	if err != nil {
+		r.log.Error("IN [GetPreferences]: could not fetch preferences ->", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	res = make([]domain.NotificationPreference, 0)
+	for rows.Next() {
+		p := domain.NotificationPreference{}
+		if err = rows.Scan(&p.Event, &p.Channel, &p.Enabled); err != nil {
+			r.log.Error("IN [GetPreferences]:", err)
+			return nil, err
+		}
+		res = append(res, p)
+	}
+
+	return res, rows.Err()
+}
+
+// Upsert every preference of prefs at once
+func (r *postgresNotificationRepository) SetPreferences(ctx context.Context, user string, prefs []domain.NotificationPreference) (err error) {
+	tx, err := r.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		r.log.Error("IN [SetPreferences]: could not begin transaction ->", err)
+		return
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	query :=
+		`INSERT INTO notification_preference (user_, event, channel, enabled)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_, event, channel) DO UPDATE SET enabled = EXCLUDED.enabled`
+	for _, p := range prefs {
+		if _, err = tx.ExecContext(ctx, query, user, p.Event, p.Channel, p.Enabled); err != nil {
+			r.log.Error("IN [SetPreferences]: could not store preference ->", err)
+			return
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.log.Error("IN [SetPreferences]: could not commit ->", err)
+	}
+
+	return
+}
@@ -0,0 +1,184 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+)
+
+const (
+	defPageLimit = 50
+	maxPageLimit = 200
+)
+
+type notificationUsecase struct {
+	notificationRepo domain.NotificationRepository
+	clock            domain.Clock
+	contextTimeout   time.Duration
+	log              utils.AggregatedLogger
+}
+
+// NewNotificationUsecase will create a new notificationUsecase object representation of domain.NotificationUsecase interface
+func NewNotificationUsecase(nr domain.NotificationRepository, clk domain.Clock, timeout time.Duration) domain.NotificationUsecase {
+	logger := utils.NewAggregatedLogger(constants.Usecase, constants.Notify)
+	return &notificationUsecase{
+		notificationRepo: nr,
+		clock:            clk,
+		contextTimeout:   timeout,
+		log:              logger,
+	}
+}
+
+func isKnown(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (u *notificationUsecase) Fetch(c context.Context, f domain.NotificationFilter) (res domain.NotificationPage, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	user, ok := domain.UserFromContext(ctx)
+	if !ok {
+		return res, domain.NewUCaseErr(http.StatusUnauthorized, errors.New("Authentication required"))
+	}
+	f.User = user.Uuid
+
+	if f.Limit <= 0 {
+		f.Limit = defPageLimit
+	}
+	if f.Limit > maxPageLimit {
+		f.Limit = maxPageLimit
+	}
+
+	notifications, next, err := u.notificationRepo.GetPage(ctx, f)
+	if errors.Is(err, domain.ErrBadParamInput) {
+		rErr = domain.NewUCaseErr(http.StatusBadRequest, errors.New("Invalid cursor"))
+		return
+	}
+	if err != nil {
+		rErr = domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Notifications fetch failed"))
+		return
+	}
+
+	unread, err := u.notificationRepo.CountUnread(ctx, user.Uuid)
+	if err != nil {
+		rErr = domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Notifications fetch failed"))
+		return
+	}
+
+	res = domain.NotificationPage{Notifications: notifications, Unread: unread, NextCursor: next}
+	return
+}
+
+func (u *notificationUsecase) MarkRead(c context.Context, uuid string) (rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	user, ok := domain.UserFromContext(ctx)
+	if !ok {
+		return domain.NewUCaseErr(http.StatusUnauthorized, errors.New("Authentication required"))
+	}
+
+	marked, err := u.notificationRepo.MarkRead(ctx, user.Uuid, uuid, u.clock.Now())
+	if err != nil {
+		return domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Notification update failed"))
+	}
+	if !marked {
+		err = errors.New(fmt.Sprint("Unread notification not found. uuid: ", uuid))
+		return domain.NewUCaseErr(http.StatusNotFound, err)
+	}
+
+	return
+}
+
+func (u *notificationUsecase) MarkAllRead(c context.Context) (rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	user, ok := domain.UserFromContext(ctx)
+	if !ok {
+		return domain.NewUCaseErr(http.StatusUnauthorized, errors.New("Authentication required"))
+	}
+
+	if err := u.notificationRepo.MarkAllRead(ctx, user.Uuid, u.clock.Now()); err != nil {
+		return domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Notification update failed"))
+	}
+
+	return
+}
+
+func (u *notificationUsecase) GetPreferences(c context.Context) (res []domain.NotificationPreference, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	user, ok := domain.UserFromContext(ctx)
+	if !ok {
+		return nil, domain.NewUCaseErr(http.StatusUnauthorized, errors.New("Authentication required"))
+	}
+
+	stored, err := u.notificationRepo.GetPreferences(ctx, user.Uuid)
+	if err != nil {
+		return nil, domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Preferences fetch failed"))
+	}
+
+	res = make([]domain.NotificationPreference, 0, len(domain.Events)*len(domain.Channels))
+	for _, event := range domain.Events {
+		for _, channel := range domain.Channels {
+			res = append(res, domain.NotificationPreference{
+				Event:   event,
+				Channel: channel,
+				Enabled: enabled(stored, event, channel),
+			})
+		}
+	}
+
+	return
+}
+
+func (u *notificationUsecase) SetPreferences(c context.Context, prefs []domain.NotificationPreference) (rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	user, ok := domain.UserFromContext(ctx)
+	if !ok {
+		return domain.NewUCaseErr(http.StatusUnauthorized, errors.New("Authentication required"))
+	}
+
+	for _, p := range prefs {
+		if !isKnown(domain.Events, p.Event) {
+			err := errors.New(fmt.Sprint("Unknown event: ", p.Event))
+			return domain.NewUCaseErr(http.StatusBadRequest, err)
+		}
+		if !isKnown(domain.Channels, p.Channel) {
+			err := errors.New(fmt.Sprint("Unknown channel: ", p.Channel))
+			return domain.NewUCaseErr(http.StatusBadRequest, err)
+		}
+	}
+
+	if err := u.notificationRepo.SetPreferences(ctx, user.Uuid, prefs); err != nil {
+		return domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Preferences update failed"))
+	}
+
+	return
+}
+
+// enabled tells whether event reaches its user through channel, missing preferences are on
+func enabled(prefs []domain.NotificationPreference, event string, channel string) bool {
+	for _, p := range prefs {
+		if p.Event == event && p.Channel == channel {
+			return p.Enabled
+		}
+	}
+	return true
+}
@@ -0,0 +1,79 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+)
+
+type notifier struct {
+	notificationRepo domain.NotificationRepository
+	userRepo         domain.UserRepository
+	mailSender       domain.MailSender
+	clock            domain.Clock
+	log              utils.AggregatedLogger
+}
+
+/*
+* NewNotifier will create an object that represent the Notifier interface.
+* A notification goes to the inbox and by mail, unless its user turned the
+* event off on either channel. It only fails when no enabled channel
+* delivered it, so a retry never duplicates what a channel already got. A
+* queued sender counts a mail as delivered once it is queued
+ */
+func NewNotifier(nr domain.NotificationRepository, ur domain.UserRepository, ms domain.MailSender, clk domain.Clock) domain.Notifier {
+	logger := utils.NewAggregatedLogger(constants.Usecase, constants.Notify)
+	return &notifier{
+		notificationRepo: nr,
+		userRepo:         ur,
+		mailSender:       ms,
+		clock:            clk,
+		log:              logger,
+	}
+}
+
+func (n *notifier) Notify(ctx context.Context, nt domain.Notification) (err error) {
+	prefs, err := n.notificationRepo.GetPreferences(ctx, nt.User)
+	if err != nil {
+		n.log.Error("IN [Notify]: could not fetch preferences ->", err)
+		return
+	}
+
+	if nt.Date.IsZero() {
+		nt.Date = n.clock.Now()
+	}
+
+	delivered := false
+	if enabled(prefs, nt.Event, domain.ChannelInbox) {
+		if err = n.notificationRepo.Store(ctx, &nt); err != nil {
+			n.log.Error("IN [Notify]: could not store notification {", nt.Event, "} for {", nt.User, "} ->", err)
+		} else {
+			delivered = true
+		}
+	}
+
+	if enabled(prefs, nt.Event, domain.ChannelEmail) {
+		if mErr := n.mail(ctx, nt); mErr != nil {
+			n.log.Error("IN [Notify]: could not mail notification {", nt.Event, "} to {", nt.User, "} ->", mErr)
+			err = mErr
+		} else {
+			delivered = true
+		}
+	}
+
+	if delivered {
+		return nil
+	}
+	return
+}
+
+func (n *notifier) mail(ctx context.Context, nt domain.Notification) (err error) {
+	user, err := n.userRepo.GetByUuid(ctx, nt.User)
+	if err != nil {
+		return
+	}
+
+	return n.mailSender.Send(ctx, user.Email, nt.Subject, nt.Body)
+}
@@ -0,0 +1,132 @@
+package http
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sicozz/papyrus/auth/delivery/http/middleware"
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/domain/dtos"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+	"github.com/sicozz/papyrus/utils/delivery"
+)
+
+// PasswordHandler will initialize the password management endpoints
+type PasswordHandler struct {
+	PUsecase domain.PasswordUsecase
+	log      utils.AggregatedLogger
+}
+
+func NewPasswordHandler(e *echo.Echo, pu domain.PasswordUsecase, authMw echo.MiddlewareFunc, authz *middleware.AuthzMiddleware) {
+	logger := utils.NewAggregatedLogger(constants.Delivery, constants.Password)
+	handler := &PasswordHandler{pu, logger}
+	self := authz.Require(middleware.Self("uname"))
+	canWrite := authz.Require(middleware.Can(domain.PermUserWrite))
+	g := e.Group("/user/:uname/password", authMw)
+	g.PATCH("", handler.Change, self)
+	g.POST("/reset", handler.RequestReset, canWrite)
+	e.GET("/password/reset", handler.ResetForm)
+	e.POST("/password/reset", handler.Reset)
+}
+
+// resetForm is the page the emailed reset link opens, it posts back to the same route
+var resetForm = template.Must(template.New("reset").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Reset password</title></head>
+<body>
+<form method="post" action="/password/reset">
+<input type="hidden" name="token" value="{{.}}">
+<label>New password <input type="password" name="new_password" required></label>
+<button type="submit">Reset</button>
+</form>
+</body>
+</html>
+`))
+
+func (h *PasswordHandler) Change(c echo.Context) error {
+	h.log.Info("REQ: change")
+	ctx := c.Request().Context()
+	uname := c.Param("uname")
+
+	var pDto dtos.PasswdChangeDto
+	err := c.Bind(&pDto)
+	if err != nil {
+		errBody := dtos.NewErrDto(fmt.Sprint("Req body binding failed: ", err))
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	if ok, err := delivery.IsRequestValid(&pDto); !ok {
+		errBody, err := dtos.NewValidationErrDto(err.Error())
+		if err != nil {
+			errValid := dtos.NewErrDto(fmt.Sprint("Req body validation failed: ", err))
+			return c.JSON(http.StatusBadRequest, errValid)
+		}
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	rErr := h.PUsecase.Change(ctx, uname, pDto.Current, pDto.Password)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+func (h *PasswordHandler) RequestReset(c echo.Context) error {
+	h.log.Info("REQ: request reset")
+	ctx := c.Request().Context()
+	uname := c.Param("uname")
+	rErr := h.PUsecase.RequestReset(ctx, uname)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.NoContent(http.StatusAccepted)
+}
+
+func (h *PasswordHandler) ResetForm(c echo.Context) error {
+	h.log.Info("REQ: reset form")
+	token := c.QueryParam("token")
+	if token == "" {
+		errBody := dtos.NewErrDto("Reset token is required")
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, echo.MIMETextHTMLCharsetUTF8)
+	c.Response().WriteHeader(http.StatusOK)
+	return resetForm.Execute(c.Response(), token)
+}
+
+func (h *PasswordHandler) Reset(c echo.Context) error {
+	h.log.Info("REQ: reset")
+	ctx := c.Request().Context()
+
+	var pDto dtos.PasswdResetDto
+	err := c.Bind(&pDto)
+	if err != nil {
+		errBody := dtos.NewErrDto(fmt.Sprint("Req body binding failed: ", err))
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	if ok, err := delivery.IsRequestValid(&pDto); !ok {
+		errBody, err := dtos.NewValidationErrDto(err.Error())
+		if err != nil {
+			errValid := dtos.NewErrDto(fmt.Sprint("Req body validation failed: ", err))
+			return c.JSON(http.StatusBadRequest, errValid)
+		}
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	rErr := h.PUsecase.Reset(ctx, pDto.Token, pDto.Password)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.NoContent(http.StatusOK)
+}
@@ -0,0 +1,216 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+	"github.com/sicozz/papyrus/utils/token"
+)
+
+const resetSubject = `Papyrus password reset`
+
+type passwordUsecase struct {
+	userRepo       domain.UserRepository
+	tokenRepo      domain.UserTokenRepository
+	sessionRepo    domain.SessionRepository
+	authorizer     domain.Authorizer
+	hasher         domain.PasswordHasher
+	mailer         domain.MailSender
+	policy         domain.PasswordPolicy
+	resetTTL       time.Duration
+	resetURL       string
+	now            func() time.Time
+	contextTimeout time.Duration
+	log            utils.AggregatedLogger
+}
+
+// NewPasswordUsecase will create a new passwordUsecase object representation of domain.PasswordUsecase interface
+func NewPasswordUsecase(
+	ur domain.UserRepository,
+	tr domain.UserTokenRepository,
+	sr domain.SessionRepository,
+	az domain.Authorizer,
+	h domain.PasswordHasher,
+	ms domain.MailSender,
+	p domain.PasswordPolicy,
+	resetTTL time.Duration,
+	resetURL string,
+	timeout time.Duration,
+) domain.PasswordUsecase {
+	logger := utils.NewAggregatedLogger(constants.Usecase, constants.Password)
+	return &passwordUsecase{
+		userRepo:       ur,
+		tokenRepo:      tr,
+		sessionRepo:    sr,
+		authorizer:     az,
+		hasher:         h,
+		mailer:         ms,
+		policy:         p,
+		resetTTL:       resetTTL,
+		resetURL:       resetURL,
+		now:            func() time.Time { return time.Now().UTC() },
+		contextTimeout: timeout,
+		log:            logger,
+	}
+}
+
+// store a new password hash and end every session opened with the old one
+func (u *passwordUsecase) store(ctx context.Context, user domain.User, nPasswd string) (rErr domain.RequestErr) {
+	hash, err := u.hasher.Hash(nPasswd)
+	if err != nil {
+		u.log.Error("IN [store]: could not hash password ->", err)
+		err = errors.New("Password hashing failed")
+		return domain.NewUCaseErr(http.StatusInternalServerError, err)
+	}
+
+	err = u.userRepo.ChgPasswd(ctx, user.Username, hash)
+	if err != nil {
+		u.log.Error("IN [store]: could not change password ->", err)
+		return domain.NewRepoErr(err, fmt.Sprint("User not found. username: ", user.Username))
+	}
+
+	if err = u.sessionRepo.RevokeAllByUser(ctx, user.Uuid); err != nil {
+		u.log.Error("IN [store]: could not revoke sessions of {", user.Username, "} ->", err)
+	}
+
+	return
+}
+
+// checkPrivileges refuses to act on a user whose permissions the actor lacks
+func (u *passwordUsecase) checkPrivileges(ctx context.Context, target domain.User) (rErr domain.RequestErr) {
+	if rErr = u.authorizer.Authorize(ctx, domain.PermUserWrite); rErr != nil {
+		return
+	}
+
+	actor, _ := domain.UserFromContext(ctx)
+	ok, err := u.authorizer.Covers(ctx, actor, target.Role)
+	if err != nil {
+		u.log.Error("IN [checkPrivileges]: could not compare roles ->", err)
+		return domain.NewUCaseErr(http.StatusInternalServerError, err)
+	}
+
+	if !ok {
+		err = errors.New("Can not act on a user with permissions you lack")
+		return domain.NewUCaseErr(http.StatusForbidden, err)
+	}
+
+	return
+}
+
+func (u *passwordUsecase) Change(c context.Context, uname string, current string, nPasswd string) (rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	user, err := u.userRepo.GetByUsername(ctx, uname)
+	if err != nil {
+		rErr = domain.NewRepoErr(err, fmt.Sprint("User not found. username: ", uname))
+		return
+	}
+
+	if err = u.hasher.Compare(user.Password, current); err != nil {
+		err = errors.New("Current password is incorrect")
+		rErr = domain.NewUCaseErr(http.StatusForbidden, err)
+		return
+	}
+
+	if err = u.policy.Check(nPasswd); err != nil {
+		rErr = domain.NewUCaseErr(http.StatusBadRequest, err)
+		return
+	}
+
+	return u.store(ctx, user, nPasswd)
+}
+
+func (u *passwordUsecase) RequestReset(c context.Context, uname string) (rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	user, err := u.userRepo.GetByUsername(ctx, uname)
+	if err != nil {
+		rErr = domain.NewRepoErr(err, fmt.Sprint("User not found. username: ", uname))
+		return
+	}
+
+	// The reset link hands the account over to whoever reads the mail
+	if rErr = u.checkPrivileges(ctx, user); rErr != nil {
+		return
+	}
+
+	// Only the latest reset token of a user is valid
+	if err = u.tokenRepo.DeleteByUser(ctx, user.Uuid, domain.TokenPasswdReset); err != nil {
+		rErr = domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Password reset failed"))
+		return
+	}
+
+	tok, err := token.New()
+	if err != nil {
+		u.log.Error("IN [RequestReset]: could not generate token ->", err)
+		rErr = domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Password reset failed"))
+		return
+	}
+
+	now := u.now()
+	t := domain.UserToken{
+		UserUuid:  user.Uuid,
+		Purpose:   domain.TokenPasswdReset,
+		TokenHash: token.Hash(tok),
+		CreatedAt: now,
+		ExpiresAt: now.Add(u.resetTTL),
+	}
+	if err = u.tokenRepo.Store(ctx, &t); err != nil {
+		rErr = domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Password reset failed"))
+		return
+	}
+
+	link := fmt.Sprint(u.resetURL, "?token=", url.QueryEscape(tok))
+	body := fmt.Sprint(
+		"Hello ", user.Name, ",\n\n",
+		"An administrator requested a password reset for your account. ",
+		"Use the following link before ", t.ExpiresAt.Format(time.RFC1123), ":\n\n",
+		link, "\n",
+	)
+	if err = u.mailer.Send(ctx, user.Email, resetSubject, body); err != nil {
+		u.log.Error("IN [RequestReset]: could not send reset mail ->", err)
+		rErr = domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Reset mail delivery failed"))
+		return
+	}
+
+	return
+}
+
+func (u *passwordUsecase) Reset(c context.Context, tok string, nPasswd string) (rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	// Check the policy first, so a rejected password does not burn the token
+	if err := u.policy.Check(nPasswd); err != nil {
+		rErr = domain.NewUCaseErr(http.StatusBadRequest, err)
+		return
+	}
+
+	t, err := u.tokenRepo.Consume(ctx, domain.TokenPasswdReset, token.Hash(tok), u.now())
+	if errors.Is(err, domain.ErrNotFound) {
+		rErr = domain.NewUCaseErr(http.StatusUnauthorized, errors.New("Invalid or expired token"))
+		return
+	}
+	if err != nil {
+		u.log.Error("IN [Reset]: could not consume token ->", err)
+		rErr = domain.NewRepoErr(err, "")
+		return
+	}
+
+	user, err := u.userRepo.GetByUuid(ctx, t.UserUuid)
+	if err != nil {
+		rErr = domain.NewRepoErr(err, "User not found")
+		return
+	}
+
+	return u.store(ctx, user, nPasswd)
+}
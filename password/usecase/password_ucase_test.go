@@ -0,0 +1,100 @@
+package usecase_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/password/usecase"
+)
+
+var (
+	superRole = domain.Role{Code: 1, Description: "super"}
+	adminRole = domain.Role{Code: 2, Description: "admin"}
+	userRole  = domain.Role{Code: 3, Description: "user"}
+
+	users = map[string]domain.User{
+		"root": {Uuid: "u-root", Username: "root", Email: "root@example.com", Role: superRole},
+		"ana":  {Uuid: "u-ana", Username: "ana", Email: "ana@example.com", Role: adminRole},
+		"eve":  {Uuid: "u-eve", Username: "eve", Email: "eve@example.com", Role: userRole},
+	}
+)
+
+type fakeUserRepo struct{ domain.UserRepository }
+
+func (fakeUserRepo) GetByUsername(ctx context.Context, uname string) (domain.User, error) {
+	u, ok := users[uname]
+	if !ok {
+		return domain.User{}, domain.ErrNotFound
+	}
+	return u, nil
+}
+
+type fakeTokenRepo struct {
+	domain.UserTokenRepository
+	stored []domain.UserToken
+}
+
+func (r *fakeTokenRepo) DeleteByUser(ctx context.Context, userUuid string, purpose string) error {
+	return nil
+}
+
+func (r *fakeTokenRepo) Store(ctx context.Context, t *domain.UserToken) error {
+	r.stored = append(r.stored, *t)
+	return nil
+}
+
+type fakeMailer struct{ to []string }
+
+func (m *fakeMailer) Send(ctx context.Context, to string, subject string, body string) error {
+	m.to = append(m.to, to)
+	return nil
+}
+
+// fakeAuthorizer lets every user write users and a role cover the ones with a greater code
+type fakeAuthorizer struct{ domain.Authorizer }
+
+func (fakeAuthorizer) Authorize(ctx context.Context, perm string) domain.RequestErr {
+	return nil
+}
+
+func (fakeAuthorizer) Covers(ctx context.Context, u domain.User, r domain.Role) (bool, error) {
+	return u.Role.Code <= r.Code, nil
+}
+
+func TestRequestResetNeedsToCoverTheTarget(t *testing.T) {
+	cases := []struct {
+		actor  string
+		target string
+		status int
+	}{
+		{"ana", "root", http.StatusForbidden},
+		{"ana", "eve", 0},
+		{"ana", "ana", 0},
+		{"root", "ana", 0},
+	}
+	for _, c := range cases {
+		tr, ms := &fakeTokenRepo{}, &fakeMailer{}
+		pu := usecase.NewPasswordUsecase(
+			fakeUserRepo{}, tr, nil, fakeAuthorizer{}, nil, ms, domain.PasswordPolicy{},
+			time.Hour, "http://papyrus/password/reset", time.Second,
+		)
+		ctx := domain.NewContextWithUser(context.Background(), users[c.actor])
+
+		rErr := pu.RequestReset(ctx, c.target)
+		if c.status == 0 {
+			if rErr != nil || len(tr.stored) != 1 || len(ms.to) != 1 || ms.to[0] != users[c.target].Email {
+				t.Errorf("%s resets %s: got %v, %d tokens, mails to %v", c.actor, c.target, rErr, len(tr.stored), ms.to)
+			}
+			continue
+		}
+		if rErr == nil || rErr.GetStatus() != c.status {
+			t.Errorf("%s resets %s: got %v, want %d", c.actor, c.target, rErr, c.status)
+		}
+		if len(tr.stored) != 0 || len(ms.to) != 0 {
+			t.Errorf("%s resets %s: %d tokens stored, mails to %v", c.actor, c.target, len(tr.stored), ms.to)
+		}
+	}
+}
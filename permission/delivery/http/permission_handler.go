@@ -0,0 +1,96 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sicozz/papyrus/auth/delivery/http/middleware"
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/domain/dtos"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+)
+
+// PermissionHandler will initialize the permission/ resources endpoint
+type PermissionHandler struct {
+	PUsecase domain.PermissionUsecase
+	log      utils.AggregatedLogger
+}
+
+func NewPermissionHandler(e *echo.Echo, pu domain.PermissionUsecase, authMw echo.MiddlewareFunc, authz *middleware.AuthzMiddleware) {
+	logger := utils.NewAggregatedLogger(constants.Delivery, constants.Permission)
+	handler := &PermissionHandler{pu, logger}
+	manage := authz.Require(middleware.Can(domain.PermRoleManage))
+	e.GET("/permission", handler.Fetch, authMw, manage)
+	g := e.Group("/role/:code/permission", authMw, manage)
+	g.GET("", handler.GetByRole)
+	g.PUT("/:name", handler.Grant)
+	g.DELETE("/:name", handler.Revoke)
+}
+
+func (h *PermissionHandler) Fetch(c echo.Context) error {
+	h.log.Info("REQ: fetch")
+	ctx := c.Request().Context()
+	perms, rErr := h.PUsecase.Fetch(ctx)
+	if rErr != nil {
+		errBody := dtos.NewErrDto("Permission fetch failed")
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.JSON(http.StatusOK, perms)
+}
+
+func (h *PermissionHandler) GetByRole(c echo.Context) error {
+	h.log.Info("REQ: get by role")
+	ctx := c.Request().Context()
+	code, err := strconv.ParseInt(c.Param("code"), 10, 64)
+	if err != nil {
+		errBody := dtos.NewErrDto("Role code must be an integer")
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	perms, rErr := h.PUsecase.GetByRole(ctx, code)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.JSON(http.StatusOK, perms)
+}
+
+func (h *PermissionHandler) Grant(c echo.Context) error {
+	h.log.Info("REQ: grant")
+	ctx := c.Request().Context()
+	code, err := strconv.ParseInt(c.Param("code"), 10, 64)
+	if err != nil {
+		errBody := dtos.NewErrDto("Role code must be an integer")
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	rErr := h.PUsecase.Grant(ctx, code, c.Param("name"))
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+func (h *PermissionHandler) Revoke(c echo.Context) error {
+	h.log.Info("REQ: revoke")
+	ctx := c.Request().Context()
+	code, err := strconv.ParseInt(c.Param("code"), 10, 64)
+	if err != nil {
+		errBody := dtos.NewErrDto("Role code must be an integer")
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	rErr := h.PUsecase.Revoke(ctx, code, c.Param("name"))
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.NoContent(http.StatusOK)
+}
@@ -0,0 +1,134 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+	"github.com/sicozz/papyrus/utils/repository"
+)
+
+type postgresPermissionRepository struct {
+	Conn *sql.DB
+	log  utils.AggregatedLogger
+}
+
+/*
+* NewPostgresPermissionRepository will create an object that represent the
+* PermissionRepository interface
+ */
+func NewPostgresPermissionRepository(conn *sql.DB) domain.PermissionRepository {
+	logger := utils.NewAggregatedLogger(constants.Repository, constants.Permission)
+	return &postgresPermissionRepository{conn, logger}
+}
+
+func (r *postgresPermissionRepository) fetch(ctx context.Context, query string, args ...interface{}) (res []domain.Permission, err error) {
+	rows, err := r.Conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		r.log.Error(err)
+		return nil, repository.MapErr(err)
+	}
+
+	defer func() {
+		errRow := rows.Close()
+		if errRow != nil {
+			r.log.Error(errRow)
+		}
+	}()
+
+	res = make([]domain.Permission, 0)
+	for rows.Next() {
+		t := domain.Permission{}
+		// Get from db
+		err = rows.Scan(
+			&t.Code,
+			&t.Name,
+			&t.Description,
+		)
+
+		if err != nil {
+			r.log.Error(err)
+			return nil, err
+		}
+		res = append(res, t)
+	}
+
+	return res, rows.Err()
+}
+
+func (r *postgresPermissionRepository) GetAll(ctx context.Context) ([]domain.Permission, error) {
+	query := `SELECT code, name, description FROM permission ORDER BY name`
+	return r.fetch(ctx, query)
+}
+
+func (r *postgresPermissionRepository) GetByName(ctx context.Context, name string) (res domain.Permission, err error) {
+	query := `SELECT code, name, description FROM permission WHERE name=$1`
+	perms, err := r.fetch(ctx, query, name)
+	if err != nil {
+		r.log.Error(err)
+		return domain.Permission{}, err
+	}
+
+	if len(perms) < 1 {
+		return domain.Permission{}, domain.ErrNotFound
+	}
+
+	res = perms[0]
+	return
+}
+
+func (r *postgresPermissionRepository) GetByRole(ctx context.Context, roleCode int64) ([]domain.Permission, error) {
+	query :=
+		`SELECT p.code, p.name, p.description
+		FROM permission p
+		JOIN role_permission rp ON rp.permission = p.code
+		WHERE rp.role = $1
+		ORDER BY p.name`
+	return r.fetch(ctx, query, roleCode)
+}
+
+// Know if a role grants a permission
+func (r *postgresPermissionRepository) RoleHas(ctx context.Context, roleCode int64, name string) (res bool, err error) {
+	query :=
+		`SELECT COUNT(*) > 0
+		FROM role_permission rp
+		JOIN permission p ON p.code = rp.permission
+		WHERE rp.role = $1 AND p.name = $2`
+	err = r.Conn.QueryRowContext(ctx, query, roleCode, name).Scan(&res)
+	if err != nil {
+		r.log.Error("IN [RoleHas]: could not query role permission ->", err)
+	}
+
+	return
+}
+
+// Grant a permission to a role
+func (r *postgresPermissionRepository) Grant(ctx context.Context, roleCode int64, permCode int64) (err error) {
+	query :=
+		`INSERT INTO role_permission (role, permission)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING`
+	_, err = r.Conn.ExecContext(ctx, query, roleCode, permCode)
+	if err != nil {
+		r.log.Error("IN [Grant]: could not grant permission ->", err)
+		return repository.MapErr(err)
+	}
+
+	return
+}
+
+// Revoke a permission from a role
+func (r *postgresPermissionRepository) Revoke(ctx context.Context, roleCode int64, permCode int64, keep string) (err error) {
+	query := `DELETE FROM role_permission WHERE role=$1 AND permission=$2`
+	err = repository.Keeping(ctx, r.Conn, keep, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, query, roleCode, permCode)
+		return err
+	})
+	if err != nil {
+		r.log.Error("IN [Revoke]: could not revoke permission ->", err)
+	}
+
+	return
+}
@@ -0,0 +1,84 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+)
+
+type authorizer struct {
+	permRepo domain.PermissionRepository
+	log      utils.AggregatedLogger
+}
+
+// NewAuthorizer will create a new authorizer object representation of domain.Authorizer interface
+func NewAuthorizer(pr domain.PermissionRepository) domain.Authorizer {
+	logger := utils.NewAggregatedLogger(constants.Usecase, constants.Authz)
+	return &authorizer{
+		permRepo: pr,
+		log:      logger,
+	}
+}
+
+func inScopes(scopes []string, perm string) bool {
+	for _, s := range scopes {
+		if s == perm {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *authorizer) Can(ctx context.Context, u domain.User, perm string) (bool, error) {
+	if scopes, ok := domain.ScopesFromContext(ctx); ok && !inScopes(scopes, perm) {
+		return false, nil
+	}
+
+	return a.permRepo.RoleHas(ctx, u.Role.Code, perm)
+}
+
+func (a *authorizer) Covers(ctx context.Context, u domain.User, r domain.Role) (bool, error) {
+	if u.Role.Code == r.Code {
+		return true, nil
+	}
+
+	perms, err := a.permRepo.GetByRole(ctx, r.Code)
+	if err != nil {
+		return false, err
+	}
+
+	for _, p := range perms {
+		ok, err := a.Can(ctx, u, p.Name)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+func (a *authorizer) Authorize(ctx context.Context, perm string) domain.RequestErr {
+	u, ok := domain.UserFromContext(ctx)
+	if !ok {
+		err := errors.New("Authentication required")
+		return domain.NewUCaseErr(http.StatusUnauthorized, err)
+	}
+
+	allowed, err := a.Can(ctx, u, perm)
+	if err != nil {
+		a.log.Error("IN [Authorize]: could not check permission {", perm, "} ->", err)
+		return domain.NewUCaseErr(http.StatusInternalServerError, err)
+	}
+
+	if !allowed {
+		err = errors.New(fmt.Sprint("Missing permission: ", perm))
+		return domain.NewUCaseErr(http.StatusForbidden, err)
+	}
+
+	return nil
+}
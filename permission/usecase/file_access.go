@@ -0,0 +1,156 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+)
+
+// accessPerms maps every kind of access to the role permission giving it by default
+var accessPerms = map[string]string{
+	domain.AccessRead:  domain.PermFileRead,
+	domain.AccessWrite: domain.PermFileWrite,
+}
+
+type fileAccess struct {
+	grantRepo   domain.FileGrantRepository
+	projectRepo domain.ProjectRepository
+	authorizer  domain.Authorizer
+	log         utils.AggregatedLogger
+}
+
+// NewFileAccess will create a new fileAccess object representation of domain.FileAccess interface
+func NewFileAccess(gr domain.FileGrantRepository, pr domain.ProjectRepository, az domain.Authorizer) domain.FileAccess {
+	logger := utils.NewAggregatedLogger(constants.Usecase, constants.FileGrant)
+	return &fileAccess{
+		grantRepo:   gr,
+		projectRepo: pr,
+		authorizer:  az,
+		log:         logger,
+	}
+}
+
+// frozen reports whether access is a write on a dir of a closed project, which nobody gets
+func (a *fileAccess) frozen(ctx context.Context, access string, dir string) (bool, error) {
+	if access != domain.AccessWrite {
+		return false, nil
+	}
+	return a.projectRepo.IsClosedDir(ctx, dir)
+}
+
+// outsider reports whether dir lies in the tree of a project u neither takes part in nor manages
+func (a *fileAccess) outsider(ctx context.Context, u domain.User, dir string) (bool, error) {
+	p, err := a.projectRepo.GetByDir(ctx, dir)
+	if errors.Is(err, domain.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	manager, err := a.authorizer.Can(ctx, u, domain.PermProjectManage)
+	if err != nil || manager {
+		return false, err
+	}
+	member, err := a.projectRepo.IsMember(ctx, p.Uuid, u.Uuid)
+
+	return !member, err
+}
+
+func (a *fileAccess) Allowed(ctx context.Context, u domain.User, access string, dir string, file string) (bool, error) {
+	if file != "" {
+		files, err := a.Filter(ctx, u, access, dir, []domain.File{{Uuid: file, Dir: dir}})
+		return len(files) == 1, err
+	}
+
+	perm, ok := accessPerms[access]
+	if !ok {
+		return false, errors.New(fmt.Sprint("unknown access: ", access))
+	}
+	if scopes, ok := domain.ScopesFromContext(ctx); ok && !inScopes(scopes, perm) {
+		return false, nil
+	}
+	if outsider, err := a.outsider(ctx, u, dir); err != nil || outsider {
+		return false, err
+	}
+	if frozen, err := a.frozen(ctx, access, dir); err != nil || frozen {
+		return false, err
+	}
+
+	allowed, found, err := a.grantRepo.GetDirDecision(ctx, access, u.Uuid, dir)
+	if err != nil || found {
+		return allowed, err
+	}
+
+	return a.authorizer.Can(ctx, u, perm)
+}
+
+func (a *fileAccess) Filter(ctx context.Context, u domain.User, access string, dir string, files []domain.File) (res []domain.File, err error) {
+	res = make([]domain.File, 0, len(files))
+	perm, ok := accessPerms[access]
+	if !ok {
+		return nil, errors.New(fmt.Sprint("unknown access: ", access))
+	}
+	// A scoped api key never reaches beyond its scopes, grants included
+	if scopes, ok := domain.ScopesFromContext(ctx); ok && !inScopes(scopes, perm) {
+		return
+	}
+	// Grants do not reach the outsiders of a project
+	outsider, err := a.outsider(ctx, u, dir)
+	if err != nil {
+		a.log.Error("IN [Filter]: could not resolve project membership ->", err)
+		return nil, err
+	}
+	if outsider {
+		return
+	}
+	frozen, err := a.frozen(ctx, access, dir)
+	if err != nil {
+		a.log.Error("IN [Filter]: could not resolve project state ->", err)
+		return nil, err
+	}
+	if frozen {
+		return
+	}
+
+	dirAllowed, dirFound, err := a.grantRepo.GetDirDecision(ctx, access, u.Uuid, dir)
+	if err != nil {
+		a.log.Error("IN [Filter]: could not resolve dir grants ->", err)
+		return nil, err
+	}
+	if dirFound && !dirAllowed {
+		return
+	}
+
+	uuids := make([]string, 0, len(files))
+	for _, f := range files {
+		uuids = append(uuids, f.Uuid)
+	}
+	decisions, err := a.grantRepo.GetFileDecisions(ctx, access, u.Uuid, uuids)
+	if err != nil {
+		a.log.Error("IN [Filter]: could not resolve file grants ->", err)
+		return nil, err
+	}
+
+	// Files without a grant of their own inherit the dir allow, or the role default
+	fallback := dirFound
+	if !dirFound {
+		fallback, err = a.authorizer.Can(ctx, u, perm)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, f := range files {
+		allowed, explicit := decisions[f.Uuid]
+		if (explicit && allowed) || (!explicit && fallback) {
+			res = append(res, f)
+		}
+	}
+
+	return
+}
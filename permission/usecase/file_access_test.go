@@ -0,0 +1,93 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/permission/usecase"
+)
+
+var (
+	member   = domain.User{Uuid: "u-ana", Username: "ana"}
+	outsider = domain.User{Uuid: "u-bob", Username: "bob"}
+	manager  = domain.User{Uuid: "u-boss", Username: "boss"}
+)
+
+// The project dir holds a dir grant for everyone, a plain dir holds none
+const (
+	projectDir = "d-apollo-specs"
+	plainDir   = "d-shared"
+)
+
+type fakeGrantRepo struct{ domain.FileGrantRepository }
+
+func (fakeGrantRepo) GetDirDecision(ctx context.Context, access string, user string, dir string) (bool, bool, error) {
+	return dir == projectDir, dir == projectDir, nil
+}
+
+func (fakeGrantRepo) GetFileDecisions(ctx context.Context, access string, user string, files []string) (map[string]bool, error) {
+	return map[string]bool{}, nil
+}
+
+type fakeProjectRepo struct{ domain.ProjectRepository }
+
+func (fakeProjectRepo) GetByDir(ctx context.Context, dir string) (domain.Project, error) {
+	if dir != projectDir {
+		return domain.Project{}, domain.ErrNotFound
+	}
+	return domain.Project{Uuid: "p-apollo"}, nil
+}
+
+func (fakeProjectRepo) IsMember(ctx context.Context, uuid string, userUuid string) (bool, error) {
+	return uuid == "p-apollo" && userUuid == member.Uuid, nil
+}
+
+func (fakeProjectRepo) IsClosedDir(ctx context.Context, dir string) (bool, error) {
+	return false, nil
+}
+
+// fakeAuthorizer gives everyone file.read and boss the management of projects
+type fakeAuthorizer struct{ domain.Authorizer }
+
+func (fakeAuthorizer) Can(ctx context.Context, u domain.User, perm string) (bool, error) {
+	if perm == domain.PermProjectManage {
+		return u.Uuid == manager.Uuid, nil
+	}
+	return perm == domain.PermFileRead, nil
+}
+
+func TestOutsidersGetNoAccessBelowAProjectRoot(t *testing.T) {
+	fa := usecase.NewFileAccess(fakeGrantRepo{}, fakeProjectRepo{}, fakeAuthorizer{})
+	ctx := context.Background()
+	files := []domain.File{{Uuid: "f-1", Dir: projectDir}, {Uuid: "f-2", Dir: projectDir}}
+
+	cases := []struct {
+		u       domain.User
+		dir     string
+		allowed bool
+	}{
+		{member, projectDir, true},
+		{manager, projectDir, true},
+		{outsider, projectDir, false},
+		{outsider, plainDir, true},
+	}
+	for _, c := range cases {
+		allowed, err := fa.Allowed(ctx, c.u, domain.AccessRead, c.dir, "")
+		if err != nil || allowed != c.allowed {
+			t.Errorf("%s on %s: got allowed %v, %v, want %v", c.u.Username, c.dir, allowed, err, c.allowed)
+		}
+		allowed, err = fa.Allowed(ctx, c.u, domain.AccessRead, c.dir, "f-1")
+		if err != nil || allowed != c.allowed {
+			t.Errorf("%s on a file of %s: got allowed %v, %v, want %v", c.u.Username, c.dir, allowed, err, c.allowed)
+		}
+
+		res, err := fa.Filter(ctx, c.u, domain.AccessRead, c.dir, files)
+		if err != nil {
+			t.Fatalf("%s filtering %s: %v", c.u.Username, c.dir, err)
+		}
+		if want := map[bool]int{true: 2, false: 0}[c.allowed]; len(res) != want {
+			t.Errorf("%s filtering %s: kept %d files, want %d", c.u.Username, c.dir, len(res), want)
+		}
+	}
+}
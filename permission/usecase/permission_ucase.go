@@ -0,0 +1,142 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+)
+
+type permissionUsecase struct {
+	permRepo       domain.PermissionRepository
+	roleRepo       domain.RoleRepository
+	authorizer     domain.Authorizer
+	contextTimeout time.Duration
+	log            utils.AggregatedLogger
+}
+
+// NewPermissionUsecase will create a new permissionUsecase object representation of domain.PermissionUsecase interface
+func NewPermissionUsecase(
+	pr domain.PermissionRepository,
+	rr domain.RoleRepository,
+	az domain.Authorizer,
+	timeout time.Duration,
+) domain.PermissionUsecase {
+	logger := utils.NewAggregatedLogger(constants.Usecase, constants.Permission)
+	return &permissionUsecase{
+		permRepo:       pr,
+		roleRepo:       rr,
+		authorizer:     az,
+		contextTimeout: timeout,
+		log:            logger,
+	}
+}
+
+func (u *permissionUsecase) Fetch(c context.Context) (res []domain.Permission, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	res, err := u.permRepo.GetAll(ctx)
+	if err != nil {
+		u.log.Error("IN [Fetch]: could not get permissions ->", err)
+		rErr = domain.NewUCaseErr(http.StatusInternalServerError, err)
+		return
+	}
+
+	return
+}
+
+func (u *permissionUsecase) GetByRole(c context.Context, roleCode int64) (res []domain.Permission, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	if _, err := u.roleRepo.GetByCode(ctx, roleCode); err != nil {
+		u.log.Error("IN [GetByRole]: could not get role ->", err)
+		rErr = domain.NewRepoErr(err, fmt.Sprint("Role not found. code: ", roleCode))
+		return
+	}
+
+	res, err := u.permRepo.GetByRole(ctx, roleCode)
+	if err != nil {
+		u.log.Error("IN [GetByRole]: could not get role permissions ->", err)
+		rErr = domain.NewUCaseErr(http.StatusInternalServerError, err)
+		return
+	}
+
+	return
+}
+
+// resolve the role and permission of a grant, checking the caller holds the permission
+func (u *permissionUsecase) resolve(ctx context.Context, roleCode int64, name string) (r domain.Role, p domain.Permission, rErr domain.RequestErr) {
+	r, err := u.roleRepo.GetByCode(ctx, roleCode)
+	if err != nil {
+		u.log.Error("IN [resolve]: could not get role ->", err)
+		rErr = domain.NewRepoErr(err, fmt.Sprint("Role not found. code: ", roleCode))
+		return
+	}
+
+	p, err = u.permRepo.GetByName(ctx, name)
+	if err != nil {
+		u.log.Error("IN [resolve]: could not get permission ->", err)
+		rErr = domain.NewRepoErr(err, fmt.Sprint("Permission not found. name: ", name))
+		return
+	}
+
+	// Nobody can hand out or take away a permission they do not hold
+	rErr = u.authorizer.Authorize(ctx, name)
+	return
+}
+
+func (u *permissionUsecase) Grant(c context.Context, roleCode int64, name string) (rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	_, p, rErr := u.resolve(ctx, roleCode, name)
+	if rErr != nil {
+		return
+	}
+
+	err := u.permRepo.Grant(ctx, roleCode, p.Code)
+	// Only a role or permission deleted since resolve breaks the grant's references
+	if errors.Is(err, domain.ErrBadParamInput) {
+		err = errors.New(fmt.Sprint("Role or permission removed while granting: ", name))
+		return domain.NewUCaseErr(http.StatusNotFound, err)
+	}
+	if err != nil {
+		u.log.Error("IN [Grant]: could not grant permission ->", err)
+		rErr = domain.NewRepoErr(err, fmt.Sprint("Role not found. code: ", roleCode))
+		return
+	}
+
+	return
+}
+
+func (u *permissionUsecase) Revoke(c context.Context, roleCode int64, name string) (rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	_, p, rErr := u.resolve(ctx, roleCode, name)
+	if rErr != nil {
+		return
+	}
+
+	// Someone active must always be able to manage roles, the repository checks it with the write
+	err := u.permRepo.Revoke(ctx, roleCode, p.Code, domain.PermRoleManage)
+	if errors.Is(err, domain.ErrLastHolder) {
+		err = errors.New("Can not remove the last users allowed to manage roles")
+		return domain.NewUCaseErr(http.StatusConflict, err)
+	}
+	if err != nil {
+		u.log.Error("IN [Revoke]: could not revoke permission ->", err)
+		err = errors.New("Permission revoke failed")
+		rErr = domain.NewUCaseErr(http.StatusInternalServerError, err)
+		return
+	}
+
+	return
+}
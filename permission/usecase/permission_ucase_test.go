@@ -0,0 +1,67 @@
+package usecase_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/permission/usecase"
+)
+
+type fakeRoleRepo struct{ domain.RoleRepository }
+
+func (fakeRoleRepo) GetByCode(ctx context.Context, code int64) (domain.Role, error) {
+	if code != 2 {
+		return domain.Role{}, domain.ErrNotFound
+	}
+	return domain.Role{Code: 2, Description: "editor"}, nil
+}
+
+// fakePermRepo answers every grant with err
+type fakePermRepo struct {
+	domain.PermissionRepository
+	err error
+}
+
+func (fakePermRepo) GetByName(ctx context.Context, name string) (domain.Permission, error) {
+	return domain.Permission{Code: 7, Name: name}, nil
+}
+
+func (r fakePermRepo) Grant(ctx context.Context, roleCode int64, permCode int64) error {
+	return r.err
+}
+
+type allowAll struct{ domain.Authorizer }
+
+func (allowAll) Authorize(ctx context.Context, perm string) domain.RequestErr {
+	return nil
+}
+
+func TestGrantStatuses(t *testing.T) {
+	cases := []struct {
+		role   int64
+		err    error
+		status int
+		msg    string
+	}{
+		{role: 2},
+		{role: 9, status: http.StatusNotFound, msg: "Role not found"},
+		{role: 2, err: domain.ErrBadParamInput, status: http.StatusNotFound, msg: "removed while granting"},
+	}
+	for _, c := range cases {
+		pu := usecase.NewPermissionUsecase(fakePermRepo{err: c.err}, fakeRoleRepo{}, allowAll{}, time.Second)
+		rErr := pu.Grant(context.Background(), c.role, domain.PermFileRead)
+		if c.status == 0 {
+			if rErr != nil {
+				t.Errorf("grant to role %d: %v", c.role, rErr)
+			}
+			continue
+		}
+		if rErr == nil || rErr.GetStatus() != c.status || !strings.Contains(rErr.Error(), c.msg) {
+			t.Errorf("grant to role %d with %v: got %v, want %d %q", c.role, c.err, rErr, c.status, c.msg)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sicozz/papyrus/auth/delivery/http/middleware"
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/domain/dtos"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+	"github.com/sicozz/papyrus/utils/delivery"
+)
+
+// PlanHandler will initialize the plan/ resources endpoint
+type PlanHandler struct {
+	PUsecase domain.PlanUsecase
+	log      utils.AggregatedLogger
+}
+
+func NewPlanHandler(e *echo.Echo, pu domain.PlanUsecase, authMw echo.MiddlewareFunc, authz *middleware.AuthzMiddleware) {
+	logger := utils.NewAggregatedLogger(constants.Delivery, constants.Plan)
+	handler := &PlanHandler{pu, logger}
+	manage := authz.Require(middleware.Can(domain.PermProjectManage))
+	g := e.Group("/plan", authMw)
+	g.GET("", handler.Fetch)
+	g.POST("", handler.Store)
+	g.GET("/:uuid", handler.GetByUuid)
+	g.PATCH("/:uuid", handler.Update)
+	g.DELETE("/:uuid", handler.Delete, manage)
+	g.PATCH("/:uuid/state", handler.ChgState)
+	g.PUT("/:uuid/assignee/:uname", handler.Assign)
+	g.DELETE("/:uuid/assignee", handler.Unassign)
+}
+
+func (h *PlanHandler) Fetch(c echo.Context) error {
+	h.log.Info("REQ: fetch")
+	ctx := c.Request().Context()
+	var qDto dtos.PlanQueryDto
+	err := c.Bind(&qDto)
+	if err != nil {
+		errBody := dtos.NewErrDto(fmt.Sprint("Req query binding failed: ", err))
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	if ok, err := delivery.IsRequestValid(&qDto); !ok {
+		errBody, err := dtos.NewValidationErrDto(err.Error())
+		if err != nil {
+			errValid := dtos.NewErrDto(fmt.Sprint("Req query validation failed: ", err))
+			return c.JSON(http.StatusBadRequest, errValid)
+		}
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	f := domain.PlanFilter{
+		Project:  qDto.Project,
+		State:    qDto.State,
+		Assignee: qDto.Assignee,
+	}
+	if qDto.Overdue != "" {
+		overdue := qDto.Overdue == "true"
+		f.Overdue = &overdue
+	}
+
+	plans, rErr := h.PUsecase.Fetch(ctx, f)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.JSON(http.StatusOK, plans)
+}
+
+func (h *PlanHandler) GetByUuid(c echo.Context) error {
+	h.log.Info("REQ: get by uuid")
+	ctx := c.Request().Context()
+	uuid := c.Param("uuid")
+	plan, rErr := h.PUsecase.GetByUuid(ctx, uuid)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.JSON(http.StatusOK, plan)
+}
+
+func (h *PlanHandler) Store(c echo.Context) error {
+	h.log.Info("REQ: store")
+	ctx := c.Request().Context()
+	var meta domain.PlanMeta
+	err := c.Bind(&meta)
+	if err != nil {
+		errBody := dtos.NewErrDto(fmt.Sprint("Req body binding failed: ", err))
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	if ok, err := delivery.IsRequestValid(&meta); !ok {
+		errBody, err := dtos.NewValidationErrDto(err.Error())
+		if err != nil {
+			errValid := dtos.NewErrDto(fmt.Sprint("Req body validation failed: ", err))
+			return c.JSON(http.StatusBadRequest, errValid)
+		}
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	plan, rErr := h.PUsecase.Store(ctx, meta)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.JSON(http.StatusCreated, plan)
+}
+
+func (h *PlanHandler) Update(c echo.Context) error {
+	h.log.Info("REQ: update")
+	ctx := c.Request().Context()
+	uuid := c.Param("uuid")
+	var upd domain.PlanUpdate
+	err := c.Bind(&upd)
+	if err != nil {
+		errBody := dtos.NewErrDto(fmt.Sprint("Req body binding failed: ", err))
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	if ok, err := delivery.IsRequestValid(&upd); !ok {
+		errBody, err := dtos.NewValidationErrDto(err.Error())
+		if err != nil {
+			errValid := dtos.NewErrDto(fmt.Sprint("Req body validation failed: ", err))
+			return c.JSON(http.StatusBadRequest, errValid)
+		}
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	plan, rErr := h.PUsecase.Update(ctx, uuid, upd)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.JSON(http.StatusOK, plan)
+}
+
+func (h *PlanHandler) ChgState(c echo.Context) error {
+	h.log.Info("REQ: change state")
+	ctx := c.Request().Context()
+	uuid := c.Param("uuid")
+	var sDto dtos.PlanStateDto
+	err := c.Bind(&sDto)
+	if err != nil {
+		errBody := dtos.NewErrDto(fmt.Sprint("Req body binding failed: ", err))
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	if ok, err := delivery.IsRequestValid(&sDto); !ok {
+		errBody, err := dtos.NewValidationErrDto(err.Error())
+		if err != nil {
+			errValid := dtos.NewErrDto(fmt.Sprint("Req body validation failed: ", err))
+			return c.JSON(http.StatusBadRequest, errValid)
+		}
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	rErr := h.PUsecase.ChgState(ctx, uuid, sDto.State)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+func (h *PlanHandler) Assign(c echo.Context) error {
+	h.log.Info("REQ: assign")
+	ctx := c.Request().Context()
+	uuid := c.Param("uuid")
+	uname := c.Param("uname")
+	rErr := h.PUsecase.Assign(ctx, uuid, uname)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+func (h *PlanHandler) Unassign(c echo.Context) error {
+	h.log.Info("REQ: unassign")
+	ctx := c.Request().Context()
+	uuid := c.Param("uuid")
+	rErr := h.PUsecase.Assign(ctx, uuid, "")
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+func (h *PlanHandler) Delete(c echo.Context) error {
+	h.log.Info("REQ: delete")
+	ctx := c.Request().Context()
+	uuid := c.Param("uuid")
+	rErr := h.PUsecase.Delete(ctx, uuid)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.NoContent(http.StatusOK)
+}
@@ -0,0 +1,271 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+	"github.com/sicozz/papyrus/utils/repository"
+)
+
+// planSelect reads plans with their state description
+const planSelect = `SELECT pl.uuid, pl.title, pl.description, pl.origin, pl.analysis,
+		pl.discovery_date, pl.record_date, pl.termination_date, ps.description,
+		pl.project, pl.issuing_user_, pl.offender_user_, pl.assigned_user_
+	FROM plan pl
+	JOIN plan_state ps ON ps.code = pl.state`
+
+// overdueCond holds for the open plans past their deadline, given the state and the current time
+const overdueCond = `(ps.description = $%d AND pl.termination_date < $%d::timestamp)`
+
+type postgresPlanRepository struct {
+	Conn *sql.DB
+	log  utils.AggregatedLogger
+}
+
+// NewPostgresPlanRepository will create an object that represent the PlanRepository interface
+func NewPostgresPlanRepository(conn *sql.DB) domain.PlanRepository {
+	logger := utils.NewAggregatedLogger(constants.Repository, constants.Plan)
+	return &postgresPlanRepository{conn, logger}
+}
+
+// nullableUuid maps the empty assignee to NULL
+func nullableUuid(uuid string) interface{} {
+	if uuid == "" {
+		return nil
+	}
+	return uuid
+}
+
+func (r *postgresPlanRepository) fetch(ctx context.Context, query string, args ...interface{}) (res []domain.Plan, err error) {
+	rows, err := r.Conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		r.log.Error(err)
+		return nil, repository.MapErr(err)
+	}
+
+	defer func() {
+		errRow := rows.Close()
+		if errRow != nil {
+			r.log.Error(errRow)
+		}
+	}()
+
+	res = make([]domain.Plan, 0)
+	for rows.Next() {
+		t := domain.Plan{}
+		assigned := sql.NullString{}
+		// Get from db
+		err = rows.Scan(
+			&t.Uuid,
+			&t.Title,
+			&t.Description,
+			&t.Origin,
+			&t.Analysis,
+			&t.DiscoveryDate,
+			&t.RecordDate,
+			&t.TerminationDate,
+			&t.State,
+			&t.Project,
+			&t.IssuingUser,
+			&t.OffenderUser,
+			&assigned,
+		)
+
+		if err != nil {
+			r.log.Error("IN [fetch]:", err)
+			return nil, err
+		}
+		t.AssignedUser = assigned.String
+		res = append(res, t)
+	}
+
+	return res, rows.Err()
+}
+
+// Fetch the plans matching f, closest deadline first
+func (r *postgresPlanRepository) Fetch(ctx context.Context, f domain.PlanFilter) (res []domain.Plan, err error) {
+	conds := make([]string, 0)
+	args := make([]interface{}, 0)
+	if f.Project != "" {
+		args = append(args, f.Project)
+		conds = append(conds, fmt.Sprintf("pl.project = $%d", len(args)))
+	}
+	if f.State != "" {
+		args = append(args, f.State)
+		conds = append(conds, fmt.Sprintf("ps.description = $%d", len(args)))
+	}
+	if f.AssigneeUuid != "" {
+		args = append(args, f.AssigneeUuid)
+		conds = append(conds, fmt.Sprintf("pl.assigned_user_ = $%d", len(args)))
+	}
+	if f.Member != "" {
+		args = append(args, f.Member)
+		conds = append(conds, fmt.Sprintf(
+			"pl.project IN (SELECT project FROM project_member WHERE user_ = $%d)",
+			len(args),
+		))
+	}
+	if f.Overdue != nil {
+		args = append(args, domain.PlanStateOpen, repository.FormatCursorTime(f.Now.UTC()))
+		cond := fmt.Sprintf(overdueCond, len(args)-1, len(args))
+		if !*f.Overdue {
+			cond = "NOT " + cond
+		}
+		conds = append(conds, cond)
+	}
+
+	query := planSelect
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	query += " ORDER BY pl.termination_date, pl.uuid"
+
+	res, err = r.fetch(ctx, query, args...)
+	if err != nil {
+		r.log.Error("IN [Fetch]: could not fetch plans ->", err)
+	}
+
+	return
+}
+
+// Get plan by uuid
+func (r *postgresPlanRepository) GetByUuid(ctx context.Context, uuid string) (res domain.Plan, err error) {
+	query := planSelect + ` WHERE pl.uuid = $1`
+
+	plans, err := r.fetch(ctx, query, uuid)
+	if err != nil {
+		return domain.Plan{}, err
+	}
+
+	if len(plans) < 1 {
+		return domain.Plan{}, domain.ErrNotFound
+	}
+
+	res = plans[0]
+
+	return
+}
+
+func (r *postgresPlanRepository) Store(ctx context.Context, p *domain.Plan) (err error) {
+	query :=
+		`INSERT INTO plan (title, description, origin, analysis, discovery_date, record_date,
+			termination_date, state, project, issuing_user_, offender_user_, assigned_user_)
+		VALUES ($1, $2, $3, $4, $5, $6, $7,
+			(SELECT code FROM plan_state WHERE description = $8),
+			$9, $10, $11, $12)
+		RETURNING uuid`
+	err = r.Conn.QueryRowContext(
+		ctx,
+		query,
+		p.Title,
+		p.Description,
+		p.Origin,
+		p.Analysis,
+		p.DiscoveryDate,
+		p.RecordDate,
+		p.TerminationDate,
+		p.State,
+		p.Project,
+		p.IssuingUser,
+		p.OffenderUser,
+		nullableUuid(p.AssignedUser),
+	).Scan(&p.Uuid)
+	if err != nil {
+		r.log.Error("IN [Store]: could not store plan ->", err)
+		return repository.MapErr(err)
+	}
+
+	return
+}
+
+func (r *postgresPlanRepository) Update(ctx context.Context, p *domain.Plan) (err error) {
+	query :=
+		`UPDATE plan SET title = $2, description = $3, origin = $4, analysis = $5,
+			discovery_date = $6, termination_date = $7, offender_user_ = $8
+		WHERE uuid = $1`
+	res, err := r.Conn.ExecContext(
+		ctx,
+		query,
+		p.Uuid,
+		p.Title,
+		p.Description,
+		p.Origin,
+		p.Analysis,
+		p.DiscoveryDate,
+		p.TerminationDate,
+		p.OffenderUser,
+	)
+	if err != nil {
+		r.log.Error("IN [Update]: could not update plan ->", err)
+		return repository.MapErr(err)
+	}
+
+	return repository.CheckAffected(res)
+}
+
+// Assign a plan to a user, or unassign it when userUuid is empty
+func (r *postgresPlanRepository) Assign(ctx context.Context, uuid string, userUuid string) (err error) {
+	res, err := r.Conn.ExecContext(
+		ctx,
+		`UPDATE plan SET assigned_user_ = $2 WHERE uuid = $1`,
+		uuid,
+		nullableUuid(userUuid),
+	)
+	if err != nil {
+		r.log.Error("IN [Assign]: could not assign plan ->", err)
+		return repository.MapErr(err)
+	}
+
+	return repository.CheckAffected(res)
+}
+
+// Change the state of a plan, only when it still is in from
+func (r *postgresPlanRepository) ChgState(ctx context.Context, uuid string, from string, to string) (ok bool, err error) {
+	res, err := r.Conn.ExecContext(
+		ctx,
+		`UPDATE plan SET state = (SELECT code FROM plan_state WHERE description = $3)
+		WHERE uuid = $1 AND state = (SELECT code FROM plan_state WHERE description = $2)`,
+		uuid,
+		from,
+		to,
+	)
+	if err != nil {
+		r.log.Error("IN [ChgState]: could not update state ->", err)
+		return
+	}
+
+	affect, err := res.RowsAffected()
+	return affect == 1, err
+}
+
+func (r *postgresPlanRepository) CountTasks(ctx context.Context, uuid string, state string) (res int64, err error) {
+	err = r.Conn.QueryRowContext(
+		ctx,
+		`SELECT COUNT(*)
+		FROM task t
+		JOIN task_state ts ON ts.code = t.state
+		WHERE t.plan = $1 AND ts.description = $2`,
+		uuid,
+		state,
+	).Scan(&res)
+	if err != nil {
+		r.log.Error("IN [CountTasks]: could not count tasks ->", err)
+	}
+
+	return
+}
+
+func (r *postgresPlanRepository) Delete(ctx context.Context, uuid string) (err error) {
+	res, err := r.Conn.ExecContext(ctx, `DELETE FROM plan WHERE uuid = $1`, uuid)
+	if err != nil {
+		r.log.Error("IN [Delete]: could not delete plan ->", err)
+		return repository.MapDeleteErr(err)
+	}
+
+	return repository.CheckAffected(res)
+}
@@ -0,0 +1,410 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+)
+
+// stateMoves lists the states every plan state can move to
+var stateMoves = map[string][]string{
+	domain.PlanStateOpen:      {domain.PlanStateClosed, domain.PlanStateAbandoned},
+	domain.PlanStateClosed:    {domain.PlanStateOpen},
+	domain.PlanStateAbandoned: {domain.PlanStateOpen},
+}
+
+type planUsecase struct {
+	planRepo       domain.PlanRepository
+	projectRepo    domain.ProjectRepository
+	userRepo       domain.UserRepository
+	authorizer     domain.Authorizer
+	clock          domain.Clock
+	contextTimeout time.Duration
+	log            utils.AggregatedLogger
+}
+
+// NewPlanUsecase will create a new planUsecase object representation of domain.PlanUsecase interface
+func NewPlanUsecase(
+	plr domain.PlanRepository,
+	pr domain.ProjectRepository,
+	ur domain.UserRepository,
+	az domain.Authorizer,
+	clk domain.Clock,
+	timeout time.Duration,
+) domain.PlanUsecase {
+	logger := utils.NewAggregatedLogger(constants.Usecase, constants.Plan)
+	return &planUsecase{
+		planRepo:       plr,
+		projectRepo:    pr,
+		userRepo:       ur,
+		authorizer:     az,
+		clock:          clk,
+		contextTimeout: timeout,
+		log:            logger,
+	}
+}
+
+func canMove(from string, to string) bool {
+	for _, s := range stateMoves[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// checkDates rejects a deadline before the discovery of the non-conformity
+func checkDates(discovery time.Time, termination time.Time) (rErr domain.RequestErr) {
+	if termination.Before(discovery) {
+		err := errors.New("The termination date cannot precede the discovery date")
+		rErr = domain.NewUCaseErr(http.StatusBadRequest, err)
+	}
+
+	return
+}
+
+// caller returns the authenticated user and whether they manage projects
+func (u *planUsecase) caller(ctx context.Context) (user domain.User, manager bool, rErr domain.RequestErr) {
+	user, ok := domain.UserFromContext(ctx)
+	if !ok {
+		return user, false, domain.NewUCaseErr(http.StatusUnauthorized, errors.New("Authentication required"))
+	}
+
+	manager, err := u.authorizer.Can(ctx, user, domain.PermProjectManage)
+	if err != nil {
+		u.log.Error("IN [caller]: could not resolve permissions ->", err)
+		rErr = domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Access check failed"))
+	}
+
+	return
+}
+
+func (u *planUsecase) getUser(ctx context.Context, uname string) (res domain.User, rErr domain.RequestErr) {
+	res, err := u.userRepo.GetByUsername(ctx, uname)
+	if err != nil {
+		rErr = domain.NewRepoErr(err, fmt.Sprint("User not found. username: ", uname))
+	}
+
+	return
+}
+
+// getProject returns the project when user may see it, either as a member or as a manager
+func (u *planUsecase) getProject(ctx context.Context, uuid string, user domain.User, manager bool) (res domain.Project, rErr domain.RequestErr) {
+	res, err := u.projectRepo.GetByUuid(ctx, uuid)
+	visible := err == nil && manager
+	if err == nil && !manager {
+		visible, err = u.projectRepo.IsMember(ctx, uuid, user.Uuid)
+		if err != nil {
+			u.log.Error("IN [getProject]: could not check membership ->", err)
+			return res, domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Access check failed"))
+		}
+	}
+	if !visible {
+		err = errors.New(fmt.Sprint("Project not found. uuid: ", uuid))
+		return domain.Project{}, domain.NewUCaseErr(http.StatusNotFound, err)
+	}
+
+	return
+}
+
+// checkMember fails when user is not a member of the project
+func (u *planUsecase) checkMember(ctx context.Context, project string, user domain.User) (rErr domain.RequestErr) {
+	member, err := u.projectRepo.IsMember(ctx, project, user.Uuid)
+	if err != nil {
+		u.log.Error("IN [checkMember]: could not check membership ->", err)
+		return domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Access check failed"))
+	}
+	if !member {
+		err = errors.New(fmt.Sprint("User is not a member of the project: ", user.Username))
+		return domain.NewUCaseErr(http.StatusBadRequest, err)
+	}
+
+	return
+}
+
+/*
+* getVisible returns the plan when the authenticated user sees its project.
+* With edit set, the project must not be closed and only the issuer or a
+* manager may go on
+ */
+func (u *planUsecase) getVisible(ctx context.Context, uuid string, edit bool) (res domain.Plan, rErr domain.RequestErr) {
+	user, manager, rErr := u.caller(ctx)
+	if rErr != nil {
+		return
+	}
+
+	res, err := u.planRepo.GetByUuid(ctx, uuid)
+	if err != nil {
+		return res, domain.NewRepoErr(err, fmt.Sprint("Plan not found. uuid: ", uuid))
+	}
+
+	project, rErr := u.getProject(ctx, res.Project, user, manager)
+	if rErr != nil {
+		err = errors.New(fmt.Sprint("Plan not found. uuid: ", uuid))
+		return domain.Plan{}, domain.NewUCaseErr(http.StatusNotFound, err)
+	}
+	res.Overdue = res.State == domain.PlanStateOpen && res.TerminationDate.Before(u.clock.Now())
+
+	if !edit {
+		return
+	}
+	if project.State == domain.ProjectStateClosed {
+		return res, domain.NewUCaseErr(http.StatusConflict, errors.New("The project of the plan is closed"))
+	}
+	if !manager && res.IssuingUser != user.Uuid {
+		return res, domain.NewUCaseErr(http.StatusForbidden, errors.New("Only the issuer or a project manager can change the plan"))
+	}
+
+	return
+}
+
+func (u *planUsecase) Fetch(c context.Context, f domain.PlanFilter) (res []domain.Plan, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	user, manager, rErr := u.caller(ctx)
+	if rErr != nil {
+		return
+	}
+	if !manager {
+		f.Member = user.Uuid
+	}
+
+	if f.Assignee != "" {
+		assignee, rErr := u.getUser(ctx, f.Assignee)
+		if rErr != nil {
+			return nil, domain.NewUCaseErr(http.StatusBadRequest, rErr)
+		}
+		f.AssigneeUuid = assignee.Uuid
+	}
+
+	f.Now = u.clock.Now()
+	res, err := u.planRepo.Fetch(ctx, f)
+	if err != nil {
+		u.log.Error("IN [Fetch]: could not fetch plans ->", err)
+		return nil, domain.NewUCaseErr(http.StatusInternalServerError, err)
+	}
+
+	for i := range res {
+		res[i].Overdue = res[i].State == domain.PlanStateOpen && res[i].TerminationDate.Before(f.Now)
+	}
+
+	return
+}
+
+func (u *planUsecase) GetByUuid(c context.Context, uuid string) (res domain.Plan, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	return u.getVisible(ctx, uuid, false)
+}
+
+func (u *planUsecase) Store(c context.Context, m domain.PlanMeta) (res domain.Plan, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	user, manager, rErr := u.caller(ctx)
+	if rErr != nil {
+		return
+	}
+
+	project, rErr := u.getProject(ctx, m.Project, user, manager)
+	if rErr != nil {
+		return
+	}
+	if project.State == domain.ProjectStateClosed {
+		return res, domain.NewUCaseErr(http.StatusConflict, errors.New("Plans cannot be raised on a closed project"))
+	}
+
+	if rErr = checkDates(m.DiscoveryDate, m.TerminationDate); rErr != nil {
+		return
+	}
+
+	offender, rErr := u.getUser(ctx, m.OffenderUser)
+	if rErr != nil {
+		return
+	}
+
+	res = domain.Plan{
+		Title:           m.Title,
+		Description:     m.Description,
+		Origin:          m.Origin,
+		Analysis:        m.Analysis,
+		DiscoveryDate:   m.DiscoveryDate.UTC(),
+		RecordDate:      u.clock.Now(),
+		TerminationDate: m.TerminationDate.UTC(),
+		State:           domain.PlanStateOpen,
+		Project:         project.Uuid,
+		IssuingUser:     user.Uuid,
+		OffenderUser:    offender.Uuid,
+	}
+
+	if m.AssignedUser != "" {
+		assignee, rErr := u.getUser(ctx, m.AssignedUser)
+		if rErr != nil {
+			return domain.Plan{}, rErr
+		}
+		if rErr = u.checkMember(ctx, project.Uuid, assignee); rErr != nil {
+			return domain.Plan{}, rErr
+		}
+		res.AssignedUser = assignee.Uuid
+	}
+
+	err := u.planRepo.Store(ctx, &res)
+	if err != nil {
+		u.log.Error("IN [Store]: could not store plan ->", err)
+		return domain.Plan{}, domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Plan creation failed"))
+	}
+	res.Overdue = res.TerminationDate.Before(res.RecordDate)
+
+	return
+}
+
+func (u *planUsecase) Update(c context.Context, uuid string, upd domain.PlanUpdate) (res domain.Plan, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	res, rErr = u.getVisible(ctx, uuid, true)
+	if rErr != nil {
+		return
+	}
+	if res.State != domain.PlanStateOpen {
+		return res, domain.NewUCaseErr(http.StatusConflict, errors.New("Only open plans can be edited"))
+	}
+
+	if upd.Title != "" {
+		res.Title = upd.Title
+	}
+	if upd.Description != "" {
+		res.Description = upd.Description
+	}
+	if upd.Origin != "" {
+		res.Origin = upd.Origin
+	}
+	if upd.Analysis != "" {
+		res.Analysis = upd.Analysis
+	}
+	if !upd.DiscoveryDate.IsZero() {
+		res.DiscoveryDate = upd.DiscoveryDate.UTC()
+	}
+	if !upd.TerminationDate.IsZero() {
+		res.TerminationDate = upd.TerminationDate.UTC()
+	}
+	if rErr = checkDates(res.DiscoveryDate, res.TerminationDate); rErr != nil {
+		return
+	}
+	if upd.OffenderUser != "" {
+		offender, rErr := u.getUser(ctx, upd.OffenderUser)
+		if rErr != nil {
+			return res, rErr
+		}
+		res.OffenderUser = offender.Uuid
+	}
+
+	err := u.planRepo.Update(ctx, &res)
+	if err != nil {
+		return res, domain.NewRepoErr(err, fmt.Sprint("Plan not found. uuid: ", res.Uuid))
+	}
+	res.Overdue = res.TerminationDate.Before(u.clock.Now())
+
+	return
+}
+
+func (u *planUsecase) Assign(c context.Context, uuid string, uname string) (rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	p, rErr := u.getVisible(ctx, uuid, true)
+	if rErr != nil {
+		return
+	}
+	if p.State != domain.PlanStateOpen {
+		return domain.NewUCaseErr(http.StatusConflict, errors.New("Only open plans can be assigned"))
+	}
+
+	assignee := domain.User{}
+	if uname != "" {
+		if assignee, rErr = u.getUser(ctx, uname); rErr != nil {
+			return
+		}
+		if rErr = u.checkMember(ctx, p.Project, assignee); rErr != nil {
+			return
+		}
+	}
+
+	err := u.planRepo.Assign(ctx, uuid, assignee.Uuid)
+	if err != nil {
+		return domain.NewRepoErr(err, fmt.Sprint("Plan not found. uuid: ", uuid))
+	}
+
+	u.log.Info("IN [Assign]: plan {", uuid, "} assigned to", uname)
+	return
+}
+
+func (u *planUsecase) ChgState(c context.Context, uuid string, state string) (rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	p, rErr := u.getVisible(ctx, uuid, true)
+	if rErr != nil {
+		return
+	}
+	if p.State == state {
+		return
+	}
+	if !canMove(p.State, state) {
+		err := errors.New(fmt.Sprint("Plan cannot move from ", p.State, " to ", state))
+		return domain.NewUCaseErr(http.StatusConflict, err)
+	}
+
+	if state == domain.PlanStateClosed {
+		if p.Analysis == "" {
+			return domain.NewUCaseErr(http.StatusConflict, errors.New("A plan cannot be closed without an analysis"))
+		}
+		done, err := u.planRepo.CountTasks(ctx, uuid, domain.TaskStateClosed)
+		if err != nil {
+			return domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Plan state change failed"))
+		}
+		if done == 0 {
+			return domain.NewUCaseErr(http.StatusConflict, errors.New("A plan cannot be closed before one of its tasks is"))
+		}
+	}
+
+	ok, err := u.planRepo.ChgState(ctx, uuid, p.State, state)
+	if err != nil {
+		u.log.Error("IN [ChgState]: could not change state ->", err)
+		return domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Plan state change failed"))
+	}
+	if !ok {
+		return domain.NewUCaseErr(http.StatusConflict, errors.New("Plan state changed meanwhile, try again"))
+	}
+
+	u.log.Info("IN [ChgState]: plan {", uuid, "} moved from", p.State, "to", state)
+	return
+}
+
+func (u *planUsecase) Delete(c context.Context, uuid string) (rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	if _, rErr = u.getVisible(ctx, uuid, true); rErr != nil {
+		return
+	}
+
+	err := u.planRepo.Delete(ctx, uuid)
+	if errors.Is(err, domain.ErrConflict) {
+		return domain.NewUCaseErr(http.StatusConflict, errors.New("Plan still has tasks"))
+	}
+	if err != nil {
+		return domain.NewRepoErr(err, fmt.Sprint("Plan not found. uuid: ", uuid))
+	}
+
+	u.log.Info("IN [Delete]: deleted plan {", uuid, "}")
+	return
+}
@@ -0,0 +1,187 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sicozz/papyrus/auth/delivery/http/middleware"
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/domain/dtos"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+	"github.com/sicozz/papyrus/utils/delivery"
+)
+
+// ProjectHandler will initialize the project/ resources endpoint
+type ProjectHandler struct {
+	PUsecase domain.ProjectUsecase
+	log      utils.AggregatedLogger
+}
+
+func NewProjectHandler(e *echo.Echo, pu domain.ProjectUsecase, authMw echo.MiddlewareFunc, authz *middleware.AuthzMiddleware) {
+	logger := utils.NewAggregatedLogger(constants.Delivery, constants.Project)
+	handler := &ProjectHandler{pu, logger}
+	manage := authz.Require(middleware.Can(domain.PermProjectManage))
+	g := e.Group("/project", authMw)
+	g.GET("", handler.Fetch)
+	g.POST("", handler.Store, manage)
+	g.GET("/:uuid", handler.GetByUuid)
+	g.PATCH("/:uuid", handler.Update, manage)
+	g.PATCH("/:uuid/state", handler.ChgState, manage)
+	g.GET("/:uuid/member", handler.GetMembers)
+	g.PUT("/:uuid/member/:uname", handler.AddMember, manage)
+	g.DELETE("/:uuid/member/:uname", handler.RemoveMember, manage)
+}
+
+func (h *ProjectHandler) Fetch(c echo.Context) error {
+	h.log.Info("REQ: fetch")
+	ctx := c.Request().Context()
+	projects, rErr := h.PUsecase.Fetch(ctx)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.JSON(http.StatusOK, projects)
+}
+
+func (h *ProjectHandler) GetByUuid(c echo.Context) error {
+	h.log.Info("REQ: get by uuid")
+	ctx := c.Request().Context()
+	uuid := c.Param("uuid")
+	project, rErr := h.PUsecase.GetByUuid(ctx, uuid)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.JSON(http.StatusOK, project)
+}
+
+func (h *ProjectHandler) Store(c echo.Context) error {
+	h.log.Info("REQ: store")
+	ctx := c.Request().Context()
+	var project domain.Project
+	err := c.Bind(&project)
+	if err != nil {
+		errBody := dtos.NewErrDto(fmt.Sprint("Req body binding failed: ", err))
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	if ok, err := delivery.IsRequestValid(&project); !ok {
+		errBody, err := dtos.NewValidationErrDto(err.Error())
+		if err != nil {
+			errValid := dtos.NewErrDto(fmt.Sprint("Req body validation failed: ", err))
+			return c.JSON(http.StatusBadRequest, errValid)
+		}
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	rErr := h.PUsecase.Store(ctx, &project)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.JSON(http.StatusCreated, project)
+}
+
+func (h *ProjectHandler) Update(c echo.Context) error {
+	h.log.Info("REQ: update")
+	ctx := c.Request().Context()
+	uuid := c.Param("uuid")
+	var uDto dtos.ProjectUpdateDto
+	err := c.Bind(&uDto)
+	if err != nil {
+		errBody := dtos.NewErrDto(fmt.Sprint("Req body binding failed: ", err))
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	if ok, err := delivery.IsRequestValid(&uDto); !ok {
+		errBody, err := dtos.NewValidationErrDto(err.Error())
+		if err != nil {
+			errValid := dtos.NewErrDto(fmt.Sprint("Req body validation failed: ", err))
+			return c.JSON(http.StatusBadRequest, errValid)
+		}
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	rErr := h.PUsecase.Update(ctx, uuid, uDto.Name, uDto.Description)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+func (h *ProjectHandler) ChgState(c echo.Context) error {
+	h.log.Info("REQ: change state")
+	ctx := c.Request().Context()
+	uuid := c.Param("uuid")
+	var sDto dtos.ProjectStateDto
+	err := c.Bind(&sDto)
+	if err != nil {
+		errBody := dtos.NewErrDto(fmt.Sprint("Req body binding failed: ", err))
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	if ok, err := delivery.IsRequestValid(&sDto); !ok {
+		errBody, err := dtos.NewValidationErrDto(err.Error())
+		if err != nil {
+			errValid := dtos.NewErrDto(fmt.Sprint("Req body validation failed: ", err))
+			return c.JSON(http.StatusBadRequest, errValid)
+		}
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	rErr := h.PUsecase.ChgState(ctx, uuid, sDto.State)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+func (h *ProjectHandler) GetMembers(c echo.Context) error {
+	h.log.Info("REQ: get members")
+	ctx := c.Request().Context()
+	uuid := c.Param("uuid")
+	members, rErr := h.PUsecase.GetMembers(ctx, uuid)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.JSON(http.StatusOK, members)
+}
+
+func (h *ProjectHandler) AddMember(c echo.Context) error {
+	h.log.Info("REQ: add member")
+	ctx := c.Request().Context()
+	uuid := c.Param("uuid")
+	uname := c.Param("uname")
+	rErr := h.PUsecase.AddMember(ctx, uuid, uname)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+func (h *ProjectHandler) RemoveMember(c echo.Context) error {
+	h.log.Info("REQ: remove member")
+	ctx := c.Request().Context()
+	uuid := c.Param("uuid")
+	uname := c.Param("uname")
+	rErr := h.PUsecase.RemoveMember(ctx, uuid, uname)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.NoContent(http.StatusOK)
+}
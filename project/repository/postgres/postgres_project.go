@@ -0,0 +1,346 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+	"github.com/sicozz/papyrus/utils/repository"
+)
+
+// projectSelect reads projects with their state description
+const projectSelect = `SELECT p.uuid, p.name, p.description, ps.description, p.dir
+	FROM project p
+	JOIN project_state ps ON ps.code = p.state`
+
+// dirTreeLock is the lock the dir repository takes to rewrite paths, renaming a root dir takes it too
+const dirTreeLock int64 = 0x646972
+
+type postgresProjectRepository struct {
+	Conn *sql.DB
+	log  utils.AggregatedLogger
+}
+
+// NewPostgresProjectRepository will create an object that represent the ProjectRepository interface
+func NewPostgresProjectRepository(conn *sql.DB) domain.ProjectRepository {
+	logger := utils.NewAggregatedLogger(constants.Repository, constants.Project)
+	return &postgresProjectRepository{conn, logger}
+}
+
+func (r *postgresProjectRepository) fetch(ctx context.Context, query string, args ...interface{}) (res []domain.Project, err error) {
+	rows, err := r.Conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		r.log.Error(err)
+		return nil, repository.MapErr(err)
+	}
+
+	defer func() {
+		errRow := rows.Close()
+		if errRow != nil {
+			r.log.Error(errRow)
+		}
+	}()
+
+	res = make([]domain.Project, 0)
+	for rows.Next() {
+		t := domain.Project{}
+		// Get from db
+		err = rows.Scan(
+			&t.Uuid,
+			&t.Name,
+			&t.Description,
+			&t.State,
+			&t.Dir,
+		)
+
+		if err != nil {
+			r.log.Error("IN [fetch]:", err)
+			return nil, err
+		}
+		res = append(res, t)
+	}
+
+	return res, rows.Err()
+}
+
+// Get all the projects
+func (r *postgresProjectRepository) GetAll(ctx context.Context) (res []domain.Project, err error) {
+	query := projectSelect + ` ORDER BY p.name`
+
+	res, err = r.fetch(ctx, query)
+	if err != nil {
+		r.log.Error("IN [GetAll]: could not fetch projects ->", err)
+	}
+
+	return
+}
+
+// Get the projects a user is member of
+func (r *postgresProjectRepository) GetByMember(ctx context.Context, userUuid string) (res []domain.Project, err error) {
+	query := projectSelect + `
+		JOIN project_member pm ON pm.project = p.uuid
+		WHERE pm.user_ = $1
+		ORDER BY p.name`
+
+	res, err = r.fetch(ctx, query, userUuid)
+	if err != nil {
+		r.log.Error("IN [GetByMember]: could not fetch projects ->", err)
+	}
+
+	return
+}
+
+// Get project by uuid
+func (r *postgresProjectRepository) GetByUuid(ctx context.Context, uuid string) (res domain.Project, err error) {
+	query := projectSelect + ` WHERE p.uuid = $1`
+
+	projects, err := r.fetch(ctx, query, uuid)
+	if err != nil {
+		return domain.Project{}, err
+	}
+
+	if len(projects) < 1 {
+		return domain.Project{}, domain.ErrNotFound
+	}
+
+	res = projects[0]
+
+	return
+}
+
+// GetByDir compares paths like IsClosedDir, so it also finds the project of the dirs below a root
+func (r *postgresProjectRepository) GetByDir(ctx context.Context, dir string) (res domain.Project, err error) {
+	query := projectSelect + `
+		JOIN dir pd ON pd.uuid = p.dir
+		JOIN dir d ON d.uuid = $1
+		WHERE d.path = pd.path OR left(d.path, length(pd.path) + 1) = pd.path || '/'
+		ORDER BY length(pd.path) DESC
+		LIMIT 1`
+
+	projects, err := r.fetch(ctx, query, dir)
+	if err != nil {
+		return domain.Project{}, repository.MapErr(err)
+	}
+
+	if len(projects) < 1 {
+		return domain.Project{}, domain.ErrNotFound
+	}
+
+	return projects[0], nil
+}
+
+// Store a new project, its root dir and its first member in one transaction
+func (r *postgresProjectRepository) Store(ctx context.Context, p *domain.Project, owner string) (err error) {
+	tx, err := r.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		r.log.Error("IN [Store]: could not begin transaction ->", err)
+		return
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	// A root needs no tree lock: no rewrite of another tree can reach it
+	err = tx.QueryRowContext(
+		ctx,
+		`INSERT INTO dir (name, parent_dir, path) VALUES ($1, NULL, '/' || $1) RETURNING uuid`,
+		p.Name,
+	).Scan(&p.Dir)
+	if err != nil {
+		r.log.Error("IN [Store]: could not store project dir ->", err)
+		return repository.MapErr(err)
+	}
+
+	err = tx.QueryRowContext(
+		ctx,
+		`INSERT INTO project (name, description, state, dir)
+		VALUES ($1, $2, (SELECT code FROM project_state WHERE description = $3), $4)
+		RETURNING uuid`,
+		p.Name,
+		p.Description,
+		p.State,
+		p.Dir,
+	).Scan(&p.Uuid)
+	if err != nil {
+		r.log.Error("IN [Store]: could not store project ->", err)
+		return repository.MapErr(err)
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO project_member (project, user_) VALUES ($1, $2)`, p.Uuid, owner)
+	if err != nil {
+		r.log.Error("IN [Store]: could not store project owner ->", err)
+		return repository.MapErr(err)
+	}
+
+	return tx.Commit()
+}
+
+// Update the name and description of a project, renaming its root dir and the paths below it
+func (r *postgresProjectRepository) Update(ctx context.Context, uuid string, name string, description string) (err error) {
+	tx, err := r.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		r.log.Error("IN [Update]: could not begin transaction ->", err)
+		return
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if _, err = tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, dirTreeLock); err != nil {
+		r.log.Error("IN [Update]: could not take tree lock ->", err)
+		return
+	}
+
+	var dir, oldPath string
+	err = tx.QueryRowContext(
+		ctx,
+		`SELECT d.uuid, d.path FROM project p JOIN dir d ON d.uuid = p.dir WHERE p.uuid = $1 FOR UPDATE`,
+		uuid,
+	).Scan(&dir, &oldPath)
+	if err != nil {
+		return repository.MapErr(err)
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE project SET name = $2, description = $3 WHERE uuid = $1`, uuid, name, description)
+	if err != nil {
+		r.log.Error("IN [Update]: could not update project ->", err)
+		return repository.MapErr(err)
+	}
+
+	if _, err = tx.ExecContext(ctx, `UPDATE dir SET name = $2 WHERE uuid = $1`, dir, name); err != nil {
+		r.log.Error("IN [Update]: could not rename project dir ->", err)
+		return repository.MapErr(err)
+	}
+
+	newPath := oldPath[:strings.LastIndex(oldPath, "/")+1] + name
+	_, err = tx.ExecContext(
+		ctx,
+		`UPDATE dir
+		SET path = $2 || substr(path, length($1) + 1)
+		WHERE path = $1 OR left(path, length($1) + 1) = $1 || '/'`,
+		oldPath,
+		newPath,
+	)
+	if err != nil {
+		r.log.Error("IN [Update]: could not update paths ->", err)
+		return repository.MapErr(err)
+	}
+
+	return tx.Commit()
+}
+
+// Change the state of a project, only when it still is in from
+func (r *postgresProjectRepository) ChgState(ctx context.Context, uuid string, from string, to string) (ok bool, err error) {
+	res, err := r.Conn.ExecContext(
+		ctx,
+		`UPDATE project SET state = (SELECT code FROM project_state WHERE description = $3)
+		WHERE uuid = $1 AND state = (SELECT code FROM project_state WHERE description = $2)`,
+		uuid,
+		from,
+		to,
+	)
+	if err != nil {
+		r.log.Error("IN [ChgState]: could not update state ->", err)
+		return
+	}
+
+	affect, err := res.RowsAffected()
+	return affect == 1, err
+}
+
+func (r *postgresProjectRepository) IsMember(ctx context.Context, uuid string, userUuid string) (res bool, err error) {
+	err = r.Conn.QueryRowContext(
+		ctx,
+		`SELECT EXISTS(SELECT 1 FROM project_member WHERE project = $1 AND user_ = $2)`,
+		uuid,
+		userUuid,
+	).Scan(&res)
+	if err != nil {
+		r.log.Error("IN [IsMember]: could not check membership ->", err)
+	}
+
+	return
+}
+
+func (r *postgresProjectRepository) GetMembers(ctx context.Context, uuid string) (res []domain.ProjectMember, err error) {
+	rows, err := r.Conn.QueryContext(
+		ctx,
+		`SELECT u.uuid, u.username, u.name, u.lastname
+		FROM project_member pm
+		JOIN user_ u ON u.uuid = pm.user_
+		WHERE pm.project = $1
+		ORDER BY u.username`,
+		uuid,
+	)
+	if err != nil {
+		r.log.Error("IN [GetMembers]: could not fetch members ->", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	res = make([]domain.ProjectMember, 0)
+	for rows.Next() {
+		t := domain.ProjectMember{}
+		err = rows.Scan(&t.UserUuid, &t.Username, &t.Name, &t.Lastname)
+		if err != nil {
+			r.log.Error("IN [GetMembers]:", err)
+			return nil, err
+		}
+		res = append(res, t)
+	}
+
+	return res, rows.Err()
+}
+
+// Add a member to a project, adding an existing one does nothing
+func (r *postgresProjectRepository) AddMember(ctx context.Context, uuid string, userUuid string) (err error) {
+	_, err = r.Conn.ExecContext(
+		ctx,
+		`INSERT INTO project_member (project, user_) VALUES ($1, $2) ON CONFLICT DO NOTHING`,
+		uuid,
+		userUuid,
+	)
+	if err != nil {
+		r.log.Error("IN [AddMember]: could not add member ->", err)
+		return repository.MapErr(err)
+	}
+
+	return
+}
+
+func (r *postgresProjectRepository) RemoveMember(ctx context.Context, uuid string, userUuid string) (err error) {
+	res, err := r.Conn.ExecContext(
+		ctx,
+		`DELETE FROM project_member WHERE project = $1 AND user_ = $2`,
+		uuid,
+		userUuid,
+	)
+	if err != nil {
+		r.log.Error("IN [RemoveMember]: could not remove member ->", err)
+		return repository.MapErr(err)
+	}
+
+	return repository.CheckAffected(res)
+}
+
+// IsClosedDir compares paths, so it also covers the dirs below a project root
+func (r *postgresProjectRepository) IsClosedDir(ctx context.Context, dir string) (res bool, err error) {
+	err = r.Conn.QueryRowContext(
+		ctx,
+		`SELECT EXISTS(
+			SELECT 1
+			FROM dir d, project p
+			JOIN dir pd ON pd.uuid = p.dir
+			JOIN project_state ps ON ps.code = p.state
+			WHERE d.uuid = $1
+				AND ps.description = $2
+				AND (d.path = pd.path OR left(d.path, length(pd.path) + 1) = pd.path || '/')
+		)`,
+		dir,
+		domain.ProjectStateClosed,
+	).Scan(&res)
+	if err != nil {
+		r.log.Error("IN [IsClosedDir]: could not check dir ->", err)
+	}
+
+	return
+}
@@ -0,0 +1,289 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+)
+
+// stateMoves lists the states every project state can move to
+var stateMoves = map[string][]string{
+	domain.ProjectStateInactive: {domain.ProjectStateActive, domain.ProjectStateClosed},
+	domain.ProjectStateActive:   {domain.ProjectStateInactive, domain.ProjectStateClosed},
+	domain.ProjectStateClosed:   {domain.ProjectStateActive},
+}
+
+type projectUsecase struct {
+	projectRepo    domain.ProjectRepository
+	userRepo       domain.UserRepository
+	authorizer     domain.Authorizer
+	contextTimeout time.Duration
+	log            utils.AggregatedLogger
+}
+
+// NewProjectUsecase will create a new projectUsecase object representation of domain.ProjectUsecase interface
+func NewProjectUsecase(
+	pr domain.ProjectRepository,
+	ur domain.UserRepository,
+	az domain.Authorizer,
+	timeout time.Duration,
+) domain.ProjectUsecase {
+	logger := utils.NewAggregatedLogger(constants.Usecase, constants.Project)
+	return &projectUsecase{
+		projectRepo:    pr,
+		userRepo:       ur,
+		authorizer:     az,
+		contextTimeout: timeout,
+		log:            logger,
+	}
+}
+
+func canMove(from string, to string) bool {
+	for _, s := range stateMoves[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// checkName rejects names unusable for the root dir of the project
+func checkName(name string) (rErr domain.RequestErr) {
+	if strings.TrimSpace(name) == "" || name == "." || name == ".." || strings.Contains(name, "/") {
+		err := errors.New(fmt.Sprint("Invalid project name: ", name))
+		rErr = domain.NewUCaseErr(http.StatusBadRequest, err)
+	}
+
+	return
+}
+
+func (u *projectUsecase) get(ctx context.Context, uuid string) (res domain.Project, rErr domain.RequestErr) {
+	res, err := u.projectRepo.GetByUuid(ctx, uuid)
+	if err != nil {
+		rErr = domain.NewRepoErr(err, fmt.Sprint("Project not found. uuid: ", uuid))
+	}
+
+	return
+}
+
+// getVisible returns the project when the authenticated user is a member or a manager
+func (u *projectUsecase) getVisible(ctx context.Context, uuid string) (res domain.Project, rErr domain.RequestErr) {
+	user, ok := domain.UserFromContext(ctx)
+	if !ok {
+		return res, domain.NewUCaseErr(http.StatusUnauthorized, errors.New("Authentication required"))
+	}
+
+	if res, rErr = u.get(ctx, uuid); rErr != nil {
+		return
+	}
+
+	visible, err := u.authorizer.Can(ctx, user, domain.PermProjectManage)
+	if err == nil && !visible {
+		visible, err = u.projectRepo.IsMember(ctx, uuid, user.Uuid)
+	}
+	if err != nil {
+		u.log.Error("IN [getVisible]: could not check membership ->", err)
+		return res, domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Access check failed"))
+	}
+	// Outsiders are not told the project exists
+	if !visible {
+		err = errors.New(fmt.Sprint("Project not found. uuid: ", uuid))
+		return domain.Project{}, domain.NewUCaseErr(http.StatusNotFound, err)
+	}
+
+	return
+}
+
+func (u *projectUsecase) getUser(ctx context.Context, uname string) (res domain.User, rErr domain.RequestErr) {
+	res, err := u.userRepo.GetByUsername(ctx, uname)
+	if err != nil {
+		rErr = domain.NewRepoErr(err, fmt.Sprint("User not found. username: ", uname))
+	}
+
+	return
+}
+
+func (u *projectUsecase) Fetch(c context.Context) (res []domain.Project, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	user, ok := domain.UserFromContext(ctx)
+	if !ok {
+		return res, domain.NewUCaseErr(http.StatusUnauthorized, errors.New("Authentication required"))
+	}
+
+	manager, err := u.authorizer.Can(ctx, user, domain.PermProjectManage)
+	if err == nil {
+		if manager {
+			res, err = u.projectRepo.GetAll(ctx)
+		} else {
+			res, err = u.projectRepo.GetByMember(ctx, user.Uuid)
+		}
+	}
+	if err != nil {
+		u.log.Error("IN [Fetch]: could not fetch projects ->", err)
+		rErr = domain.NewUCaseErr(http.StatusInternalServerError, err)
+	}
+
+	return
+}
+
+func (u *projectUsecase) GetByUuid(c context.Context, uuid string) (res domain.Project, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	return u.getVisible(ctx, uuid)
+}
+
+func (u *projectUsecase) Store(c context.Context, p *domain.Project) (rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	user, ok := domain.UserFromContext(ctx)
+	if !ok {
+		return domain.NewUCaseErr(http.StatusUnauthorized, errors.New("Authentication required"))
+	}
+
+	if rErr = checkName(p.Name); rErr != nil {
+		return
+	}
+
+	p.State = domain.ProjectStateInactive
+	err := u.projectRepo.Store(ctx, p, user.Uuid)
+	if errors.Is(err, domain.ErrConflict) {
+		err = errors.New(fmt.Sprint("A root directory already uses the name: ", p.Name))
+		return domain.NewUCaseErr(http.StatusConflict, err)
+	}
+	if err != nil {
+		u.log.Error("IN [Store]: could not store project ->", err)
+		return domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Project creation failed"))
+	}
+
+	u.log.Info("IN [Store]: created project {", p.Uuid, "} with dir {", p.Dir, "}")
+	return
+}
+
+func (u *projectUsecase) Update(c context.Context, uuid string, name string, description string) (rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	p, rErr := u.get(ctx, uuid)
+	if rErr != nil {
+		return
+	}
+	if p.State == domain.ProjectStateClosed {
+		return domain.NewUCaseErr(http.StatusConflict, errors.New("Closed projects cannot be edited"))
+	}
+
+	// Empty fields keep their current value
+	if name == "" {
+		name = p.Name
+	} else if rErr = checkName(name); rErr != nil {
+		return
+	}
+	if description == "" {
+		description = p.Description
+	}
+	err := u.projectRepo.Update(ctx, uuid, name, description)
+	if errors.Is(err, domain.ErrConflict) {
+		err = errors.New(fmt.Sprint("A root directory already uses the name: ", name))
+		return domain.NewUCaseErr(http.StatusConflict, err)
+	}
+	if err != nil {
+		return domain.NewRepoErr(err, fmt.Sprint("Project not found. uuid: ", uuid))
+	}
+
+	return
+}
+
+func (u *projectUsecase) ChgState(c context.Context, uuid string, state string) (rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	p, rErr := u.get(ctx, uuid)
+	if rErr != nil {
+		return
+	}
+	if p.State == state {
+		return
+	}
+	if !canMove(p.State, state) {
+		err := errors.New(fmt.Sprint("Project cannot move from ", p.State, " to ", state))
+		return domain.NewUCaseErr(http.StatusConflict, err)
+	}
+
+	ok, err := u.projectRepo.ChgState(ctx, uuid, p.State, state)
+	if err != nil {
+		u.log.Error("IN [ChgState]: could not change state ->", err)
+		return domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Project state change failed"))
+	}
+	if !ok {
+		return domain.NewUCaseErr(http.StatusConflict, errors.New("Project state changed meanwhile, try again"))
+	}
+
+	u.log.Info("IN [ChgState]: project {", uuid, "} moved from", p.State, "to", state)
+	return
+}
+
+func (u *projectUsecase) GetMembers(c context.Context, uuid string) (res []domain.ProjectMember, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	if _, rErr = u.getVisible(ctx, uuid); rErr != nil {
+		return
+	}
+
+	res, err := u.projectRepo.GetMembers(ctx, uuid)
+	if err != nil {
+		rErr = domain.NewUCaseErr(http.StatusInternalServerError, err)
+	}
+
+	return
+}
+
+func (u *projectUsecase) AddMember(c context.Context, uuid string, uname string) (rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	if _, rErr = u.get(ctx, uuid); rErr != nil {
+		return
+	}
+	user, rErr := u.getUser(ctx, uname)
+	if rErr != nil {
+		return
+	}
+
+	err := u.projectRepo.AddMember(ctx, uuid, user.Uuid)
+	if err != nil {
+		return domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Member addition failed"))
+	}
+
+	return
+}
+
+func (u *projectUsecase) RemoveMember(c context.Context, uuid string, uname string) (rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	if _, rErr = u.get(ctx, uuid); rErr != nil {
+		return
+	}
+	user, rErr := u.getUser(ctx, uname)
+	if rErr != nil {
+		return
+	}
+
+	err := u.projectRepo.RemoveMember(ctx, uuid, user.Uuid)
+	if err != nil {
+		return domain.NewRepoErr(err, fmt.Sprint("User is not a member. username: ", uname))
+	}
+
+	return
+}
@@ -0,0 +1,143 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sicozz/papyrus/auth/delivery/http/middleware"
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/domain/dtos"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+	"github.com/sicozz/papyrus/utils/delivery"
+)
+
+// RoleHandler will initialize the role/ resources endpoint
+type RoleHandler struct {
+	RUsecase domain.RoleUsecase
+	log      utils.AggregatedLogger
+}
+
+func NewRoleHandler(e *echo.Echo, ru domain.RoleUsecase, authMw echo.MiddlewareFunc, authz *middleware.AuthzMiddleware) {
+	logger := utils.NewAggregatedLogger(constants.Delivery, constants.Role)
+	handler := &RoleHandler{ru, logger}
+	manage := authz.Require(middleware.Can(domain.PermRoleManage))
+	g := e.Group("/role", authMw)
+	g.GET("", handler.Fetch)
+	g.POST("", handler.Store, manage)
+	g.GET("/:code", handler.GetByCode)
+	g.PATCH("/:code", handler.Rename, manage)
+	g.DELETE("/:code", handler.Delete, manage)
+}
+
+func (h *RoleHandler) Fetch(c echo.Context) error {
+	h.log.Info("REQ: fetch")
+	ctx := c.Request().Context()
+	roles, rErr := h.RUsecase.Fetch(ctx)
+	if rErr != nil {
+		errBody := dtos.NewErrDto("Role fetch failed")
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.JSON(http.StatusOK, roles)
+}
+
+func (h *RoleHandler) GetByCode(c echo.Context) error {
+	h.log.Info("REQ: get by code")
+	ctx := c.Request().Context()
+	code, err := strconv.ParseInt(c.Param("code"), 10, 64)
+	if err != nil {
+		errBody := dtos.NewErrDto("Role code must be an integer")
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	role, rErr := h.RUsecase.GetByCode(ctx, code)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.JSON(http.StatusOK, role)
+}
+
+func (h *RoleHandler) Store(c echo.Context) (err error) {
+	h.log.Info("REQ: store")
+	var role domain.Role
+	err = c.Bind(&role)
+	if err != nil {
+		errBody := dtos.NewErrDto(err.Error())
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	if ok, err := delivery.IsRequestValid(&role); !ok {
+		errBody, err := dtos.NewValidationErrDto(err.Error())
+		if err != nil {
+			errParse := dtos.NewErrDto(err.Error())
+			return c.JSON(http.StatusBadRequest, errParse)
+		}
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	ctx := c.Request().Context()
+	rErr := h.RUsecase.Store(ctx, &role)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.JSON(http.StatusCreated, role)
+}
+
+func (h *RoleHandler) Rename(c echo.Context) error {
+	h.log.Info("REQ: rename")
+	ctx := c.Request().Context()
+	code, err := strconv.ParseInt(c.Param("code"), 10, 64)
+	if err != nil {
+		errBody := dtos.NewErrDto("Role code must be an integer")
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	var rDto dtos.RenameDto
+	err = c.Bind(&rDto)
+	if err != nil {
+		errBody := dtos.NewErrDto(fmt.Sprint("Req body binding failed: ", err))
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	if ok, err := delivery.IsRequestValid(&rDto); !ok {
+		errBody, err := dtos.NewValidationErrDto(err.Error())
+		if err != nil {
+			errValid := dtos.NewErrDto(fmt.Sprint("Req body validation failed: ", err))
+			return c.JSON(http.StatusBadRequest, errValid)
+		}
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	rErr := h.RUsecase.Rename(ctx, code, rDto.Description)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+func (h *RoleHandler) Delete(c echo.Context) error {
+	h.log.Info("REQ: delete")
+	ctx := c.Request().Context()
+	code, err := strconv.ParseInt(c.Param("code"), 10, 64)
+	if err != nil {
+		errBody := dtos.NewErrDto("Role code must be an integer")
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	rErr := h.RUsecase.Delete(ctx, code)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.NoContent(http.StatusOK)
+}
@@ -3,12 +3,11 @@ package postgres
 import (
 	"context"
 	"database/sql"
-	"errors"
-	"fmt"
 
 	"github.com/sicozz/papyrus/domain"
 	"github.com/sicozz/papyrus/utils"
 	"github.com/sicozz/papyrus/utils/constants"
+	"github.com/sicozz/papyrus/utils/repository"
 )
 
 type postgresRoleRepository struct {
@@ -30,7 +29,7 @@ func (r *postgresRoleRepository) fetch(ctx context.Context, query string, args .
 	rows, err := r.Conn.QueryContext(ctx, query, args...)
 	if err != nil {
 		r.log.Error(err)
-		return nil, err
+		return nil, repository.MapErr(err)
 	}
 
 	defer func() {
@@ -47,6 +46,7 @@ func (r *postgresRoleRepository) fetch(ctx context.Context, query string, args .
 		err = rows.Scan(
 			&t.Code,
 			&t.Description,
+			&t.Level,
 		)
 
 		if err != nil {
@@ -56,20 +56,19 @@ func (r *postgresRoleRepository) fetch(ctx context.Context, query string, args .
 		res = append(res, t)
 	}
 
-	return res, nil
+	return res, rows.Err()
 }
 
 func (r *postgresRoleRepository) GetByCode(ctx context.Context, code int64) (res domain.Role, err error) {
-	query := `SELECT code, description FROM role WHERE code=$1`
+	query := `SELECT code, description, level FROM role WHERE code=$1`
 	roles, err := r.fetch(ctx, query, code)
 	if err != nil {
 		r.log.Error(err)
 		return domain.Role{}, err
 	}
 
-	if l := len(roles); l != 1 {
-		r.log.Error("Could not find role with code:", code)
-		return domain.Role{}, err
+	if len(roles) < 1 {
+		return domain.Role{}, domain.ErrNotFound
 	}
 
 	res = roles[0]
@@ -77,17 +76,15 @@ func (r *postgresRoleRepository) GetByCode(ctx context.Context, code int64) (res
 }
 
 func (r *postgresRoleRepository) GetByDescription(ctx context.Context, desc string) (res domain.Role, err error) {
-	query := `SELECT code, description FROM role WHERE description=$1`
+	query := `SELECT code, description, level FROM role WHERE description=$1`
 	roles, err := r.fetch(ctx, query, desc)
 	if err != nil {
 		r.log.Error(err)
 		return domain.Role{}, err
 	}
 
-	if l := len(roles); l != 1 {
-		r.log.Error("Could not find role with description:", desc)
-		err = errors.New(fmt.Sprint("No role with description: ", desc))
-		return domain.Role{}, err
+	if len(roles) < 1 {
+		return domain.Role{}, domain.ErrNotFound
 	}
 
 	res = roles[0]
@@ -95,6 +92,42 @@ func (r *postgresRoleRepository) GetByDescription(ctx context.Context, desc stri
 }
 
 func (r *postgresRoleRepository) GetAll(ctx context.Context) ([]domain.Role, error) {
-	query := `SELECT code, description FROM role`
+	query := `SELECT code, description, level FROM role`
 	return r.fetch(ctx, query)
 }
+
+// Store a new role, a taken description is ErrConflict
+func (r *postgresRoleRepository) Store(ctx context.Context, ro *domain.Role) (err error) {
+	query := `INSERT INTO role (description, level) VALUES ($1, $2) RETURNING code`
+	err = r.Conn.QueryRowContext(ctx, query, ro.Description, ro.Level).Scan(&ro.Code)
+	if err != nil {
+		r.log.Error("IN [Store]: could not store role ->", err)
+		err = repository.MapErr(err)
+	}
+
+	return
+}
+
+// Rename a role, a taken description is ErrConflict
+func (r *postgresRoleRepository) Rename(ctx context.Context, code int64, desc string) (err error) {
+	query := `UPDATE role SET description=$1 WHERE code=$2`
+	res, err := r.Conn.ExecContext(ctx, query, desc, code)
+	if err != nil {
+		r.log.Error("IN [Rename]: could not rename role ->", err)
+		return repository.MapErr(err)
+	}
+
+	return repository.CheckAffected(res)
+}
+
+// Delete a role, one still referenced by users is ErrConflict
+func (r *postgresRoleRepository) Delete(ctx context.Context, code int64) (err error) {
+	query := `DELETE FROM role WHERE code=$1`
+	res, err := r.Conn.ExecContext(ctx, query, code)
+	if err != nil {
+		r.log.Error("IN [Delete]: could not delete role ->", err)
+		return repository.MapDeleteErr(err)
+	}
+
+	return repository.CheckAffected(res)
+}
@@ -0,0 +1,166 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+)
+
+type roleUsecase struct {
+	roleRepo       domain.RoleRepository
+	userRepo       domain.UserRepository
+	contextTimeout time.Duration
+	log            utils.AggregatedLogger
+}
+
+// NewRoleUsecase will create a new roleUsecase object representation of domain.RoleUsecase interface
+func NewRoleUsecase(rr domain.RoleRepository, ur domain.UserRepository, timeout time.Duration) domain.RoleUsecase {
+	logger := utils.NewAggregatedLogger(constants.Usecase, constants.Role)
+	return &roleUsecase{
+		roleRepo:       rr,
+		userRepo:       ur,
+		contextTimeout: timeout,
+		log:            logger,
+	}
+}
+
+// The authorization and the totp policy look the base roles up by description
+func isBaseRole(r domain.Role) bool {
+	return r.Description == domain.RoleStandard || r.Description == domain.RoleAdmin || r.Description == domain.RoleSuper
+}
+
+func (u *roleUsecase) Fetch(c context.Context) (res []domain.Role, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	res, err := u.roleRepo.GetAll(ctx)
+	if err != nil {
+		u.log.Error("IN [Fetch]: could not get roles ->", err)
+		rErr = domain.NewUCaseErr(http.StatusInternalServerError, err)
+		return
+	}
+
+	return
+}
+
+func (u *roleUsecase) GetByCode(c context.Context, code int64) (res domain.Role, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	res, err := u.roleRepo.GetByCode(ctx, code)
+	if err != nil {
+		u.log.Error("IN [GetByCode]: could not get role ->", err)
+		rErr = domain.NewRepoErr(err, fmt.Sprint("Role not found. code: ", code))
+		return domain.Role{}, rErr
+	}
+
+	return
+}
+
+func (u *roleUsecase) GetByDescription(c context.Context, desc string) (res domain.Role, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	res, err := u.roleRepo.GetByDescription(ctx, desc)
+	if err != nil {
+		u.log.Error("IN [GetByDescription]: could not get role ->", err)
+		rErr = domain.NewRepoErr(err, fmt.Sprint("Role not found. description: ", desc))
+		return
+	}
+
+	return
+}
+
+func (u *roleUsecase) Store(c context.Context, r *domain.Role) (rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	err := u.roleRepo.Store(ctx, r)
+	if err != nil {
+		u.log.Error("IN [Store]: could not store role ->", err)
+		rErr = domain.NewRepoErr(err, "Role description already taken")
+		return
+	}
+
+	return
+}
+
+func (u *roleUsecase) Rename(c context.Context, code int64, desc string) (rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	r, rErr := u.GetByCode(ctx, code)
+	if rErr != nil {
+		return
+	}
+
+	if isBaseRole(r) {
+		err := errors.New("Base roles can not be renamed")
+		rErr = domain.NewUCaseErr(http.StatusConflict, err)
+		return
+	}
+
+	err := u.roleRepo.Rename(ctx, code, desc)
+	if errors.Is(err, domain.ErrConflict) {
+		err = errors.New("Role description already taken")
+		rErr = domain.NewUCaseErr(http.StatusConflict, err)
+		return
+	}
+	if err != nil {
+		u.log.Error("IN [Rename]: could not rename role ->", err)
+		rErr = domain.NewRepoErr(err, fmt.Sprint("Role not found. code: ", code))
+		return
+	}
+
+	return
+}
+
+func (u *roleUsecase) Delete(c context.Context, code int64) (rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	r, rErr := u.GetByCode(ctx, code)
+	if rErr != nil {
+		return
+	}
+
+	if isBaseRole(r) {
+		err := errors.New("Base roles can not be deleted")
+		rErr = domain.NewUCaseErr(http.StatusConflict, err)
+		return
+	}
+
+	n, err := u.userRepo.CountByRole(ctx, r)
+	if err != nil {
+		u.log.Error("IN [Delete]: could not count role users ->", err)
+		rErr = domain.NewUCaseErr(http.StatusInternalServerError, err)
+		return
+	}
+
+	if n > 0 {
+		err = errors.New(fmt.Sprint("Role still assigned to ", n, " users"))
+		rErr = domain.NewUCaseErr(http.StatusConflict, err)
+		return
+	}
+
+	// A user may have taken the role since it was counted
+	err = u.roleRepo.Delete(ctx, code)
+	if errors.Is(err, domain.ErrConflict) {
+		err = errors.New("Role still assigned to users")
+		rErr = domain.NewUCaseErr(http.StatusConflict, err)
+		return
+	}
+	if err != nil {
+		u.log.Error("IN [Delete]: could not delete role ->", err)
+		rErr = domain.NewRepoErr(err, fmt.Sprint("Role not found. code: ", code))
+		return
+	}
+
+	return
+}
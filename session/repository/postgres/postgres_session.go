@@ -0,0 +1,137 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+	"github.com/sicozz/papyrus/utils/repository"
+)
+
+type postgresSessionRepository struct {
+	Conn *sql.DB
+	log  utils.AggregatedLogger
+}
+
+/*
+* NewPostgresSessionRepository will create an object that represent the
+* SessionRepository interface
+ */
+func NewPostgresSessionRepository(conn *sql.DB) domain.SessionRepository {
+	logger := utils.NewAggregatedLogger(constants.Repository, constants.Session)
+	return &postgresSessionRepository{conn, logger}
+}
+
+func (r *postgresSessionRepository) fetch(ctx context.Context, query string, args ...interface{}) (res []domain.Session, err error) {
+	rows, err := r.Conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		r.log.Error(err)
+		return nil, repository.MapErr(err)
+	}
+
+	defer func() {
+		errRow := rows.Close()
+		if errRow != nil {
+			r.log.Error(errRow)
+		}
+	}()
+
+	res = make([]domain.Session, 0)
+	for rows.Next() {
+		t := domain.Session{}
+		// Get from db
+		err = rows.Scan(
+			&t.Uuid,
+			&t.UserUuid,
+			&t.TokenHash,
+			&t.CreatedAt,
+			&t.ExpiresAt,
+			&t.Revoked,
+		)
+
+		if err != nil {
+			r.log.Error("IN [fetch]:", err)
+			return nil, err
+		}
+		res = append(res, t)
+	}
+
+	return res, rows.Err()
+}
+
+// Get session by the hash of its refresh token
+func (r *postgresSessionRepository) GetByTokenHash(ctx context.Context, hash string) (res domain.Session, err error) {
+	query :=
+		`SELECT uuid, user_, token_hash, created_at, expires_at, revoked
+		FROM session
+		WHERE token_hash = $1`
+
+	sessions, err := r.fetch(ctx, query, hash)
+	if err != nil {
+		return domain.Session{}, err
+	}
+
+	if len(sessions) < 1 {
+		return domain.Session{}, domain.ErrNotFound
+	}
+
+	res = sessions[0]
+
+	return
+}
+
+// Store a new session
+func (r *postgresSessionRepository) Store(ctx context.Context, s *domain.Session) (err error) {
+	query :=
+		`INSERT INTO session (user_, token_hash, expires_at)
+		VALUES ($1, $2, $3)
+		RETURNING uuid, created_at`
+	stmt, err := r.Conn.PrepareContext(ctx, query)
+	if err != nil {
+		r.log.Error("IN [Store]: could not prepare context ->", err)
+		return
+	}
+	defer stmt.Close()
+
+	err = stmt.QueryRowContext(
+		ctx,
+		s.UserUuid,
+		s.TokenHash,
+		s.ExpiresAt,
+	).Scan(&s.Uuid, &s.CreatedAt)
+
+	return
+}
+
+/*
+* Revoke a session. Reports false when the session was already revoked, so
+* concurrent rotations of the same refresh token can be told apart
+ */
+func (r *postgresSessionRepository) Revoke(ctx context.Context, uuid string) (res bool, err error) {
+	query := `UPDATE session SET revoked=true WHERE uuid=$1 AND NOT revoked`
+	result, err := r.Conn.ExecContext(ctx, query, uuid)
+	if err != nil {
+		r.log.Error("IN [Revoke]: could not revoke session ->", err)
+		return
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return
+	}
+
+	return n == 1, nil
+}
+
+// Revoke every session of a user
+func (r *postgresSessionRepository) RevokeAllByUser(ctx context.Context, userUuid string) (err error) {
+	query := `UPDATE session SET revoked=true WHERE user_=$1 AND NOT revoked`
+	_, err = r.Conn.ExecContext(ctx, query, userUuid)
+	if err != nil {
+		r.log.Error("IN [RevokeAllByUser]: could not revoke sessions ->", err)
+	}
+
+	return
+}
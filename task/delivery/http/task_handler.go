@@ -0,0 +1,150 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/domain/dtos"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+	"github.com/sicozz/papyrus/utils/delivery"
+)
+
+// TaskHandler will initialize the task/ resources endpoint
+type TaskHandler struct {
+	TUsecase domain.TaskUsecase
+	log      utils.AggregatedLogger
+}
+
+func NewTaskHandler(e *echo.Echo, tu domain.TaskUsecase, authMw echo.MiddlewareFunc) {
+	logger := utils.NewAggregatedLogger(constants.Delivery, constants.Task)
+	handler := &TaskHandler{tu, logger}
+	g := e.Group("/task", authMw)
+	g.GET("", handler.Fetch)
+	g.POST("", handler.Store)
+	g.GET("/:uuid", handler.GetByUuid)
+	g.PUT("/:uuid/assignee/:uname", handler.Assign)
+	g.POST("/:uuid/close", handler.Close)
+	g.POST("/:uuid/abandon", handler.Abandon)
+}
+
+func (h *TaskHandler) Fetch(c echo.Context) error {
+	h.log.Info("REQ: fetch")
+	ctx := c.Request().Context()
+	var qDto dtos.TaskQueryDto
+	err := c.Bind(&qDto)
+	if err != nil {
+		errBody := dtos.NewErrDto(fmt.Sprint("Req query binding failed: ", err))
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	if ok, err := delivery.IsRequestValid(&qDto); !ok {
+		errBody, err := dtos.NewValidationErrDto(err.Error())
+		if err != nil {
+			errValid := dtos.NewErrDto(fmt.Sprint("Req query validation failed: ", err))
+			return c.JSON(http.StatusBadRequest, errValid)
+		}
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	f := domain.TaskFilter{
+		Scope: qDto.Scope,
+		Plan:  qDto.Plan,
+		State: qDto.State,
+	}
+	if qDto.Overdue != "" {
+		overdue := qDto.Overdue == "true"
+		f.Overdue = &overdue
+	}
+
+	tasks, rErr := h.TUsecase.Fetch(ctx, f)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.JSON(http.StatusOK, tasks)
+}
+
+func (h *TaskHandler) GetByUuid(c echo.Context) error {
+	h.log.Info("REQ: get by uuid")
+	ctx := c.Request().Context()
+	uuid := c.Param("uuid")
+	task, rErr := h.TUsecase.GetByUuid(ctx, uuid)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.JSON(http.StatusOK, task)
+}
+
+func (h *TaskHandler) Store(c echo.Context) error {
+	h.log.Info("REQ: store")
+	ctx := c.Request().Context()
+	var meta domain.TaskMeta
+	err := c.Bind(&meta)
+	if err != nil {
+		errBody := dtos.NewErrDto(fmt.Sprint("Req body binding failed: ", err))
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	if ok, err := delivery.IsRequestValid(&meta); !ok {
+		errBody, err := dtos.NewValidationErrDto(err.Error())
+		if err != nil {
+			errValid := dtos.NewErrDto(fmt.Sprint("Req body validation failed: ", err))
+			return c.JSON(http.StatusBadRequest, errValid)
+		}
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	task, rErr := h.TUsecase.Store(ctx, meta)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.JSON(http.StatusCreated, task)
+}
+
+func (h *TaskHandler) Assign(c echo.Context) error {
+	h.log.Info("REQ: assign")
+	ctx := c.Request().Context()
+	uuid := c.Param("uuid")
+	uname := c.Param("uname")
+	rErr := h.TUsecase.Assign(ctx, uuid, uname)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+func (h *TaskHandler) Close(c echo.Context) error {
+	h.log.Info("REQ: close")
+	ctx := c.Request().Context()
+	uuid := c.Param("uuid")
+	rErr := h.TUsecase.Close(ctx, uuid)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+func (h *TaskHandler) Abandon(c echo.Context) error {
+	h.log.Info("REQ: abandon")
+	ctx := c.Request().Context()
+	uuid := c.Param("uuid")
+	rErr := h.TUsecase.Abandon(ctx, uuid)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.NoContent(http.StatusOK)
+}
@@ -0,0 +1,250 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+	"github.com/sicozz/papyrus/utils/repository"
+)
+
+// taskSelect reads tasks with their state description
+const taskSelect = `SELECT t.uuid, t.title, t.description, t.date, t.deadline, ts.description,
+		t.dir, t.evidence_dir, t.issuing_user, t.assigned_user, t.plan
+	FROM task t
+	JOIN task_state ts ON ts.code = t.state`
+
+// visibleCond holds for the tasks a user issued, is assigned, or sees through the project of their plan
+const visibleCond = `(t.issuing_user = $%[1]d OR t.assigned_user = $%[1]d OR t.plan IN (
+		SELECT pl.uuid FROM plan pl
+		JOIN project_member pm ON pm.project = pl.project
+		WHERE pm.user_ = $%[1]d))`
+
+// overdueCond holds for the open tasks past their deadline, given the state and the current time
+const overdueCond = `(ts.description = $%d AND t.deadline < $%d::timestamp)`
+
+type postgresTaskRepository struct {
+	Conn *sql.DB
+	log  utils.AggregatedLogger
+}
+
+// NewPostgresTaskRepository will create an object that represent the TaskRepository interface
+func NewPostgresTaskRepository(conn *sql.DB) domain.TaskRepository {
+	logger := utils.NewAggregatedLogger(constants.Repository, constants.Task)
+	return &postgresTaskRepository{conn, logger}
+}
+
+// nullableUuid maps the empty assignee or plan to NULL
+func nullableUuid(uuid string) interface{} {
+	if uuid == "" {
+		return nil
+	}
+	return uuid
+}
+
+func (r *postgresTaskRepository) fetch(ctx context.Context, query string, args ...interface{}) (res []domain.Task, err error) {
+	rows, err := r.Conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		r.log.Error(err)
+		return nil, repository.MapErr(err)
+	}
+
+	defer func() {
+		errRow := rows.Close()
+		if errRow != nil {
+			r.log.Error(errRow)
+		}
+	}()
+
+	res = make([]domain.Task, 0)
+	for rows.Next() {
+		t := domain.Task{}
+		assigned := sql.NullString{}
+		plan := sql.NullString{}
+		// Get from db
+		err = rows.Scan(
+			&t.Uuid,
+			&t.Title,
+			&t.Description,
+			&t.Date,
+			&t.Deadline,
+			&t.State,
+			&t.Dir,
+			&t.EvidenceDir,
+			&t.IssuingUser,
+			&assigned,
+			&plan,
+		)
+
+		if err != nil {
+			r.log.Error("IN [fetch]:", err)
+			return nil, err
+		}
+		t.AssignedUser = assigned.String
+		t.Plan = plan.String
+		res = append(res, t)
+	}
+
+	return res, rows.Err()
+}
+
+// Fetch the tasks matching f, closest deadline first
+func (r *postgresTaskRepository) Fetch(ctx context.Context, f domain.TaskFilter) (res []domain.Task, err error) {
+	conds := make([]string, 0)
+	args := make([]interface{}, 0)
+	if f.Assignee != "" {
+		args = append(args, f.Assignee)
+		conds = append(conds, fmt.Sprintf("t.assigned_user = $%d", len(args)))
+	}
+	if f.Issuer != "" {
+		args = append(args, f.Issuer)
+		conds = append(conds, fmt.Sprintf("t.issuing_user = $%d", len(args)))
+	}
+	if f.Plan != "" {
+		args = append(args, f.Plan)
+		conds = append(conds, fmt.Sprintf("t.plan = $%d", len(args)))
+	}
+	if f.State != "" {
+		args = append(args, f.State)
+		conds = append(conds, fmt.Sprintf("ts.description = $%d", len(args)))
+	}
+	if f.Viewer != "" {
+		args = append(args, f.Viewer)
+		conds = append(conds, fmt.Sprintf(visibleCond, len(args)))
+	}
+	if f.Overdue != nil {
+		args = append(args, domain.TaskStateOpen, repository.FormatCursorTime(f.Now.UTC()))
+		cond := fmt.Sprintf(overdueCond, len(args)-1, len(args))
+		if !*f.Overdue {
+			cond = "NOT " + cond
+		}
+		conds = append(conds, cond)
+	}
+
+	query := taskSelect
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	query += " ORDER BY t.deadline, t.uuid"
+
+	res, err = r.fetch(ctx, query, args...)
+	if err != nil {
+		r.log.Error("IN [Fetch]: could not fetch tasks ->", err)
+	}
+
+	return
+}
+
+// Get task by uuid
+func (r *postgresTaskRepository) GetByUuid(ctx context.Context, uuid string) (res domain.Task, err error) {
+	query := taskSelect + ` WHERE t.uuid = $1`
+
+	tasks, err := r.fetch(ctx, query, uuid)
+	if err != nil {
+		return domain.Task{}, err
+	}
+
+	if len(tasks) < 1 {
+		return domain.Task{}, domain.ErrNotFound
+	}
+
+	res = tasks[0]
+
+	return
+}
+
+func (r *postgresTaskRepository) Store(ctx context.Context, t *domain.Task) (err error) {
+	query :=
+		`INSERT INTO task (title, description, date, deadline, state, dir, evidence_dir,
+			issuing_user, assigned_user, plan)
+		VALUES ($1, $2, $3, $4,
+			(SELECT code FROM task_state WHERE description = $5),
+			$6, $7, $8, $9, $10)
+		RETURNING uuid`
+	err = r.Conn.QueryRowContext(
+		ctx,
+		query,
+		t.Title,
+		t.Description,
+		t.Date,
+		t.Deadline,
+		t.State,
+		t.Dir,
+		t.EvidenceDir,
+		t.IssuingUser,
+		nullableUuid(t.AssignedUser),
+		nullableUuid(t.Plan),
+	).Scan(&t.Uuid)
+	if err != nil {
+		r.log.Error("IN [Store]: could not store task ->", err)
+		return repository.MapErr(err)
+	}
+
+	return
+}
+
+func (r *postgresTaskRepository) Assign(ctx context.Context, uuid string, userUuid string) (err error) {
+	res, err := r.Conn.ExecContext(
+		ctx,
+		`UPDATE task SET assigned_user = $2 WHERE uuid = $1`,
+		uuid,
+		nullableUuid(userUuid),
+	)
+	if err != nil {
+		r.log.Error("IN [Assign]: could not assign task ->", err)
+		return repository.MapErr(err)
+	}
+
+	return repository.CheckAffected(res)
+}
+
+// Change the state of a task, only when it still is in from
+func (r *postgresTaskRepository) ChgState(ctx context.Context, uuid string, from string, to string) (ok bool, err error) {
+	res, err := r.Conn.ExecContext(
+		ctx,
+		`UPDATE task SET state = (SELECT code FROM task_state WHERE description = $3)
+		WHERE uuid = $1 AND state = (SELECT code FROM task_state WHERE description = $2)`,
+		uuid,
+		from,
+		to,
+	)
+	if err != nil {
+		r.log.Error("IN [ChgState]: could not update state ->", err)
+		return
+	}
+
+	affect, err := res.RowsAffected()
+	return affect == 1, err
+}
+
+func (r *postgresTaskRepository) CountEvidence(ctx context.Context, uuid string) (res int64, err error) {
+	err = r.Conn.QueryRowContext(
+		ctx,
+		`SELECT COUNT(*)
+		FROM task t
+		JOIN file f ON f.dir = t.evidence_dir
+		JOIN file_state fs ON fs.code = f.state
+		WHERE t.uuid = $1 AND fs.description = $2`,
+		uuid,
+		domain.FileStateActive,
+	).Scan(&res)
+	if err != nil {
+		r.log.Error("IN [CountEvidence]: could not count evidence ->", err)
+	}
+
+	return
+}
+
+func (r *postgresTaskRepository) IsVisible(ctx context.Context, uuid string, userUuid string) (res bool, err error) {
+	query := fmt.Sprintf(`SELECT EXISTS(SELECT 1 FROM task t WHERE t.uuid = $1 AND `+visibleCond+`)`, 2)
+	err = r.Conn.QueryRowContext(ctx, query, uuid, userUuid).Scan(&res)
+	if err != nil {
+		r.log.Error("IN [IsVisible]: could not check visibility ->", err)
+	}
+
+	return
+}
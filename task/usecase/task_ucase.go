@@ -0,0 +1,435 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+)
+
+type taskUsecase struct {
+	taskRepo       domain.TaskRepository
+	planRepo       domain.PlanRepository
+	projectRepo    domain.ProjectRepository
+	dirRepo        domain.DirRepository
+	userRepo       domain.UserRepository
+	access         domain.FileAccess
+	authorizer     domain.Authorizer
+	notifier       domain.Notifier
+	clock          domain.Clock
+	contextTimeout time.Duration
+	log            utils.AggregatedLogger
+}
+
+// NewTaskUsecase will create a new taskUsecase object representation of domain.TaskUsecase interface
+func NewTaskUsecase(
+	tr domain.TaskRepository,
+	plr domain.PlanRepository,
+	pr domain.ProjectRepository,
+	dr domain.DirRepository,
+	ur domain.UserRepository,
+	fa domain.FileAccess,
+	az domain.Authorizer,
+	n domain.Notifier,
+	clk domain.Clock,
+	timeout time.Duration,
+) domain.TaskUsecase {
+	logger := utils.NewAggregatedLogger(constants.Usecase, constants.Task)
+	return &taskUsecase{
+		taskRepo:       tr,
+		planRepo:       plr,
+		projectRepo:    pr,
+		dirRepo:        dr,
+		userRepo:       ur,
+		access:         fa,
+		authorizer:     az,
+		notifier:       n,
+		clock:          clk,
+		contextTimeout: timeout,
+		log:            logger,
+	}
+}
+
+// checkTitle rejects titles unusable as the name of the evidence dir
+func checkTitle(title string) (rErr domain.RequestErr) {
+	if strings.TrimSpace(title) == "" || title == "." || title == ".." || strings.Contains(title, "/") {
+		err := errors.New(fmt.Sprint("Invalid task title: ", title))
+		rErr = domain.NewUCaseErr(http.StatusBadRequest, err)
+	}
+
+	return
+}
+
+/*
+* notifyAssigned tells the assignee of t who handed it to them. Nobody is told
+* about what they assigned to themselves, and a failed delivery never fails
+* the assignment
+ */
+func (u *taskUsecase) notifyAssigned(ctx context.Context, t domain.Task, by domain.User) {
+	if t.AssignedUser == "" || t.AssignedUser == by.Uuid {
+		return
+	}
+
+	n := domain.Notification{
+		User:    t.AssignedUser,
+		Event:   domain.EventTaskAssigned,
+		Subject: fmt.Sprint("Task assigned: ", t.Title),
+		Body:    fmt.Sprint(by.Username, " assigned you the task ", t.Title, ", due ", t.Deadline.Format(time.RFC3339), "."),
+		Ref:     t.Uuid,
+		Date:    u.clock.Now(),
+	}
+	if err := u.notifier.Notify(ctx, n); err != nil {
+		u.log.Error("IN [notifyAssigned]: could not notify {", t.AssignedUser, "} of task {", t.Uuid, "} ->", err)
+	}
+}
+
+// caller returns the authenticated user and whether they manage projects
+func (u *taskUsecase) caller(ctx context.Context) (user domain.User, manager bool, rErr domain.RequestErr) {
+	user, ok := domain.UserFromContext(ctx)
+	if !ok {
+		return user, false, domain.NewUCaseErr(http.StatusUnauthorized, errors.New("Authentication required"))
+	}
+
+	manager, err := u.authorizer.Can(ctx, user, domain.PermProjectManage)
+	if err != nil {
+		u.log.Error("IN [caller]: could not resolve permissions ->", err)
+		rErr = domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Access check failed"))
+	}
+
+	return
+}
+
+func (u *taskUsecase) getUser(ctx context.Context, uname string) (res domain.User, rErr domain.RequestErr) {
+	res, err := u.userRepo.GetByUsername(ctx, uname)
+	if err != nil {
+		rErr = domain.NewRepoErr(err, fmt.Sprint("User not found. username: ", uname))
+	}
+
+	return
+}
+
+// checkWrite fails when user cannot write in dir
+func (u *taskUsecase) checkWrite(ctx context.Context, user domain.User, dir string) (rErr domain.RequestErr) {
+	if _, err := u.dirRepo.GetByUuid(ctx, dir); err != nil {
+		return domain.NewRepoErr(err, fmt.Sprint("Directory not found. uuid: ", dir))
+	}
+
+	allowed, err := u.access.Allowed(ctx, user, domain.AccessWrite, dir, "")
+	if err != nil {
+		u.log.Error("IN [checkWrite]: could not resolve access ->", err)
+		return domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Access check failed"))
+	}
+	if !allowed {
+		return domain.NewUCaseErr(http.StatusForbidden, errors.New(fmt.Sprint("No write access to directory ", dir)))
+	}
+
+	return
+}
+
+// checkMember fails when the task belongs to a plan and user is not in its project
+func (u *taskUsecase) checkMember(ctx context.Context, plan domain.Plan, user domain.User) (rErr domain.RequestErr) {
+	if plan.Uuid == "" {
+		return
+	}
+
+	member, err := u.projectRepo.IsMember(ctx, plan.Project, user.Uuid)
+	if err != nil {
+		u.log.Error("IN [checkMember]: could not check membership ->", err)
+		return domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Access check failed"))
+	}
+	if !member {
+		err = errors.New(fmt.Sprint("User is not a member of the project of the plan: ", user.Username))
+		return domain.NewUCaseErr(http.StatusBadRequest, err)
+	}
+
+	return
+}
+
+// getPlan returns the plan of a task, or an empty one when the task has none
+func (u *taskUsecase) getPlan(ctx context.Context, uuid string) (res domain.Plan, rErr domain.RequestErr) {
+	if uuid == "" {
+		return
+	}
+
+	res, err := u.planRepo.GetByUuid(ctx, uuid)
+	if err != nil {
+		rErr = domain.NewRepoErr(err, fmt.Sprint("Plan not found. uuid: ", uuid))
+	}
+
+	return
+}
+
+/*
+* getOpen returns the open task when the authenticated user sees it. Only
+* the issuer, a manager, or the assignee when assignee is set, may go on
+ */
+func (u *taskUsecase) getOpen(ctx context.Context, uuid string, assignee bool) (res domain.Task, rErr domain.RequestErr) {
+	user, manager, rErr := u.caller(ctx)
+	if rErr != nil {
+		return
+	}
+
+	if res, rErr = u.getVisible(ctx, uuid, user, manager); rErr != nil {
+		return
+	}
+	if !manager && res.IssuingUser != user.Uuid && (!assignee || res.AssignedUser != user.Uuid) {
+		return res, domain.NewUCaseErr(http.StatusForbidden, errors.New("Not allowed to change the task"))
+	}
+	if res.State != domain.TaskStateOpen {
+		err := errors.New(fmt.Sprint("Task is not open, it is ", res.State))
+		return res, domain.NewUCaseErr(http.StatusConflict, err)
+	}
+
+	return
+}
+
+// getVisible returns the task when user issued it, is assigned it, sees its plan or is a manager
+func (u *taskUsecase) getVisible(ctx context.Context, uuid string, user domain.User, manager bool) (res domain.Task, rErr domain.RequestErr) {
+	res, err := u.taskRepo.GetByUuid(ctx, uuid)
+	visible := err == nil && manager
+	if err == nil && !manager {
+		visible, err = u.taskRepo.IsVisible(ctx, uuid, user.Uuid)
+		if err != nil {
+			u.log.Error("IN [getVisible]: could not check visibility ->", err)
+			return res, domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Access check failed"))
+		}
+	}
+	if !visible {
+		err = errors.New(fmt.Sprint("Task not found. uuid: ", uuid))
+		return domain.Task{}, domain.NewUCaseErr(http.StatusNotFound, err)
+	}
+	res.Overdue = res.State == domain.TaskStateOpen && res.Deadline.Before(u.clock.Now())
+
+	return
+}
+
+func (u *taskUsecase) Fetch(c context.Context, f domain.TaskFilter) (res []domain.Task, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	user, manager, rErr := u.caller(ctx)
+	if rErr != nil {
+		return
+	}
+	if !manager {
+		f.Viewer = user.Uuid
+	}
+
+	switch f.Scope {
+	case domain.TaskScopeMine:
+		f.Assignee = user.Uuid
+	case domain.TaskScopeIssued:
+		f.Issuer = user.Uuid
+	}
+
+	f.Now = u.clock.Now()
+	res, err := u.taskRepo.Fetch(ctx, f)
+	if err != nil {
+		u.log.Error("IN [Fetch]: could not fetch tasks ->", err)
+		return nil, domain.NewUCaseErr(http.StatusInternalServerError, err)
+	}
+
+	for i := range res {
+		res[i].Overdue = res[i].State == domain.TaskStateOpen && res[i].Deadline.Before(f.Now)
+	}
+
+	return
+}
+
+func (u *taskUsecase) GetByUuid(c context.Context, uuid string) (res domain.Task, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	user, manager, rErr := u.caller(ctx)
+	if rErr != nil {
+		return
+	}
+
+	return u.getVisible(ctx, uuid, user, manager)
+}
+
+func (u *taskUsecase) Store(c context.Context, m domain.TaskMeta) (res domain.Task, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	user, manager, rErr := u.caller(ctx)
+	if rErr != nil {
+		return
+	}
+
+	if rErr = checkTitle(m.Title); rErr != nil {
+		return
+	}
+	now := u.clock.Now()
+	if m.Deadline.Before(now) {
+		return res, domain.NewUCaseErr(http.StatusBadRequest, errors.New("The deadline cannot be in the past"))
+	}
+
+	if rErr = u.checkWrite(ctx, user, m.Dir); rErr != nil {
+		return
+	}
+
+	plan, rErr := u.getPlan(ctx, m.Plan)
+	if rErr != nil {
+		return
+	}
+	if plan.Uuid != "" {
+		if !manager {
+			if rErr = u.checkMember(ctx, plan, user); rErr != nil {
+				err := errors.New(fmt.Sprint("Plan not found. uuid: ", m.Plan))
+				return res, domain.NewUCaseErr(http.StatusNotFound, err)
+			}
+		}
+		if plan.State != domain.PlanStateOpen {
+			return res, domain.NewUCaseErr(http.StatusConflict, errors.New("Tasks can only be added to open plans"))
+		}
+	}
+
+	res = domain.Task{
+		Title:       m.Title,
+		Description: m.Description,
+		Date:        now,
+		Deadline:    m.Deadline.UTC(),
+		State:       domain.TaskStateOpen,
+		Dir:         m.Dir,
+		EvidenceDir: m.EvidenceDir,
+		IssuingUser: user.Uuid,
+		Plan:        plan.Uuid,
+	}
+
+	if m.AssignedUser != "" {
+		assignee, rErr := u.getUser(ctx, m.AssignedUser)
+		if rErr != nil {
+			return domain.Task{}, rErr
+		}
+		if rErr = u.checkMember(ctx, plan, assignee); rErr != nil {
+			return domain.Task{}, rErr
+		}
+		res.AssignedUser = assignee.Uuid
+	}
+
+	var created *domain.Dir
+	if res.EvidenceDir != "" {
+		if rErr = u.checkWrite(ctx, user, res.EvidenceDir); rErr != nil {
+			return domain.Task{}, rErr
+		}
+	} else {
+		created = &domain.Dir{Name: m.Title, ParentDir: m.Dir}
+		err := u.dirRepo.Store(ctx, created)
+		if errors.Is(err, domain.ErrConflict) {
+			err = errors.New(fmt.Sprint("Directory name already in use for the evidence: ", m.Title))
+			return domain.Task{}, domain.NewUCaseErr(http.StatusConflict, err)
+		}
+		if err != nil {
+			u.log.Error("IN [Store]: could not store evidence dir ->", err)
+			return domain.Task{}, domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Task creation failed"))
+		}
+		res.EvidenceDir = created.Uuid
+	}
+
+	err := u.taskRepo.Store(ctx, &res)
+	if err != nil {
+		u.log.Error("IN [Store]: could not store task ->", err)
+		if created != nil {
+			if errDel := u.dirRepo.Delete(ctx, created.Uuid); errDel != nil {
+				u.log.Error("IN [Store]: could not drop evidence dir {", created.Uuid, "} ->", errDel)
+			}
+		}
+		return domain.Task{}, domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Task creation failed"))
+	}
+
+	u.log.Info("IN [Store]: created task {", res.Uuid, "} with evidence dir {", res.EvidenceDir, "}")
+	u.notifyAssigned(ctx, res, user)
+	return
+}
+
+func (u *taskUsecase) Assign(c context.Context, uuid string, uname string) (rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	t, rErr := u.getOpen(ctx, uuid, false)
+	if rErr != nil {
+		return
+	}
+
+	assignee, rErr := u.getUser(ctx, uname)
+	if rErr != nil {
+		return
+	}
+	if assignee.Uuid == t.AssignedUser {
+		return
+	}
+
+	plan, rErr := u.getPlan(ctx, t.Plan)
+	if rErr != nil {
+		return
+	}
+	if rErr = u.checkMember(ctx, plan, assignee); rErr != nil {
+		return
+	}
+
+	err := u.taskRepo.Assign(ctx, uuid, assignee.Uuid)
+	if err != nil {
+		return domain.NewRepoErr(err, fmt.Sprint("Task not found. uuid: ", uuid))
+	}
+
+	u.log.Info("IN [Assign]: task {", uuid, "} assigned to", uname)
+	t.AssignedUser = assignee.Uuid
+	if user, ok := domain.UserFromContext(ctx); ok {
+		u.notifyAssigned(ctx, t, user)
+	}
+	return
+}
+
+func (u *taskUsecase) Close(c context.Context, uuid string) (rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	t, rErr := u.getOpen(ctx, uuid, true)
+	if rErr != nil {
+		return
+	}
+
+	evidence, err := u.taskRepo.CountEvidence(ctx, uuid)
+	if err != nil {
+		return domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Task close failed"))
+	}
+	if evidence == 0 {
+		err = errors.New(fmt.Sprint("Upload at least one file to the evidence directory ", t.EvidenceDir))
+		return domain.NewUCaseErr(http.StatusConflict, err)
+	}
+
+	return u.chgState(ctx, t, domain.TaskStateClosed)
+}
+
+func (u *taskUsecase) Abandon(c context.Context, uuid string) (rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	t, rErr := u.getOpen(ctx, uuid, false)
+	if rErr != nil {
+		return
+	}
+
+	return u.chgState(ctx, t, domain.TaskStateAbandoned)
+}
+
+func (u *taskUsecase) chgState(ctx context.Context, t domain.Task, state string) (rErr domain.RequestErr) {
+	ok, err := u.taskRepo.ChgState(ctx, t.Uuid, t.State, state)
+	if err != nil {
+		u.log.Error("IN [chgState]: could not change state ->", err)
+		return domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Task state change failed"))
+	}
+	if !ok {
+		return domain.NewUCaseErr(http.StatusConflict, errors.New("Task state changed meanwhile, try again"))
+	}
+
+	u.log.Info("IN [chgState]: task {", t.Uuid, "} moved from", t.State, "to", state)
+	return
+}
@@ -0,0 +1,105 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sicozz/papyrus/auth/delivery/http/middleware"
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/domain/dtos"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+	"github.com/sicozz/papyrus/utils/delivery"
+)
+
+// TotpHandler will initialize the two-factor enrollment endpoints
+type TotpHandler struct {
+	TUsecase domain.TotpUsecase
+	log      utils.AggregatedLogger
+}
+
+func NewTotpHandler(e *echo.Echo, tu domain.TotpUsecase, authMw echo.MiddlewareFunc, authz *middleware.AuthzMiddleware) {
+	logger := utils.NewAggregatedLogger(constants.Delivery, constants.Totp)
+	handler := &TotpHandler{tu, logger}
+	self := authz.Require(middleware.Self("uname"))
+	selfOrWrite := authz.Require(middleware.Self("uname"), middleware.Can(domain.PermUserWrite))
+	g := e.Group("/user/:uname/totp", authMw)
+	g.POST("", handler.Enroll, self)
+	g.POST("/confirm", handler.Confirm, self)
+	g.DELETE("", handler.Disable, selfOrWrite)
+}
+
+func (h *TotpHandler) Enroll(c echo.Context) error {
+	h.log.Info("REQ: enroll")
+	ctx := c.Request().Context()
+	uname := c.Param("uname")
+	enrollment, rErr := h.TUsecase.Enroll(ctx, uname)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.JSON(http.StatusCreated, enrollment)
+}
+
+func (h *TotpHandler) Confirm(c echo.Context) error {
+	h.log.Info("REQ: confirm")
+	ctx := c.Request().Context()
+	uname := c.Param("uname")
+
+	var tDto dtos.TotpCodeDto
+	err := c.Bind(&tDto)
+	if err != nil {
+		errBody := dtos.NewErrDto(fmt.Sprint("Req body binding failed: ", err))
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	if ok, err := delivery.IsRequestValid(&tDto); !ok {
+		errBody, err := dtos.NewValidationErrDto(err.Error())
+		if err != nil {
+			errValid := dtos.NewErrDto(fmt.Sprint("Req body validation failed: ", err))
+			return c.JSON(http.StatusBadRequest, errValid)
+		}
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	codes, rErr := h.TUsecase.Confirm(ctx, uname, tDto.Code)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.JSON(http.StatusOK, map[string][]string{"recovery_codes": codes})
+}
+
+func (h *TotpHandler) Disable(c echo.Context) error {
+	h.log.Info("REQ: disable")
+	ctx := c.Request().Context()
+	uname := c.Param("uname")
+
+	// DELETE bodies are not bound by default
+	var tDto dtos.TotpDisableDto
+	err := (&echo.DefaultBinder{}).BindBody(c, &tDto)
+	if err != nil {
+		errBody := dtos.NewErrDto(fmt.Sprint("Req body binding failed: ", err))
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	if ok, err := delivery.IsRequestValid(&tDto); !ok {
+		errBody, err := dtos.NewValidationErrDto(err.Error())
+		if err != nil {
+			errValid := dtos.NewErrDto(fmt.Sprint("Req body validation failed: ", err))
+			return c.JSON(http.StatusBadRequest, errValid)
+		}
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	rErr := h.TUsecase.Disable(ctx, uname, tDto.Code)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.NoContent(http.StatusOK)
+}
@@ -0,0 +1,163 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+)
+
+type postgresTotpRepository struct {
+	Conn *sql.DB
+	log  utils.AggregatedLogger
+}
+
+/*
+* NewPostgresTotpRepository will create an object that represent the
+* TotpRepository interface
+ */
+func NewPostgresTotpRepository(conn *sql.DB) domain.TotpRepository {
+	logger := utils.NewAggregatedLogger(constants.Repository, constants.Totp)
+	return &postgresTotpRepository{conn, logger}
+}
+
+// Get the enrollment of a user
+func (r *postgresTotpRepository) GetByUser(ctx context.Context, userUuid string) (res domain.Totp, err error) {
+	query :=
+		`SELECT user_, secret, confirmed, last_step, created_at
+		FROM user_totp
+		WHERE user_ = $1`
+
+	err = r.Conn.QueryRowContext(ctx, query, userUuid).Scan(
+		&res.UserUuid,
+		&res.Secret,
+		&res.Confirmed,
+		&res.LastStep,
+		&res.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Totp{}, domain.ErrNotFound
+	}
+	if err != nil {
+		r.log.Error("IN [GetByUser]: could not get enrollment ->", err)
+	}
+
+	return
+}
+
+// Store a new, unconfirmed enrollment replacing any previous one
+func (r *postgresTotpRepository) Store(ctx context.Context, t *domain.Totp) (err error) {
+	query :=
+		`INSERT INTO user_totp (user_, secret, confirmed, last_step, created_at)
+		VALUES ($1, $2, false, 0, $3)
+		ON CONFLICT (user_) DO UPDATE SET
+			secret = EXCLUDED.secret,
+			confirmed = false,
+			last_step = 0,
+			created_at = EXCLUDED.created_at`
+
+	_, err = r.Conn.ExecContext(ctx, query, t.UserUuid, t.Secret, t.CreatedAt)
+	if err != nil {
+		r.log.Error("IN [Store]: could not store enrollment ->", err)
+	}
+
+	return
+}
+
+// Confirm the enrollment of a user
+func (r *postgresTotpRepository) Confirm(ctx context.Context, userUuid string) (err error) {
+	query := `UPDATE user_totp SET confirmed = true WHERE user_ = $1`
+	_, err = r.Conn.ExecContext(ctx, query, userUuid)
+	if err != nil {
+		r.log.Error("IN [Confirm]: could not confirm enrollment ->", err)
+	}
+
+	return
+}
+
+// Delete the enrollment and the recovery codes of a user
+func (r *postgresTotpRepository) Delete(ctx context.Context, userUuid string) (err error) {
+	tx, err := r.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		r.log.Error("IN [Delete]: could not begin transaction ->", err)
+		return
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if _, err = tx.ExecContext(ctx, `DELETE FROM recovery_code WHERE user_ = $1`, userUuid); err != nil {
+		r.log.Error("IN [Delete]: could not delete recovery codes ->", err)
+		return
+	}
+
+	if _, err = tx.ExecContext(ctx, `DELETE FROM user_totp WHERE user_ = $1`, userUuid); err != nil {
+		r.log.Error("IN [Delete]: could not delete enrollment ->", err)
+		return
+	}
+
+	return tx.Commit()
+}
+
+// Record a time step as used, refusing steps not newer than the last one
+func (r *postgresTotpRepository) UseStep(ctx context.Context, userUuid string, step int64) (res bool, err error) {
+	query := `UPDATE user_totp SET last_step = $2 WHERE user_ = $1 AND last_step < $2`
+	result, err := r.Conn.ExecContext(ctx, query, userUuid, step)
+	if err != nil {
+		r.log.Error("IN [UseStep]: could not use step ->", err)
+		return
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return
+	}
+
+	return n == 1, nil
+}
+
+// Replace the recovery codes of a user
+func (r *postgresTotpRepository) StoreRecoveryCodes(ctx context.Context, userUuid string, hashes []string) (err error) {
+	tx, err := r.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		r.log.Error("IN [StoreRecoveryCodes]: could not begin transaction ->", err)
+		return
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if _, err = tx.ExecContext(ctx, `DELETE FROM recovery_code WHERE user_ = $1`, userUuid); err != nil {
+		r.log.Error("IN [StoreRecoveryCodes]: could not delete recovery codes ->", err)
+		return
+	}
+
+	for _, h := range hashes {
+		query := `INSERT INTO recovery_code (user_, code_hash) VALUES ($1, $2)`
+		if _, err = tx.ExecContext(ctx, query, userUuid, h); err != nil {
+			r.log.Error("IN [StoreRecoveryCodes]: could not store recovery code ->", err)
+			return
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Use a recovery code, false when it does not exist or was already used
+func (r *postgresTotpRepository) UseRecoveryCode(ctx context.Context, userUuid string, hash string, now time.Time) (res bool, err error) {
+	query :=
+		`UPDATE recovery_code SET used_at = $3
+		WHERE user_ = $1 AND code_hash = $2 AND used_at IS NULL`
+	result, err := r.Conn.ExecContext(ctx, query, userUuid, hash, now)
+	if err != nil {
+		r.log.Error("IN [UseRecoveryCode]: could not use recovery code ->", err)
+		return
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return
+	}
+
+	return n == 1, nil
+}
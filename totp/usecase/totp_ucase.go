@@ -0,0 +1,331 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+	"github.com/sicozz/papyrus/utils/token"
+	"github.com/sicozz/papyrus/utils/totp"
+)
+
+const (
+	recoveryCodes    = 10
+	recoveryCodeSize = 10
+	totpSkew         = 1
+)
+
+type totpUsecase struct {
+	userRepo       domain.UserRepository
+	totpRepo       domain.TotpRepository
+	authorizer     domain.Authorizer
+	clock          domain.Clock
+	issuer         string
+	requiredRoles  map[string]bool
+	contextTimeout time.Duration
+	log            utils.AggregatedLogger
+}
+
+// NewTotpUsecase will create a new totpUsecase object representation of domain.TotpUsecase interface
+func NewTotpUsecase(
+	ur domain.UserRepository,
+	tr domain.TotpRepository,
+	az domain.Authorizer,
+	clk domain.Clock,
+	issuer string,
+	requiredRoles []string,
+	timeout time.Duration,
+) domain.TotpUsecase {
+	logger := utils.NewAggregatedLogger(constants.Usecase, constants.Totp)
+	required := make(map[string]bool, len(requiredRoles))
+	for _, r := range requiredRoles {
+		required[r] = true
+	}
+
+	return &totpUsecase{
+		userRepo:       ur,
+		totpRepo:       tr,
+		authorizer:     az,
+		clock:          clk,
+		issuer:         issuer,
+		requiredRoles:  required,
+		contextTimeout: timeout,
+		log:            logger,
+	}
+}
+
+// normalizeRecoveryCode makes recovery codes insensitive to case and dashes
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
+
+/*
+* newRecoveryCodes draws 80 bit codes. They are random enough to be stored as a
+* plain SHA-256 and looked up by it, a salted password hash would have to be
+* compared against every stored code
+ */
+func newRecoveryCodes() (codes []string, hashes []string, err error) {
+	enc := base32.StdEncoding.WithPadding(base32.NoPadding)
+	for i := 0; i < recoveryCodes; i++ {
+		b := make([]byte, recoveryCodeSize)
+		if _, err = rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		c := strings.ToLower(enc.EncodeToString(b))
+		codes = append(codes, c[:4]+"-"+c[4:8]+"-"+c[8:12]+"-"+c[12:])
+		hashes = append(hashes, token.Hash(c))
+	}
+
+	return
+}
+
+func (u *totpUsecase) getUser(ctx context.Context, uname string) (res domain.User, rErr domain.RequestErr) {
+	res, err := u.userRepo.GetByUsername(ctx, uname)
+	if err != nil {
+		rErr = domain.NewRepoErr(err, fmt.Sprint("User not found. username: ", uname))
+	}
+
+	return
+}
+
+// checkPrivileges refuses to act on a user whose permissions the actor lacks
+func (u *totpUsecase) checkPrivileges(ctx context.Context, target domain.User) (rErr domain.RequestErr) {
+	if rErr = u.authorizer.Authorize(ctx, domain.PermUserWrite); rErr != nil {
+		return
+	}
+
+	actor, _ := domain.UserFromContext(ctx)
+	ok, err := u.authorizer.Covers(ctx, actor, target.Role)
+	if err != nil {
+		u.log.Error("IN [checkPrivileges]: could not compare roles ->", err)
+		return domain.NewUCaseErr(http.StatusInternalServerError, err)
+	}
+
+	if !ok {
+		err = errors.New("Can not act on a user with permissions you lack")
+		return domain.NewUCaseErr(http.StatusForbidden, err)
+	}
+
+	return
+}
+
+// matchCode checks a TOTP code against the secret, refusing replayed steps
+func (u *totpUsecase) matchCode(ctx context.Context, t domain.Totp, code string) (rErr domain.RequestErr) {
+	step, ok := totp.Match(t.Secret, code, u.clock.Now(), totpSkew)
+	if !ok {
+		return domain.NewUCaseErr(http.StatusUnauthorized, errors.New("Invalid two-factor code"))
+	}
+
+	fresh, err := u.totpRepo.UseStep(ctx, t.UserUuid, step)
+	if err != nil {
+		return domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Two-factor check failed"))
+	}
+	if !fresh {
+		return domain.NewUCaseErr(http.StatusUnauthorized, errors.New("Two-factor code already used"))
+	}
+
+	return
+}
+
+// useRecoveryCode spends the unused recovery code matching code
+func (u *totpUsecase) useRecoveryCode(ctx context.Context, userUuid string, code string) (rErr domain.RequestErr) {
+	hash := token.Hash(normalizeRecoveryCode(code))
+	used, err := u.totpRepo.UseRecoveryCode(ctx, userUuid, hash, u.clock.Now())
+	if err != nil {
+		return domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Two-factor check failed"))
+	}
+	if !used {
+		return domain.NewUCaseErr(http.StatusUnauthorized, errors.New("Invalid two-factor code"))
+	}
+
+	return
+}
+
+// checkCode accepts a TOTP code or, failing the six digit shape, a recovery code
+func (u *totpUsecase) checkCode(ctx context.Context, t domain.Totp, code string) (rErr domain.RequestErr) {
+	if len(code) == 6 {
+		return u.matchCode(ctx, t, code)
+	}
+
+	return u.useRecoveryCode(ctx, t.UserUuid, code)
+}
+
+func (u *totpUsecase) Enroll(c context.Context, uname string) (res domain.TotpEnrollment, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	user, rErr := u.getUser(ctx, uname)
+	if rErr != nil {
+		return
+	}
+
+	old, err := u.totpRepo.GetByUser(ctx, user.Uuid)
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		u.log.Error("IN [Enroll]: could not get totp ->", err)
+		return res, domain.NewRepoErr(err, "")
+	}
+	if err == nil && old.Confirmed {
+		err = errors.New("Two-factor authentication already enabled")
+		rErr = domain.NewUCaseErr(http.StatusConflict, err)
+		return
+	}
+
+	secret, err := totp.NewSecret()
+	if err != nil {
+		u.log.Error("IN [Enroll]: could not generate secret ->", err)
+		rErr = domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Enrollment failed"))
+		return
+	}
+
+	t := domain.Totp{
+		UserUuid:  user.Uuid,
+		Secret:    secret,
+		CreatedAt: u.clock.Now(),
+	}
+	if err = u.totpRepo.Store(ctx, &t); err != nil {
+		rErr = domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Enrollment failed"))
+		return
+	}
+
+	res = domain.TotpEnrollment{
+		Secret: secret,
+		Uri:    totp.URI(u.issuer, user.Username, secret),
+	}
+	return
+}
+
+func (u *totpUsecase) Confirm(c context.Context, uname string, code string) (res []string, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	user, rErr := u.getUser(ctx, uname)
+	if rErr != nil {
+		return
+	}
+
+	t, err := u.totpRepo.GetByUser(ctx, user.Uuid)
+	if err != nil {
+		rErr = domain.NewRepoErr(err, "No pending two-factor enrollment")
+		return
+	}
+	if t.Confirmed {
+		err = errors.New("Two-factor authentication already enabled")
+		rErr = domain.NewUCaseErr(http.StatusConflict, err)
+		return
+	}
+
+	if rErr = u.matchCode(ctx, t, code); rErr != nil {
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		u.log.Error("IN [Confirm]: could not generate recovery codes ->", err)
+		rErr = domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Enrollment failed"))
+		return
+	}
+
+	if err = u.totpRepo.StoreRecoveryCodes(ctx, user.Uuid, hashes); err != nil {
+		rErr = domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Enrollment failed"))
+		return
+	}
+
+	if err = u.totpRepo.Confirm(ctx, user.Uuid); err != nil {
+		rErr = domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Enrollment failed"))
+		return
+	}
+
+	return codes, nil
+}
+
+func (u *totpUsecase) Disable(c context.Context, uname string, code string) (rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	user, rErr := u.getUser(ctx, uname)
+	if rErr != nil {
+		return
+	}
+
+	/*
+	* A stolen session must not be enough to drop the second factor of its
+	* owner. User writers may still reset it for someone who lost their device,
+	* but only for users whose permissions they hold themselves
+	 */
+	if actor, ok := domain.UserFromContext(ctx); !ok || actor.Uuid == user.Uuid {
+		t, err := u.totpRepo.GetByUser(ctx, user.Uuid)
+		if err != nil {
+			return domain.NewRepoErr(err, "Two-factor authentication not enabled")
+		}
+		if t.Confirmed {
+			if rErr = u.checkCode(ctx, t, code); rErr != nil {
+				return
+			}
+		}
+	} else if rErr = u.checkPrivileges(ctx, user); rErr != nil {
+		return
+	}
+
+	if err := u.totpRepo.Delete(ctx, user.Uuid); err != nil {
+		rErr = domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Two-factor disable failed"))
+		return
+	}
+
+	u.log.Info("IN [Disable]: two-factor authentication disabled for {", uname, "}")
+	return
+}
+
+func (u *totpUsecase) Enabled(c context.Context, user domain.User) (res bool, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	t, err := u.totpRepo.GetByUser(ctx, user.Uuid)
+	if errors.Is(err, domain.ErrNotFound) {
+		return false, nil
+	}
+	// Failing open here would let a login skip its second factor
+	if err != nil {
+		u.log.Error("IN [Enabled]: could not get totp ->", err)
+		return false, domain.NewRepoErr(err, "")
+	}
+
+	return t.Confirmed, nil
+}
+
+func (u *totpUsecase) Required(user domain.User) bool {
+	return u.requiredRoles[user.Role.Description]
+}
+
+func (u *totpUsecase) Verify(c context.Context, user domain.User, code string) (rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	t, err := u.totpRepo.GetByUser(ctx, user.Uuid)
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		u.log.Error("IN [Verify]: could not get totp ->", err)
+		return domain.NewRepoErr(err, "")
+	}
+	if err != nil || !t.Confirmed {
+		err = errors.New("Two-factor authentication not enabled")
+		return domain.NewUCaseErr(http.StatusUnauthorized, err)
+	}
+
+	if rErr = u.checkCode(ctx, t, code); rErr != nil {
+		return
+	}
+
+	if len(code) != 6 {
+		u.log.Warn("IN [Verify]: recovery code used by {", user.Username, "}")
+	}
+	return
+}
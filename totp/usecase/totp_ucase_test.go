@@ -0,0 +1,257 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/totp/usecase"
+	"github.com/sicozz/papyrus/utils/clock"
+	"github.com/sicozz/papyrus/utils/token"
+	"github.com/sicozz/papyrus/utils/totp"
+)
+
+var (
+	owner = domain.User{Uuid: "u-ana", Username: "ana", Role: domain.Role{Code: 2}}
+	admin = domain.User{Uuid: "u-root", Username: "root", Role: domain.Role{Code: 1}}
+	peer  = domain.User{Uuid: "u-eve", Username: "eve", Role: domain.Role{Code: 3}}
+)
+
+type fakeUserRepo struct{ domain.UserRepository }
+
+func (fakeUserRepo) GetByUsername(ctx context.Context, uname string) (domain.User, error) {
+	for _, u := range []domain.User{owner, admin, peer} {
+		if u.Username == uname {
+			return u, nil
+		}
+	}
+	return domain.User{}, domain.ErrNotFound
+}
+
+// fakeAuthorizer lets every user write users and a role cover the ones with a greater code
+type fakeAuthorizer struct{ domain.Authorizer }
+
+func (fakeAuthorizer) Authorize(ctx context.Context, perm string) domain.RequestErr {
+	return nil
+}
+
+func (fakeAuthorizer) Covers(ctx context.Context, u domain.User, r domain.Role) (bool, error) {
+	return u.Role.Code <= r.Code, nil
+}
+
+// fakeTotpRepo keeps one user's enrollment and recovery codes, by hash and used or not, in memory
+type fakeTotpRepo struct {
+	t     *domain.Totp
+	codes map[string]bool
+	err   error
+}
+
+func newFakeTotpRepo() *fakeTotpRepo {
+	return &fakeTotpRepo{codes: map[string]bool{}}
+}
+
+func (r *fakeTotpRepo) GetByUser(ctx context.Context, userUuid string) (domain.Totp, error) {
+	if r.err != nil {
+		return domain.Totp{}, r.err
+	}
+	if r.t == nil || r.t.UserUuid != userUuid {
+		return domain.Totp{}, domain.ErrNotFound
+	}
+	return *r.t, nil
+}
+
+func (r *fakeTotpRepo) Store(ctx context.Context, t *domain.Totp) error {
+	c := *t
+	r.t = &c
+	return nil
+}
+
+func (r *fakeTotpRepo) Confirm(ctx context.Context, userUuid string) error {
+	r.t.Confirmed = true
+	return nil
+}
+
+func (r *fakeTotpRepo) Delete(ctx context.Context, userUuid string) error {
+	r.t = nil
+	r.codes = map[string]bool{}
+	return nil
+}
+
+func (r *fakeTotpRepo) UseStep(ctx context.Context, userUuid string, step int64) (bool, error) {
+	if r.t.LastStep >= step {
+		return false, nil
+	}
+	r.t.LastStep = step
+	return true, nil
+}
+
+func (r *fakeTotpRepo) StoreRecoveryCodes(ctx context.Context, userUuid string, hashes []string) error {
+	r.codes = map[string]bool{}
+	for _, h := range hashes {
+		r.codes[h] = false
+	}
+	return nil
+}
+
+func (r *fakeTotpRepo) UseRecoveryCode(ctx context.Context, userUuid string, hash string, now time.Time) (bool, error) {
+	used, ok := r.codes[hash]
+	if !ok || used {
+		return false, nil
+	}
+	r.codes[hash] = true
+	return true, nil
+}
+
+func newUsecase(tr domain.TotpRepository, clk domain.Clock) domain.TotpUsecase {
+	return usecase.NewTotpUsecase(fakeUserRepo{}, tr, fakeAuthorizer{}, clk, "papyrus", nil, time.Second)
+}
+
+func codeAt(t *testing.T, secret string, at time.Time) string {
+	code, err := totp.CodeAt(secret, totp.Step(at))
+	if err != nil {
+		t.Fatalf("computing code: %v", err)
+	}
+	return code
+}
+
+// enroll walks the owner through enrollment and returns their secret and recovery codes
+func enroll(t *testing.T, tu domain.TotpUsecase, clk *clock.Fake) (string, []string) {
+	ctx := domain.NewContextWithUser(context.Background(), owner)
+	e, rErr := tu.Enroll(ctx, owner.Username)
+	if rErr != nil {
+		t.Fatalf("enrolling: %v", rErr)
+	}
+
+	codes, rErr := tu.Confirm(ctx, owner.Username, codeAt(t, e.Secret, clk.Now()))
+	if rErr != nil {
+		t.Fatalf("confirming: %v", rErr)
+	}
+	clk.Advance(30 * time.Second)
+
+	return e.Secret, codes
+}
+
+func wantStatus(t *testing.T, what string, rErr domain.RequestErr, status int) {
+	t.Helper()
+	if rErr == nil || rErr.GetStatus() != status {
+		t.Fatalf("%s: got %v, want %d", what, rErr, status)
+	}
+}
+
+func TestVerifyCodeWindow(t *testing.T) {
+	clk := &clock.Fake{T: time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)}
+	tu := newUsecase(newFakeTotpRepo(), clk)
+	secret, _ := enroll(t, tu, clk)
+
+	code := codeAt(t, secret, clk.Now())
+	if rErr := tu.Verify(context.Background(), owner, code); rErr != nil {
+		t.Fatalf("verifying a current code: %v", rErr)
+	}
+	wantStatus(t, "replaying a code", tu.Verify(context.Background(), owner, code), http.StatusUnauthorized)
+
+	// One step of skew is tolerated, two are not
+	clk.Advance(90 * time.Second)
+	stale := codeAt(t, secret, clk.Now().Add(-60*time.Second))
+	wantStatus(t, "verifying a code two steps old", tu.Verify(context.Background(), owner, stale), http.StatusUnauthorized)
+	if rErr := tu.Verify(context.Background(), owner, codeAt(t, secret, clk.Now().Add(30*time.Second))); rErr != nil {
+		t.Fatalf("verifying a code one step ahead: %v", rErr)
+	}
+}
+
+func TestRecoveryCodesAreSingleUse(t *testing.T) {
+	clk := &clock.Fake{T: time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)}
+	tr := newFakeTotpRepo()
+	tu := newUsecase(tr, clk)
+	_, codes := enroll(t, tu, clk)
+
+	if len(codes) != 10 {
+		t.Fatalf("got %d recovery codes, want 10", len(codes))
+	}
+	for _, c := range codes {
+		if len(strings.ReplaceAll(c, "-", "")) != 16 {
+			t.Fatalf("recovery code %q is not 80 bits", c)
+		}
+	}
+	for _, c := range codes {
+		if _, ok := tr.codes[token.Hash(strings.ReplaceAll(c, "-", ""))]; !ok {
+			t.Fatalf("recovery code %q not stored by its hash", c)
+		}
+	}
+
+	if rErr := tu.Verify(context.Background(), owner, codes[0]); rErr != nil {
+		t.Fatalf("using a recovery code: %v", rErr)
+	}
+	wantStatus(t, "reusing a recovery code", tu.Verify(context.Background(), owner, codes[0]), http.StatusUnauthorized)
+
+	loose := strings.ToUpper(strings.ReplaceAll(codes[1], "-", " "))
+	if rErr := tu.Verify(context.Background(), owner, loose); rErr != nil {
+		t.Fatalf("using a recovery code typed loosely: %v", rErr)
+	}
+}
+
+func TestEnabledFailsClosed(t *testing.T) {
+	clk := &clock.Fake{T: time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)}
+	tr := newFakeTotpRepo()
+	tu := newUsecase(tr, clk)
+
+	enabled, rErr := tu.Enabled(context.Background(), owner)
+	if rErr != nil || enabled {
+		t.Fatalf("without enrollment: got %v, %v, want false, nil", enabled, rErr)
+	}
+
+	tr.err = errors.New("connection reset")
+	_, rErr = tu.Enabled(context.Background(), owner)
+	wantStatus(t, "checking enrollment while the store fails", rErr, http.StatusInternalServerError)
+}
+
+func TestDisableNeedsSecondFactor(t *testing.T) {
+	clk := &clock.Fake{T: time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)}
+	tr := newFakeTotpRepo()
+	tu := newUsecase(tr, clk)
+	secret, _ := enroll(t, tu, clk)
+	ctx := domain.NewContextWithUser(context.Background(), owner)
+
+	wantStatus(t, "disabling without a code", tu.Disable(ctx, owner.Username, ""), http.StatusUnauthorized)
+	if tr.t == nil {
+		t.Fatal("enrollment dropped without a code")
+	}
+
+	if rErr := tu.Disable(ctx, owner.Username, codeAt(t, secret, clk.Now())); rErr != nil {
+		t.Fatalf("disabling with a current code: %v", rErr)
+	}
+	if tr.t != nil {
+		t.Fatal("enrollment kept after disabling")
+	}
+}
+
+func TestAdminResetsLostSecondFactor(t *testing.T) {
+	clk := &clock.Fake{T: time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)}
+	tr := newFakeTotpRepo()
+	tu := newUsecase(tr, clk)
+	enroll(t, tu, clk)
+
+	ctx := domain.NewContextWithUser(context.Background(), admin)
+	if rErr := tu.Disable(ctx, owner.Username, ""); rErr != nil {
+		t.Fatalf("resetting another user's second factor: %v", rErr)
+	}
+	if tr.t != nil {
+		t.Fatal("enrollment kept after the reset")
+	}
+}
+
+func TestResetNeedsToCoverTheOwner(t *testing.T) {
+	clk := &clock.Fake{T: time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)}
+	tr := newFakeTotpRepo()
+	tu := newUsecase(tr, clk)
+	enroll(t, tu, clk)
+
+	ctx := domain.NewContextWithUser(context.Background(), peer)
+	wantStatus(t, "resetting the second factor of a higher role", tu.Disable(ctx, owner.Username, ""), http.StatusForbidden)
+	if tr.t == nil {
+		t.Fatal("enrollment dropped by a user lacking the owner's permissions")
+	}
+}
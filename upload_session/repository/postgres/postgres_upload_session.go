@@ -0,0 +1,109 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+)
+
+type postgresUploadSessionRepository struct {
+	Conn *sql.DB
+	log  utils.AggregatedLogger
+}
+
+/*
+* NewPostgresUploadSessionRepository will create an object that represent the
+* UploadSessionRepository interface
+ */
+func NewPostgresUploadSessionRepository(conn *sql.DB) domain.UploadSessionRepository {
+	logger := utils.NewAggregatedLogger(constants.Repository, constants.File)
+	return &postgresUploadSessionRepository{conn, logger}
+}
+
+// Get upload session by uuid
+func (r *postgresUploadSessionRepository) GetByUuid(ctx context.Context, uuid string) (res domain.UploadSession, err error) {
+	query :=
+		`SELECT uuid, user_, code, name, type, dir, revision_user, approval_user,
+			mime_type, size, offset_, created_at
+		FROM upload_session
+		WHERE uuid = $1`
+
+	err = r.Conn.QueryRowContext(ctx, query, uuid).Scan(
+		&res.Uuid,
+		&res.UserUuid,
+		&res.Meta.Code,
+		&res.Meta.Name,
+		&res.Meta.Type,
+		&res.Meta.Dir,
+		&res.Meta.RevisionUser,
+		&res.Meta.ApprovalUser,
+		&res.Meta.MimeType,
+		&res.Size,
+		&res.Offset,
+		&res.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.UploadSession{}, domain.ErrNotFound
+	}
+
+	return
+}
+
+// Store a new upload session
+func (r *postgresUploadSessionRepository) Store(ctx context.Context, s *domain.UploadSession) (err error) {
+	query :=
+		`INSERT INTO upload_session (user_, code, name, type, dir, revision_user,
+			approval_user, mime_type, size)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING uuid, offset_, created_at`
+	err = r.Conn.QueryRowContext(
+		ctx,
+		query,
+		s.UserUuid,
+		s.Meta.Code,
+		s.Meta.Name,
+		s.Meta.Type,
+		s.Meta.Dir,
+		s.Meta.RevisionUser,
+		s.Meta.ApprovalUser,
+		s.Meta.MimeType,
+		s.Size,
+	).Scan(&s.Uuid, &s.Offset, &s.CreatedAt)
+	if err != nil {
+		r.log.Error("IN [Store]: could not store upload session ->", err)
+	}
+
+	return
+}
+
+// Move the offset of a session, only if nobody moved it first
+func (r *postgresUploadSessionRepository) Advance(ctx context.Context, uuid string, from int64, to int64) (res bool, err error) {
+	query := `UPDATE upload_session SET offset_=$3 WHERE uuid=$1 AND offset_=$2`
+	result, err := r.Conn.ExecContext(ctx, query, uuid, from, to)
+	if err != nil {
+		r.log.Error("IN [Advance]: could not advance upload session ->", err)
+		return
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return
+	}
+
+	return n == 1, nil
+}
+
+// Delete an upload session
+func (r *postgresUploadSessionRepository) Delete(ctx context.Context, uuid string) (err error) {
+	query := `DELETE FROM upload_session WHERE uuid=$1`
+	_, err = r.Conn.ExecContext(ctx, query, uuid)
+	if err != nil {
+		r.log.Error("IN [Delete]: could not delete upload session ->", err)
+	}
+
+	return
+}
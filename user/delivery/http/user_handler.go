@@ -3,13 +3,15 @@ package http
 import (
 	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/labstack/echo/v4"
+	"github.com/sicozz/papyrus/auth/delivery/http/middleware"
 	"github.com/sicozz/papyrus/domain"
 	"github.com/sicozz/papyrus/domain/dtos"
 	"github.com/sicozz/papyrus/utils"
 	"github.com/sicozz/papyrus/utils/constants"
-	"gopkg.in/go-playground/validator.v9"
+	"github.com/sicozz/papyrus/utils/delivery"
 )
 
 // UserHandler will initialize the users/ resources endpoint
@@ -18,36 +20,53 @@ type UserHandler struct {
 	log      utils.AggregatedLogger
 }
 
-func NewUserHandler(e *echo.Echo, us domain.UserUsecase) {
+func NewUserHandler(e *echo.Echo, us domain.UserUsecase, authMw echo.MiddlewareFunc, authz *middleware.AuthzMiddleware) {
 	logger := utils.NewAggregatedLogger(constants.Delivery, constants.User)
 	handler := &UserHandler{us, logger}
-	e.GET("/user", handler.Fetch)
-	e.POST("/user", handler.Store)
-	e.GET("/user/:uname", handler.GetByUsername)
-	e.DELETE("/user/:uname", handler.Delete)
-	e.PATCH("/user/:uname", handler.Update)
-	e.POST("/login", handler.Login)
-}
-
-func isRequestValid(u any) (bool, error) {
-	validate := validator.New()
-	err := validate.Struct(u)
-	if err != nil {
-		return false, err
-	}
-	return true, nil
+	canRead := authz.Require(middleware.Can(domain.PermUserRead))
+	canWrite := authz.Require(middleware.Can(domain.PermUserWrite))
+	selfOrRead := authz.Require(middleware.Self("uname"), middleware.Can(domain.PermUserRead))
+	selfOrWrite := authz.Require(middleware.Self("uname"), middleware.Can(domain.PermUserWrite))
+	g := e.Group("/user", authMw)
+	g.GET("", handler.Fetch, canRead)
+	g.POST("", handler.Store, canWrite)
+	g.GET("/:uname", handler.GetByUsername, selfOrRead)
+	g.DELETE("/:uname", handler.Delete, canWrite)
+	g.PATCH("/:uname", handler.Update, selfOrWrite)
+	g.GET("/:uname/lock", handler.GetLock, canWrite)
+	g.DELETE("/:uname/lock", handler.Unlock, canWrite)
+	g.GET("/:uname/login_attempts", handler.FetchLoginAttempts, canWrite)
 }
 
 func (h *UserHandler) Fetch(c echo.Context) error {
 	h.log.Info("REQ: fetch")
 	ctx := c.Request().Context()
-	users, rErr := h.UUsecase.Fetch(ctx)
+	f := domain.UserFilter{
+		Cursor: c.QueryParam("cursor"),
+		Sort:   c.QueryParam("sort"),
+		Role:   c.QueryParam("role"),
+		State:  c.QueryParam("state"),
+		Query:  c.QueryParam("q"),
+	}
+	if l := c.QueryParam("limit"); l != "" {
+		var err error
+		f.Limit, err = strconv.ParseInt(l, 10, 64)
+		if err != nil {
+			errBody := dtos.NewErrDto("Limit must be an integer")
+			return c.JSON(http.StatusBadRequest, errBody)
+		}
+	}
+
+	page, rErr := h.UUsecase.Fetch(ctx, f)
 	if rErr != nil {
 		errBody := dtos.NewErrDto("User fetch failed")
+		if rErr.GetStatus() == http.StatusBadRequest {
+			errBody = dtos.NewErrDto(rErr.Error())
+		}
 		return c.JSON(rErr.GetStatus(), errBody)
 	}
 
-	return c.JSON(http.StatusOK, users)
+	return c.JSON(http.StatusOK, page)
 }
 
 func (h *UserHandler) GetByUsername(c echo.Context) error {
@@ -72,7 +91,7 @@ func (h *UserHandler) Store(c echo.Context) (err error) {
 		return c.JSON(http.StatusBadRequest, errBody)
 	}
 
-	if ok, err := isRequestValid(&user); !ok {
+	if ok, err := delivery.IsRequestValid(&user); !ok {
 		errBody, err := dtos.NewValidationErrDto(err.Error())
 		if err != nil {
 			errParse := dtos.NewErrDto(err.Error())
@@ -104,34 +123,6 @@ func (h *UserHandler) Delete(c echo.Context) error {
 	return c.NoContent(http.StatusOK)
 }
 
-func (h *UserHandler) Login(c echo.Context) error {
-	h.log.Info("REQ: login")
-	ctx := c.Request().Context()
-	var lDto dtos.LoginDto
-	err := c.Bind(&lDto)
-	if err != nil {
-		errBody := dtos.NewErrDto(fmt.Sprint("Req body binding failed: ", err))
-		return c.JSON(http.StatusBadRequest, errBody)
-	}
-
-	if ok, err := isRequestValid(&lDto); !ok {
-		errBody, err := dtos.NewValidationErrDto(err.Error())
-		if err != nil {
-			errValid := dtos.NewErrDto(fmt.Sprint("Req body validation failed: ", err))
-			return c.JSON(http.StatusBadRequest, errValid)
-		}
-		return c.JSON(http.StatusBadRequest, errBody)
-	}
-
-	user, rErr := h.UUsecase.Login(ctx, lDto.Username, lDto.Password)
-	if rErr != nil {
-		errBody := dtos.NewErrDto("Wrong username or password")
-		return c.JSON(rErr.GetStatus(), errBody)
-	}
-
-	return c.JSON(http.StatusOK, user)
-}
-
 func (h *UserHandler) Update(c echo.Context) error {
 	h.log.Info("REQ: update")
 	ctx := c.Request().Context()
@@ -144,7 +135,7 @@ func (h *UserHandler) Update(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, errBody)
 	}
 
-	if ok, err := isRequestValid(&uUpDto); !ok {
+	if ok, err := delivery.IsRequestValid(&uUpDto); !ok {
 		errBody, err := dtos.NewValidationErrDto(err.Error())
 		if err != nil {
 			errValid := dtos.NewErrDto(fmt.Sprint("Req body validation failed: ", err))
@@ -169,3 +160,52 @@ func (h *UserHandler) Update(c echo.Context) error {
 
 	return c.NoContent(http.StatusOK)
 }
+
+func (h *UserHandler) GetLock(c echo.Context) error {
+	h.log.Info("REQ: get lock")
+	ctx := c.Request().Context()
+	uname := c.Param("uname")
+	lock, rErr := h.UUsecase.GetLock(ctx, uname)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.JSON(http.StatusOK, lock)
+}
+
+func (h *UserHandler) Unlock(c echo.Context) error {
+	h.log.Info("REQ: unlock")
+	ctx := c.Request().Context()
+	uname := c.Param("uname")
+	rErr := h.UUsecase.Unlock(ctx, uname)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+func (h *UserHandler) FetchLoginAttempts(c echo.Context) error {
+	h.log.Info("REQ: fetch login attempts")
+	ctx := c.Request().Context()
+	uname := c.Param("uname")
+	limit := int64(0)
+	if l := c.QueryParam("limit"); l != "" {
+		var err error
+		limit, err = strconv.ParseInt(l, 10, 64)
+		if err != nil {
+			errBody := dtos.NewErrDto("Limit must be an integer")
+			return c.JSON(http.StatusBadRequest, errBody)
+		}
+	}
+
+	attempts, rErr := h.UUsecase.FetchLoginAttempts(ctx, uname, limit)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.JSON(http.StatusOK, attempts)
+}
@@ -3,12 +3,13 @@ package postgres
 import (
 	"context"
 	"database/sql"
-	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/sicozz/papyrus/domain"
 	"github.com/sicozz/papyrus/utils"
 	"github.com/sicozz/papyrus/utils/constants"
+	"github.com/sicozz/papyrus/utils/repository"
 )
 
 type postgresUserRepository struct {
@@ -26,7 +27,7 @@ func (r *postgresUserRepository) fetch(ctx context.Context, query string, args .
 	rows, err := r.Conn.QueryContext(ctx, query, args...)
 	if err != nil {
 		r.log.Error(err)
-		return nil, err
+		return nil, repository.MapErr(err)
 	}
 
 	defer func() {
@@ -51,6 +52,7 @@ func (r *postgresUserRepository) fetch(ctx context.Context, query string, args .
 			&t.Lastname,
 			&roleCode,
 			&stateCode,
+			&t.CreatedAt,
 		)
 
 		if err != nil {
@@ -66,31 +68,167 @@ func (r *postgresUserRepository) fetch(ctx context.Context, query string, args .
 		res = append(res, t)
 	}
 
-	return res, nil
+	return res, rows.Err()
 }
 
-// Retrieve all users
-func (r *postgresUserRepository) GetAll(ctx context.Context) (res []domain.User, err error) {
+// Columns users can be sorted by
+var sortColumns = map[string]string{
+	domain.UserSortCreatedAt: "created_at",
+	domain.UserSortUsername:  "username",
+	domain.UserSortEmail:     "email",
+	domain.UserSortName:      "name",
+	domain.UserSortLastname:  "lastname",
+}
+
+// escapeLike escapes the LIKE wildcards of a user provided substring
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// filterConds builds the WHERE conditions of f, appending their arguments to args
+func filterConds(f domain.UserFilter, args []interface{}) ([]string, []interface{}) {
+	conds := make([]string, 0)
+	if f.RoleCode > 0 {
+		args = append(args, f.RoleCode)
+		conds = append(conds, fmt.Sprintf("role = $%d", len(args)))
+	}
+	if f.StateCode > 0 {
+		args = append(args, f.StateCode)
+		conds = append(conds, fmt.Sprintf("state = $%d", len(args)))
+	}
+	if f.Query != "" {
+		args = append(args, "%"+escapeLike(f.Query)+"%")
+		conds = append(conds, fmt.Sprintf(
+			"(username ILIKE $%[1]d OR email ILIKE $%[1]d OR name ILIKE $%[1]d OR lastname ILIKE $%[1]d)",
+			len(args),
+		))
+	}
+
+	return conds, args
+}
+
+func whereClause(conds []string) string {
+	if len(conds) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(conds, " AND ")
+}
+
+// sortKey returns the value of the sort column of u, as stored in cursors
+func sortKey(sort string, u domain.User) string {
+	switch sort {
+	case domain.UserSortUsername:
+		return u.Username
+	case domain.UserSortEmail:
+		return u.Email
+	case domain.UserSortName:
+		return u.Name
+	case domain.UserSortLastname:
+		return u.Lastname
+	default:
+		return repository.FormatCursorTime(u.CreatedAt)
+	}
+}
+
+/*
+* Retrieve a page of users using keyset pagination: rows are ordered by the
+* sort column and uuid, and a page starts right after the cursor's row
+ */
+func (r *postgresUserRepository) GetPage(ctx context.Context, f domain.UserFilter) (res []domain.User, next string, err error) {
+	sort, dir, cmp := strings.TrimPrefix(f.Sort, "-"), "ASC", ">"
+	if strings.HasPrefix(f.Sort, "-") {
+		dir, cmp = "DESC", "<"
+	}
+	col, ok := sortColumns[sort]
+	if !ok {
+		return nil, "", domain.ErrBadParamInput
+	}
+
+	conds, args := filterConds(f, make([]interface{}, 0))
+	if f.Cursor != "" {
+		key, uuid, err := repository.DecodeCursor(f.Cursor, f.Sort)
+		if err != nil {
+			return nil, "", domain.ErrBadParamInput
+		}
+
+		// Time keys go as text: a timestamp param would be shifted by the session time zone
+		keyType := "text"
+		if sort == domain.UserSortCreatedAt {
+			if _, err = repository.ParseCursorTime(key); err != nil {
+				return nil, "", domain.ErrBadParamInput
+			}
+			keyType = "timestamp"
+		}
+		args = append(args, key, uuid)
+		conds = append(conds, fmt.Sprintf("(%s, uuid) %s ($%d::%s, $%d::uuid)", col, cmp, len(args)-1, keyType, len(args)))
+	}
+
+	// One extra row tells whether there is a next page
+	args = append(args, f.Limit+1)
+	query := fmt.Sprintf(
+		`SELECT uuid, username, email, password, name, lastname, role, state, created_at
+		FROM user_
+		%s
+		ORDER BY %s %s, uuid %s
+		LIMIT $%d`,
+		whereClause(conds), col, dir, dir, len(args),
+	)
+
+	res, err = r.fetch(ctx, query, args...)
+	if err != nil {
+		r.log.Error("IN [GetPage]: could not fetch users ->", err)
+		return nil, "", err
+	}
+	for i := range res {
+		res[i].Password = ""
+	}
+
+	if int64(len(res)) > f.Limit {
+		res = res[:f.Limit]
+		last := res[len(res)-1]
+		next = repository.EncodeCursor(f.Sort, sortKey(sort, last), last.Uuid)
+	}
+
+	return
+}
+
+// Count the users matching the filters of f, ignoring its cursor
+func (r *postgresUserRepository) Count(ctx context.Context, f domain.UserFilter) (res int64, err error) {
+	conds, args := filterConds(f, make([]interface{}, 0))
+	query := `SELECT COUNT(*) FROM user_ ` + whereClause(conds)
+	err = r.Conn.QueryRowContext(ctx, query, args...).Scan(&res)
+	if err != nil {
+		r.log.Error("IN [Count]: could not count users ->", err)
+	}
+
+	return
+}
+
+// Get user by uuid
+func (r *postgresUserRepository) GetByUuid(ctx context.Context, uuid string) (res domain.User, err error) {
 	query :=
-		`SELECT uuid, username, email, password, name, lastname, role, state
-		FROM user_`
+		`SELECT uuid, username, email, password, name, lastname, role, state, created_at
+		FROM user_
+		WHERE uuid = $1`
 
-	res, err = r.fetch(ctx, query)
+	users, err := r.fetch(ctx, query, uuid)
 	if err != nil {
-		return nil, err
+		return domain.User{}, err
 	}
-	for _, u := range res {
-		u.Password = ""
+
+	if len(users) < 1 {
+		return domain.User{}, domain.ErrNotFound
 	}
 
+	res = users[0]
+
 	return
 }
 
-// Get user by id
+// Get user by username
 func (r *postgresUserRepository) GetByUsername(ctx context.Context, uname string) (res domain.User, err error) {
-	// TODO: Refactor operations that expect only 1 row
 	query :=
-		`SELECT uuid, username, email, password, name, lastname, role, state
+		`SELECT uuid, username, email, password, name, lastname, role, state, created_at
 		FROM user_
 		WHERE username = $1`
 
@@ -100,11 +238,7 @@ func (r *postgresUserRepository) GetByUsername(ctx context.Context, uname string
 	}
 
 	if len(users) < 1 {
-		return domain.User{}, errors.New(fmt.Sprintln("No user with username:", uname))
-	}
-
-	for _, u := range users {
-		u.Password = ""
+		return domain.User{}, domain.ErrNotFound
 	}
 
 	res = users[0]
@@ -113,50 +247,75 @@ func (r *postgresUserRepository) GetByUsername(ctx context.Context, uname string
 }
 
 // Know if a user has already taken a username
-func (r *postgresUserRepository) ExistByUname(ctx context.Context, uname string) (res bool) {
-	query := `SELECT COUNT(*) > 0 FROM user_ WHERE username = $1`
-	stmt, err := r.Conn.PrepareContext(ctx, query)
+func (r *postgresUserRepository) ExistByUname(ctx context.Context, uname string) (res bool, err error) {
+	query := `SELECT EXISTS (SELECT 1 FROM user_ WHERE username = $1)`
+	err = r.Conn.QueryRowContext(ctx, query, uname).Scan(&res)
 	if err != nil {
-		r.log.Error("IN [ExistByUname]: could not prepare context ->", err)
-		return
+		r.log.Error("IN [ExistByUname]: could not check username ->", err)
 	}
-	defer stmt.Close()
-
-	err = stmt.QueryRowContext(ctx, uname).Scan(&res)
 
 	return
 }
 
 // Know if a user has already taken an email
-func (r *postgresUserRepository) ExistByEmail(ctx context.Context, email string) (res bool) {
-	query := `SELECT COUNT(*) > 0 FROM user_ WHERE email = $1`
-	stmt, err := r.Conn.PrepareContext(ctx, query)
+func (r *postgresUserRepository) ExistByEmail(ctx context.Context, email string) (res bool, err error) {
+	query := `SELECT EXISTS (SELECT 1 FROM user_ WHERE email = $1)`
+	err = r.Conn.QueryRowContext(ctx, query, email).Scan(&res)
 	if err != nil {
-		r.log.Error("IN [ExistByEmail]: could not prepare context ->", err)
-		return
+		r.log.Error("IN [ExistByEmail]: could not check email ->", err)
 	}
-	defer stmt.Close()
 
-	err = stmt.QueryRowContext(ctx, email).Scan(&res)
+	return
+}
+
+// Count the users that hold a role
+func (r *postgresUserRepository) CountByRole(ctx context.Context, ro domain.Role) (res int64, err error) {
+	query := `SELECT COUNT(*) FROM user_ WHERE role = $1`
+	err = r.Conn.QueryRowContext(ctx, query, ro.Code).Scan(&res)
+	if err != nil {
+		r.log.Error("IN [CountByRole]: could not count users ->", err)
+	}
+
+	return
+}
+
+// Count the users in a state
+func (r *postgresUserRepository) CountByState(ctx context.Context, st domain.UserState) (res int64, err error) {
+	query := `SELECT COUNT(*) FROM user_ WHERE state = $1`
+	err = r.Conn.QueryRowContext(ctx, query, st.Code).Scan(&res)
+	if err != nil {
+		r.log.Error("IN [CountByState]: could not count users ->", err)
+	}
+
+	return
+}
+
+// Count the active users whose role grants a permission
+func (r *postgresUserRepository) CountByPermission(ctx context.Context, perm string) (res int64, err error) {
+	query :=
+		`SELECT COUNT(*)
+		FROM user_ u
+		JOIN role_permission rp ON rp.role = u.role
+		JOIN permission p ON p.code = rp.permission
+		JOIN user_state us ON us.code = u.state
+		WHERE p.name = $1 AND us.description = $2`
+	err = r.Conn.QueryRowContext(ctx, query, perm, domain.UserStateActive).Scan(&res)
+	if err != nil {
+		r.log.Error("IN [CountByPermission]: could not count users ->", err)
+	}
 
 	return
 }
 
-// Store a new user
+// Store a new user, a taken username or email is ErrConflict
 func (r *postgresUserRepository) Store(ctx context.Context, u *domain.User) (err error) {
 	query :=
 		`INSERT INTO user_ (username, email, password, name, lastname, role, state)
 		VALUES ($1, $2, $3, $4, $5, $6, $7)
 		RETURNING uuid`
-	stmt, err := r.Conn.PrepareContext(ctx, query)
-	if err != nil {
-		r.log.Error("IN [Store]: could not prepare context ->", err)
-		return
-	}
-	defer stmt.Close()
-
-	err = stmt.QueryRowContext(
+	err = r.Conn.QueryRowContext(
 		ctx,
+		query,
 		u.Username,
 		u.Email,
 		u.Password,
@@ -165,84 +324,102 @@ func (r *postgresUserRepository) Store(ctx context.Context, u *domain.User) (err
 		u.Role.Code,
 		u.State.Code,
 	).Scan(&u.Uuid)
+	if err != nil {
+		r.log.Error("IN [Store]: could not store user ->", err)
+		err = repository.MapErr(err)
+	}
 
 	return
 }
 
-// Delete a user
-func (r *postgresUserRepository) Delete(ctx context.Context, uname string) (err error) {
-	query := `DELETE FROM user_ WHERE username=$1 RETURNING uuid`
-	stmt, err := r.Conn.PrepareContext(ctx, query)
+/*
+* Delete a user, one still referenced by other rows is ErrConflict and one
+* whose removal leaves nobody holding keep is ErrLastHolder
+ */
+func (r *postgresUserRepository) Delete(ctx context.Context, uname string, keep string) (err error) {
+	query := `DELETE FROM user_ WHERE username=$1`
+	err = repository.Keeping(ctx, r.Conn, keep, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, query, uname)
+		if err != nil {
+			return repository.MapDeleteErr(err)
+		}
+		return repository.CheckAffected(res)
+	})
 	if err != nil {
-		r.log.Error("IN [Delete]: could not prepare context ->", err)
-		return
-	}
-	defer stmt.Close()
-
-	var uuid string
-	err = stmt.QueryRowContext(ctx, uname).Scan(&uuid)
-	if uuid == "" && err == nil {
-		err = errors.New("Could not delete user")
+		r.log.Error("IN [Delete]: could not delete user ->", err)
 	}
 
 	return
 }
 
-// Change user email
-func (r *postgresUserRepository) ChgEmail(ctx context.Context, uname string, nEmail string) (err error) {
-	query := `UPDATE user_ SET email=$1 WHERE username=$2`
+// update runs a single user UPDATE, ErrNotFound when no user matched
+func (r *postgresUserRepository) update(ctx context.Context, query string, args ...interface{}) (err error) {
+	res, err := r.Conn.ExecContext(ctx, query, args...)
+	if err != nil {
+		r.log.Error("IN [update]: could not update user ->", err)
+		return repository.MapErr(err)
+	}
 
-	_, err = r.fetch(ctx, query, nEmail, uname)
+	return repository.CheckAffected(res)
+}
 
-	return
+// Change user email, a taken email is ErrConflict
+func (r *postgresUserRepository) ChgEmail(ctx context.Context, uname string, nEmail string) (err error) {
+	return r.update(ctx, `UPDATE user_ SET email=$1 WHERE username=$2`, nEmail, uname)
 }
 
 // Change user name
 func (r *postgresUserRepository) ChgName(ctx context.Context, uname string, nName string) (err error) {
-	query := `UPDATE user_ SET name=$1 WHERE username=$2`
-
-	_, err = r.fetch(ctx, query, nName, uname)
-
-	return
+	return r.update(ctx, `UPDATE user_ SET name=$1 WHERE username=$2`, nName, uname)
 }
 
 // Change user lastname
 func (r *postgresUserRepository) ChgLstname(ctx context.Context, uname string, nLname string) (err error) {
-	query := `UPDATE user_ SET lastname=$1 WHERE username=$2`
-
-	_, err = r.fetch(ctx, query, nLname, uname)
-
-	return
+	return r.update(ctx, `UPDATE user_ SET lastname=$1 WHERE username=$2`, nLname, uname)
 }
 
-// Change user role
-func (r *postgresUserRepository) ChgRole(ctx context.Context, uname string, ro domain.Role) (err error) {
+// Change user role, one that leaves nobody holding keep is ErrLastHolder
+func (r *postgresUserRepository) ChgRole(ctx context.Context, uname string, ro domain.Role, keep string) (err error) {
 	query := `UPDATE user_ SET role=$1 WHERE username=$2`
-
-	_, err = r.fetch(ctx, query, ro.Code, uname)
+	err = repository.Keeping(ctx, r.Conn, keep, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, query, ro.Code, uname)
+		if err != nil {
+			return repository.MapErr(err)
+		}
+		return repository.CheckAffected(res)
+	})
+	if err != nil {
+		r.log.Error("IN [ChgRole]: could not change role ->", err)
+	}
 
 	return
 }
 
-// Change user state
-func (r *postgresUserRepository) ChgState(ctx context.Context, uname string, st domain.UserState) (err error) {
+// Change user state, keeping an active holder of keep like ChgRole
+func (r *postgresUserRepository) ChgState(ctx context.Context, uname string, st domain.UserState, keep string) (err error) {
 	query := `UPDATE user_ SET state=$1 WHERE username=$2`
-
-	_, err = r.fetch(ctx, query, st.Code, uname)
+	err = repository.Keeping(ctx, r.Conn, keep, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, query, st.Code, uname)
+		if err != nil {
+			return repository.MapErr(err)
+		}
+		return repository.CheckAffected(res)
+	})
+	if err != nil {
+		r.log.Error("IN [ChgState]: could not change user_state ->", err)
+	}
 
 	return
 }
 
-// Authenticate a user
-func (r *postgresUserRepository) Login(ctx context.Context, uname string, passwd string) (res domain.User, err error) {
-	user, err := r.GetByUsername(ctx, uname)
+// Change user password hash
+func (r *postgresUserRepository) ChgPasswd(ctx context.Context, uname string, hash string) (err error) {
+	// A password change that silently matched nobody must not look successful
+	res, err := r.Conn.ExecContext(ctx, `UPDATE user_ SET password=$1 WHERE username=$2`, hash, uname)
 	if err != nil {
-		return domain.User{}, err
-	}
-
-	if user.Password != passwd {
-		return domain.User{}, errors.New("Incorrect password or username")
+		r.log.Error("IN [ChgPasswd]: could not change password ->", err)
+		return repository.MapErr(err)
 	}
 
-	return user, nil
+	return repository.CheckAffected(res)
 }
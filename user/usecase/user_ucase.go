@@ -13,25 +13,53 @@ import (
 )
 
 const (
-	defRoleDesc      = `estandar`
-	defUserStateDesc = `inactivo`
+	defRoleDesc      = domain.RoleStandard
+	defUserStateDesc = domain.UserStateInactive
+	defPageLimit     = 50
+	maxPageLimit     = 200
 )
 
 type userUsecase struct {
 	userRepo       domain.UserRepository
 	roleRepo       domain.RoleRepository
 	userStateRepo  domain.UserStateRepository
+	sessionRepo    domain.SessionRepository
+	hasher         domain.PasswordHasher
+	policy         domain.PasswordPolicy
+	authorizer     domain.Authorizer
+	throttleUcase  domain.LoginThrottleUsecase
+	verifyUcase    domain.VerificationUsecase
+	notifier       domain.Notifier
 	contextTimeout time.Duration
 	log            utils.AggregatedLogger
 }
 
 // NewUserUsecase will create a new userUsecase object representation of domain.UserUsecase interface
-func NewUserUsecase(ur domain.UserRepository, rr domain.RoleRepository, usr domain.UserStateRepository, timeout time.Duration) domain.UserUsecase {
+func NewUserUsecase(
+	ur domain.UserRepository,
+	rr domain.RoleRepository,
+	usr domain.UserStateRepository,
+	sr domain.SessionRepository,
+	h domain.PasswordHasher,
+	pp domain.PasswordPolicy,
+	az domain.Authorizer,
+	ltu domain.LoginThrottleUsecase,
+	vu domain.VerificationUsecase,
+	n domain.Notifier,
+	timeout time.Duration,
+) domain.UserUsecase {
 	logger := utils.NewAggregatedLogger(constants.Usecase, constants.User)
 	return &userUsecase{
 		userRepo:       ur,
 		roleRepo:       rr,
 		userStateRepo:  usr,
+		sessionRepo:    sr,
+		hasher:         h,
+		policy:         pp,
+		authorizer:     az,
+		throttleUcase:  ltu,
+		verifyUcase:    vu,
+		notifier:       n,
 		contextTimeout: timeout,
 		log:            logger,
 	}
@@ -79,46 +107,121 @@ func (u *userUsecase) fillUserDetails(ctx context.Context, users []domain.User)
 	return
 }
 
-func (u *userUsecase) Fetch(c context.Context) (res []domain.User, rErr domain.RequestErr) {
+func (u *userUsecase) Fetch(c context.Context, f domain.UserFilter) (res domain.UserPage, rErr domain.RequestErr) {
 	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
 	defer cancel()
 
-	res, err := u.userRepo.GetAll(ctx)
+	if f.Limit <= 0 {
+		f.Limit = defPageLimit
+	}
+	if f.Limit > maxPageLimit {
+		f.Limit = maxPageLimit
+	}
+	if f.Sort == "" {
+		f.Sort = domain.UserSortCreatedAt
+	}
+
+	if f.Role != "" {
+		role, err := u.roleRepo.GetByDescription(ctx, f.Role)
+		if errors.Is(err, domain.ErrNotFound) {
+			err = errors.New(fmt.Sprint("Role not found. description: ", f.Role))
+			rErr = domain.NewUCaseErr(http.StatusBadRequest, err)
+			return
+		}
+		if err != nil {
+			u.log.Error("IN [Fetch]: could not get role ->", err)
+			rErr = domain.NewRepoErr(err, "")
+			return
+		}
+		f.RoleCode = role.Code
+	}
+
+	if f.State != "" {
+		state, err := u.userStateRepo.GetByDescription(ctx, f.State)
+		if errors.Is(err, domain.ErrNotFound) {
+			err = errors.New(fmt.Sprint("User state not found. description: ", f.State))
+			rErr = domain.NewUCaseErr(http.StatusBadRequest, err)
+			return
+		}
+		if err != nil {
+			u.log.Error("IN [Fetch]: could not get user_state ->", err)
+			rErr = domain.NewRepoErr(err, "")
+			return
+		}
+		f.StateCode = state.Code
+	}
+
+	users, next, err := u.userRepo.GetPage(ctx, f)
+	if errors.Is(err, domain.ErrBadParamInput) {
+		err = errors.New("Invalid sort or cursor")
+		rErr = domain.NewUCaseErr(http.StatusBadRequest, err)
+		return
+	}
 	if err != nil {
 		u.log.Error("IN [Fetch]: could not get users ->", err)
 		rErr = domain.NewUCaseErr(http.StatusInternalServerError, err)
 		return
 	}
 
-	err = u.fillUserDetails(ctx, res)
+	total, err := u.userRepo.Count(ctx, f)
+	if err != nil {
+		u.log.Error("IN [Fetch]: could not count users ->", err)
+		rErr = domain.NewUCaseErr(http.StatusInternalServerError, err)
+		return
+	}
+
+	err = u.fillUserDetails(ctx, users)
 	if err != nil {
 		u.log.Error("IN [Fetch]: could not fill user details ->", err)
 		rErr = domain.NewUCaseErr(http.StatusInternalServerError, err)
 		return
 	}
 
+	res = domain.UserPage{
+		Users:      users,
+		NextCursor: next,
+		Total:      total,
+	}
 	return
 }
 
-func (u *userUsecase) GetByUsername(c context.Context, uname string) (res domain.User, rErr domain.RequestErr) {
-	// Refactor filluserdetails
+func (u *userUsecase) GetByUuid(c context.Context, uuid string) (res domain.User, rErr domain.RequestErr) {
 	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
 	defer cancel()
 
-	if exists := u.userRepo.ExistByUname(ctx, uname); !exists {
-		err := errors.New(fmt.Sprint("User not found. username: ", uname))
-		rErr = domain.NewUCaseErr(http.StatusNotFound, err)
+	res, err := u.userRepo.GetByUuid(ctx, uuid)
+	if err != nil {
+		u.log.Error("IN [GetByUuid]: could not get user ->", err)
+		rErr = domain.NewRepoErr(err, fmt.Sprint("User not found. uuid: ", uuid))
+		return domain.User{}, rErr
+	}
+
+	res.Password = ""
+	resArr := make([]domain.User, 1)
+	resArr[0] = res
+	err = u.fillUserDetails(ctx, resArr)
+	res = resArr[0]
+	if err != nil {
+		u.log.Error("IN [GetByUuid]: could not fill user details ->", err)
+		rErr = domain.NewUCaseErr(http.StatusInternalServerError, err)
 		return
 	}
+	return
+}
+
+func (u *userUsecase) GetByUsername(c context.Context, uname string) (res domain.User, rErr domain.RequestErr) {
+	// Refactor filluserdetails
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
 
 	res, err := u.userRepo.GetByUsername(ctx, uname)
 	if err != nil {
 		u.log.Error("IN [GetByUsername]: could not get user ->", err)
-		err = errors.New(fmt.Sprint("User fetch failed. username: ", uname))
-		rErr = domain.NewUCaseErr(http.StatusNotFound, err)
+		rErr = domain.NewRepoErr(err, fmt.Sprint("User not found. username: ", uname))
 		return domain.User{}, rErr
 	}
 
+	res.Password = ""
 	resArr := make([]domain.User, 1)
 	resArr[0] = res
 	err = u.fillUserDetails(ctx, resArr)
@@ -135,35 +238,171 @@ func (u *userUsecase) Store(c context.Context, user *domain.User) (rErr domain.R
 	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
 	defer cancel()
 
-	if exists := u.userRepo.ExistByUname(ctx, user.Username); exists {
-		err := errors.New("Username already taken")
+	if err := u.policy.Check(user.Password); err != nil {
+		rErr = domain.NewUCaseErr(http.StatusBadRequest, err)
+		return
+	}
+
+	exists, err := u.userRepo.ExistByUname(ctx, user.Username)
+	if err != nil {
+		u.log.Error("IN [Store]: could not check username ->", err)
+		rErr = domain.NewRepoErr(err, "")
+		return
+	}
+	if exists {
+		err = errors.New("Username already taken")
 		rErr = domain.NewUCaseErr(http.StatusConflict, err)
 		return
 	}
 
-	if exists := u.userRepo.ExistByEmail(ctx, user.Email); exists {
-		err := errors.New("Email already taken")
+	exists, err = u.userRepo.ExistByEmail(ctx, user.Email)
+	if err != nil {
+		u.log.Error("IN [Store]: could not check email ->", err)
+		rErr = domain.NewRepoErr(err, "")
+		return
+	}
+	if exists {
+		err = errors.New("Email already taken")
 		rErr = domain.NewUCaseErr(http.StatusConflict, err)
 		return
 	}
 
+	// The defaults are seeded, missing them is a broken database and not a 404
 	r, err := u.roleRepo.GetByDescription(ctx, defRoleDesc)
 	if err != nil {
+		u.log.Error("IN [Store]: could not get base role ->", err)
 		err = errors.New("Base role fetch failed")
-		rErr = domain.NewUCaseErr(http.StatusNotFound, err)
+		rErr = domain.NewUCaseErr(http.StatusInternalServerError, err)
 		return
 	}
 	user.Role = r
 
 	s, err := u.userStateRepo.GetByDescription(ctx, defUserStateDesc)
 	if err != nil {
-		err = errors.New("Base role fetch failed")
-		rErr = domain.NewUCaseErr(http.StatusNotFound, err)
+		u.log.Error("IN [Store]: could not get base user_state ->", err)
+		err = errors.New("Base user_state fetch failed")
+		rErr = domain.NewUCaseErr(http.StatusInternalServerError, err)
 		return
 	}
 	user.State = s
 
+	hash, err := u.hasher.Hash(user.Password)
+	if err != nil {
+		u.log.Error("IN [Store]: could not hash password ->", err)
+		err = errors.New("Password hashing failed")
+		rErr = domain.NewUCaseErr(http.StatusInternalServerError, err)
+		return
+	}
+	user.Password = hash
+
 	err = u.userRepo.Store(ctx, user)
+	user.Password = ""
+	if err != nil {
+		u.log.Error("IN [Store]: could not store user ->", err)
+		rErr = domain.NewRepoErr(err, "Username or email already taken")
+		return
+	}
+
+	// The user exists even if the mail fails, an admin can resend it later
+	if vErr := u.verifyUcase.Send(ctx, user.Username); vErr != nil {
+		u.log.Error("IN [Store]: could not send verification to {", user.Username, "} ->", vErr)
+	}
+
+	return
+}
+
+// getDetailed fetches a user by username with its role and state filled
+func (u *userUsecase) getDetailed(ctx context.Context, uname string) (res domain.User, rErr domain.RequestErr) {
+	res, err := u.userRepo.GetByUsername(ctx, uname)
+	if err != nil {
+		u.log.Error("IN [getDetailed]: could not get user ->", err)
+		rErr = domain.NewRepoErr(err, fmt.Sprint("User not found. username: ", uname))
+		return domain.User{}, rErr
+	}
+
+	res.Password = ""
+	resArr := []domain.User{res}
+	err = u.fillUserDetails(ctx, resArr)
+	res = resArr[0]
+	if err != nil {
+		u.log.Error("IN [getDetailed]: could not fill user details ->", err)
+		rErr = domain.NewUCaseErr(http.StatusInternalServerError, err)
+		return
+	}
+
+	return
+}
+
+/*
+* checkPrivileges enforces that the authenticated user may write users and
+* holds every permission of the target user's role
+ */
+func (u *userUsecase) checkPrivileges(ctx context.Context, target domain.User) (rErr domain.RequestErr) {
+	if rErr = u.authorizer.Authorize(ctx, domain.PermUserWrite); rErr != nil {
+		return
+	}
+
+	actor, _ := domain.UserFromContext(ctx)
+	ok, err := u.authorizer.Covers(ctx, actor, target.Role)
+	if err != nil {
+		u.log.Error("IN [checkPrivileges]: could not compare roles ->", err)
+		return domain.NewUCaseErr(http.StatusInternalServerError, err)
+	}
+
+	if !ok {
+		err = errors.New("Can not act on a user with permissions you lack")
+		return domain.NewUCaseErr(http.StatusForbidden, err)
+	}
+
+	return
+}
+
+// lastManagerErr answers a write that would leave nobody able to manage roles
+var lastManagerErr = domain.NewUCaseErr(
+	http.StatusConflict,
+	errors.New("Can not remove the last user allowed to manage roles"),
+)
+
+/*
+* checkLastManager refuses to take the role.manage permission away from the
+* last active users holding it, by deleting them, changing their role or
+* deactivating them. Only active holders count, an inactive one can not act.
+* It fails early before any change is made, the repository repeats the check
+* atomically with the write
+ */
+func (u *userUsecase) checkLastManager(ctx context.Context, target domain.User, nRole *domain.Role) (rErr domain.RequestErr) {
+	if target.State.Description != domain.UserStateActive {
+		return
+	}
+
+	isManager, err := u.authorizer.Can(ctx, target, domain.PermRoleManage)
+	if err != nil {
+		return domain.NewUCaseErr(http.StatusInternalServerError, err)
+	}
+	if !isManager {
+		return
+	}
+
+	if nRole != nil {
+		keeps, err := u.authorizer.Can(ctx, domain.User{Role: *nRole}, domain.PermRoleManage)
+		if err != nil {
+			return domain.NewUCaseErr(http.StatusInternalServerError, err)
+		}
+		if keeps {
+			return
+		}
+	}
+
+	n, err := u.userRepo.CountByPermission(ctx, domain.PermRoleManage)
+	if err != nil {
+		u.log.Error("IN [checkLastManager]: could not count role managers ->", err)
+		return domain.NewUCaseErr(http.StatusInternalServerError, err)
+	}
+
+	if n <= 1 {
+		return lastManagerErr
+	}
+
 	return
 }
 
@@ -171,16 +410,26 @@ func (u *userUsecase) Delete(c context.Context, uname string) (rErr domain.Reque
 	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
 	defer cancel()
 
-	if exists := u.userRepo.ExistByUname(ctx, uname); !exists {
-		err := errors.New(fmt.Sprint("User not found. username: ", uname))
-		rErr = domain.NewUCaseErr(http.StatusNotFound, err)
+	target, rErr := u.getDetailed(ctx, uname)
+	if rErr != nil {
+		return
+	}
+
+	if rErr = u.checkPrivileges(ctx, target); rErr != nil {
+		return
+	}
+
+	if rErr = u.checkLastManager(ctx, target, nil); rErr != nil {
 		return
 	}
 
-	err := u.userRepo.Delete(ctx, uname)
+	err := u.userRepo.Delete(ctx, uname, domain.PermRoleManage)
+	if errors.Is(err, domain.ErrLastHolder) {
+		return lastManagerErr
+	}
 	if err != nil {
 		u.log.Error("IN [Delete]: could not delete user {", uname, "} ->", err)
-		rErr = domain.NewUCaseErr(http.StatusInternalServerError, err)
+		rErr = domain.NewRepoErr(err, fmt.Sprint("User delete failed. username: ", uname))
 		return
 	}
 
@@ -191,18 +440,77 @@ func (u *userUsecase) Update(c context.Context, uname string, uUp *domain.User)
 	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
 	defer cancel()
 
-	if exists := u.userRepo.ExistByUname(ctx, uname); !exists {
-		err := errors.New(fmt.Sprint("User not found. username: ", uname))
-		rErr = domain.NewUCaseErr(http.StatusNotFound, err)
+	target, rErr := u.getDetailed(ctx, uname)
+	if rErr != nil {
 		return
 	}
 
+	/*
+	* Authorize before touching anything: changing someone else, whatever the
+	* field, or one's own role or state takes user.write over the target
+	 */
+	actor, _ := domain.UserFromContext(ctx)
+	changesAccess := uUp.Role.Description != "" || uUp.State.Description != ""
+	if actor.Uuid != target.Uuid || changesAccess {
+		if rErr = u.checkPrivileges(ctx, target); rErr != nil {
+			return
+		}
+	}
+
+	var r domain.Role
+	if uUp.Role.Description != "" {
+		var err error
+		r, err = u.roleRepo.GetByDescription(ctx, uUp.Role.Description)
+		if err != nil {
+			u.log.Error("IN [Update]: could not get role ->", err)
+			rErr = domain.NewRepoErr(err, "Role not found")
+			return
+		}
+
+		ok, err := u.authorizer.Covers(ctx, actor, r)
+		if err != nil {
+			u.log.Error("IN [Update]: could not compare roles ->", err)
+			rErr = domain.NewUCaseErr(http.StatusInternalServerError, err)
+			return
+		}
+		if !ok {
+			err = errors.New("Can not grant a role with permissions you lack")
+			rErr = domain.NewUCaseErr(http.StatusForbidden, err)
+			return
+		}
+
+		if rErr = u.checkLastManager(ctx, target, &r); rErr != nil {
+			return
+		}
+	}
+
+	var s domain.UserState
+	if uUp.State.Description != "" {
+		var err error
+		s, err = u.userStateRepo.GetByDescription(ctx, uUp.State.Description)
+		if err != nil {
+			u.log.Error("IN [Update]: could not get user_state ->", err)
+			rErr = domain.NewRepoErr(err, "User_state not found")
+			return
+		}
+
+		if s.Description != domain.UserStateActive && target.State.Description == domain.UserStateActive {
+			if rErr = u.checkLastManager(ctx, target, nil); rErr != nil {
+				return
+			}
+		}
+	}
+
 	if uUp.Email != "" {
 		err := u.userRepo.ChgEmail(ctx, uname, uUp.Email)
 		if err != nil {
 			u.log.Error("IN [Update]: could not change email ->", err)
-			err = errors.New(fmt.Sprint("User patch failed: ", err))
-			rErr = domain.NewUCaseErr(http.StatusInternalServerError, err)
+			if errors.Is(err, domain.ErrConflict) {
+				err = errors.New("Email already taken")
+				rErr = domain.NewUCaseErr(http.StatusConflict, err)
+				return
+			}
+			rErr = domain.NewRepoErr(err, fmt.Sprint("User not found. username: ", uname))
 			return
 		}
 	}
@@ -211,8 +519,7 @@ func (u *userUsecase) Update(c context.Context, uname string, uUp *domain.User)
 		err := u.userRepo.ChgName(ctx, uname, uUp.Name)
 		if err != nil {
 			u.log.Error("IN [Update]: could not change name ->", err)
-			err = errors.New(fmt.Sprint("User patch failed: ", err))
-			rErr = domain.NewUCaseErr(http.StatusInternalServerError, err)
+			rErr = domain.NewRepoErr(err, fmt.Sprint("User not found. username: ", uname))
 			return
 		}
 	}
@@ -221,59 +528,92 @@ func (u *userUsecase) Update(c context.Context, uname string, uUp *domain.User)
 		err := u.userRepo.ChgLstname(ctx, uname, uUp.Lastname)
 		if err != nil {
 			u.log.Error("IN [Update]: could not change lastname ->", err)
-			err = errors.New(fmt.Sprint("User patch failed: ", err))
-			rErr = domain.NewUCaseErr(http.StatusInternalServerError, err)
+			rErr = domain.NewRepoErr(err, fmt.Sprint("User not found. username: ", uname))
 			return
 		}
 	}
 
 	if uUp.Role.Description != "" {
-		r, err := u.roleRepo.GetByDescription(ctx, uUp.Role.Description)
-		if err != nil {
-			u.log.Error("IN [Update]: could not get role ->", err)
-			err = errors.New(fmt.Sprint("Role not found"))
-			rErr = domain.NewUCaseErr(http.StatusNotFound, err)
-			return
+		err := u.userRepo.ChgRole(ctx, uname, r, domain.PermRoleManage)
+		if errors.Is(err, domain.ErrLastHolder) {
+			return lastManagerErr
 		}
-		err = u.userRepo.ChgRole(ctx, uname, r)
 		if err != nil {
 			u.log.Error("IN [Update]: could not change role ->", err)
-			err = errors.New(fmt.Sprint("User patch failed: ", err))
-			rErr = domain.NewUCaseErr(http.StatusInternalServerError, err)
+			rErr = domain.NewRepoErr(err, fmt.Sprint("User not found. username: ", uname))
 			return
 		}
 	}
 
 	if uUp.State.Description != "" {
-		s, err := u.userStateRepo.GetByDescription(ctx, uUp.State.Description)
-		if err != nil {
-			u.log.Error("IN [Update]: could not get user_state ->", err)
-			err = errors.New(fmt.Sprint("User_state not found"))
-			rErr = domain.NewUCaseErr(http.StatusNotFound, err)
+		err := u.userRepo.ChgState(ctx, uname, s, domain.PermRoleManage)
+		if errors.Is(err, domain.ErrLastHolder) {
+			return lastManagerErr
 		}
-
-		err = u.userRepo.ChgState(ctx, uname, s)
 		if err != nil {
 			u.log.Error("IN [Update]: could not change user_state ->", err)
-			err = errors.New(fmt.Sprint("User patch failed: ", err))
-			rErr = domain.NewUCaseErr(http.StatusInternalServerError, err)
+			rErr = domain.NewRepoErr(err, fmt.Sprint("User not found. username: ", uname))
 			return
 		}
+
+		// Tokens are checked against the state, revoking the sessions ends them sooner
+		if s.Description != domain.UserStateActive && target.State.Description == domain.UserStateActive {
+			if err = u.sessionRepo.RevokeAllByUser(ctx, target.Uuid); err != nil {
+				u.log.Error("IN [Update]: could not revoke sessions of {", uname, "} ->", err)
+			}
+		}
+
+		if s.Description == domain.UserStateActive && target.State.Description != domain.UserStateActive {
+			n := domain.NewActivationNotification(target, time.Now().UTC())
+			if err = u.notifier.Notify(ctx, n); err != nil {
+				u.log.Warn("IN [Update]: could not notify {", uname, "} ->", err)
+			}
+		}
 	}
 
 	return
 }
 
-func (u *userUsecase) Login(c context.Context, uname string, passwd string) (res domain.User, rErr domain.RequestErr) {
+func (u *userUsecase) Login(c context.Context, uname string, passwd string, ip string) (res domain.User, rErr domain.RequestErr) {
 	// Refactor flluserdetails
 	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
 	defer cancel()
 
-	res, err := u.userRepo.Login(ctx, uname, passwd)
+	if rErr = u.throttleUcase.Check(ctx, uname, ip); rErr != nil {
+		return
+	}
+
+	res, err := u.userRepo.GetByUsername(ctx, uname)
+	if errors.Is(err, domain.ErrNotFound) {
+		u.throttleUcase.RecordFailure(ctx, uname, ip)
+		err = errors.New("Incorrect password or username")
+		rErr = domain.NewUCaseErr(http.StatusUnauthorized, err)
+		return domain.User{}, rErr
+	}
 	if err != nil {
+		u.log.Error("IN [Login]: could not get user ->", err)
+		return domain.User{}, domain.NewRepoErr(err, "")
+	}
+
+	if err = u.hasher.Compare(res.Password, passwd); err != nil {
+		u.throttleUcase.RecordFailure(ctx, uname, ip)
+		err = errors.New("Incorrect password or username")
 		rErr = domain.NewUCaseErr(http.StatusUnauthorized, err)
-		return
+		return domain.User{}, rErr
+	}
+	u.throttleUcase.RecordSuccess(ctx, uname, ip)
+
+	// Transparently upgrade hashes generated with outdated cost parameters
+	if u.hasher.NeedsRehash(res.Password) {
+		hash, err := u.hasher.Hash(passwd)
+		if err == nil {
+			err = u.userRepo.ChgPasswd(ctx, uname, hash)
+		}
+		if err != nil {
+			u.log.Warn("IN [Login]: could not rehash password of {", uname, "} ->", err)
+		}
 	}
+	res.Password = ""
 
 	resArr := make([]domain.User, 1)
 	resArr[0] = res
@@ -284,5 +624,64 @@ func (u *userUsecase) Login(c context.Context, uname string, passwd string) (res
 		rErr = domain.NewUCaseErr(http.StatusInternalServerError, err)
 		return
 	}
+
+	// Only active accounts may log in, whatever other states get created
+	if res.State.Description != domain.UserStateActive {
+		err = errors.New("Account not active")
+		if res.State.Description == domain.UserStateInactive {
+			err = errors.New("Account not activated, follow the link sent to your email")
+		}
+		rErr = domain.NewUCaseErr(http.StatusForbidden, err)
+		return domain.User{}, rErr
+	}
+	return
+}
+
+// checkExists answers 404 when no user is called uname
+func (u *userUsecase) checkExists(ctx context.Context, uname string) (rErr domain.RequestErr) {
+	exists, err := u.userRepo.ExistByUname(ctx, uname)
+	if err != nil {
+		u.log.Error("IN [checkExists]: could not check user {", uname, "} ->", err)
+		return domain.NewRepoErr(err, "")
+	}
+	if !exists {
+		err = errors.New(fmt.Sprint("User not found. username: ", uname))
+		return domain.NewUCaseErr(http.StatusNotFound, err)
+	}
+
 	return
 }
+
+func (u *userUsecase) GetLock(c context.Context, uname string) (res domain.LoginLock, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	if rErr = u.checkExists(ctx, uname); rErr != nil {
+		return
+	}
+
+	return u.throttleUcase.GetLock(ctx, uname)
+}
+
+func (u *userUsecase) Unlock(c context.Context, uname string) (rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	if rErr = u.checkExists(ctx, uname); rErr != nil {
+		return
+	}
+
+	u.log.Info("IN [Unlock]: clearing login lock of {", uname, "}")
+	return u.throttleUcase.Unlock(ctx, uname)
+}
+
+func (u *userUsecase) FetchLoginAttempts(c context.Context, uname string, limit int64) (res []domain.LoginAttempt, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	if rErr = u.checkExists(ctx, uname); rErr != nil {
+		return
+	}
+
+	return u.throttleUcase.FetchAttempts(ctx, uname, limit)
+}
@@ -0,0 +1,305 @@
+package usecase_test
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/user/usecase"
+)
+
+var (
+	superRole     = domain.Role{Code: 1, Description: domain.RoleSuper, Level: 3}
+	adminRole     = domain.Role{Code: 2, Description: domain.RoleAdmin, Level: 2}
+	standardRole  = domain.Role{Code: 3, Description: domain.RoleStandard, Level: 1}
+	activeState   = domain.UserState{Code: 1, Description: domain.UserStateActive}
+	inactiveState = domain.UserState{Code: 2, Description: domain.UserStateInactive}
+)
+
+// rolePerms lists which of the fake roles may manage roles
+var rolePerms = map[int64]bool{superRole.Code: true, adminRole.Code: true}
+
+// fakeUserRepo keeps users in memory, guarding its writes like the postgres one
+type fakeUserRepo struct {
+	domain.UserRepository
+	mu    sync.Mutex
+	users map[string]domain.User
+}
+
+func newFakeUserRepo(users ...domain.User) *fakeUserRepo {
+	r := &fakeUserRepo{users: map[string]domain.User{}}
+	for _, u := range users {
+		r.users[u.Username] = u
+	}
+	return r
+}
+
+func (r *fakeUserRepo) holders() (n int64) {
+	for _, u := range r.users {
+		if rolePerms[u.Role.Code] && u.State.Code == activeState.Code {
+			n++
+		}
+	}
+	return
+}
+
+func (r *fakeUserRepo) GetByUsername(ctx context.Context, uname string) (domain.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.users[uname]
+	if !ok {
+		return domain.User{}, domain.ErrNotFound
+	}
+	return u, nil
+}
+
+func (r *fakeUserRepo) CountByPermission(ctx context.Context, perm string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.holders(), nil
+}
+
+func (r *fakeUserRepo) Delete(ctx context.Context, uname string, keep string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.users[uname]
+	if !ok {
+		return domain.ErrNotFound
+	}
+	delete(r.users, uname)
+	if keep != "" && r.holders() < 1 {
+		r.users[uname] = u
+		return domain.ErrLastHolder
+	}
+	return nil
+}
+
+func (r *fakeUserRepo) ChgRole(ctx context.Context, uname string, ro domain.Role, keep string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.users[uname]
+	if !ok {
+		return domain.ErrNotFound
+	}
+	old := u.Role
+	u.Role = ro
+	r.users[uname] = u
+	if keep != "" && r.holders() < 1 {
+		u.Role = old
+		r.users[uname] = u
+		return domain.ErrLastHolder
+	}
+	return nil
+}
+
+func (r *fakeUserRepo) ChgState(ctx context.Context, uname string, st domain.UserState, keep string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.users[uname]
+	if !ok {
+		return domain.ErrNotFound
+	}
+	old := u.State
+	u.State = st
+	r.users[uname] = u
+	if keep != "" && r.holders() < 1 {
+		u.State = old
+		r.users[uname] = u
+		return domain.ErrLastHolder
+	}
+	return nil
+}
+
+func (r *fakeUserRepo) ChgEmail(ctx context.Context, uname string, email string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.users[uname]
+	if !ok {
+		return domain.ErrNotFound
+	}
+	u.Email = email
+	r.users[uname] = u
+	return nil
+}
+
+type fakeRoleRepo struct{ domain.RoleRepository }
+
+func (fakeRoleRepo) GetAll(ctx context.Context) ([]domain.Role, error) {
+	return []domain.Role{superRole, adminRole, standardRole}, nil
+}
+
+func (fakeRoleRepo) GetByDescription(ctx context.Context, desc string) (domain.Role, error) {
+	for _, r := range []domain.Role{superRole, adminRole, standardRole} {
+		if r.Description == desc {
+			return r, nil
+		}
+	}
+	return domain.Role{}, domain.ErrNotFound
+}
+
+type fakeStateRepo struct{ domain.UserStateRepository }
+
+func (fakeStateRepo) GetAll(ctx context.Context) ([]domain.UserState, error) {
+	return []domain.UserState{activeState, inactiveState}, nil
+}
+
+func (fakeStateRepo) GetByDescription(ctx context.Context, desc string) (domain.UserState, error) {
+	for _, s := range []domain.UserState{activeState, inactiveState} {
+		if s.Description == desc {
+			return s, nil
+		}
+	}
+	return domain.UserState{}, domain.ErrNotFound
+}
+
+/*
+* fakeAuthorizer lets everyone write users, managers being the fake roles that
+* hold role.manage. A role covers the ones of its level and below
+ */
+type fakeAuthorizer struct{}
+
+func (fakeAuthorizer) Can(ctx context.Context, u domain.User, perm string) (bool, error) {
+	return perm == domain.PermRoleManage && rolePerms[u.Role.Code], nil
+}
+
+func (fakeAuthorizer) Covers(ctx context.Context, u domain.User, r domain.Role) (bool, error) {
+	return u.Role.Level >= r.Level, nil
+}
+
+func (fakeAuthorizer) Authorize(ctx context.Context, perm string) domain.RequestErr {
+	return nil
+}
+
+func newUsecase(ur domain.UserRepository) domain.UserUsecase {
+	return usecase.NewUserUsecase(
+		ur,
+		fakeRoleRepo{},
+		fakeStateRepo{},
+		nil,
+		nil,
+		domain.PasswordPolicy{},
+		fakeAuthorizer{},
+		nil,
+		nil,
+		nil,
+		time.Second,
+	)
+}
+
+func manager(uname string, r domain.Role) domain.User {
+	return domain.User{Uuid: uname, Username: uname, Role: r, State: activeState}
+}
+
+func actorCtx() context.Context {
+	return domain.NewContextWithUser(context.Background(), manager("root", superRole))
+}
+
+func TestDeleteKeepsLastManager(t *testing.T) {
+	ur := newFakeUserRepo(manager("ana", superRole), manager("bob", adminRole))
+	uu := newUsecase(ur)
+
+	if rErr := uu.Delete(actorCtx(), "ana"); rErr != nil {
+		t.Fatalf("deleting one of two managers: %v", rErr)
+	}
+
+	rErr := uu.Delete(actorCtx(), "bob")
+	if rErr == nil || rErr.GetStatus() != http.StatusConflict {
+		t.Fatalf("deleting the last manager: got %v, want 409", rErr)
+	}
+	if _, ok := ur.users["bob"]; !ok {
+		t.Fatal("the last manager was deleted")
+	}
+}
+
+func TestDemotionKeepsLastManager(t *testing.T) {
+	ur := newFakeUserRepo(manager("ana", superRole), manager("eve", standardRole))
+	uu := newUsecase(ur)
+
+	// A role that still manages roles is always fine
+	up := &domain.User{Role: domain.Role{Description: domain.RoleAdmin}}
+	if rErr := uu.Update(actorCtx(), "ana", up); rErr != nil {
+		t.Fatalf("moving the last manager to another managing role: %v", rErr)
+	}
+
+	up = &domain.User{Role: domain.Role{Description: domain.RoleStandard}}
+	rErr := uu.Update(actorCtx(), "ana", up)
+	if rErr == nil || rErr.GetStatus() != http.StatusConflict {
+		t.Fatalf("demoting the last manager: got %v, want 409", rErr)
+	}
+	if ur.users["ana"].Role.Code != adminRole.Code {
+		t.Fatal("the last manager was demoted")
+	}
+}
+
+func TestDeactivationKeepsLastActiveManager(t *testing.T) {
+	retired := manager("old", superRole)
+	retired.State = inactiveState
+	ur := newFakeUserRepo(manager("ana", superRole), retired)
+	uu := newUsecase(ur)
+
+	up := &domain.User{State: domain.UserState{Description: domain.UserStateInactive}}
+	rErr := uu.Update(actorCtx(), "ana", up)
+	if rErr == nil || rErr.GetStatus() != http.StatusConflict {
+		t.Fatalf("deactivating the last active manager: got %v, want 409", rErr)
+	}
+	if ur.users["ana"].State.Code != activeState.Code {
+		t.Fatal("the last active manager was deactivated")
+	}
+
+	// An inactive manager leaves without taking role.manage away from anyone active
+	if rErr = uu.Delete(actorCtx(), "old"); rErr != nil {
+		t.Fatalf("deleting an inactive manager: %v", rErr)
+	}
+}
+
+func TestConcurrentRemovalsKeepOneManager(t *testing.T) {
+	for i := 0; i < 50; i++ {
+		ur := newFakeUserRepo(manager("ana", superRole), manager("bob", adminRole))
+		uu := newUsecase(ur)
+
+		var wg sync.WaitGroup
+		errs := make([]domain.RequestErr, 2)
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			errs[0] = uu.Delete(actorCtx(), "ana")
+		}()
+		go func() {
+			defer wg.Done()
+			up := &domain.User{Role: domain.Role{Description: domain.RoleStandard}}
+			errs[1] = uu.Update(actorCtx(), "bob", up)
+		}()
+		wg.Wait()
+
+		if ur.holders() != 1 {
+			t.Fatalf("run %d: %d managers left, errors %v", i, ur.holders(), errs)
+		}
+		if (errs[0] == nil) == (errs[1] == nil) {
+			t.Fatalf("run %d: want exactly one removal to fail, got %v", i, errs)
+		}
+	}
+}
+
+func TestUpdateOfAnotherUserNeedsToCoverThem(t *testing.T) {
+	ur := newFakeUserRepo(manager("root", superRole), manager("ana", adminRole), manager("eve", standardRole))
+	uu := newUsecase(ur)
+	asAna := domain.NewContextWithUser(context.Background(), ur.users["ana"])
+
+	rErr := uu.Update(asAna, "root", &domain.User{Email: "ana@example.com"})
+	if rErr == nil || rErr.GetStatus() != http.StatusForbidden {
+		t.Fatalf("admin changing the email of a super: got %v, want 403", rErr)
+	}
+	if ur.users["root"].Email != "" {
+		t.Fatal("the email of the super was changed")
+	}
+
+	if rErr = uu.Update(asAna, "eve", &domain.User{Email: "eve@example.com"}); rErr != nil {
+		t.Fatalf("admin changing the email of a standard user: %v", rErr)
+	}
+	if rErr = uu.Update(asAna, "ana", &domain.User{Email: "ana@example.com"}); rErr != nil {
+		t.Fatalf("admin changing their own email: %v", rErr)
+	}
+}
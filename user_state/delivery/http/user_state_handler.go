@@ -0,0 +1,143 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sicozz/papyrus/auth/delivery/http/middleware"
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/domain/dtos"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+	"github.com/sicozz/papyrus/utils/delivery"
+)
+
+// UserStateHandler will initialize the user_state/ resources endpoint
+type UserStateHandler struct {
+	USUsecase domain.UserStateUsecase
+	log       utils.AggregatedLogger
+}
+
+func NewUserStateHandler(e *echo.Echo, usu domain.UserStateUsecase, authMw echo.MiddlewareFunc, authz *middleware.AuthzMiddleware) {
+	logger := utils.NewAggregatedLogger(constants.Delivery, constants.UserState)
+	handler := &UserStateHandler{usu, logger}
+	manage := authz.Require(middleware.Can(domain.PermUserWrite))
+	g := e.Group("/user_state", authMw)
+	g.GET("", handler.Fetch)
+	g.POST("", handler.Store, manage)
+	g.GET("/:code", handler.GetByCode)
+	g.PATCH("/:code", handler.Rename, manage)
+	g.DELETE("/:code", handler.Delete, manage)
+}
+
+func (h *UserStateHandler) Fetch(c echo.Context) error {
+	h.log.Info("REQ: fetch")
+	ctx := c.Request().Context()
+	states, rErr := h.USUsecase.Fetch(ctx)
+	if rErr != nil {
+		errBody := dtos.NewErrDto("User_state fetch failed")
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.JSON(http.StatusOK, states)
+}
+
+func (h *UserStateHandler) GetByCode(c echo.Context) error {
+	h.log.Info("REQ: get by code")
+	ctx := c.Request().Context()
+	code, err := strconv.ParseInt(c.Param("code"), 10, 64)
+	if err != nil {
+		errBody := dtos.NewErrDto("User_state code must be an integer")
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	state, rErr := h.USUsecase.GetByCode(ctx, code)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.JSON(http.StatusOK, state)
+}
+
+func (h *UserStateHandler) Store(c echo.Context) (err error) {
+	h.log.Info("REQ: store")
+	var state domain.UserState
+	err = c.Bind(&state)
+	if err != nil {
+		errBody := dtos.NewErrDto(err.Error())
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	if ok, err := delivery.IsRequestValid(&state); !ok {
+		errBody, err := dtos.NewValidationErrDto(err.Error())
+		if err != nil {
+			errParse := dtos.NewErrDto(err.Error())
+			return c.JSON(http.StatusBadRequest, errParse)
+		}
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	ctx := c.Request().Context()
+	rErr := h.USUsecase.Store(ctx, &state)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.JSON(http.StatusCreated, state)
+}
+
+func (h *UserStateHandler) Rename(c echo.Context) error {
+	h.log.Info("REQ: rename")
+	ctx := c.Request().Context()
+	code, err := strconv.ParseInt(c.Param("code"), 10, 64)
+	if err != nil {
+		errBody := dtos.NewErrDto("User_state code must be an integer")
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	var rDto dtos.RenameDto
+	err = c.Bind(&rDto)
+	if err != nil {
+		errBody := dtos.NewErrDto(fmt.Sprint("Req body binding failed: ", err))
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	if ok, err := delivery.IsRequestValid(&rDto); !ok {
+		errBody, err := dtos.NewValidationErrDto(err.Error())
+		if err != nil {
+			errValid := dtos.NewErrDto(fmt.Sprint("Req body validation failed: ", err))
+			return c.JSON(http.StatusBadRequest, errValid)
+		}
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	rErr := h.USUsecase.Rename(ctx, code, rDto.Description)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+func (h *UserStateHandler) Delete(c echo.Context) error {
+	h.log.Info("REQ: delete")
+	ctx := c.Request().Context()
+	code, err := strconv.ParseInt(c.Param("code"), 10, 64)
+	if err != nil {
+		errBody := dtos.NewErrDto("User_state code must be an integer")
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	rErr := h.USUsecase.Delete(ctx, code)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.NoContent(http.StatusOK)
+}
@@ -3,12 +3,11 @@ package postgres
 import (
 	"context"
 	"database/sql"
-	"errors"
-	"fmt"
 
 	"github.com/sicozz/papyrus/domain"
 	"github.com/sicozz/papyrus/utils"
 	"github.com/sicozz/papyrus/utils/constants"
+	"github.com/sicozz/papyrus/utils/repository"
 )
 
 type postgresUserStateRepository struct {
@@ -29,7 +28,7 @@ func (r *postgresUserStateRepository) fetch(ctx context.Context, query string, a
 	rows, err := r.Conn.QueryContext(ctx, query, args...)
 	if err != nil {
 		r.log.Error(err)
-		return nil, err
+		return nil, repository.MapErr(err)
 	}
 
 	defer func() {
@@ -55,7 +54,7 @@ func (r *postgresUserStateRepository) fetch(ctx context.Context, query string, a
 		res = append(res, t)
 	}
 
-	return res, nil
+	return res, rows.Err()
 }
 
 func (r *postgresUserStateRepository) GetByCode(ctx context.Context, code int64) (res domain.UserState, err error) {
@@ -66,9 +65,8 @@ func (r *postgresUserStateRepository) GetByCode(ctx context.Context, code int64)
 		return domain.UserState{}, err
 	}
 
-	if l := len(states); l != 1 {
-		r.log.Error("Could not find user_state with code: ", code)
-		return domain.UserState{}, err
+	if len(states) < 1 {
+		return domain.UserState{}, domain.ErrNotFound
 	}
 
 	res = states[0]
@@ -83,10 +81,8 @@ func (r *postgresUserStateRepository) GetByDescription(ctx context.Context, desc
 		return domain.UserState{}, err
 	}
 
-	if l := len(states); l != 1 {
-		r.log.Error("Could not find user_state with description: ", desc)
-		err = errors.New(fmt.Sprint("No user__state with description: ", desc))
-		return domain.UserState{}, err
+	if len(states) < 1 {
+		return domain.UserState{}, domain.ErrNotFound
 	}
 
 	res = states[0]
@@ -97,3 +93,39 @@ func (r *postgresUserStateRepository) GetAll(ctx context.Context) ([]domain.User
 	query := `SELECT code, description FROM user_state`
 	return r.fetch(ctx, query)
 }
+
+// Store a new user_state, a taken description is ErrConflict
+func (r *postgresUserStateRepository) Store(ctx context.Context, s *domain.UserState) (err error) {
+	query := `INSERT INTO user_state (description) VALUES ($1) RETURNING code`
+	err = r.Conn.QueryRowContext(ctx, query, s.Description).Scan(&s.Code)
+	if err != nil {
+		r.log.Error("IN [Store]: could not store user_state ->", err)
+		err = repository.MapErr(err)
+	}
+
+	return
+}
+
+// Rename a user_state, a taken description is ErrConflict
+func (r *postgresUserStateRepository) Rename(ctx context.Context, code int64, desc string) (err error) {
+	query := `UPDATE user_state SET description=$1 WHERE code=$2`
+	res, err := r.Conn.ExecContext(ctx, query, desc, code)
+	if err != nil {
+		r.log.Error("IN [Rename]: could not rename user_state ->", err)
+		return repository.MapErr(err)
+	}
+
+	return repository.CheckAffected(res)
+}
+
+// Delete a user_state, one still referenced by users is ErrConflict
+func (r *postgresUserStateRepository) Delete(ctx context.Context, code int64) (err error) {
+	query := `DELETE FROM user_state WHERE code=$1`
+	res, err := r.Conn.ExecContext(ctx, query, code)
+	if err != nil {
+		r.log.Error("IN [Delete]: could not delete user_state ->", err)
+		return repository.MapDeleteErr(err)
+	}
+
+	return repository.CheckAffected(res)
+}
@@ -0,0 +1,166 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+)
+
+type userStateUsecase struct {
+	userStateRepo  domain.UserStateRepository
+	userRepo       domain.UserRepository
+	contextTimeout time.Duration
+	log            utils.AggregatedLogger
+}
+
+// NewUserStateUsecase will create a new userStateUsecase object representation of domain.UserStateUsecase interface
+func NewUserStateUsecase(usr domain.UserStateRepository, ur domain.UserRepository, timeout time.Duration) domain.UserStateUsecase {
+	logger := utils.NewAggregatedLogger(constants.Usecase, constants.UserState)
+	return &userStateUsecase{
+		userStateRepo:  usr,
+		userRepo:       ur,
+		contextTimeout: timeout,
+		log:            logger,
+	}
+}
+
+// The account lifecycle relies on the base user_states
+func isBaseState(s domain.UserState) bool {
+	return s.Description == domain.UserStateInactive || s.Description == domain.UserStateActive
+}
+
+func (u *userStateUsecase) Fetch(c context.Context) (res []domain.UserState, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	res, err := u.userStateRepo.GetAll(ctx)
+	if err != nil {
+		u.log.Error("IN [Fetch]: could not get user_states ->", err)
+		rErr = domain.NewUCaseErr(http.StatusInternalServerError, err)
+		return
+	}
+
+	return
+}
+
+func (u *userStateUsecase) GetByCode(c context.Context, code int64) (res domain.UserState, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	res, err := u.userStateRepo.GetByCode(ctx, code)
+	if err != nil {
+		u.log.Error("IN [GetByCode]: could not get user_state ->", err)
+		rErr = domain.NewRepoErr(err, fmt.Sprint("User_state not found. code: ", code))
+		return domain.UserState{}, rErr
+	}
+
+	return
+}
+
+func (u *userStateUsecase) GetByDescription(c context.Context, desc string) (res domain.UserState, rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	res, err := u.userStateRepo.GetByDescription(ctx, desc)
+	if err != nil {
+		u.log.Error("IN [GetByDescription]: could not get user_state ->", err)
+		rErr = domain.NewRepoErr(err, fmt.Sprint("User_state not found. description: ", desc))
+		return
+	}
+
+	return
+}
+
+func (u *userStateUsecase) Store(c context.Context, s *domain.UserState) (rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	err := u.userStateRepo.Store(ctx, s)
+	if err != nil {
+		u.log.Error("IN [Store]: could not store user_state ->", err)
+		rErr = domain.NewRepoErr(err, "User_state description already taken")
+		return
+	}
+
+	return
+}
+
+func (u *userStateUsecase) Rename(c context.Context, code int64, desc string) (rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	st, rErr := u.GetByCode(ctx, code)
+	if rErr != nil {
+		return
+	}
+
+	if isBaseState(st) {
+		err := errors.New("Base user_states can not be renamed")
+		rErr = domain.NewUCaseErr(http.StatusConflict, err)
+		return
+	}
+
+	err := u.userStateRepo.Rename(ctx, code, desc)
+	if errors.Is(err, domain.ErrConflict) {
+		err = errors.New("User_state description already taken")
+		rErr = domain.NewUCaseErr(http.StatusConflict, err)
+		return
+	}
+	if err != nil {
+		u.log.Error("IN [Rename]: could not rename user_state ->", err)
+		rErr = domain.NewRepoErr(err, fmt.Sprint("User_state not found. code: ", code))
+		return
+	}
+
+	return
+}
+
+func (u *userStateUsecase) Delete(c context.Context, code int64) (rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	st, rErr := u.GetByCode(ctx, code)
+	if rErr != nil {
+		return
+	}
+
+	if isBaseState(st) {
+		err := errors.New("Base user_states can not be deleted")
+		rErr = domain.NewUCaseErr(http.StatusConflict, err)
+		return
+	}
+
+	n, err := u.userRepo.CountByState(ctx, st)
+	if err != nil {
+		u.log.Error("IN [Delete]: could not count user_state users ->", err)
+		rErr = domain.NewUCaseErr(http.StatusInternalServerError, err)
+		return
+	}
+
+	if n > 0 {
+		err = errors.New(fmt.Sprint("User_state still assigned to ", n, " users"))
+		rErr = domain.NewUCaseErr(http.StatusConflict, err)
+		return
+	}
+
+	// A user may have taken the user_state since it was counted
+	err = u.userStateRepo.Delete(ctx, code)
+	if errors.Is(err, domain.ErrConflict) {
+		err = errors.New("User_state still assigned to users")
+		rErr = domain.NewUCaseErr(http.StatusConflict, err)
+		return
+	}
+	if err != nil {
+		u.log.Error("IN [Delete]: could not delete user_state ->", err)
+		rErr = domain.NewRepoErr(err, fmt.Sprint("User_state not found. code: ", code))
+		return
+	}
+
+	return
+}
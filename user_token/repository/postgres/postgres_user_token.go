@@ -0,0 +1,87 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+)
+
+type postgresUserTokenRepository struct {
+	Conn *sql.DB
+	log  utils.AggregatedLogger
+}
+
+/*
+* NewPostgresUserTokenRepository will create an object that represent the
+* UserTokenRepository interface
+ */
+func NewPostgresUserTokenRepository(conn *sql.DB) domain.UserTokenRepository {
+	logger := utils.NewAggregatedLogger(constants.Repository, constants.UserToken)
+	return &postgresUserTokenRepository{conn, logger}
+}
+
+// Store a new user token
+func (r *postgresUserTokenRepository) Store(ctx context.Context, t *domain.UserToken) (err error) {
+	query :=
+		`INSERT INTO user_token (user_, purpose, token_hash, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING uuid`
+	stmt, err := r.Conn.PrepareContext(ctx, query)
+	if err != nil {
+		r.log.Error("IN [Store]: could not prepare context ->", err)
+		return
+	}
+	defer stmt.Close()
+
+	err = stmt.QueryRowContext(
+		ctx,
+		t.UserUuid,
+		t.Purpose,
+		t.TokenHash,
+		t.CreatedAt,
+		t.ExpiresAt,
+	).Scan(&t.Uuid)
+
+	return
+}
+
+// Consume a token, so it can not be used twice
+func (r *postgresUserTokenRepository) Consume(ctx context.Context, purpose string, hash string, now time.Time) (res domain.UserToken, err error) {
+	query :=
+		`UPDATE user_token SET used_at = $3
+		WHERE purpose = $1 AND token_hash = $2 AND used_at IS NULL AND expires_at > $3
+		RETURNING uuid, user_, purpose, token_hash, created_at, expires_at`
+
+	err = r.Conn.QueryRowContext(ctx, query, purpose, hash, now).Scan(
+		&res.Uuid,
+		&res.UserUuid,
+		&res.Purpose,
+		&res.TokenHash,
+		&res.CreatedAt,
+		&res.ExpiresAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.UserToken{}, domain.ErrNotFound
+	}
+	if err != nil {
+		r.log.Error("IN [Consume]: could not consume token ->", err)
+	}
+
+	return
+}
+
+// Delete every token of a user for a purpose
+func (r *postgresUserTokenRepository) DeleteByUser(ctx context.Context, userUuid string, purpose string) (err error) {
+	query := `DELETE FROM user_token WHERE user_ = $1 AND purpose = $2`
+	_, err = r.Conn.ExecContext(ctx, query, userUuid, purpose)
+	if err != nil {
+		r.log.Error("IN [DeleteByUser]: could not delete tokens ->", err)
+	}
+
+	return
+}
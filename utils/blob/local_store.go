@@ -0,0 +1,130 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+)
+
+type localStore struct {
+	root string
+	log  utils.AggregatedLogger
+}
+
+/*
+* NewLocalStore will create an object that represent the BlobStore interface.
+* Blobs are kept as plain files below root
+ */
+func NewLocalStore(root string) (domain.BlobStore, error) {
+	if err := os.MkdirAll(root, 0o750); err != nil {
+		return nil, err
+	}
+
+	logger := utils.NewAggregatedLogger(constants.Utils, constants.Blob)
+	return &localStore{root, logger}, nil
+}
+
+// path maps key below root, refusing keys that would escape it
+func (s *localStore) path(key string) (string, error) {
+	p := filepath.Join(s.root, filepath.FromSlash(key))
+	if !strings.HasPrefix(p, filepath.Clean(s.root)+string(filepath.Separator)) {
+		return "", domain.ErrBadParamInput
+	}
+	return p, nil
+}
+
+func mapErr(err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return domain.ErrNotFound
+	}
+	return err
+}
+
+// Put writes to a temporary file renamed into place, so readers never see partial blobs
+func (s *localStore) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) (err error) {
+	p, err := s.path(key)
+	if err != nil {
+		return
+	}
+	if err = os.MkdirAll(filepath.Dir(p), 0o750); err != nil {
+		return
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".upload-*")
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	_, err = io.Copy(tmp, readerWithContext(ctx, r))
+	if errClose := tmp.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		s.log.Error("IN [Put]: could not write blob {", key, "} ->", err)
+		return
+	}
+
+	return os.Rename(tmp.Name(), p)
+}
+
+func (s *localStore) Get(ctx context.Context, key string, offset int64, length int64) (res io.ReadCloser, err error) {
+	p, err := s.path(key)
+	if err != nil {
+		return
+	}
+
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, mapErr(err)
+	}
+
+	if _, err = f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return
+	}
+
+	if length < 0 {
+		return f, nil
+	}
+
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(f, length), f}, nil
+}
+
+func (s *localStore) Stat(ctx context.Context, key string) (res domain.BlobInfo, err error) {
+	p, err := s.path(key)
+	if err != nil {
+		return
+	}
+
+	fi, err := os.Stat(p)
+	if err != nil {
+		return res, mapErr(err)
+	}
+
+	return domain.BlobInfo{Key: key, Size: fi.Size()}, nil
+}
+
+func (s *localStore) Delete(ctx context.Context, key string) (err error) {
+	p, err := s.path(key)
+	if err != nil {
+		return
+	}
+
+	return mapErr(os.Remove(p))
+}
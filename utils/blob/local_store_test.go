@@ -0,0 +1,117 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sicozz/papyrus/domain"
+)
+
+func readAll(t *testing.T, rc io.ReadCloser) string {
+	t.Helper()
+	defer rc.Close()
+	b, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("reading blob: %v", err)
+	}
+	return string(b)
+}
+
+func TestLocalStoreRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = s.Put(ctx, "ab/abcdef", strings.NewReader("0123456789"), -1, "text/plain"); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+
+	rc, err := s.Get(ctx, "ab/abcdef", 0, -1)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got := readAll(t, rc); got != "0123456789" {
+		t.Fatalf("get: got %q", got)
+	}
+
+	rc, err = s.Get(ctx, "ab/abcdef", 3, 4)
+	if err != nil {
+		t.Fatalf("get range: %v", err)
+	}
+	if got := readAll(t, rc); got != "3456" {
+		t.Fatalf("get range: got %q, want 3456", got)
+	}
+
+	info, err := s.Stat(ctx, "ab/abcdef")
+	if err != nil || info.Size != 10 {
+		t.Fatalf("stat: got %+v, %v", info, err)
+	}
+
+	if err = s.Delete(ctx, "ab/abcdef"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err = s.Get(ctx, "ab/abcdef", 0, -1); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("get after delete: got %v, want ErrNotFound", err)
+	}
+	if err = s.Delete(ctx, "ab/abcdef"); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("delete twice: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestLocalStoreRefusesEscapingKeys(t *testing.T) {
+	s, err := NewLocalStore(filepath.Join(t.TempDir(), "blobs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"../outside", "ab/../../outside", ".."} {
+		err = s.Put(context.Background(), key, strings.NewReader("x"), -1, "")
+		if !errors.Is(err, domain.ErrBadParamInput) {
+			t.Fatalf("put %q: got %v, want ErrBadParamInput", key, err)
+		}
+	}
+}
+
+// failingReader hands out some bytes, then fails like a dropped client
+type failingReader struct{ n int }
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.n <= 0 {
+		return 0, errors.New("connection reset")
+	}
+	n := copy(p, strings.Repeat("x", r.n))
+	r.n -= n
+	return n, nil
+}
+
+func TestLocalStoreLeavesNoPartialBlob(t *testing.T) {
+	root := t.TempDir()
+	s, err := NewLocalStore(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = s.Put(context.Background(), "ab/partial", &failingReader{n: 100}, -1, ""); err == nil {
+		t.Fatal("put of a failing reader succeeded")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err = s.Put(ctx, "ab/canceled", strings.NewReader("data"), -1, ""); !errors.Is(err, context.Canceled) {
+		t.Fatalf("put with a canceled context: got %v", err)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(root, "ab"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("partial blobs left behind: %v", entries)
+	}
+}
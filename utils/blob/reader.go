@@ -0,0 +1,23 @@
+package blob
+
+import (
+	"context"
+	"io"
+)
+
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+// readerWithContext stops a long copy as soon as ctx is done
+func readerWithContext(ctx context.Context, r io.Reader) io.Reader {
+	return &ctxReader{ctx, r}
+}
+
+func (r *ctxReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
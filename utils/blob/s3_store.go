@@ -0,0 +1,254 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+)
+
+// minPartSize is the smallest part S3 accepts in a multipart upload, but the last
+const minPartSize = 5 << 20
+
+// S3Config is representing how to reach an S3 compatible bucket
+type S3Config struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	// PathStyle addresses the bucket as endpoint/bucket, as MinIO expects
+	PathStyle bool
+	// PartSize bounds the memory a Put uses, at least 5 MiB
+	PartSize int64
+}
+
+type s3Store struct {
+	endpoint *url.URL
+	cfg      S3Config
+	signer   *signer
+	client   *http.Client
+	log      utils.AggregatedLogger
+}
+
+/*
+* NewS3Store will create an object that represent the BlobStore interface on
+* top of an S3 compatible service. Uploads larger than a part are sent as
+* multipart uploads, so at most one part is held in memory
+ */
+func NewS3Store(cfg S3Config) (domain.BlobStore, error) {
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if endpoint.Scheme == "" || endpoint.Host == "" || cfg.Bucket == "" {
+		return nil, errors.New("s3 store needs an absolute endpoint and a bucket")
+	}
+	if cfg.PartSize < minPartSize {
+		cfg.PartSize = minPartSize
+	}
+
+	logger := utils.NewAggregatedLogger(constants.Utils, constants.Blob)
+	return &s3Store{
+		endpoint: endpoint,
+		cfg:      cfg,
+		signer:   &signer{cfg.AccessKey, cfg.SecretKey, cfg.Region},
+		client:   &http.Client{},
+		log:      logger,
+	}, nil
+}
+
+// objectURL builds the url of key, with query parameters q
+func (s *s3Store) objectURL(key string, q url.Values) string {
+	u := *s.endpoint
+	path := "/" + uriEncode(key, false)
+	if s.cfg.PathStyle {
+		path = "/" + uriEncode(s.cfg.Bucket, true) + path
+	} else {
+		u.Host = s.cfg.Bucket + "." + u.Host
+	}
+	res := u.Scheme + "://" + u.Host + strings.TrimSuffix(s.endpoint.Path, "/") + path
+	if len(q) > 0 {
+		res += "?" + q.Encode()
+	}
+
+	return res
+}
+
+// do signs and sends a request, turning non 2xx answers into errors
+func (s *s3Store) do(ctx context.Context, method string, key string, q url.Values, header http.Header, body []byte) (res *http.Response, err error) {
+	req, err := http.NewRequestWithContext(ctx, method, s.objectURL(key, q), bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	if body == nil {
+		req.Body = http.NoBody
+		req.ContentLength = 0
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	payload := emptyPayload
+	if len(body) > 0 {
+		payload = sha256Hex(body)
+	}
+	s.signer.sign(req, payload, time.Now())
+
+	res, err = s.client.Do(req)
+	if err != nil {
+		return
+	}
+
+	if res.StatusCode == http.StatusNotFound {
+		res.Body.Close()
+		return nil, domain.ErrNotFound
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		res.Body.Close()
+		return nil, fmt.Errorf("s3 %s %s: %s: %s", method, key, res.Status, msg)
+	}
+
+	return
+}
+
+type initiateResult struct {
+	UploadId string `xml:"UploadId"`
+}
+
+type completePart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+type completeUpload struct {
+	XMLName xml.Name       `xml:"CompleteMultipartUpload"`
+	Parts   []completePart `xml:"Part"`
+}
+
+func (s *s3Store) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) (err error) {
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+
+	buf := make([]byte, s.cfg.PartSize)
+	n, err := io.ReadFull(r, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		// Fits in a single part
+		res, err := s.do(ctx, http.MethodPut, key, nil, header, buf[:n])
+		if err != nil {
+			s.log.Error("IN [Put]: could not put blob {", key, "} ->", err)
+			return err
+		}
+		return res.Body.Close()
+	}
+	if err != nil {
+		return
+	}
+
+	res, err := s.do(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, header, nil)
+	if err != nil {
+		s.log.Error("IN [Put]: could not initiate multipart upload {", key, "} ->", err)
+		return
+	}
+	initiated := initiateResult{}
+	err = xml.NewDecoder(res.Body).Decode(&initiated)
+	res.Body.Close()
+	if err != nil {
+		return
+	}
+
+	uploadId := initiated.UploadId
+	defer func() {
+		if err != nil {
+			// Abort with a fresh context, the request's one may be what failed
+			abortCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			if res, errAbort := s.do(abortCtx, http.MethodDelete, key, url.Values{"uploadId": {uploadId}}, nil, nil); errAbort == nil {
+				res.Body.Close()
+			}
+		}
+	}()
+
+	parts := make([]completePart, 0)
+	for n > 0 {
+		q := url.Values{"partNumber": {strconv.Itoa(len(parts) + 1)}, "uploadId": {uploadId}}
+		res, err = s.do(ctx, http.MethodPut, key, q, nil, buf[:n])
+		if err != nil {
+			s.log.Error("IN [Put]: could not upload part of {", key, "} ->", err)
+			return
+		}
+		res.Body.Close()
+		parts = append(parts, completePart{len(parts) + 1, res.Header.Get("ETag")})
+
+		n, err = io.ReadFull(r, buf)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			err = nil
+		}
+		if err != nil {
+			return
+		}
+	}
+
+	body, err := xml.Marshal(completeUpload{Parts: parts})
+	if err != nil {
+		return
+	}
+	res, err = s.do(ctx, http.MethodPost, key, url.Values{"uploadId": {uploadId}}, nil, body)
+	if err != nil {
+		s.log.Error("IN [Put]: could not complete multipart upload {", key, "} ->", err)
+		return
+	}
+
+	return res.Body.Close()
+}
+
+func (s *s3Store) Get(ctx context.Context, key string, offset int64, length int64) (res io.ReadCloser, err error) {
+	header := http.Header{}
+	if offset > 0 || length >= 0 {
+		rng := fmt.Sprintf("bytes=%d-", offset)
+		if length >= 0 {
+			rng += strconv.FormatInt(offset+length-1, 10)
+		}
+		header.Set("Range", rng)
+	}
+
+	resp, err := s.do(ctx, http.MethodGet, key, nil, header, nil)
+	if err != nil {
+		return
+	}
+
+	return resp.Body, nil
+}
+
+func (s *s3Store) Stat(ctx context.Context, key string) (res domain.BlobInfo, err error) {
+	resp, err := s.do(ctx, http.MethodHead, key, nil, nil, nil)
+	if err != nil {
+		return
+	}
+	resp.Body.Close()
+
+	return domain.BlobInfo{Key: key, Size: resp.ContentLength}, nil
+}
+
+func (s *s3Store) Delete(ctx context.Context, key string) (err error) {
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, nil, nil)
+	if err != nil {
+		return
+	}
+
+	return resp.Body.Close()
+}
@@ -0,0 +1,296 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sicozz/papyrus/domain"
+)
+
+const (
+	testAccessKey = "minio"
+	testSecretKey = "minio-secret"
+	testBucket    = "papyrus"
+)
+
+/*
+* fakeS3 is a MinIO stand-in keeping objects in memory. It checks the SigV4
+* signature and payload hash of every request and records what it was asked
+ */
+type fakeS3 struct {
+	t        *testing.T
+	mu       sync.Mutex
+	objects  map[string][]byte
+	uploads  map[string]map[int][]byte
+	requests []string
+	// failPart makes the upload of that part number fail
+	failPart int
+}
+
+func newFakeS3(t *testing.T) (*fakeS3, *httptest.Server) {
+	f := &fakeS3{t: t, objects: map[string][]byte{}, uploads: map[string]map[int][]byte{}}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+// checkSignature signs a copy of r holding only its signed headers and compares the result
+func (f *fakeS3) checkSignature(r *http.Request, body []byte) error {
+	auth := r.Header.Get("Authorization")
+	i := strings.Index(auth, "SignedHeaders=")
+	j := strings.Index(auth, ", Signature=")
+	if i < 0 || j < 0 {
+		return errors.New("no signature")
+	}
+
+	if got := r.Header.Get("X-Amz-Content-Sha256"); got != sha256Hex(body) {
+		return fmt.Errorf("payload hash %s does not match the body", got)
+	}
+	now, err := time.Parse(amzDateFmt, r.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(r.Method, "http://"+r.Host+r.URL.RequestURI(), nil)
+	if err != nil {
+		return err
+	}
+	for _, h := range strings.Split(auth[i+len("SignedHeaders="):j], ";") {
+		if h != "host" {
+			req.Header.Set(h, r.Header.Get(h))
+		}
+	}
+	(&signer{testAccessKey, testSecretKey, "us-east-1"}).sign(req, r.Header.Get("X-Amz-Content-Sha256"), now)
+	if req.Header.Get("Authorization") != auth {
+		return errors.New("signature mismatch")
+	}
+
+	return nil
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	if err := f.checkSignature(r, body); err != nil {
+		f.t.Errorf("%s %s: %v", r.Method, r.URL, err)
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	key, ok := strings.CutPrefix(r.URL.Path, "/"+testBucket+"/")
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	q := r.URL.Query()
+	uploadId := q.Get("uploadId")
+
+	switch {
+	case r.Method == http.MethodPost && q.Has("uploads"):
+		f.requests = append(f.requests, "initiate")
+		uploadId = fmt.Sprint("upload-", len(f.uploads)+1)
+		f.uploads[uploadId] = map[int][]byte{}
+		fmt.Fprintf(w, "<InitiateMultipartUploadResult><UploadId>%s</UploadId></InitiateMultipartUploadResult>", uploadId)
+
+	case r.Method == http.MethodPut && uploadId != "":
+		n, _ := strconv.Atoi(q.Get("partNumber"))
+		f.requests = append(f.requests, fmt.Sprint("part ", n))
+		if n == f.failPart {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		f.uploads[uploadId][n] = body
+		w.Header().Set("ETag", fmt.Sprintf("%q", fmt.Sprint("etag-", n)))
+
+	case r.Method == http.MethodPost && uploadId != "":
+		f.requests = append(f.requests, "complete")
+		var done completeUpload
+		if err := xml.Unmarshal(body, &done); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var obj []byte
+		for i, p := range done.Parts {
+			if p.PartNumber != i+1 || p.ETag != fmt.Sprintf("%q", fmt.Sprint("etag-", i+1)) {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			obj = append(obj, f.uploads[uploadId][p.PartNumber]...)
+		}
+		f.objects[key] = obj
+		delete(f.uploads, uploadId)
+
+	case r.Method == http.MethodDelete && uploadId != "":
+		f.requests = append(f.requests, "abort")
+		delete(f.uploads, uploadId)
+		w.WriteHeader(http.StatusNoContent)
+
+	case r.Method == http.MethodPut:
+		f.requests = append(f.requests, "put")
+		f.objects[key] = body
+
+	case r.Method == http.MethodDelete:
+		if _, ok := f.objects[key]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		obj, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		http.ServeContent(w, r, key, time.Time{}, bytes.NewReader(obj))
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func newTestS3Store(t *testing.T, endpoint string) domain.BlobStore {
+	s, err := NewS3Store(S3Config{
+		Endpoint:  endpoint,
+		Region:    "us-east-1",
+		Bucket:    testBucket,
+		AccessKey: testAccessKey,
+		SecretKey: testSecretKey,
+		PathStyle: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestS3StoreRoundTrip(t *testing.T) {
+	f, srv := newFakeS3(t)
+	s := newTestS3Store(t, srv.URL)
+	ctx := context.Background()
+
+	key := "ab/some key+ñ"
+	if err := s.Put(ctx, key, strings.NewReader("0123456789"), -1, "text/plain"); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if len(f.requests) != 1 || f.requests[0] != "put" {
+		t.Fatalf("small blob sent as %v, want a single put", f.requests)
+	}
+
+	rc, err := s.Get(ctx, key, 0, -1)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got := readAll(t, rc); got != "0123456789" {
+		t.Fatalf("get: got %q", got)
+	}
+
+	rc, err = s.Get(ctx, key, 3, 4)
+	if err != nil {
+		t.Fatalf("get range: %v", err)
+	}
+	if got := readAll(t, rc); got != "3456" {
+		t.Fatalf("get range: got %q, want 3456", got)
+	}
+
+	info, err := s.Stat(ctx, key)
+	if err != nil || info.Size != 10 {
+		t.Fatalf("stat: got %+v, %v", info, err)
+	}
+
+	if err = s.Delete(ctx, key); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err = s.Get(ctx, key, 0, -1); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("get after delete: got %v, want ErrNotFound", err)
+	}
+	if _, err = s.Stat(ctx, key); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("stat after delete: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestS3StoreMultipartPut(t *testing.T) {
+	f, srv := newFakeS3(t)
+	s := newTestS3Store(t, srv.URL)
+
+	data := make([]byte, 2*minPartSize+1234)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+	// A plain reader, the store can not know the size up front
+	if err := s.Put(context.Background(), "ab/big", io.MultiReader(bytes.NewReader(data)), -1, ""); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+
+	want := []string{"initiate", "part 1", "part 2", "part 3", "complete"}
+	if strings.Join(f.requests, ",") != strings.Join(want, ",") {
+		t.Fatalf("requests %v, want %v", f.requests, want)
+	}
+	if !bytes.Equal(f.objects["ab/big"], data) {
+		t.Fatal("assembled object differs from the data put")
+	}
+	if len(f.uploads) != 0 {
+		t.Fatalf("multipart uploads left open: %v", f.uploads)
+	}
+}
+
+func TestS3StoreMultipartAbortsOnFailure(t *testing.T) {
+	f, srv := newFakeS3(t)
+	f.failPart = 2
+	s := newTestS3Store(t, srv.URL)
+
+	data := make([]byte, 2*minPartSize+1)
+	if err := s.Put(context.Background(), "ab/big", bytes.NewReader(data), -1, ""); err == nil {
+		t.Fatal("put succeeded with a failing part")
+	}
+
+	if last := f.requests[len(f.requests)-1]; last != "abort" {
+		t.Fatalf("requests %v, want the upload aborted", f.requests)
+	}
+	if _, ok := f.objects["ab/big"]; ok {
+		t.Fatal("object created from a failed upload")
+	}
+	if len(f.uploads) != 0 {
+		t.Fatalf("multipart uploads left open: %v", f.uploads)
+	}
+}
+
+func TestS3ObjectURL(t *testing.T) {
+	cases := []struct {
+		pathStyle bool
+		endpoint  string
+		want      string
+	}{
+		{true, "http://minio:9000", "http://minio:9000/papyrus/ab/a%20b"},
+		{true, "http://minio:9000/s3/", "http://minio:9000/s3/papyrus/ab/a%20b"},
+		{false, "https://s3.eu-west-1.amazonaws.com", "https://papyrus.s3.eu-west-1.amazonaws.com/ab/a%20b"},
+	}
+	for _, c := range cases {
+		bs, err := NewS3Store(S3Config{Endpoint: c.endpoint, Bucket: testBucket, PathStyle: c.pathStyle})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := bs.(*s3Store).objectURL("ab/a b", nil); got != c.want {
+			t.Errorf("objectURL on %s: got %s, want %s", c.endpoint, got, c.want)
+		}
+	}
+
+	if _, err := NewS3Store(S3Config{Endpoint: "minio:9000", Bucket: testBucket}); err == nil {
+		t.Error("relative endpoint accepted")
+	}
+}
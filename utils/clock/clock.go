@@ -0,0 +1,32 @@
+package clock
+
+import (
+	"time"
+
+	"github.com/sicozz/papyrus/domain"
+)
+
+type systemClock struct{}
+
+// NewSystemClock will create an object that represent the Clock interface with the UTC wall clock
+func NewSystemClock() domain.Clock {
+	return systemClock{}
+}
+
+func (systemClock) Now() time.Time {
+	return time.Now().UTC()
+}
+
+// Fake is a manually driven Clock for tests and offline runs
+type Fake struct {
+	T time.Time
+}
+
+func (f *Fake) Now() time.Time {
+	return f.T
+}
+
+// Advance moves the fake clock forward by d
+func (f *Fake) Advance(d time.Duration) {
+	f.T = f.T.Add(d)
+}
@@ -13,8 +13,31 @@ const (
 type Domain string
 
 const (
-	None      Domain = ""
-	User      Domain = "USER"
-	Role      Domain = "ROLE"
-	UserState Domain = "USER_STATE"
+	None       Domain = ""
+	User       Domain = "USER"
+	Role       Domain = "ROLE"
+	UserState  Domain = "USER_STATE"
+	Auth       Domain = "AUTH"
+	Session    Domain = "SESSION"
+	Authz      Domain = "AUTHZ"
+	Permission Domain = "PERMISSION"
+	Throttle   Domain = "LOGIN_THROTTLE"
+	UserToken  Domain = "USER_TOKEN"
+	Password   Domain = "PASSWORD"
+	Mail       Domain = "MAIL"
+	Verify     Domain = "VERIFICATION"
+	Totp       Domain = "TOTP"
+	ApiKey     Domain = "API_KEY"
+	Dir        Domain = "DIR"
+	Blob       Domain = "BLOB"
+	File       Domain = "FILE"
+	Version    Domain = "VERSION"
+	FileGrant  Domain = "FILE_GRANT"
+	Audit      Domain = "AUDIT"
+	Project    Domain = "PROJECT"
+	Plan       Domain = "PLAN"
+	Task       Domain = "TASK"
+	Job        Domain = "JOB"
+	Notify     Domain = "NOTIFICATION"
+	Migration  Domain = "MIGRATION"
 )
@@ -0,0 +1,15 @@
+package delivery
+
+import "gopkg.in/go-playground/validator.v9"
+
+// validate caches the struct rules, it is safe for concurrent use
+var validate = validator.New()
+
+// IsRequestValid checks a bound request body against its validate tags
+func IsRequestValid(u any) (bool, error) {
+	err := validate.Struct(u)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
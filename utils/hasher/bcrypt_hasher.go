@@ -0,0 +1,46 @@
+package hasher
+
+import (
+	"github.com/sicozz/papyrus/domain"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type bcryptHasher struct {
+	cost int
+}
+
+/*
+* NewBcryptHasher will create an object that represent the PasswordHasher
+* interface. Costs outside of the bcrypt bounds fall back to the default cost
+ */
+func NewBcryptHasher(cost int) domain.PasswordHasher {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		cost = bcrypt.DefaultCost
+	}
+	return &bcryptHasher{cost}
+}
+
+// Hash a plaintext password
+func (h *bcryptHasher) Hash(passwd string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(passwd), h.cost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+// Compare a stored hash with a plaintext password
+func (h *bcryptHasher) Compare(hash string, passwd string) error {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(passwd))
+}
+
+// Know if a stored hash was generated with other cost parameters
+func (h *bcryptHasher) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	if err != nil {
+		return true
+	}
+
+	return cost != h.cost
+}
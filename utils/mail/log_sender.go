@@ -0,0 +1,27 @@
+package mail
+
+import (
+	"context"
+
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+)
+
+type logSender struct {
+	log utils.AggregatedLogger
+}
+
+/*
+* NewLogSender will create an object that represent the MailSender interface.
+* It writes every mail to the log instead of delivering it
+ */
+func NewLogSender() domain.MailSender {
+	logger := utils.NewAggregatedLogger(constants.Utils, constants.Mail)
+	return &logSender{logger}
+}
+
+func (s *logSender) Send(ctx context.Context, to string, subject string, body string) error {
+	s.log.Info("MAIL to {", to, "} subject {", subject, "}:", body)
+	return nil
+}
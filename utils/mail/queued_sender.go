@@ -0,0 +1,68 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+)
+
+// ErrQueueFull is returned when a mail arrives while every slot of the queue is taken
+var ErrQueueFull = errors.New("mail queue full")
+
+type outgoing struct {
+	to      string
+	subject string
+	body    string
+}
+
+type queuedSender struct {
+	next    domain.MailSender
+	mails   chan outgoing
+	timeout time.Duration
+	log     utils.AggregatedLogger
+}
+
+/*
+* NewQueuedSender will create an object that represent the MailSender
+* interface. Send only queues the mail, workers relay it through next in the
+* background, each bounded by timeout instead of the caller's context. A mail
+* next fails to deliver is logged and dropped
+ */
+func NewQueuedSender(next domain.MailSender, size int, workers int, timeout time.Duration) domain.MailSender {
+	logger := utils.NewAggregatedLogger(constants.Utils, constants.Mail)
+	s := &queuedSender{
+		next:    next,
+		mails:   make(chan outgoing, size),
+		timeout: timeout,
+		log:     logger,
+	}
+	for i := 0; i < workers; i++ {
+		go s.work()
+	}
+
+	return s
+}
+
+func (s *queuedSender) Send(ctx context.Context, to string, subject string, body string) error {
+	select {
+	case s.mails <- outgoing{to, subject, body}:
+		return nil
+	default:
+		s.log.Error("IN [Send]: could not queue mail to {", to, "} ->", ErrQueueFull)
+		return ErrQueueFull
+	}
+}
+
+func (s *queuedSender) work() {
+	for m := range s.mails {
+		ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+		if err := s.next.Send(ctx, m.to, m.subject, m.body); err != nil {
+			s.log.Error("IN [work]: could not deliver mail to {", m.to, "} ->", err)
+		}
+		cancel()
+	}
+}
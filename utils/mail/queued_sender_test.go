@@ -0,0 +1,78 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// blockingSender hands every mail to sent once release lets it through
+type blockingSender struct {
+	release chan struct{}
+	sent    chan string
+}
+
+func (s *blockingSender) Send(ctx context.Context, to string, subject string, body string) error {
+	select {
+	case <-s.release:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	s.sent <- to
+	return nil
+}
+
+func TestQueuedSenderDoesNotWaitForDelivery(t *testing.T) {
+	next := &blockingSender{release: make(chan struct{}), sent: make(chan string, 4)}
+	s := NewQueuedSender(next, 2, 1, time.Minute)
+
+	// The request context ends as soon as Send returns
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := s.Send(ctx, "ana@example.com", "Hi", "body"); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	cancel()
+
+	select {
+	case to := <-next.sent:
+		t.Fatalf("mail to %s delivered before its release", to)
+	default:
+	}
+
+	close(next.release)
+	select {
+	case to := <-next.sent:
+		if to != "ana@example.com" {
+			t.Fatalf("delivered to %s", to)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("queued mail never delivered")
+	}
+}
+
+func TestQueuedSenderRefusesWhenFull(t *testing.T) {
+	next := &blockingSender{release: make(chan struct{}), sent: make(chan string, 4)}
+	defer close(next.release)
+	s := NewQueuedSender(next, 1, 1, time.Minute)
+	ctx := context.Background()
+
+	// The worker holds the first mail, the queue the second one
+	if err := s.Send(ctx, "a@example.com", "Hi", "body"); err != nil {
+		t.Fatalf("first send: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(s.(*queuedSender).mails) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("worker never took the first mail")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := s.Send(ctx, "b@example.com", "Hi", "body"); err != nil {
+		t.Fatalf("second send: %v", err)
+	}
+
+	if err := s.Send(ctx, "c@example.com", "Hi", "body"); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("send on a full queue: got %v, want ErrQueueFull", err)
+	}
+}
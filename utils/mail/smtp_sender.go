@@ -0,0 +1,120 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+)
+
+// SMTPConfig holds the server mails are relayed through
+type SMTPConfig struct {
+	Host string
+	Port string
+	// Username and Password are only used when Username is set
+	Username string
+	Password string
+	From     string
+}
+
+type smtpSender struct {
+	cfg SMTPConfig
+	// roots verifies the STARTTLS certificate, nil trusts the system ones
+	roots *x509.CertPool
+	log   utils.AggregatedLogger
+}
+
+/*
+* NewSMTPSender will create an object that represent the MailSender interface.
+* It relays every mail through the configured server, upgrading the
+* connection with STARTTLS whenever the server offers it
+ */
+func NewSMTPSender(cfg SMTPConfig) domain.MailSender {
+	logger := utils.NewAggregatedLogger(constants.Utils, constants.Mail)
+	return &smtpSender{cfg: cfg, log: logger}
+}
+
+func (s *smtpSender) Send(ctx context.Context, to string, subject string, body string) (err error) {
+	addr := net.JoinHostPort(s.cfg.Host, s.cfg.Port)
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		s.log.Error("IN [Send]: could not reach smtp server ->", err)
+		return
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline) //nolint:errcheck
+	}
+
+	c, err := smtp.NewClient(conn, s.cfg.Host)
+	if err != nil {
+		conn.Close()
+		s.log.Error("IN [Send]: could not greet smtp server ->", err)
+		return
+	}
+	defer c.Close()
+
+	if err = s.deliver(c, to, subject, body); err != nil {
+		s.log.Error("IN [Send]: could not send mail to {", to, "} ->", err)
+		return
+	}
+
+	return c.Quit()
+}
+
+func (s *smtpSender) deliver(c *smtp.Client, to string, subject string, body string) (err error) {
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err = c.StartTLS(&tls.Config{ServerName: s.cfg.Host, RootCAs: s.roots}); err != nil {
+			return
+		}
+	}
+	if s.cfg.Username != "" {
+		auth := smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)
+		if err = c.Auth(auth); err != nil {
+			return
+		}
+	}
+
+	if err = c.Mail(s.cfg.From); err != nil {
+		return
+	}
+	if err = c.Rcpt(to); err != nil {
+		return
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return
+	}
+	if _, err = w.Write(message(s.cfg.From, to, subject, body)); err != nil {
+		w.Close()
+		return
+	}
+
+	return w.Close()
+}
+
+// message builds a plain text mail, headers can not be injected through its fields
+func message(from string, to string, subject string, body string) []byte {
+	strip := strings.NewReplacer("\r", " ", "\n", " ")
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", strip.Replace(from))
+	fmt.Fprintf(&b, "To: %s\r\n", strip.Replace(to))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", strip.Replace(subject)))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(body, "\r\n", "\n"))
+
+	return []byte(b.String())
+}
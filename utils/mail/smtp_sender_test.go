@@ -0,0 +1,275 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"io"
+	"math/big"
+	"mime"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// received is a mail as the fake server got it
+type received struct {
+	from string
+	to   string
+	auth string
+	tls  bool
+	data string
+}
+
+/*
+* fakeSMTP is a one mail per connection SMTP server on the loopback. It
+* offers STARTTLS only when it has a certificate
+ */
+type fakeSMTP struct {
+	ln   net.Listener
+	cert *tls.Config
+	mu   sync.Mutex
+	got  []received
+	done chan struct{}
+}
+
+func newFakeSMTP(t *testing.T, cert *tls.Config) *fakeSMTP {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeSMTP{ln: ln, cert: cert, done: make(chan struct{})}
+	t.Cleanup(func() {
+		ln.Close()
+		<-f.done
+	})
+	go f.serve()
+	return f
+}
+
+func (f *fakeSMTP) serve() {
+	defer close(f.done)
+	for {
+		conn, err := f.ln.Accept()
+		if err != nil {
+			return
+		}
+		f.session(conn)
+	}
+}
+
+func (f *fakeSMTP) session(conn net.Conn) {
+	defer func() { conn.Close() }()
+	conn.SetDeadline(time.Now().Add(5 * time.Second)) //nolint:errcheck
+
+	tp := textproto.NewConn(conn)
+	r := received{}
+	tp.PrintfLine("220 fake ESMTP") //nolint:errcheck
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			ext := "250-fake\r\n"
+			if f.cert != nil && !r.tls {
+				ext += "250-STARTTLS\r\n"
+			}
+			tp.PrintfLine("%s250 AUTH PLAIN", ext) //nolint:errcheck
+		case "STARTTLS":
+			tp.PrintfLine("220 ready") //nolint:errcheck
+			tc := tls.Server(conn, f.cert)
+			// A failed handshake shows on the client side
+			if err = tc.Handshake(); err != nil {
+				return
+			}
+			conn, r.tls = tc, true
+			tp = textproto.NewConn(tc)
+		case "AUTH":
+			mech, resp, _ := strings.Cut(arg, " ")
+			b, _ := base64.StdEncoding.DecodeString(resp)
+			r.auth = mech + " " + string(b)
+			tp.PrintfLine("235 ok") //nolint:errcheck
+		case "MAIL":
+			r.from = arg
+			tp.PrintfLine("250 ok") //nolint:errcheck
+		case "RCPT":
+			r.to = arg
+			tp.PrintfLine("250 ok") //nolint:errcheck
+		case "DATA":
+			tp.PrintfLine("354 go on") //nolint:errcheck
+			b, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			r.data = string(b)
+			f.mu.Lock()
+			f.got = append(f.got, r)
+			f.mu.Unlock()
+			tp.PrintfLine("250 queued") //nolint:errcheck
+		case "QUIT":
+			tp.PrintfLine("221 bye") //nolint:errcheck
+			return
+		default:
+			tp.PrintfLine("502 unknown %s", verb) //nolint:errcheck
+		}
+	}
+}
+
+func (f *fakeSMTP) mails() []received {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]received(nil), f.got...)
+}
+
+func (f *fakeSMTP) sender(cfg SMTPConfig, roots *x509.CertPool) *smtpSender {
+	host, port, _ := net.SplitHostPort(f.ln.Addr().String())
+	cfg.Host, cfg.Port = host, port
+	s := NewSMTPSender(cfg).(*smtpSender)
+	s.roots = roots
+	return s
+}
+
+// selfSigned returns a server config for 127.0.0.1 and the pool trusting it
+func selfSigned(t *testing.T) (*tls.Config, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "fake smtp"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	return &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}, pool
+}
+
+func parse(t *testing.T, data string) *mail.Message {
+	m, err := mail.ReadMessage(bufio.NewReader(strings.NewReader(data)))
+	if err != nil {
+		t.Fatalf("parsing mail: %v\n%s", err, data)
+	}
+	return m
+}
+
+func TestSMTPSenderUpgradesWithStartTLS(t *testing.T) {
+	cert, roots := selfSigned(t)
+	f := newFakeSMTP(t, cert)
+	s := f.sender(SMTPConfig{Username: "papyrus", Password: "secret", From: "papyrus@example.com"}, roots)
+
+	err := s.Send(context.Background(), "ana@example.com", "Überfällig", "line one\r\nline two\n.\n")
+	if err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	got := f.mails()
+	if len(got) != 1 {
+		t.Fatalf("server got %d mails, want 1", len(got))
+	}
+	r := got[0]
+	if !r.tls {
+		t.Fatal("mail sent before STARTTLS")
+	}
+	if r.auth != "PLAIN \x00papyrus\x00secret" {
+		t.Fatalf("auth %q", r.auth)
+	}
+	if !strings.HasPrefix(r.from, "FROM:<papyrus@example.com>") || r.to != "TO:<ana@example.com>" {
+		t.Fatalf("envelope from %q to %q", r.from, r.to)
+	}
+
+	m := parse(t, r.data)
+	subject, err := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject"))
+	if err != nil || subject != "Überfällig" {
+		t.Fatalf("subject %q, %v", subject, err)
+	}
+	if m.Header.Get("To") != "ana@example.com" || m.Header.Get("Content-Type") != "text/plain; charset=utf-8" {
+		t.Fatalf("headers %v", m.Header)
+	}
+	body, _ := io.ReadAll(m.Body)
+	if string(body) != "line one\nline two\n.\n" {
+		t.Fatalf("body %q", body)
+	}
+}
+
+func TestSMTPSenderWithoutStartTLS(t *testing.T) {
+	f := newFakeSMTP(t, nil)
+	s := f.sender(SMTPConfig{From: "papyrus@example.com"}, nil)
+
+	if err := s.Send(context.Background(), "ana@example.com", "Hi", "plain"); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	got := f.mails()
+	if len(got) != 1 || got[0].tls || got[0].auth != "" {
+		t.Fatalf("server got %+v, want one plain mail without auth", got)
+	}
+}
+
+func TestSMTPSenderFailsOnUntrustedCertificate(t *testing.T) {
+	cert, _ := selfSigned(t)
+	f := newFakeSMTP(t, cert)
+	s := f.sender(SMTPConfig{From: "papyrus@example.com"}, x509.NewCertPool())
+
+	if err := s.Send(context.Background(), "ana@example.com", "Hi", "secret"); err == nil {
+		t.Fatal("mail sent over an unverified connection")
+	}
+	if got := f.mails(); len(got) != 0 {
+		t.Fatalf("server got %d mails", len(got))
+	}
+}
+
+func TestSMTPSenderStripsHeaderInjection(t *testing.T) {
+	f := newFakeSMTP(t, nil)
+	s := f.sender(SMTPConfig{From: "papyrus@example.com"}, nil)
+
+	err := s.Send(context.Background(), "ana@example.com", "Hi\r\nBcc: eve@example.com\nX-Evil: 1", "body")
+	if err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	m := parse(t, f.mails()[0].data)
+	if m.Header.Get("Bcc") != "" || m.Header.Get("X-Evil") != "" {
+		t.Fatalf("header injected through the subject: %v", m.Header)
+	}
+	if got := m.Header.Get("Subject"); got != "Hi  Bcc: eve@example.com X-Evil: 1" {
+		t.Fatalf("subject %q", got)
+	}
+
+	// The recipient reaches the envelope too, where the client refuses line breaks
+	if err = s.Send(context.Background(), "ana@example.com\r\nBcc: eve@example.com", "Hi", "body"); err == nil {
+		t.Fatal("recipient with a line break accepted")
+	}
+	if got := f.mails(); len(got) != 1 {
+		t.Fatalf("server got %d mails, want 1", len(got))
+	}
+
+	msg := parse(t, string(message("papyrus@example.com", "ana@example.com\r\nBcc: eve@example.com", "Hi", "body")))
+	if msg.Header.Get("Bcc") != "" {
+		t.Fatalf("header injected through the recipient: %v", msg.Header)
+	}
+}
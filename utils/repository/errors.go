@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+	"github.com/sicozz/papyrus/domain"
+)
+
+// Postgres error codes mapped to domain errors
+const (
+	uniqueViolation     = "23505"
+	foreignKeyViolation = "23503"
+	// invalidText is raised by a malformed value, such as a uuid, cast to its column type
+	invalidText = "22P02"
+)
+
+/*
+* MapErr will translate a postgres failure into a domain error: a missing row
+* or a malformed key, which can not name any row, is ErrNotFound, a unique
+* violation is ErrConflict, a reference to a missing row is ErrBadParamInput
+* and any other error is returned as is
+ */
+func MapErr(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.ErrNotFound
+	}
+
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+	switch pqErr.Code {
+	case uniqueViolation:
+		return domain.ErrConflict
+	case foreignKeyViolation:
+		return domain.ErrBadParamInput
+	case invalidText:
+		return domain.ErrNotFound
+	}
+
+	return err
+}
+
+/*
+* MapDeleteErr will translate the failure of a delete like MapErr, except that
+* a foreign key violation there means the row is still referenced, ErrConflict
+ */
+func MapDeleteErr(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation {
+		return domain.ErrConflict
+	}
+
+	return MapErr(err)
+}
+
+// CheckAffected will return ErrNotFound when a write matched no row
+func CheckAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return domain.ErrNotFound
+	}
+
+	return nil
+}
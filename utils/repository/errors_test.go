@@ -0,0 +1,54 @@
+package repository_test
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/utils/repository"
+)
+
+func TestMapErr(t *testing.T) {
+	timeout := errors.New("i/o timeout")
+	cases := []struct {
+		err  error
+		want error
+	}{
+		{sql.ErrNoRows, domain.ErrNotFound},
+		{fmt.Errorf("scanning: %w", sql.ErrNoRows), domain.ErrNotFound},
+		{&pq.Error{Code: "23505"}, domain.ErrConflict},
+		{&pq.Error{Code: "23503"}, domain.ErrBadParamInput},
+		// invalid input syntax for type uuid: "abc"
+		{&pq.Error{Code: "22P02"}, domain.ErrNotFound},
+		{timeout, timeout},
+	}
+	for _, c := range cases {
+		if got := repository.MapErr(c.err); !errors.Is(got, c.want) {
+			t.Errorf("MapErr(%v): got %v, want %v", c.err, got, c.want)
+		}
+	}
+
+	other := &pq.Error{Code: "57014"}
+	if got := repository.MapErr(other); got != other {
+		t.Errorf("MapErr(%v): got %v, want it unchanged", other, got)
+	}
+}
+
+func TestMapDeleteErr(t *testing.T) {
+	cases := []struct {
+		err  error
+		want error
+	}{
+		{&pq.Error{Code: "23503"}, domain.ErrConflict},
+		{sql.ErrNoRows, domain.ErrNotFound},
+		{&pq.Error{Code: "22P02"}, domain.ErrNotFound},
+	}
+	for _, c := range cases {
+		if got := repository.MapDeleteErr(c.err); !errors.Is(got, c.want) {
+			t.Errorf("MapDeleteErr(%v): got %v, want %v", c.err, got, c.want)
+		}
+	}
+}
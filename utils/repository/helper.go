@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	timeFormat = "2006-01-02T15:04:05.999999Z07:00" // keep postgres' microsecond precision so no row is skipped
+	cursorSep  = "|"
+	uuidLen    = 36
+)
+
+/*
+* DecodeCursor will decode cursor from user for postgres into the sort key and
+* uuid of the last row of the previous page. A cursor issued for another order
+* is refused, its key would not compare with the sort column
+ */
+func DecodeCursor(cursor string, order string) (key string, uuid string, err error) {
+	byt, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return
+	}
+
+	s := string(byt)
+	i, j := strings.Index(s, cursorSep), strings.LastIndex(s, cursorSep)
+	if i < 0 || i == j || len(s)-j-1 != uuidLen {
+		return "", "", errors.New("malformed cursor")
+	}
+	if s[:i] != order {
+		return "", "", errors.New("cursor issued for another order")
+	}
+
+	return s[i+1 : j], s[j+1:], nil
+}
+
+/*
+* EncodeCursor will encode cursor from postgres to user. The order, sort field
+* with its direction, ties the cursor to its listing and the uuid breaks ties
+* between rows sharing the same sort key
+ */
+func EncodeCursor(order string, key string, uuid string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(order + cursorSep + key + cursorSep + uuid))
+}
+
+// FormatCursorTime will format a time sort key for EncodeCursor
+func FormatCursorTime(t time.Time) string {
+	return t.Format(timeFormat)
+}
+
+// ParseCursorTime will parse a time sort key returned by DecodeCursor
+func ParseCursorTime(key string) (time.Time, error) {
+	return time.Parse(timeFormat, key)
+}
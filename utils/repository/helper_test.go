@@ -0,0 +1,37 @@
+package repository_test
+
+import (
+	"testing"
+
+	"github.com/sicozz/papyrus/utils/repository"
+)
+
+const uuid = "0b6f4b4e-6f1f-4c43-9a5e-2f7c1d2e3a4b"
+
+func TestCursorRoundTrip(t *testing.T) {
+	c := repository.EncodeCursor("-username", "ana|maria", uuid)
+	key, id, err := repository.DecodeCursor(c, "-username")
+	if err != nil {
+		t.Fatalf("decoding: %v", err)
+	}
+	if key != "ana|maria" || id != uuid {
+		t.Fatalf("got %q %q, want %q %q", key, id, "ana|maria", uuid)
+	}
+}
+
+func TestCursorRefusesOtherOrder(t *testing.T) {
+	c := repository.EncodeCursor("-username", "ana", uuid)
+	for _, order := range []string{"username", "-email", "created_at"} {
+		if _, _, err := repository.DecodeCursor(c, order); err == nil {
+			t.Fatalf("cursor for -username accepted for %s", order)
+		}
+	}
+}
+
+func TestCursorRefusesGarbage(t *testing.T) {
+	for _, c := range []string{"%%%", "YWJj", repository.EncodeCursor("-date", "", "short")} {
+		if _, _, err := repository.DecodeCursor(c, "-date"); err == nil {
+			t.Fatalf("malformed cursor %q accepted", c)
+		}
+	}
+}
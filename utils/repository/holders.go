@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/sicozz/papyrus/domain"
+)
+
+// holdersLock serializes the writes that can take a permission away from users
+const holdersLock = 0x686f6c64
+
+/*
+* Keeping will run write in a transaction and roll it back with
+* domain.ErrLastHolder when afterwards no active user holds perm. Every
+* write that can take perm away passes through here under the same lock, so
+* two of them can not both see a holder left and remove the last two. An
+* empty perm only runs write
+ */
+func Keeping(ctx context.Context, db *sql.DB, perm string, write func(tx *sql.Tx) error) (err error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if perm != "" {
+		if _, err = tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, holdersLock); err != nil {
+			return
+		}
+	}
+
+	if err = write(tx); err != nil {
+		return
+	}
+
+	if perm != "" {
+		var n int64
+		query :=
+			`SELECT COUNT(*)
+			FROM user_ u
+			JOIN role_permission rp ON rp.role = u.role
+			JOIN permission p ON p.code = rp.permission
+			JOIN user_state us ON us.code = u.state
+			WHERE p.name = $1 AND us.description = $2`
+		err = tx.QueryRowContext(ctx, query, perm, domain.UserStateActive).Scan(&n)
+		if err != nil {
+			return
+		}
+		if n < 1 {
+			return domain.ErrLastHolder
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,72 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sicozz/papyrus/auth/delivery/http/middleware"
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/domain/dtos"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+)
+
+// VerificationHandler will initialize the email verification endpoints
+type VerificationHandler struct {
+	VUsecase domain.VerificationUsecase
+	log      utils.AggregatedLogger
+}
+
+func NewVerificationHandler(e *echo.Echo, vu domain.VerificationUsecase, authMw echo.MiddlewareFunc, authz *middleware.AuthzMiddleware) {
+	logger := utils.NewAggregatedLogger(constants.Delivery, constants.Verify)
+	handler := &VerificationHandler{vu, logger}
+	canWrite := authz.Require(middleware.Can(domain.PermUserWrite))
+	g := e.Group("/user/:uname/verification", authMw, canWrite)
+	g.POST("", handler.Resend)
+	g.POST("/bypass", handler.Bypass)
+	e.GET("/email/verify", handler.Verify)
+}
+
+func (h *VerificationHandler) Resend(c echo.Context) error {
+	h.log.Info("REQ: resend")
+	ctx := c.Request().Context()
+	uname := c.Param("uname")
+	rErr := h.VUsecase.Send(ctx, uname)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.NoContent(http.StatusAccepted)
+}
+
+func (h *VerificationHandler) Bypass(c echo.Context) error {
+	h.log.Info("REQ: bypass")
+	ctx := c.Request().Context()
+	uname := c.Param("uname")
+	rErr := h.VUsecase.Bypass(ctx, uname)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+func (h *VerificationHandler) Verify(c echo.Context) error {
+	h.log.Info("REQ: verify")
+	ctx := c.Request().Context()
+	tok := c.QueryParam("token")
+	if tok == "" {
+		errBody := dtos.NewErrDto("Missing verification token")
+		return c.JSON(http.StatusBadRequest, errBody)
+	}
+
+	rErr := h.VUsecase.Verify(ctx, tok)
+	if rErr != nil {
+		errBody := dtos.NewErrDto(rErr.Error())
+		return c.JSON(rErr.GetStatus(), errBody)
+	}
+
+	return c.JSON(http.StatusOK, dtos.NewBaseDto("Account activated"))
+}
@@ -0,0 +1,192 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+	"github.com/sicozz/papyrus/utils/token"
+)
+
+const verifySubject = `Papyrus account verification`
+
+type verificationUsecase struct {
+	userRepo       domain.UserRepository
+	userStateRepo  domain.UserStateRepository
+	tokenRepo      domain.UserTokenRepository
+	mailer         domain.MailSender
+	notifier       domain.Notifier
+	ttl            time.Duration
+	verifyURL      string
+	now            func() time.Time
+	contextTimeout time.Duration
+	log            utils.AggregatedLogger
+}
+
+// NewVerificationUsecase will create a new verificationUsecase object representation of domain.VerificationUsecase interface
+func NewVerificationUsecase(
+	ur domain.UserRepository,
+	usr domain.UserStateRepository,
+	tr domain.UserTokenRepository,
+	ms domain.MailSender,
+	n domain.Notifier,
+	ttl time.Duration,
+	verifyURL string,
+	timeout time.Duration,
+) domain.VerificationUsecase {
+	logger := utils.NewAggregatedLogger(constants.Usecase, constants.Verify)
+	return &verificationUsecase{
+		userRepo:       ur,
+		userStateRepo:  usr,
+		tokenRepo:      tr,
+		mailer:         ms,
+		notifier:       n,
+		ttl:            ttl,
+		verifyURL:      verifyURL,
+		now:            func() time.Time { return time.Now().UTC() },
+		contextTimeout: timeout,
+		log:            logger,
+	}
+}
+
+// getInactive fetches a user, refusing the ones that are already active
+func (u *verificationUsecase) getInactive(ctx context.Context, uname string) (res domain.User, rErr domain.RequestErr) {
+	res, err := u.userRepo.GetByUsername(ctx, uname)
+	if err != nil {
+		rErr = domain.NewRepoErr(err, fmt.Sprint("User not found. username: ", uname))
+		return
+	}
+
+	inactive, err := u.userStateRepo.GetByDescription(ctx, domain.UserStateInactive)
+	if err != nil {
+		u.log.Error("IN [getInactive]: could not get inactive state ->", err)
+		rErr = domain.NewUCaseErr(http.StatusInternalServerError, err)
+		return
+	}
+
+	if res.State.Code != inactive.Code {
+		err = errors.New("User is already verified")
+		rErr = domain.NewUCaseErr(http.StatusConflict, err)
+		return
+	}
+
+	return
+}
+
+func (u *verificationUsecase) activate(ctx context.Context, user domain.User) (rErr domain.RequestErr) {
+	active, err := u.userStateRepo.GetByDescription(ctx, domain.UserStateActive)
+	if err != nil {
+		u.log.Error("IN [activate]: could not get active state ->", err)
+		return domain.NewUCaseErr(http.StatusInternalServerError, err)
+	}
+
+	if err = u.userRepo.ChgState(ctx, user.Username, active, ""); err != nil {
+		u.log.Error("IN [activate]: could not activate {", user.Username, "} ->", err)
+		return domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Activation failed"))
+	}
+
+	if err = u.tokenRepo.DeleteByUser(ctx, user.Uuid, domain.TokenVerifyEmail); err != nil {
+		u.log.Warn("IN [activate]: could not delete verification tokens ->", err)
+	}
+
+	n := domain.NewActivationNotification(user, u.now())
+	if err = u.notifier.Notify(ctx, n); err != nil {
+		u.log.Warn("IN [activate]: could not notify {", user.Username, "} ->", err)
+	}
+
+	return
+}
+
+func (u *verificationUsecase) Send(c context.Context, uname string) (rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	user, rErr := u.getInactive(ctx, uname)
+	if rErr != nil {
+		return
+	}
+
+	// Only the latest verification link of a user is valid
+	if err := u.tokenRepo.DeleteByUser(ctx, user.Uuid, domain.TokenVerifyEmail); err != nil {
+		rErr = domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Verification failed"))
+		return
+	}
+
+	tok, err := token.New()
+	if err != nil {
+		u.log.Error("IN [Send]: could not generate token ->", err)
+		rErr = domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Verification failed"))
+		return
+	}
+
+	now := u.now()
+	t := domain.UserToken{
+		UserUuid:  user.Uuid,
+		Purpose:   domain.TokenVerifyEmail,
+		TokenHash: token.Hash(tok),
+		CreatedAt: now,
+		ExpiresAt: now.Add(u.ttl),
+	}
+	if err = u.tokenRepo.Store(ctx, &t); err != nil {
+		rErr = domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Verification failed"))
+		return
+	}
+
+	link := fmt.Sprint(u.verifyURL, "?token=", url.QueryEscape(tok))
+	body := fmt.Sprint(
+		"Hello ", user.Name, ",\n\n",
+		"Welcome to Papyrus. Activate your account with the following link before ",
+		t.ExpiresAt.Format(time.RFC1123), ":\n\n",
+		link, "\n",
+	)
+	if err = u.mailer.Send(ctx, user.Email, verifySubject, body); err != nil {
+		u.log.Error("IN [Send]: could not send verification mail ->", err)
+		rErr = domain.NewUCaseErr(http.StatusInternalServerError, errors.New("Verification mail delivery failed"))
+		return
+	}
+
+	return
+}
+
+func (u *verificationUsecase) Verify(c context.Context, tok string) (rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	t, err := u.tokenRepo.Consume(ctx, domain.TokenVerifyEmail, token.Hash(tok), u.now())
+	if errors.Is(err, domain.ErrNotFound) {
+		rErr = domain.NewUCaseErr(http.StatusUnauthorized, errors.New("Invalid or expired token"))
+		return
+	}
+	if err != nil {
+		u.log.Error("IN [Verify]: could not consume token ->", err)
+		rErr = domain.NewRepoErr(err, "")
+		return
+	}
+
+	user, err := u.userRepo.GetByUuid(ctx, t.UserUuid)
+	if err != nil {
+		rErr = domain.NewRepoErr(err, "User not found")
+		return
+	}
+
+	return u.activate(ctx, user)
+}
+
+func (u *verificationUsecase) Bypass(c context.Context, uname string) (rErr domain.RequestErr) {
+	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	defer cancel()
+
+	user, rErr := u.getInactive(ctx, uname)
+	if rErr != nil {
+		return
+	}
+
+	u.log.Info("IN [Bypass]: activating {", uname, "} without verification")
+	return u.activate(ctx, user)
+}
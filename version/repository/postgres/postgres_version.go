@@ -0,0 +1,196 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/sicozz/papyrus/domain"
+	"github.com/sicozz/papyrus/utils"
+	"github.com/sicozz/papyrus/utils/constants"
+	"github.com/sicozz/papyrus/utils/repository"
+)
+
+// versionSelect reads versions with the username of their uploader
+const versionSelect = `SELECT v.uuid, v.file, v.number, v.path, v.date, v.name, v.size,
+		v.mime_type, COALESCE(v.sha256, ''), COALESCE(v.user_::text, ''),
+		COALESCE(u.username, ''), v.note, COALESCE(v.restored_from, 0)
+	FROM version v
+	LEFT JOIN user_ u ON u.uuid = v.user_`
+
+type postgresVersionRepository struct {
+	Conn *sql.DB
+	log  utils.AggregatedLogger
+}
+
+// NewPostgresVersionRepository will create an object that represent the VersionRepository interface
+func NewPostgresVersionRepository(conn *sql.DB) domain.VersionRepository {
+	logger := utils.NewAggregatedLogger(constants.Repository, constants.Version)
+	return &postgresVersionRepository{conn, logger}
+}
+
+func (r *postgresVersionRepository) fetch(ctx context.Context, query string, args ...interface{}) (res []domain.FileVersion, err error) {
+	rows, err := r.Conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		r.log.Error(err)
+		return nil, repository.MapErr(err)
+	}
+
+	defer func() {
+		errRow := rows.Close()
+		if errRow != nil {
+			r.log.Error(errRow)
+		}
+	}()
+
+	res = make([]domain.FileVersion, 0)
+	for rows.Next() {
+		t := domain.FileVersion{}
+		err = rows.Scan(
+			&t.Uuid,
+			&t.File,
+			&t.Number,
+			&t.Path,
+			&t.Date,
+			&t.Name,
+			&t.Size,
+			&t.MimeType,
+			&t.Sha256,
+			&t.User,
+			&t.Username,
+			&t.Note,
+			&t.RestoredFrom,
+		)
+
+		if err != nil {
+			r.log.Error("IN [fetch]:", err)
+			return nil, err
+		}
+		res = append(res, t)
+	}
+
+	return res, rows.Err()
+}
+
+// Get the versions of a file, newest first
+func (r *postgresVersionRepository) GetByFile(ctx context.Context, file string) (res []domain.FileVersion, err error) {
+	query := versionSelect + ` WHERE v.file = $1 ORDER BY v.number DESC`
+
+	res, err = r.fetch(ctx, query, file)
+	if err != nil {
+		r.log.Error("IN [GetByFile]: could not fetch versions ->", err)
+	}
+
+	return
+}
+
+// Get a version of a file by its number
+func (r *postgresVersionRepository) GetByNumber(ctx context.Context, file string, number int) (res domain.FileVersion, err error) {
+	query := versionSelect + ` WHERE v.file = $1 AND v.number = $2`
+
+	versions, err := r.fetch(ctx, query, file, number)
+	if err != nil {
+		return domain.FileVersion{}, err
+	}
+
+	if len(versions) < 1 {
+		return domain.FileVersion{}, domain.ErrNotFound
+	}
+
+	res = versions[0]
+
+	return
+}
+
+/*
+* Store takes the next number of the file by bumping its head, which also
+* mirrors the new content and resets the workflow, then records the version
+* and its upload. The row lock of the update serializes concurrent uploads to
+* the same file
+ */
+func (r *postgresVersionRepository) Store(ctx context.Context, v *domain.FileVersion) (ok bool, err error) {
+	tx, err := r.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		r.log.Error("IN [Store]: could not begin transaction ->", err)
+		return
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	query :=
+		`UPDATE file SET
+			version = version + 1,
+			name = $2,
+			path = $3,
+			size = $4,
+			mime_type = $5,
+			sha256 = $6,
+			input_date = $7,
+			stage = (SELECT code FROM file_stage WHERE description = $8)
+		WHERE uuid = $1
+			AND state = (SELECT code FROM file_state WHERE description = $9)
+		RETURNING version`
+	err = tx.QueryRowContext(
+		ctx,
+		query,
+		v.File,
+		v.Name,
+		v.Path,
+		v.Size,
+		v.MimeType,
+		v.Sha256,
+		v.Date,
+		domain.FileStageLoaded,
+		domain.FileStateActive,
+	).Scan(&v.Number)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		r.log.Error("IN [Store]: could not bump file head ->", err)
+		return
+	}
+
+	query =
+		`INSERT INTO version (date, file, number, name, path, size, mime_type, sha256,
+			user_, note, restored_from)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NULLIF($11, 0))
+		RETURNING uuid`
+	err = tx.QueryRowContext(
+		ctx,
+		query,
+		v.Date,
+		v.File,
+		v.Number,
+		v.Name,
+		v.Path,
+		v.Size,
+		v.MimeType,
+		v.Sha256,
+		v.User,
+		v.Note,
+		v.RestoredFrom,
+	).Scan(&v.Uuid)
+	if err != nil {
+		r.log.Error("IN [Store]: could not store version ->", err)
+		return
+	}
+
+	// A restore shares an older blob, nothing was transferred
+	if v.RestoredFrom == 0 {
+		_, err = tx.ExecContext(
+			ctx,
+			`INSERT INTO upload (date, user_, file, version, bytes) VALUES ($1, $2, $3, $4, $5)`,
+			v.Date,
+			v.User,
+			v.File,
+			v.Number,
+			v.Size,
+		)
+		if err != nil {
+			r.log.Error("IN [Store]: could not log upload ->", err)
+			return
+		}
+	}
+
+	return true, tx.Commit()
+}